@@ -110,6 +110,17 @@ struct {
     __uint(pinning, LIBBPF_PIN_BY_NAME);
 } yadns_xdp_metrics SEC(".maps");
 
+// per name hit counters: only keys placed by controller
+// (e.g. response policy names) are counted, counters are
+// updated if bpf metrics enabled
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_HASH);
+    __type(key, struct dns_query);
+    __type(value, u64);
+    __uint(max_entries, 1048576);
+    __uint(pinning, LIBBPF_PIN_BY_NAME);
+} yadns_xdp_rr_hits SEC(".maps");
+
 struct {
     __uint(type, BPF_MAP_TYPE_PROG_ARRAY);
     __type(key, u32);
@@ -605,6 +616,17 @@ static uint32_t yadns_xdp_ttl(uint32_t ttl, uint16_t rid) {
     return ttl;
 }
 
+// counting hit of qname matched (if its key is placed
+// into hits map)
+static void yadns_xdp_rr_hit(struct dns_query* q) {
+    if (yadns_xdp_bpf_metrics_enabled) {
+        u64* hits = bpf_map_lookup_elem(&yadns_xdp_rr_hits, q);
+        if (hits != NULL) {
+            *hits += 1;
+        }
+    }
+}
+
 // matching qname for record a (should we have a general function to
 // match all types we interested in: A, AAAA, CNAME, NS? or just have
 // them all different
@@ -613,6 +635,7 @@ static int yadns_xdp_rr_a_match(struct xdp_md* ctx, struct dns_query* q, struct
     if (rr > 0) {
         a->ip_addr = rr->ip_addr;
         a->ttl = yadns_xdp_ttl(rr->ttl, rid);
+        yadns_xdp_rr_hit(q);
         return 0;
     }
     return -1;
@@ -623,6 +646,7 @@ static int yadns_xdp_rr_aaaa_match(struct xdp_md* ctx, struct dns_query* q, stru
     if (rr > 0) {
         a->ip_addr = rr->ip_addr;
         a->ttl = yadns_xdp_ttl(rr->ttl, rid);
+        yadns_xdp_rr_hit(q);
 
 #ifdef DEBUG
         bpf_printk("yadns_xdp: dns AAAA query found qname:'%s' qtype:'%i'", q->qname, q->qtype);
@@ -1,11 +1,12 @@
 module github.com/yandex/yadns-controller
 
-go 1.21
+go 1.22
 
 toolchain go1.22.5
 
 require (
 	github.com/cilium/ebpf v0.16.0
+	github.com/klauspost/compress v1.18.0
 	github.com/labstack/echo/v4 v4.12.0
 	github.com/miekg/dns v1.1.61
 	github.com/spf13/cobra v1.8.1
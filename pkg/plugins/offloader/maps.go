@@ -1,6 +1,7 @@
 package offloader
 
 import (
+	"errors"
 	"fmt"
 	"net"
 	"net/netip"
@@ -398,6 +399,106 @@ func (m *RRMapAAAA) update(qname RRQname, qtype uint16, ttl uint32,
 	return m.Mp.Update(key, value, flags)
 }
 
+/*
+struct {
+    __uint(type, BPF_MAP_TYPE_PERCPU_HASH);
+    __type(key, struct dns_query);
+    __type(value, u64);
+    __uint(max_entries, 1048576);
+    __uint(pinning, LIBBPF_PIN_BY_NAME);
+} yadns_xdp_rr_hits SEC(".maps");
+*/
+
+type RRHits interface {
+	MapName() string
+	LoadPinnedMap() error
+	Close() error
+
+	// registering key to be counted (keeping
+	// counters of key registered) and removing it
+	Register(qname RRQname, qtype uint16) error
+	Remove(qname RRQname, qtype uint16) error
+
+	// hits of key summed over all cpus
+	Hits(qname RRQname, qtype uint16) (uint64, error)
+
+	Keys() ([]RRKey, error)
+}
+
+type RRHitsMap struct {
+	Mp *ebpf.Map `ebpf:"yadns_xdp_rr_hits"`
+
+	PinPath string
+}
+
+func (m *RRHitsMap) MapName() string {
+	return "yadns_xdp_rr_hits"
+}
+
+func (m *RRHitsMap) LoadPinnedMap() error {
+	var err error
+	root := DefaultOffloaderPinPath
+	if len(m.PinPath) > 0 {
+		root = m.PinPath
+	}
+	path := filepath.Join(root, m.MapName())
+	m.Mp, err = ebpf.LoadPinnedMap(path, nil)
+	return err
+}
+
+func (m *RRHitsMap) Close() error {
+	return m.Mp.Close()
+}
+
+func (m *RRHitsMap) Register(qname RRQname, qtype uint16) error {
+	cpus, err := ebpf.PossibleCPU()
+	if err != nil {
+		return err
+	}
+
+	key := RRKey{Qtype: qtype, Qclass: DefaultClassIN, Qname: qname}
+	err = m.Mp.Update(key, make([]uint64, cpus), ebpf.UpdateNoExist)
+	if errors.Is(err, ebpf.ErrKeyExist) {
+		return nil
+	}
+	return err
+}
+
+func (m *RRHitsMap) Remove(qname RRQname, qtype uint16) error {
+	key := RRKey{Qtype: qtype, Qclass: DefaultClassIN, Qname: qname}
+	return m.Mp.Delete(key)
+}
+
+func (m *RRHitsMap) Hits(qname RRQname, qtype uint16) (uint64, error) {
+	var values []uint64
+	key := RRKey{Qtype: qtype, Qclass: DefaultClassIN, Qname: qname}
+	if err := m.Mp.Lookup(key, &values); err != nil {
+		return 0, err
+	}
+
+	var hits uint64
+	for _, v := range values {
+		hits += v
+	}
+	return hits, nil
+}
+
+func (m *RRHitsMap) Keys() ([]RRKey, error) {
+	out := make([]RRKey, 0)
+	var (
+		entries = m.Mp.Iterate()
+		key     RRKey
+		values  []uint64
+	)
+	for entries.Next(&key, &values) {
+		out = append(out, key)
+	}
+	if err := entries.Err(); err != nil {
+		return out, err
+	}
+	return out, nil
+}
+
 type IPNet struct {
 	IP   netip.Addr
 	Mask uint32
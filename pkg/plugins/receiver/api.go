@@ -1,15 +1,67 @@
 package receiver
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
+	"github.com/miekg/dns"
+
+	"github.com/yandex/yadns-controller/pkg/internal/api"
 )
 
 func (t *TReceiverPlugin) SetupMethods(group *echo.Group) {
 	// getting metrics from watcher worker
 	group.GET(fmt.Sprintf("/%s/metrics", NamePlugin), t.Metrics)
+
+	// secondary zones managment in runtime
+	group.GET(fmt.Sprintf("/%s/zones", NamePlugin), t.GetZones)
+	group.GET(fmt.Sprintf("/%s/zones/:zone", NamePlugin), t.GetZone)
+	group.POST(fmt.Sprintf("/%s/zones", NamePlugin), t.AddZone)
+	group.PATCH(fmt.Sprintf("/%s/zones/:zone", NamePlugin), t.PatchZone)
+	group.DELETE(fmt.Sprintf("/%s/zones/:zone", NamePlugin), t.RemoveZone)
+
+	// records skipped during import per zone
+	group.GET(fmt.Sprintf("/%s/zones/:zone/skipped", NamePlugin), t.GetZoneSkipped)
+
+	// zone snapshots generations, rolling back to
+	// generation pins zone till unpinned
+	group.GET(fmt.Sprintf("/%s/zones/:zone/snapshots", NamePlugin), t.GetZoneSnapshots)
+	group.POST(fmt.Sprintf("/%s/zones/:zone/snapshots/:generation/rollback", NamePlugin),
+		t.RollbackZone)
+	group.DELETE(fmt.Sprintf("/%s/zones/:zone/pin", NamePlugin), t.UnpinZone)
+
+	// zone diff between snapshots, zone files, live
+	// transfer and bpf maps
+	group.GET(fmt.Sprintf("/%s/zones/:zone/diff", NamePlugin), t.GetZoneDiff)
+
+	// bpf maps records browser, records are read and
+	// changed in maps directly (not via zones data)
+	group.GET(fmt.Sprintf("/%s/objects", NamePlugin), t.GetObjects)
+	group.GET(fmt.Sprintf("/%s/objects/:name/:type", NamePlugin), t.GetObject)
+	group.POST(fmt.Sprintf("/%s/objects", NamePlugin), t.CreateObjects)
+	group.DELETE(fmt.Sprintf("/%s/objects/:name/:type", NamePlugin), t.RemoveObject)
+	group.DELETE(fmt.Sprintf("/%s/objects", NamePlugin), t.CleanObjects)
+
+	// owners of maps entries and conflicts
+	group.GET(fmt.Sprintf("/%s/ownership/conflicts", NamePlugin), t.GetOwnershipConflicts)
+	group.GET(fmt.Sprintf("/%s/ownership/:name/:type", NamePlugin), t.GetOwnership)
+
+	// local records overriding zones data
+	group.GET(fmt.Sprintf("/%s/overrides", NamePlugin), t.GetOverrides)
+	group.POST(fmt.Sprintf("/%s/overrides", NamePlugin), t.AddOverride)
+	group.DELETE(fmt.Sprintf("/%s/overrides/:name/:type", NamePlugin), t.RemoveOverride)
+
+	// zones updates violating guards
+	group.GET(fmt.Sprintf("/%s/quarantine", NamePlugin), t.GetQuarantine)
+	group.POST(fmt.Sprintf("/%s/quarantine/:zone/approve", NamePlugin), t.ApproveQuarantine)
+	group.POST(fmt.Sprintf("/%s/quarantine/:zone/reject", NamePlugin), t.RejectQuarantine)
 }
 
 func (t *TReceiverPlugin) Metrics(ctx echo.Context) error {
@@ -25,3 +77,1355 @@ func (t *TReceiverPlugin) Metrics(ctx echo.Context) error {
 
 	return ctx.Blob(http.StatusOK, "application/json", content)
 }
+
+// request to add zone, zone is enabled if
+// enabled is not set explicitly
+type TZoneRequest struct {
+	Zone string `json:"zone"`
+
+	TZonePatch
+}
+
+func (t *TZoneRequest) AsJSON() []byte {
+	body, _ := json.MarshalIndent(t, "", "  ")
+	return body
+}
+
+func (t *TZoneRequest) Config() TConfigZone {
+	var config TConfigZone
+	config.Enabled = true
+	t.Apply(&config)
+	return config
+}
+
+// mapping zones state errors into http codes
+func ZonesHTTPError(err error) error {
+	code := http.StatusBadRequest
+	switch {
+	case errors.Is(err, ErrZoneNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, ErrZoneExists), errors.Is(err, ErrZoneNotRuntime):
+		code = http.StatusConflict
+	}
+	return echo.NewHTTPError(code, err.Error())
+}
+
+func (t *TReceiverPlugin) GetZones(ctx echo.Context) error {
+	id := "(receiver) (api) (zones)"
+
+	if t.zones == nil {
+		err := fmt.Errorf("zones state is not ready")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	zones := t.zones.GetZonesInfo()
+	t.G().L.Debugf("%s requested zones, found:'%d'", id, len(zones))
+
+	return ctx.JSONPretty(http.StatusOK, zones, "  ")
+}
+
+func (t *TReceiverPlugin) GetZone(ctx echo.Context) error {
+	id := "(receiver) (api) (zone)"
+
+	if t.zones == nil {
+		err := fmt.Errorf("zones state is not ready")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	zone, err := ZoneName(ctx.Param("zone"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	t.G().L.Debugf("%s requested zone:'%s'", id, zone)
+
+	info, err := t.zones.GetZoneInfo(zone)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+
+	return ctx.JSONPretty(http.StatusOK, info, "  ")
+}
+
+func (t *TReceiverPlugin) GetZoneSkipped(ctx echo.Context) error {
+	id := "(receiver) (api) (zone) (skipped)"
+
+	if t.zones == nil {
+		err := fmt.Errorf("zones state is not ready")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	zone, err := ZoneName(ctx.Param("zone"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	reason := ctx.QueryParam("reason")
+	t.G().L.Debugf("%s requested skipped records zone:'%s' reason:'%s'", id, zone, reason)
+
+	report, err := t.zones.GetSkipReport(zone, reason)
+	if err != nil {
+		return ZonesHTTPError(err)
+	}
+
+	return ctx.JSONPretty(http.StatusOK, report, "  ")
+}
+
+func (t *TReceiverPlugin) AddZone(ctx echo.Context) error {
+	id := "(receiver) (api) (zone) (add)"
+
+	if t.zones == nil {
+		err := fmt.Errorf("zones state is not ready")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	request := TZoneRequest{}
+	if err := ctx.Bind(&request); err != nil {
+		return err
+	}
+
+	zone, err := ZoneName(request.Zone)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	config := request.Config()
+	t.G().L.Debugf("%s request to add zone:'%s' as %s", id, zone, config.String())
+
+	if err = t.zones.AddRuntimeZone(zone, config); err != nil {
+		t.G().L.Errorf("%s error adding zone:'%s', err:'%s'", id, zone, err)
+		return ZonesHTTPError(err)
+	}
+
+	info, err := t.zones.GetZoneInfo(zone)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+
+	return ctx.JSONPretty(http.StatusCreated, info, "  ")
+}
+
+func (t *TReceiverPlugin) PatchZone(ctx echo.Context) error {
+	id := "(receiver) (api) (zone) (patch)"
+
+	if t.zones == nil {
+		err := fmt.Errorf("zones state is not ready")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	zone, err := ZoneName(ctx.Param("zone"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	patch := TZonePatch{}
+	if err := ctx.Bind(&patch); err != nil {
+		return err
+	}
+
+	config, err := t.zones.PatchRuntimeZone(zone, &patch)
+	if err != nil {
+		t.G().L.Errorf("%s error patching zone:'%s', err:'%s'", id, zone, err)
+		return ZonesHTTPError(err)
+	}
+	t.G().L.Debugf("%s zone:'%s' patched as %s", id, zone, config.String())
+
+	info, err := t.zones.GetZoneInfo(zone)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusNotFound, err.Error())
+	}
+
+	return ctx.JSONPretty(http.StatusOK, info, "  ")
+}
+
+func (t *TReceiverPlugin) RemoveZone(ctx echo.Context) error {
+	id := "(receiver) (api) (zone) (remove)"
+
+	if t.zones == nil {
+		err := fmt.Errorf("zones state is not ready")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	zone, err := ZoneName(ctx.Param("zone"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	t.G().L.Debugf("%s request to remove zone:'%s'", id, zone)
+
+	if err = t.zones.RemoveRuntimeZone(zone); err != nil {
+		t.G().L.Errorf("%s error removing zone:'%s', err:'%s'", id, zone, err)
+		return ZonesHTTPError(err)
+	}
+
+	return ctx.String(http.StatusOK, "OK")
+}
+
+// mapping generations errors into http codes
+func GenerationsHTTPError(err error) error {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrZoneNotFound), errors.Is(err, ErrGenerationNotFound),
+		errors.Is(err, ErrPinNotFound):
+		code = http.StatusNotFound
+	}
+	return echo.NewHTTPError(code, err.Error())
+}
+
+func (t *TReceiverPlugin) GetZoneSnapshots(ctx echo.Context) error {
+	id := "(receiver) (api) (zone) (snapshots)"
+
+	if t.zones == nil {
+		err := fmt.Errorf("zones state is not ready")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	zone, err := ZoneName(ctx.Param("zone"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	generations, err := t.zones.GetGenerations(zone)
+	if err != nil {
+		t.G().L.Errorf("%s error listing generations zone:'%s', err:'%s'", id, zone, err)
+		return GenerationsHTTPError(err)
+	}
+
+	t.G().L.Debugf("%s requested zone:'%s' generations, found:'%d'", id, zone,
+		len(generations))
+
+	return ctx.JSONPretty(http.StatusOK, generations, "  ")
+}
+
+func (t *TReceiverPlugin) RollbackZone(ctx echo.Context) error {
+	id := "(receiver) (api) (zone) (rollback)"
+
+	if t.zones == nil {
+		err := fmt.Errorf("zones state is not ready")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	zone, err := ZoneName(ctx.Param("zone"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	generation, err := strconv.ParseInt(ctx.Param("generation"), 10, 64)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	t.G().L.Debugf("%s request to rollback zone:'%s' to generation:'%d'", id, zone, generation)
+
+	result, err := t.zones.RollbackZone(ctx.Request().Context(), zone, generation)
+	if err != nil {
+		t.G().L.Errorf("%s error rolling back zone:'%s', err:'%s'", id, zone, err)
+		return GenerationsHTTPError(err)
+	}
+
+	return ctx.JSONPretty(http.StatusOK, result, "  ")
+}
+
+func (t *TReceiverPlugin) UnpinZone(ctx echo.Context) error {
+	id := "(receiver) (api) (zone) (unpin)"
+
+	if t.zones == nil {
+		err := fmt.Errorf("zones state is not ready")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	zone, err := ZoneName(ctx.Param("zone"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	t.G().L.Debugf("%s request to unpin zone:'%s'", id, zone)
+
+	if _, err = t.zones.UnpinZone(zone); err != nil {
+		t.G().L.Errorf("%s error unpinning zone:'%s', err:'%s'", id, zone, err)
+		return GenerationsHTTPError(err)
+	}
+
+	return ctx.String(http.StatusOK, "OK")
+}
+
+// mapping diff errors into http codes
+func DiffHTTPError(err error) error {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrDiffSource), errors.Is(err, ErrDiffFormat):
+		code = http.StatusBadRequest
+	case errors.Is(err, ErrZoneNotFound), errors.Is(err, ErrGenerationNotFound),
+		errors.Is(err, os.ErrNotExist):
+		code = http.StatusNotFound
+	}
+	return echo.NewHTTPError(code, err.Error())
+}
+
+func (t *TReceiverPlugin) GetZoneDiff(ctx echo.Context) error {
+	id := "(receiver) (api) (zone) (diff)"
+
+	if t.zones == nil {
+		err := fmt.Errorf("zones state is not ready")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	zone, err := ZoneName(ctx.Param("zone"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	from := ctx.QueryParam("from")
+	to := ctx.QueryParam("to")
+
+	format := ctx.QueryParam("format")
+	if len(format) == 0 {
+		format = DiffFormatJSON
+	}
+	if err = ValidDiffFormat(format); err != nil {
+		return DiffHTTPError(err)
+	}
+
+	t.G().L.Debugf("%s request to diff zone:'%s' from:'%s' to:'%s' format:'%s'", id,
+		zone, from, to, format)
+
+	diff, err := t.zones.DiffZone(ctx.Request().Context(), zone, from, to)
+	if err != nil {
+		t.G().L.Errorf("%s error making diff zone:'%s', err:'%s'", id, zone, err)
+		return DiffHTTPError(err)
+	}
+
+	if format == DiffFormatJSON {
+		return ctx.JSONPretty(http.StatusOK, diff, "  ")
+	}
+
+	out, err := diff.Format(format)
+	if err != nil {
+		return DiffHTTPError(err)
+	}
+
+	return ctx.String(http.StatusOK, out)
+}
+
+// page of records in maps, records are in zone
+// file format
+type TObjectsPage struct {
+	Total   int      `json:"total"`
+	Offset  int      `json:"offset"`
+	Count   int      `json:"count"`
+	Records []string `json:"records"`
+}
+
+// request to create records in maps
+type TObjectsRequest struct {
+	Records []string `json:"records"`
+	Dryrun  bool     `json:"dryrun"`
+}
+
+func (t *TObjectsRequest) AsJSON() []byte {
+	body, _ := json.MarshalIndent(t, "", "  ")
+	return body
+}
+
+// mapping objects errors into http codes
+func ObjectsHTTPError(err error) error {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrObjectFilter):
+		code = http.StatusBadRequest
+	case errors.Is(err, ErrObjectNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, ErrObjectExists):
+		code = http.StatusConflict
+	}
+	return echo.NewHTTPError(code, err.Error())
+}
+
+// getting objects filter from query "filter" (could
+// be repeated), "offset" and "count"
+func ObjectsFilter(ctx echo.Context) (TObjectFilter, error) {
+	var filter TObjectFilter
+	var err error
+
+	filter.Names = ctx.QueryParams()["filter"]
+
+	if v := ctx.QueryParam("offset"); len(v) > 0 {
+		if filter.Offset, err = strconv.Atoi(v); err != nil {
+			return filter, fmt.Errorf("%w: offset:'%s'", ErrObjectFilter, v)
+		}
+	}
+	if v := ctx.QueryParam("count"); len(v) > 0 {
+		if filter.Count, err = strconv.Atoi(v); err != nil {
+			return filter, fmt.Errorf("%w: count:'%s'", ErrObjectFilter, v)
+		}
+	}
+
+	return filter, filter.Validate()
+}
+
+// getting record type from string, maps keep only
+// A and AAAA records
+func ObjectType(qtype string) (uint16, error) {
+	t, ok := dns.StringToType[strings.ToUpper(qtype)]
+	if !ok || (t != dns.TypeA && t != dns.TypeAAAA) {
+		return 0, fmt.Errorf("type:'%s' expected one of ['A,AAAA']", qtype)
+	}
+	return t, nil
+}
+
+func (t *TReceiverPlugin) GetObjects(ctx echo.Context) error {
+	id := "(receiver) (api) (objects)"
+
+	filter, err := ObjectsFilter(ctx)
+	if err != nil {
+		return ObjectsHTTPError(err)
+	}
+
+	obj := NewObjects(t)
+	obj.Filter = filter
+
+	rrs, total, err := obj.ListPageRR()
+	if err != nil {
+		t.G().L.Errorf("%s error listing objects, err:'%s'", id, err)
+		return ObjectsHTTPError(err)
+	}
+
+	page := TObjectsPage{Total: total, Offset: filter.Offset, Count: len(rrs),
+		Records: []string{}}
+	for _, rr := range rrs {
+		page.Records = append(page.Records, rr.String())
+	}
+
+	t.G().L.Debugf("%s requested objects filter:['%s'] offset:'%d' count:'%d', found:'%d' total:'%d'",
+		id, strings.Join(filter.Names, ","), filter.Offset, filter.Count, len(rrs), total)
+
+	return ctx.JSONPretty(http.StatusOK, page, "  ")
+}
+
+func (t *TReceiverPlugin) GetObject(ctx echo.Context) error {
+	id := "(receiver) (api) (object)"
+
+	name := ctx.Param("name")
+	qtype, err := ObjectType(ctx.Param("type"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	rr, err := NewObjects(t).GetRR(name, qtype)
+	if err != nil {
+		t.G().L.Errorf("%s error getting object name:'%s', err:'%s'", id, name, err)
+		return ObjectsHTTPError(err)
+	}
+
+	page := TObjectsPage{Total: 1, Count: 1, Records: []string{rr.String()}}
+	return ctx.JSONPretty(http.StatusOK, page, "  ")
+}
+
+func (t *TReceiverPlugin) CreateObjects(ctx echo.Context) error {
+	id := "(receiver) (api) (objects) (create)"
+
+	var request TObjectsRequest
+	if err := ctx.Bind(&request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	var rrs []dns.RR
+	for _, raw := range request.Records {
+		rr, err := dns.NewRR(raw)
+		if err != nil || rr == nil {
+			err = fmt.Errorf("record:'%s' could not be parsed, err:'%v'", raw, err)
+			return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+		}
+		rrs = append(rrs, rr)
+	}
+
+	t.G().L.Debugf("%s request to create objects count:'%d' dryrun:'%t'", id,
+		len(rrs), request.Dryrun)
+
+	obj := NewObjects(t)
+	obj.Dryrun = request.Dryrun
+
+	result, err := obj.ImportRR(rrs)
+	if err != nil {
+		t.G().L.Errorf("%s error creating objects, err:'%s'", id, err)
+		return ObjectsHTTPError(err)
+	}
+
+	// records created are owned as manual ones
+	if !obj.Dryrun {
+		var owned []dns.RR
+		for _, rr := range rrs {
+			if _, err := ObjectType(dns.TypeToString[rr.Header().Rrtype]); err == nil {
+				owned = append(owned, rr)
+			}
+		}
+		if err = t.Owners().Claim(owned); err != nil {
+			t.G().L.Errorf("%s error claiming objects, err:'%s'", id, err)
+		}
+	}
+
+	return ctx.JSONPretty(http.StatusOK, result, "  ")
+}
+
+func (t *TReceiverPlugin) RemoveObject(ctx echo.Context) error {
+	id := "(receiver) (api) (object) (remove)"
+
+	name := ctx.Param("name")
+	qtype, err := ObjectType(ctx.Param("type"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	obj := NewObjects(t)
+	obj.Dryrun = ctx.QueryParam("dryrun") == "true"
+
+	t.G().L.Debugf("%s request to remove object name:'%s' type:'%s' dryrun:'%t'", id,
+		name, dns.TypeToString[qtype], obj.Dryrun)
+
+	if err = obj.RemoveNameRR(name, qtype); err != nil {
+		t.G().L.Errorf("%s error removing object name:'%s', err:'%s'", id, name, err)
+		return ObjectsHTTPError(err)
+	}
+
+	if !obj.Dryrun {
+		key := OverrideKey(name, dns.TypeToString[qtype])
+		if err = t.Owners().Release([]string{key}); err != nil {
+			t.G().L.Errorf("%s error releasing object name:'%s', err:'%s'", id, name, err)
+		}
+	}
+
+	result := TObjectsResult{Removed: 1, Dryrun: obj.Dryrun}
+	return ctx.JSONPretty(http.StatusOK, result, "  ")
+}
+
+func (t *TReceiverPlugin) CleanObjects(ctx echo.Context) error {
+	id := "(receiver) (api) (objects) (clean)"
+
+	filter, err := ObjectsFilter(ctx)
+	if err != nil {
+		return ObjectsHTTPError(err)
+	}
+
+	if err = filter.ValidateClean(); err != nil {
+		return ObjectsHTTPError(err)
+	}
+
+	// cleaning all records requires explicit "all"
+	if len(filter.Names) == 0 && ctx.QueryParam("all") != "true" {
+		err := fmt.Errorf("%w: filter is not set", ErrObjectFilter)
+		return ObjectsHTTPError(err)
+	}
+
+	obj := NewObjects(t)
+	obj.Filter = filter
+	obj.Dryrun = ctx.QueryParam("dryrun") == "true"
+
+	t.G().L.Debugf("%s request to clean objects filter:['%s'] dryrun:'%t'", id,
+		strings.Join(filter.Names, ","), obj.Dryrun)
+
+	// records matched are released by manual owner
+	rrs, err := obj.ListRR()
+	if err != nil {
+		t.G().L.Errorf("%s error listing objects, err:'%s'", id, err)
+		return ObjectsHTTPError(err)
+	}
+
+	removed, err := obj.CleanRR()
+	if err != nil {
+		t.G().L.Errorf("%s error cleaning objects, err:'%s'", id, err)
+		return ObjectsHTTPError(err)
+	}
+
+	if !obj.Dryrun {
+		var keys []string
+		for _, rr := range rrs {
+			keys = append(keys, RecordKey(rr))
+		}
+		if err = t.Owners().Release(keys); err != nil {
+			t.G().L.Errorf("%s error releasing objects, err:'%s'", id, err)
+		}
+	}
+
+	result := TObjectsResult{Removed: removed, Dryrun: obj.Dryrun}
+	return ctx.JSONPretty(http.StatusOK, result, "  ")
+}
+
+// mapping ownership errors into http codes
+func OwnershipHTTPError(err error) error {
+	code := http.StatusInternalServerError
+	if errors.Is(err, ErrOwnerNotFound) {
+		code = http.StatusNotFound
+	}
+	return echo.NewHTTPError(code, err.Error())
+}
+
+func (t *TReceiverPlugin) GetOwnership(ctx echo.Context) error {
+	id := "(receiver) (api) (ownership)"
+
+	if t.zones == nil {
+		err := fmt.Errorf("zones state is not ready")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	name := ctx.Param("name")
+	qtype, err := ObjectType(ctx.Param("type"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	ownership, err := t.Owners().Get(OverrideKey(name, dns.TypeToString[qtype]))
+	if err != nil {
+		t.G().L.Debugf("%s error getting owner name:'%s', err:'%s'", id, name, err)
+		return OwnershipHTTPError(err)
+	}
+
+	return ctx.JSONPretty(http.StatusOK, ownership, "  ")
+}
+
+func (t *TReceiverPlugin) GetOwnershipConflicts(ctx echo.Context) error {
+	id := "(receiver) (api) (ownership) (conflicts)"
+
+	if t.zones == nil {
+		err := fmt.Errorf("zones state is not ready")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	conflicts := t.Owners().Conflicts()
+	t.G().L.Debugf("%s requested conflicts, found:'%d'", id, len(conflicts))
+
+	return ctx.JSONPretty(http.StatusOK, conflicts, "  ")
+}
+
+// request to add override, expire is override
+// lifetime in seconds (0 is never)
+type TOverrideRequest struct {
+	Record  string `json:"record"`
+	Expire  int    `json:"expire"`
+	Comment string `json:"comment"`
+}
+
+func (t *TOverrideRequest) AsJSON() []byte {
+	body, _ := json.MarshalIndent(t, "", "  ")
+	return body
+}
+
+func (t *TReceiverPlugin) GetOverrides(ctx echo.Context) error {
+	id := "(receiver) (api) (overrides)"
+
+	if t.zones == nil {
+		err := fmt.Errorf("zones state is not ready")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	overrides := t.zones.overrides.List()
+	t.G().L.Debugf("%s requested overrides, found:'%d'", id, len(overrides))
+
+	return ctx.JSONPretty(http.StatusOK, overrides, "  ")
+}
+
+func (t *TReceiverPlugin) AddOverride(ctx echo.Context) error {
+	id := "(receiver) (api) (override) (add)"
+
+	if t.zones == nil {
+		err := fmt.Errorf("zones state is not ready")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	request := TOverrideRequest{}
+	if err := ctx.Bind(&request); err != nil {
+		return err
+	}
+
+	override, err := NewOverride(request.Record, request.Expire, request.Comment)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	t.G().L.Debugf("%s request to override:'%s' as %s", id, override.Key(),
+		override.RR().String())
+
+	if err = t.zones.SetOverride(override); err != nil {
+		t.G().L.Errorf("%s error overriding:'%s', err:'%s'", id, override.Key(), err)
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return ctx.JSONPretty(http.StatusCreated, override, "  ")
+}
+
+func (t *TReceiverPlugin) RemoveOverride(ctx echo.Context) error {
+	id := "(receiver) (api) (override) (remove)"
+
+	if t.zones == nil {
+		err := fmt.Errorf("zones state is not ready")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	name, qtype := ctx.Param("name"), ctx.Param("type")
+	t.G().L.Debugf("%s request to remove override name:'%s' type:'%s'", id, name, qtype)
+
+	if _, err := t.zones.RemoveOverride(name, qtype); err != nil {
+		t.G().L.Errorf("%s error removing override name:'%s' type:'%s', err:'%s'", id,
+			name, qtype, err)
+		code := http.StatusInternalServerError
+		if errors.Is(err, ErrOverrideNotFound) {
+			code = http.StatusNotFound
+		}
+		return echo.NewHTTPError(code, err.Error())
+	}
+
+	return ctx.String(http.StatusOK, "OK")
+}
+
+func (t *TReceiverPlugin) GetQuarantine(ctx echo.Context) error {
+	id := "(receiver) (api) (quarantine)"
+
+	if t.zones == nil {
+		err := fmt.Errorf("zones state is not ready")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	quarantine := t.zones.quarantine.List()
+	t.G().L.Debugf("%s requested quarantine, found:'%d'", id, len(quarantine))
+
+	return ctx.JSONPretty(http.StatusOK, quarantine, "  ")
+}
+
+func QuarantineHTTPError(err error) error {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrQuarantineNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, ErrZonePinned):
+		code = http.StatusConflict
+	}
+	return echo.NewHTTPError(code, err.Error())
+}
+
+func (t *TReceiverPlugin) ApproveQuarantine(ctx echo.Context) error {
+	id := "(receiver) (api) (quarantine) (approve)"
+
+	if t.zones == nil {
+		err := fmt.Errorf("zones state is not ready")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	zone := ctx.Param("zone")
+	t.G().L.Debugf("%s request to approve zone:'%s'", id, zone)
+
+	result, err := t.zones.ApproveQuarantine(ctx.Request().Context(), zone)
+	if err != nil {
+		t.G().L.Errorf("%s error approving zone:'%s', err:'%s'", id, zone, err)
+		return QuarantineHTTPError(err)
+	}
+
+	return ctx.JSONPretty(http.StatusOK, result, "  ")
+}
+
+func (t *TReceiverPlugin) RejectQuarantine(ctx echo.Context) error {
+	id := "(receiver) (api) (quarantine) (reject)"
+
+	if t.zones == nil {
+		err := fmt.Errorf("zones state is not ready")
+		return echo.NewHTTPError(http.StatusServiceUnavailable, err.Error())
+	}
+
+	zone := ctx.Param("zone")
+	t.G().L.Debugf("%s request to reject zone:'%s'", id, zone)
+
+	if err := t.zones.RejectQuarantine(zone); err != nil {
+		t.G().L.Errorf("%s error rejecting zone:'%s', err:'%s'", id, zone, err)
+		return QuarantineHTTPError(err)
+	}
+
+	return ctx.String(http.StatusOK, "OK")
+}
+
+// getting error message from api response
+func ClientError(code int, content []byte) error {
+	var message struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(content, &message); err == nil && len(message.Message) > 0 {
+		return fmt.Errorf("http error '%s', message:'%s'", http.StatusText(code),
+			message.Message)
+	}
+	return fmt.Errorf("http error '%s'", http.StatusText(code))
+}
+
+func (t *TReceiverPlugin) GetClientZones(zone string) ([]*TZoneInfo, error) {
+	id := "(receiver) (client) (zones)"
+
+	client := api.NewClient(t.G())
+
+	url := fmt.Sprintf("%s/zones", NamePlugin)
+	if len(zone) > 0 {
+		url = fmt.Sprintf("%s/zones/%s", NamePlugin, zone)
+	}
+
+	resp, code, err := client.Request(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	t.G().L.DumpBytes(id, resp, 0)
+
+	if code != http.StatusOK {
+		err := ClientError(code, resp)
+		t.G().L.Errorf("%s request error, err:'%s'", id, err)
+		return nil, err
+	}
+
+	var zones []*TZoneInfo
+	if len(zone) > 0 {
+		var info TZoneInfo
+		if err = json.Unmarshal(resp, &info); err != nil {
+			return nil, err
+		}
+		zones = append(zones, &info)
+		return zones, nil
+	}
+
+	if err = json.Unmarshal(resp, &zones); err != nil {
+		return nil, err
+	}
+
+	return zones, nil
+}
+
+func (t *TReceiverPlugin) AddClientZone(request *TZoneRequest) (*TZoneInfo, error) {
+	id := "(receiver) (client) (zone) (add)"
+
+	client := api.NewClient(t.G())
+
+	resp, code, err := client.Request(http.MethodPost, fmt.Sprintf("%s/zones",
+		NamePlugin), request.AsJSON())
+	if err != nil {
+		return nil, err
+	}
+	t.G().L.DumpBytes(id, resp, 0)
+
+	if code != http.StatusCreated {
+		err := ClientError(code, resp)
+		t.G().L.Errorf("%s request error, err:'%s'", id, err)
+		return nil, err
+	}
+
+	var info TZoneInfo
+	if err = json.Unmarshal(resp, &info); err != nil {
+		return nil, err
+	}
+
+	return &info, nil
+}
+
+func (t *TReceiverPlugin) RemoveClientZone(zone string) error {
+	id := "(receiver) (client) (zone) (remove)"
+
+	client := api.NewClient(t.G())
+
+	resp, code, err := client.Request(http.MethodDelete, fmt.Sprintf("%s/zones/%s",
+		NamePlugin, zone), nil)
+	if err != nil {
+		return err
+	}
+	t.G().L.DumpBytes(id, resp, 0)
+
+	if code != http.StatusOK {
+		err := ClientError(code, resp)
+		t.G().L.Errorf("%s request error, err:'%s'", id, err)
+		return err
+	}
+
+	return nil
+}
+
+func (t *TReceiverPlugin) GetClientZoneSkipped(zone string, reason string) (*TSkipReport, error) {
+	id := "(receiver) (client) (zone) (skipped)"
+
+	client := api.NewClient(t.G())
+
+	url := fmt.Sprintf("%s/zones/%s/skipped", NamePlugin, zone)
+	if len(reason) > 0 {
+		url = fmt.Sprintf("%s?reason=%s", url, reason)
+	}
+
+	resp, code, err := client.Request(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	t.G().L.DumpBytes(id, resp, 0)
+
+	if code != http.StatusOK {
+		err := ClientError(code, resp)
+		t.G().L.Errorf("%s request error, err:'%s'", id, err)
+		return nil, err
+	}
+
+	var report TSkipReport
+	if err = json.Unmarshal(resp, &report); err != nil {
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+func (t *TReceiverPlugin) GetClientOverrides() ([]TOverride, error) {
+	id := "(receiver) (client) (overrides)"
+
+	client := api.NewClient(t.G())
+
+	resp, code, err := client.Request(http.MethodGet, fmt.Sprintf("%s/overrides",
+		NamePlugin), nil)
+	if err != nil {
+		return nil, err
+	}
+	t.G().L.DumpBytes(id, resp, 0)
+
+	if code != http.StatusOK {
+		err := ClientError(code, resp)
+		t.G().L.Errorf("%s request error, err:'%s'", id, err)
+		return nil, err
+	}
+
+	var overrides []TOverride
+	if err = json.Unmarshal(resp, &overrides); err != nil {
+		return nil, err
+	}
+
+	return overrides, nil
+}
+
+func (t *TReceiverPlugin) AddClientOverride(request *TOverrideRequest) (*TOverride, error) {
+	id := "(receiver) (client) (override) (add)"
+
+	client := api.NewClient(t.G())
+
+	resp, code, err := client.Request(http.MethodPost, fmt.Sprintf("%s/overrides",
+		NamePlugin), request.AsJSON())
+	if err != nil {
+		return nil, err
+	}
+	t.G().L.DumpBytes(id, resp, 0)
+
+	if code != http.StatusCreated {
+		err := ClientError(code, resp)
+		t.G().L.Errorf("%s request error, err:'%s'", id, err)
+		return nil, err
+	}
+
+	var override TOverride
+	if err = json.Unmarshal(resp, &override); err != nil {
+		return nil, err
+	}
+
+	return &override, nil
+}
+
+func (t *TReceiverPlugin) RemoveClientOverride(name string, qtype string) error {
+	id := "(receiver) (client) (override) (remove)"
+
+	client := api.NewClient(t.G())
+
+	resp, code, err := client.Request(http.MethodDelete, fmt.Sprintf("%s/overrides/%s/%s",
+		NamePlugin, Dot(name), strings.ToUpper(qtype)), nil)
+	if err != nil {
+		return err
+	}
+	t.G().L.DumpBytes(id, resp, 0)
+
+	if code != http.StatusOK {
+		err := ClientError(code, resp)
+		t.G().L.Errorf("%s request error, err:'%s'", id, err)
+		return err
+	}
+
+	return nil
+}
+
+func (t *TReceiverPlugin) GetClientQuarantine() ([]TQuarantine, error) {
+	id := "(receiver) (client) (quarantine)"
+
+	client := api.NewClient(t.G())
+
+	resp, code, err := client.Request(http.MethodGet, fmt.Sprintf("%s/quarantine",
+		NamePlugin), nil)
+	if err != nil {
+		return nil, err
+	}
+	t.G().L.DumpBytes(id, resp, 0)
+
+	if code != http.StatusOK {
+		err := ClientError(code, resp)
+		t.G().L.Errorf("%s request error, err:'%s'", id, err)
+		return nil, err
+	}
+
+	var quarantine []TQuarantine
+	if err = json.Unmarshal(resp, &quarantine); err != nil {
+		return nil, err
+	}
+
+	return quarantine, nil
+}
+
+func (t *TReceiverPlugin) ApproveClientQuarantine(zone string) (*TSyncMapResult, error) {
+	id := "(receiver) (client) (quarantine) (approve)"
+
+	client := api.NewClient(t.G())
+
+	resp, code, err := client.Request(http.MethodPost, fmt.Sprintf("%s/quarantine/%s/approve",
+		NamePlugin, zone), nil)
+	if err != nil {
+		return nil, err
+	}
+	t.G().L.DumpBytes(id, resp, 0)
+
+	if code != http.StatusOK {
+		err := ClientError(code, resp)
+		t.G().L.Errorf("%s request error, err:'%s'", id, err)
+		return nil, err
+	}
+
+	var result TSyncMapResult
+	if err = json.Unmarshal(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (t *TReceiverPlugin) RejectClientQuarantine(zone string) error {
+	id := "(receiver) (client) (quarantine) (reject)"
+
+	client := api.NewClient(t.G())
+
+	resp, code, err := client.Request(http.MethodPost, fmt.Sprintf("%s/quarantine/%s/reject",
+		NamePlugin, zone), nil)
+	if err != nil {
+		return err
+	}
+	t.G().L.DumpBytes(id, resp, 0)
+
+	if code != http.StatusOK {
+		err := ClientError(code, resp)
+		t.G().L.Errorf("%s request error, err:'%s'", id, err)
+		return err
+	}
+
+	return nil
+}
+
+func (t *TReceiverPlugin) GetClientZoneSnapshots(zone string) ([]TSnapshotGeneration, error) {
+	id := "(receiver) (client) (zone) (snapshots)"
+
+	client := api.NewClient(t.G())
+
+	resp, code, err := client.Request(http.MethodGet, fmt.Sprintf("%s/zones/%s/snapshots",
+		NamePlugin, zone), nil)
+	if err != nil {
+		return nil, err
+	}
+	t.G().L.DumpBytes(id, resp, 0)
+
+	if code != http.StatusOK {
+		err := ClientError(code, resp)
+		t.G().L.Errorf("%s request error, err:'%s'", id, err)
+		return nil, err
+	}
+
+	var generations []TSnapshotGeneration
+	if err = json.Unmarshal(resp, &generations); err != nil {
+		return nil, err
+	}
+
+	return generations, nil
+}
+
+func (t *TReceiverPlugin) RollbackClientZone(zone string, generation int64) (*TSyncMapResult, error) {
+	id := "(receiver) (client) (zone) (rollback)"
+
+	client := api.NewClient(t.G())
+
+	resp, code, err := client.Request(http.MethodPost, fmt.Sprintf("%s/zones/%s/snapshots/%d/rollback",
+		NamePlugin, zone, generation), nil)
+	if err != nil {
+		return nil, err
+	}
+	t.G().L.DumpBytes(id, resp, 0)
+
+	if code != http.StatusOK {
+		err := ClientError(code, resp)
+		t.G().L.Errorf("%s request error, err:'%s'", id, err)
+		return nil, err
+	}
+
+	var result TSyncMapResult
+	if err = json.Unmarshal(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (t *TReceiverPlugin) UnpinClientZone(zone string) error {
+	id := "(receiver) (client) (zone) (unpin)"
+
+	client := api.NewClient(t.G())
+
+	resp, code, err := client.Request(http.MethodDelete, fmt.Sprintf("%s/zones/%s/pin",
+		NamePlugin, zone), nil)
+	if err != nil {
+		return err
+	}
+	t.G().L.DumpBytes(id, resp, 0)
+
+	if code != http.StatusOK {
+		err := ClientError(code, resp)
+		t.G().L.Errorf("%s request error, err:'%s'", id, err)
+		return err
+	}
+
+	return nil
+}
+
+func (t *TReceiverPlugin) GetClientZoneDiff(zone string, from string, to string) (*TZoneDiff, error) {
+	id := "(receiver) (client) (zone) (diff)"
+
+	client := api.NewClient(t.G())
+
+	query := url.Values{}
+	query.Set("from", from)
+	query.Set("to", to)
+	query.Set("format", DiffFormatJSON)
+
+	resp, code, err := client.Request(http.MethodGet, fmt.Sprintf("%s/zones/%s/diff?%s",
+		NamePlugin, zone, query.Encode()), nil)
+	if err != nil {
+		return nil, err
+	}
+	t.G().L.DumpBytes(id, resp, 0)
+
+	if code != http.StatusOK {
+		err := ClientError(code, resp)
+		t.G().L.Errorf("%s request error, err:'%s'", id, err)
+		return nil, err
+	}
+
+	var diff TZoneDiff
+	if err = json.Unmarshal(resp, &diff); err != nil {
+		return nil, err
+	}
+
+	return &diff, nil
+}
+
+// objects request query: filters, paging and flags
+func objectsQuery(filter *TObjectFilter, flags map[string]bool) string {
+	query := url.Values{}
+	if filter != nil {
+		for _, f := range filter.Names {
+			query.Add("filter", f)
+		}
+		if filter.Offset > 0 {
+			query.Set("offset", strconv.Itoa(filter.Offset))
+		}
+		if filter.Count > 0 {
+			query.Set("count", strconv.Itoa(filter.Count))
+		}
+	}
+	for k, v := range flags {
+		if v {
+			query.Set(k, "true")
+		}
+	}
+	if len(query) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("?%s", query.Encode())
+}
+
+func (t *TReceiverPlugin) GetClientObjects(filter *TObjectFilter) (*TObjectsPage, error) {
+	id := "(receiver) (client) (objects)"
+
+	client := api.NewClient(t.G())
+
+	resp, code, err := client.Request(http.MethodGet, fmt.Sprintf("%s/objects%s",
+		NamePlugin, objectsQuery(filter, nil)), nil)
+	if err != nil {
+		return nil, err
+	}
+	t.G().L.DumpBytes(id, resp, 0)
+
+	if code != http.StatusOK {
+		err := ClientError(code, resp)
+		t.G().L.Errorf("%s request error, err:'%s'", id, err)
+		return nil, err
+	}
+
+	var page TObjectsPage
+	if err = json.Unmarshal(resp, &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+func (t *TReceiverPlugin) GetClientObject(name string, qtype string) (*TObjectsPage, error) {
+	id := "(receiver) (client) (object)"
+
+	client := api.NewClient(t.G())
+
+	resp, code, err := client.Request(http.MethodGet, fmt.Sprintf("%s/objects/%s/%s",
+		NamePlugin, name, qtype), nil)
+	if err != nil {
+		return nil, err
+	}
+	t.G().L.DumpBytes(id, resp, 0)
+
+	if code != http.StatusOK {
+		err := ClientError(code, resp)
+		t.G().L.Errorf("%s request error, err:'%s'", id, err)
+		return nil, err
+	}
+
+	var page TObjectsPage
+	if err = json.Unmarshal(resp, &page); err != nil {
+		return nil, err
+	}
+
+	return &page, nil
+}
+
+func (t *TReceiverPlugin) GetClientOwnership(name string, qtype string) (*TOwnership, error) {
+	id := "(receiver) (client) (ownership)"
+
+	client := api.NewClient(t.G())
+
+	resp, code, err := client.Request(http.MethodGet, fmt.Sprintf("%s/ownership/%s/%s",
+		NamePlugin, name, qtype), nil)
+	if err != nil {
+		return nil, err
+	}
+	t.G().L.DumpBytes(id, resp, 0)
+
+	if code != http.StatusOK {
+		err := ClientError(code, resp)
+		t.G().L.Errorf("%s request error, err:'%s'", id, err)
+		return nil, err
+	}
+
+	var ownership TOwnership
+	if err = json.Unmarshal(resp, &ownership); err != nil {
+		return nil, err
+	}
+
+	return &ownership, nil
+}
+
+func (t *TReceiverPlugin) GetClientOwnershipConflicts() ([]TOwnership, error) {
+	id := "(receiver) (client) (ownership) (conflicts)"
+
+	client := api.NewClient(t.G())
+
+	resp, code, err := client.Request(http.MethodGet, fmt.Sprintf("%s/ownership/conflicts",
+		NamePlugin), nil)
+	if err != nil {
+		return nil, err
+	}
+	t.G().L.DumpBytes(id, resp, 0)
+
+	if code != http.StatusOK {
+		err := ClientError(code, resp)
+		t.G().L.Errorf("%s request error, err:'%s'", id, err)
+		return nil, err
+	}
+
+	var conflicts []TOwnership
+	if err = json.Unmarshal(resp, &conflicts); err != nil {
+		return nil, err
+	}
+
+	return conflicts, nil
+}
+
+func (t *TReceiverPlugin) CreateClientObjects(request *TObjectsRequest) (*TObjectsResult, error) {
+	id := "(receiver) (client) (objects) (create)"
+
+	client := api.NewClient(t.G())
+
+	resp, code, err := client.Request(http.MethodPost, fmt.Sprintf("%s/objects", NamePlugin),
+		request.AsJSON())
+	if err != nil {
+		return nil, err
+	}
+	t.G().L.DumpBytes(id, resp, 0)
+
+	if code != http.StatusOK {
+		err := ClientError(code, resp)
+		t.G().L.Errorf("%s request error, err:'%s'", id, err)
+		return nil, err
+	}
+
+	var result TObjectsResult
+	if err = json.Unmarshal(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (t *TReceiverPlugin) RemoveClientObject(name string, qtype string,
+	dryrun bool) (*TObjectsResult, error) {
+
+	id := "(receiver) (client) (object) (remove)"
+
+	client := api.NewClient(t.G())
+
+	resp, code, err := client.Request(http.MethodDelete, fmt.Sprintf("%s/objects/%s/%s%s",
+		NamePlugin, name, qtype, objectsQuery(nil, map[string]bool{"dryrun": dryrun})), nil)
+	if err != nil {
+		return nil, err
+	}
+	t.G().L.DumpBytes(id, resp, 0)
+
+	if code != http.StatusOK {
+		err := ClientError(code, resp)
+		t.G().L.Errorf("%s request error, err:'%s'", id, err)
+		return nil, err
+	}
+
+	var result TObjectsResult
+	if err = json.Unmarshal(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (t *TReceiverPlugin) CleanClientObjects(filter *TObjectFilter, all bool,
+	dryrun bool) (*TObjectsResult, error) {
+
+	id := "(receiver) (client) (objects) (clean)"
+
+	client := api.NewClient(t.G())
+
+	flags := map[string]bool{"all": all, "dryrun": dryrun}
+	resp, code, err := client.Request(http.MethodDelete, fmt.Sprintf("%s/objects%s",
+		NamePlugin, objectsQuery(filter, flags)), nil)
+	if err != nil {
+		return nil, err
+	}
+	t.G().L.DumpBytes(id, resp, 0)
+
+	if code != http.StatusOK {
+		err := ClientError(code, resp)
+		t.G().L.Errorf("%s request error, err:'%s'", id, err)
+		return nil, err
+	}
+
+	var result TObjectsResult
+	if err = json.Unmarshal(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
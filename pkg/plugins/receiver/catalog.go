@@ -0,0 +1,564 @@
+package receiver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// catalog zones (rfc9432) define member zones as PTR records
+// under "zones" label of catalog, catalog is transferred via
+// AXFR/IXFR as any other zone and member zones are placed
+// into "catalog" zones configuration layer, members removed
+// from catalog are removed from zones state and purged
+
+const (
+	// default catalog refresh interval in seconds if
+	// it is not set in config or SOA
+	DefaultCatalogRefresh = 60
+
+	// catalog schema version supported
+	CatalogVersion = "2"
+
+	// catalog zone labels and properties
+	CatalogLabelZones        = "zones"
+	CatalogLabelVersion      = "version"
+	CatalogPropertyGroup     = "group"
+	CatalogPropertyExt       = "ext"
+	CatalogPropertyPrimaries = "primaries"
+
+	// port of primaries defined in catalog
+	DefaultCatalogPrimaryPort = "53"
+)
+
+// catalog zone configuration: catalog primaries, tsig
+// keys and refresh are set as for secondary zone, member
+// defines defaults for member zones and groups override
+// member settings for zones with group property set
+type TConfigCatalog struct {
+	TConfigZone `yaml:",inline"`
+
+	// member zones defaults, if primary is not set
+	// catalog primaries are used
+	Member TConfigZone `json:"member" yaml:"member"`
+
+	// settings per catalog group property
+	Groups map[string]TConfigZone `json:"groups" yaml:"groups"`
+}
+
+type TCatalogMember struct {
+	// member zone name and its unique id in catalog
+	Zone string `json:"zone"`
+	ID   string `json:"id"`
+
+	// group property (if any)
+	Group string `json:"group"`
+
+	// primaries defined in catalog via "primaries.ext"
+	// custom property (if any)
+	Primaries []string `json:"primaries"`
+}
+
+// Making a key of record to track catalog content
+// regardless of TTL and names case
+func CatalogRecordKey(r dns.RR) string {
+	q := dns.Copy(r)
+	q.Header().Ttl = 0
+	q.Header().Name = strings.ToLower(q.Header().Name)
+	return q.String()
+}
+
+// Getting addresses of primaries defined as A/AAAA records
+// of "primaries.ext" property or its labels
+func catalogPrimary(r dns.RR) string {
+	switch v := r.(type) {
+	case *dns.A:
+		return net.JoinHostPort(v.A.String(), DefaultCatalogPrimaryPort)
+	case *dns.AAAA:
+		return net.JoinHostPort(v.AAAA.String(), DefaultCatalogPrimaryPort)
+	}
+	return ""
+}
+
+// Checking if labels are "primaries.ext" custom property
+// (optionally with one more label)
+func isCatalogPrimaries(labels []string) bool {
+	n := len(labels)
+	if n < 2 || n > 3 {
+		return false
+	}
+	return labels[n-1] == CatalogPropertyExt && labels[n-2] == CatalogPropertyPrimaries
+}
+
+// Parsing catalog zone records, returning members per zone
+// name and catalog level primaries (if any)
+func ParseCatalog(catalog string, rr []dns.RR) (map[string]TCatalogMember, []string, error) {
+	origin := Dot(strings.ToLower(catalog))
+	zones := fmt.Sprintf("%s.%s", CatalogLabelZones, origin)
+
+	version := ""
+
+	ptrs := make(map[string][]string)
+	groups := make(map[string]string)
+	primaries := make(map[string][]string)
+	var defaults []string
+
+	for _, r := range rr {
+		h := r.Header()
+		name := strings.ToLower(h.Name)
+
+		if !dns.IsSubDomain(origin, name) {
+			continue
+		}
+
+		if name == fmt.Sprintf("%s.%s", CatalogLabelVersion, origin) {
+			if txt, ok := r.(*dns.TXT); ok {
+				version = strings.Join(txt.Txt, "")
+			}
+			continue
+		}
+
+		if !dns.IsSubDomain(zones, name) || name == zones {
+			// catalog level properties
+			labels := dns.SplitDomainName(strings.TrimSuffix(name, origin))
+			if isCatalogPrimaries(labels) {
+				if primary := catalogPrimary(r); len(primary) > 0 {
+					defaults = append(defaults, primary)
+				}
+			}
+			continue
+		}
+
+		// labels relative to zones label, the last one
+		// is member unique id
+		labels := dns.SplitDomainName(strings.TrimSuffix(name, zones))
+		member := labels[len(labels)-1]
+		properties := labels[:len(labels)-1]
+
+		switch {
+		case len(properties) == 0:
+			if ptr, ok := r.(*dns.PTR); ok {
+				ptrs[member] = append(ptrs[member], ptr.Ptr)
+			}
+		case len(properties) == 1 && properties[0] == CatalogPropertyGroup:
+			if txt, ok := r.(*dns.TXT); ok {
+				groups[member] = strings.Join(txt.Txt, "")
+			}
+		case isCatalogPrimaries(properties):
+			if primary := catalogPrimary(r); len(primary) > 0 {
+				primaries[member] = append(primaries[member], primary)
+			}
+		}
+	}
+
+	if version != CatalogVersion {
+		return nil, nil, fmt.Errorf("catalog:'%s' version:'%s' is not supported", catalog, version)
+	}
+
+	// member ids are processed in sorted order to resolve
+	// duplicated zones in a stable way
+	var ids []string
+	for member := range ptrs {
+		ids = append(ids, member)
+	}
+	sort.Strings(ids)
+
+	members := make(map[string]TCatalogMember)
+	for _, member := range ids {
+		if len(ptrs[member]) != 1 {
+			// member with more than one PTR is broken
+			// and should be ignored
+			continue
+		}
+
+		zone, err := ZoneName(ptrs[member][0])
+		if err != nil || zone == RemoveDot(origin) {
+			continue
+		}
+
+		if _, ok := members[zone]; ok {
+			continue
+		}
+
+		m := TCatalogMember{Zone: zone, ID: member, Group: groups[member]}
+		m.Primaries = append(m.Primaries, primaries[member]...)
+		sort.Strings(m.Primaries)
+		members[zone] = m
+	}
+
+	sort.Strings(defaults)
+
+	return members, defaults, nil
+}
+
+// Overriding zone settings with settings set in override
+func MergeConfigZone(config TConfigZone, override TConfigZone) TConfigZone {
+	if len(override.Primary) > 0 {
+		config.Primary = override.Primary
+	}
+	if len(override.AllowNotify) > 0 {
+		config.AllowNotify = override.AllowNotify
+	}
+	if override.Refresh > 0 {
+		config.Refresh = override.Refresh
+	}
+	if len(override.TsigKeys) > 0 {
+		config.TsigKeys = override.TsigKeys
+	}
+	return config
+}
+
+type CatalogZone struct {
+	zone   string
+	config TConfigCatalog
+
+	// the last catalog SOA and records
+	soa     *dns.SOA
+	records map[string]dns.RR
+
+	// members and catalog level primaries
+	members   map[string]TCatalogMember
+	primaries []string
+}
+
+func NewCatalogZone(zone string, config TConfigCatalog) *CatalogZone {
+	var c CatalogZone
+	c.zone = RemoveDot(strings.ToLower(zone))
+	c.config = config
+	c.records = make(map[string]dns.RR)
+	c.members = make(map[string]TCatalogMember)
+	return &c
+}
+
+// Applying AXFR or IXFR of catalog, if catalog could not be
+// parsed after changes the previous content is kept
+func (c *CatalogZone) Apply(rr []dns.RR) error {
+	if len(rr) == 0 {
+		return fmt.Errorf("catalog:'%s' empty transfer", c.zone)
+	}
+
+	soa, ok := rr[0].(*dns.SOA)
+	if !ok {
+		return fmt.Errorf("catalog:'%s' SOA records misconfiguration", c.zone)
+	}
+
+	if len(rr) == 1 {
+		// no any changes as catalog is up to date
+		return nil
+	}
+
+	if _, ok := rr[len(rr)-1].(*dns.SOA); !ok {
+		return fmt.Errorf("catalog:'%s' SOA records misconfiguration", c.zone)
+	}
+
+	records := make(map[string]dns.RR)
+
+	// IXFR has SOA as the second record as deletion
+	// section starts, otherwise it is AXFR
+	incremental := len(rr) > 2 && rr[1].Header().Rrtype == dns.TypeSOA
+	if incremental {
+		for k, v := range c.records {
+			records[k] = v
+		}
+	}
+
+	section := SectionAddition
+	for _, r := range rr[1 : len(rr)-1] {
+		if r.Header().Rrtype == dns.TypeSOA {
+			// IXFR sections are deletion and addition
+			// pairs each started with SOA
+			if section == SectionAddition {
+				section = SectionDeletion
+			} else {
+				section = SectionAddition
+			}
+			continue
+		}
+		if section == SectionDeletion {
+			delete(records, CatalogRecordKey(r))
+			continue
+		}
+		records[CatalogRecordKey(r)] = r
+	}
+
+	var list []dns.RR
+	for _, r := range records {
+		list = append(list, r)
+	}
+
+	members, primaries, err := ParseCatalog(c.zone, list)
+	if err != nil {
+		return err
+	}
+
+	c.soa = soa
+	c.records = records
+	c.members = members
+	c.primaries = primaries
+
+	return nil
+}
+
+// Getting member zones configurations of catalog
+func (c *CatalogZone) Configs() map[string]TConfigZone {
+	configs := make(map[string]TConfigZone)
+
+	for zone, member := range c.members {
+		config := c.config.Member
+		config.Enabled = true
+		config.Type = TransferTypeAXFR
+		config.Random = nil
+
+		if len(config.Primary) == 0 {
+			config.Primary = c.config.Primary
+		}
+
+		if group, ok := c.config.Groups[member.Group]; ok && len(member.Group) > 0 {
+			config = MergeConfigZone(config, group)
+		}
+
+		// primaries defined in catalog take precedence
+		switch {
+		case len(member.Primaries) > 0:
+			config.Primary = member.Primaries
+		case len(c.primaries) > 0:
+			config.Primary = c.primaries
+		}
+
+		configs[zone] = config
+	}
+
+	return configs
+}
+
+type CatalogWorker struct {
+	p *TReceiverPlugin
+
+	// zones state to configure
+	zones *ZonesState
+
+	// catalogs in sorted order
+	catalogs []*CatalogZone
+}
+
+func NewCatalogWorker(p *TReceiverPlugin, zones *ZonesState) *CatalogWorker {
+	var w CatalogWorker
+	w.p = p
+	w.zones = zones
+
+	var names []string
+	catalogs := p.L().AxfrTransfer.Zones.Catalogs
+	for zone, config := range catalogs {
+		if config.Enabled {
+			names = append(names, zone)
+		}
+	}
+	sort.Strings(names)
+
+	for _, zone := range names {
+		w.catalogs = append(w.catalogs, NewCatalogZone(zone, catalogs[zone]))
+	}
+
+	return &w
+}
+
+// Getting TSIG keys of catalog: catalog keys override
+// primary alias keys
+func (w *CatalogWorker) TsigKeys(c *CatalogZone, primary string) ([]*TTsigKey, error) {
+	if len(c.config.TsigKeys) == 0 {
+		return w.zones.TsigKeys(c.zone, primary)
+	}
+
+	var keys []*TTsigKey
+	for _, name := range c.config.TsigKeys {
+		key, ok := w.zones.keyring.Get(name)
+		if !ok {
+			return nil, fmt.Errorf("catalog:'%s' tsig key:'%s' not found", c.zone, name)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// Transferring catalog from primaries in order, IXFR is
+// requested if catalog was transferred before, nil is
+// returned if catalog has no changes
+func (w *CatalogWorker) Transfer(c *CatalogZone) ([]dns.RR, error) {
+	id := "(catalog) (transfer)"
+
+	var errs []string
+	for _, primary := range c.config.Primary {
+		server := w.zones.Primary(primary)
+
+		tlsconfig, err := w.zones.TransferTLS(primary, server)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		keys, err := w.TsigKeys(c, primary)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		failed := func(key *TTsigKey, err error) {
+			w.zones.TsigFailure(c.zone, key, err)
+		}
+
+		var opts *TransferOptions
+		request := func(key *TTsigKey) error {
+			var err error
+			opts, err = RequestSOAWithOptions(server, c.zone,
+				&TransferOptions{Tsig: key, TLS: tlsconfig})
+			return err
+		}
+
+		key, err := WithTsigKeys(keys, request, failed)
+		if err != nil {
+			w.p.G().L.Errorf("%s error SOA request catalog:'%s' via primary:'%s', err:'%s'",
+				id, c.zone, server, err)
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		if c.soa != nil && c.soa.Serial == opts.Serial {
+			if opts.Conn != nil {
+				opts.Conn.Close()
+			}
+			w.p.G().L.Debugf("%s catalog:'%s' serial:'%d' no changes", id, c.zone, opts.Serial)
+			return nil, nil
+		}
+
+		opts.Mode = TransferModeAXFR
+		if c.soa != nil {
+			opts.Mode = TransferModeIXFR
+			opts.Serial = c.soa.Serial
+		}
+
+		var rr []dns.RR
+		transfer := func(key *TTsigKey) error {
+			var err error
+			opts.Tsig = key
+			rr, err = TransferZone(server, c.zone, opts)
+			return err
+		}
+
+		if _, err = WithTsigKeys(PreferTsigKey(keys, key), transfer, failed); err != nil {
+			w.p.G().L.Errorf("%s error transferring catalog:'%s' via primary:'%s', err:'%s'",
+				id, c.zone, server, err)
+			errs = append(errs, err.Error())
+			continue
+		}
+
+		w.p.G().L.Debugf("%s catalog:'%s' transferred via primary:'%s' mode:'%s' rr:'%d'",
+			id, c.zone, server, TransferModeAsString(opts.Mode), len(rr))
+
+		return rr, nil
+	}
+
+	return nil, fmt.Errorf("catalog:'%s' transfer failed, errors:['%s']", c.zone,
+		strings.Join(errs, "','"))
+}
+
+// Getting member zones configurations of all catalogs,
+// zone in more than one catalog is taken from the first
+func (w *CatalogWorker) Configs() map[string]TConfigZone {
+	id := "(catalog) (configs)"
+
+	configs := make(map[string]TConfigZone)
+	for _, c := range w.catalogs {
+		for zone, config := range c.Configs() {
+			if _, ok := configs[zone]; ok {
+				w.p.G().L.Errorf("%s zone:'%s' is member of more than one catalog", id, zone)
+				continue
+			}
+			configs[zone] = config
+		}
+	}
+	return configs
+}
+
+// Refreshing all catalogs and applying members changes to
+// zones state, catalogs failed keep their previous members
+func (w *CatalogWorker) Refresh() {
+	id := "(catalog) (refresh)"
+
+	for _, c := range w.catalogs {
+		rr, err := w.Transfer(c)
+		if err != nil {
+			w.zones.SetTransferStatus(c.zone, err)
+			continue
+		}
+		if rr == nil {
+			continue
+		}
+
+		if err = c.Apply(rr); err != nil {
+			w.p.G().L.Errorf("%s error applying catalog:'%s', err:'%s'", id, c.zone, err)
+			w.zones.SetTransferStatus(c.zone, err)
+			continue
+		}
+
+		w.zones.SetTransferStatus(c.zone, nil)
+		w.p.G().L.Debugf("%s catalog:'%s' serial:'%d' members:'%d'", id, c.zone,
+			c.soa.Serial, len(c.members))
+	}
+
+	configs := w.Configs()
+	added, changed, removed := w.zones.SetLayerZones(ZonesLayerCatalog, configs)
+
+	if len(added)+len(changed)+len(removed) > 0 {
+		w.p.G().L.Debugf("%s zones:'%d' added:['%s'] changed:['%s'] removed:['%s']",
+			id, len(configs), strings.Join(added, ","), strings.Join(changed, ","),
+			strings.Join(removed, ","))
+	}
+
+	w.zones.ReconfigureZones(changed, removed)
+}
+
+// Getting interval till the next catalogs refresh: the
+// minimal of catalogs refresh (config or SOA)
+func (w *CatalogWorker) Interval() time.Duration {
+	interval := 0
+	for _, c := range w.catalogs {
+		refresh := c.config.Refresh
+		if refresh <= 0 && c.soa != nil {
+			refresh = int(c.soa.Refresh)
+		}
+		if refresh <= 0 {
+			refresh = DefaultCatalogRefresh
+		}
+		if interval == 0 || refresh < interval {
+			interval = refresh
+		}
+	}
+	return time.Duration(interval) * time.Second
+}
+
+func (w *CatalogWorker) Run(ctx context.Context) error {
+	id := "(catalog) (worker)"
+
+	if len(w.catalogs) == 0 {
+		return nil
+	}
+
+	for {
+		w.Refresh()
+
+		interval := w.Interval()
+		w.p.G().L.Debugf("%s catalogs:'%d' next refresh in '%s'", id, len(w.catalogs), interval)
+
+		select {
+		case <-ctx.Done():
+			w.p.G().L.Debugf("%s context stop on catalogs worker", id)
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
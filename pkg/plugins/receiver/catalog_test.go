@@ -0,0 +1,229 @@
+package receiver
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+var TestCatalogVersions = map[uint32]string{
+	1: `version.catalog.example. 0 IN TXT "2"
+m1.zones.catalog.example. 0 IN PTR a.example.
+m2.zones.catalog.example. 0 IN PTR b.example.
+group.m2.zones.catalog.example. 0 IN TXT "fast"
+primaries.ext.m2.zones.catalog.example. 0 IN A 192.0.2.53`,
+
+	2: `version.catalog.example. 0 IN TXT "2"
+m1.zones.catalog.example. 0 IN PTR a.example.
+m3.zones.catalog.example. 0 IN PTR c.example.`,
+
+	3: `version.catalog.example. 0 IN TXT "3"
+m1.zones.catalog.example. 0 IN PTR a.example.`,
+}
+
+// IXFR from serial 1 to 2, other versions are served
+// as AXFR only
+var TestCatalogIXFR = `m2.zones.catalog.example. 0 IN PTR b.example.
+group.m2.zones.catalog.example. 0 IN TXT "fast"
+primaries.ext.m2.zones.catalog.example. 0 IN A 192.0.2.53
+--
+m3.zones.catalog.example. 0 IN PTR c.example.`
+
+func NewTestCatalogRR(content string) []dns.RR {
+	var out []dns.RR
+	for _, line := range strings.Split(content, "\n") {
+		if r, err := dns.NewRR(line); err == nil && r != nil {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func NewTestCatalogSOA(serial uint32) dns.RR {
+	soa, _ := dns.NewRR(fmt.Sprintf("catalog.example. 0 IN SOA invalid. invalid. %d 3600 600 86400 0", serial))
+	return soa
+}
+
+// starting catalog primary on tcp and udp of the same port
+// serving version set in serial
+func NewTestCatalogPrimary(t *testing.T, serial *uint32) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening, err:'%s'", err)
+	}
+	pc, err := net.ListenPacket("udp", l.Addr().String())
+	if err != nil {
+		t.Fatalf("error listening, err:'%s'", err)
+	}
+
+	handler := func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Authoritative = true
+
+		current := atomic.LoadUint32(serial)
+		soa := NewTestCatalogSOA(current)
+
+		switch r.Question[0].Qtype {
+		case dns.TypeSOA:
+			m.Answer = []dns.RR{soa}
+		case dns.TypeIXFR:
+			requested := r.Ns[0].(*dns.SOA).Serial
+			if requested == 1 && current == 2 {
+				sections := strings.Split(TestCatalogIXFR, "--")
+				m.Answer = append(m.Answer, soa, NewTestCatalogSOA(1))
+				m.Answer = append(m.Answer, NewTestCatalogRR(sections[0])...)
+				m.Answer = append(m.Answer, soa)
+				m.Answer = append(m.Answer, NewTestCatalogRR(sections[1])...)
+				m.Answer = append(m.Answer, soa)
+				break
+			}
+			fallthrough
+		case dns.TypeAXFR:
+			m.Answer = append(m.Answer, soa)
+			m.Answer = append(m.Answer, NewTestCatalogRR(TestCatalogVersions[current])...)
+			m.Answer = append(m.Answer, soa)
+		}
+		_ = w.WriteMsg(m)
+	}
+
+	tcp := &dns.Server{Listener: l, Handler: dns.HandlerFunc(handler)}
+	udp := &dns.Server{PacketConn: pc, Handler: dns.HandlerFunc(handler)}
+	go func() {
+		_ = tcp.ActivateAndServe()
+	}()
+	go func() {
+		_ = udp.ActivateAndServe()
+	}()
+
+	return l.Addr().String(), func() {
+		_ = tcp.Shutdown()
+		_ = udp.Shutdown()
+	}
+}
+
+func TestCatalogRefresh(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+
+	var serial uint32
+	server, shutdown := NewTestCatalogPrimary(t, &serial)
+	defer shutdown()
+
+	p.c.AxfrTransfer.Enabled = true
+	p.c.AxfrTransfer.Zones.Catalogs = map[string]TConfigCatalog{
+		"catalog.example": {
+			TConfigZone: TConfigZone{Enabled: true, Primary: []string{server}},
+			Member:      TConfigZone{Refresh: 300},
+			Groups:      map[string]TConfigZone{"fast": {Refresh: 30}},
+		},
+	}
+
+	zones := NewZonesState(p)
+	worker := NewCatalogWorker(p, zones)
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		// catalog version served by primary
+		serial uint32
+
+		// expected catalog serial, zones in catalog
+		// layer as "zone/primary/refresh"
+		catalog uint32
+		zones   []string
+
+		// zone states expected to be removed
+		removed []string
+	}
+
+	var Tests = []TTest{
+		{
+			"6a1b2c3d-4e5f-4a6b-8c7d-8e9f0a1b2c3d",
+			true,
+			1, 1,
+			[]string{
+				fmt.Sprintf("a.example/%s/300", server),
+				"b.example/192.0.2.53:53/30",
+			},
+			nil,
+		},
+		{
+			// member removed and added via IXFR
+			"7b2c3d4e-5f6a-4b7c-9d8e-9f0a1b2c3d4e",
+			true,
+			2, 2,
+			[]string{
+				fmt.Sprintf("a.example/%s/300", server),
+				fmt.Sprintf("c.example/%s/300", server),
+			},
+			[]string{"b.example"},
+		},
+		{
+			// unsupported version, members are kept
+			"8c3d4e5f-6a7b-4c8d-8e9f-0a1b2c3d4e5f",
+			true,
+			3, 2,
+			[]string{
+				fmt.Sprintf("a.example/%s/300", server),
+				fmt.Sprintf("c.example/%s/300", server),
+			},
+			nil,
+		},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		// zones states of all members to check removal
+		for zone := range zones.GetLayerZones(ZonesLayerCatalog) {
+			zones.zones[zone] = TZoneState{Zone: zone}
+		}
+
+		atomic.StoreUint32(&serial, test.serial)
+		worker.Refresh()
+
+		if s := worker.catalogs[0].soa; s == nil || s.Serial != test.catalog {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("catalog serial expected:'%d'", test.catalog))
+			continue
+		}
+
+		var got []string
+		for zone, config := range zones.GetLayerZones(ZonesLayerCatalog) {
+			got = append(got, fmt.Sprintf("%s/%s/%d", zone, strings.Join(config.Primary, ","),
+				config.Refresh))
+		}
+		sort.Strings(got)
+
+		if strings.Join(got, " ") != strings.Join(test.zones, " ") {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("zones expected:'%s' got:'%s'",
+				strings.Join(test.zones, " "), strings.Join(got, " ")))
+			continue
+		}
+
+		failed := false
+		for _, zone := range test.removed {
+			if _, ok := zones.zones[zone]; ok {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("zone:'%s' expected to be removed", zone))
+				failed = true
+			}
+		}
+		if failed {
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
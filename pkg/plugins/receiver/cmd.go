@@ -1,11 +1,19 @@
 package receiver
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
 	"github.com/spf13/cobra"
 )
 
 // receiving data for zone "example.net" as its configured
-// in corresponding section in configuration
+// in corresponding section in configuration, one-shot
+// commands "fetch", "cook" and "import" run without server
 // receiver fetch --zone="example.net" --debug
 
 // command line switches
@@ -36,7 +44,1646 @@ func (c *cmdReceiver) Command() *cobra.Command {
 Fetches data from external sources and push them as
 snaphots to validate, import or cook later
 `
-	// T.B.D.
+	zonesCmd := cmdReceiverZones{p: c.p}
+	zonesCmd.s = c
+	cmd.AddCommand(zonesCmd.Command())
+
+	overridesCmd := cmdReceiverOverrides{p: c.p, s: c}
+	cmd.AddCommand(overridesCmd.Command())
+
+	quarantineCmd := cmdReceiverQuarantine{p: c.p, s: c}
+	cmd.AddCommand(quarantineCmd.Command())
+
+	diffCmd := cmdReceiverDiff{p: c.p, s: c}
+	cmd.AddCommand(diffCmd.Command())
+
+	objectsCmd := cmdReceiverObjects{p: c.p, s: c}
+	cmd.AddCommand(objectsCmd.Command())
+
+	ownershipCmd := cmdReceiverOwnership{p: c.p, s: c}
+	cmd.AddCommand(ownershipCmd.Command())
+
+	fetchCmd := cmdReceiverFetch{p: c.p, s: c}
+	cmd.AddCommand(fetchCmd.Command())
+
+	cookCmd := cmdReceiverCook{p: c.p, s: c}
+	cmd.AddCommand(cookCmd.Command())
+
+	importCmd := cmdReceiverImport{p: c.p, s: c}
+	cmd.AddCommand(importCmd.Command())
+
+	return cmd
+}
+
+type cmdReceiverZones struct {
+	p *TReceiverPlugin
+	s *cmdReceiver
+
+	// zone to operate
+	zone string
+}
+
+func (c *cmdReceiverZones) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "zones"
+	cmd.Short = "Managing secondary zones via api"
+	cmd.Long = "Listing, adding and removing secondary zones in runtime"
+
+	cmd.PersistentFlags().StringVarP(&c.zone, "zone", "", "", "zone name")
+
+	var examples = []string{
+		`  a) listing all configured zones with their states
+
+     receiver zones list --debug`,
+
+		`  b) adding zone "example.net" transferred via AXFR/IXFR
+     from primary (primary could be an alias)
+
+     receiver zones add --zone example.net --primary "[::1]:53" --type axfr`,
+
+		`  c) showing zone state, serial and transfer status
+
+     receiver zones show --zone example.net`,
+
+		`  d) removing zone added in runtime
+
+     receiver zones remove --zone example.net`,
+
+		`  e) listing records of zone skipped during import
+     (not placed into bpf maps), optionally by reason:
+     qname-length, multi-address, unsupported-type, out-of-zone
+
+     receiver zones skipped --zone example.net --reason qname-length`,
+
+		`  f) listing snapshots generations of zone with serials
+
+     receiver zones snapshots --zone example.net`,
+
+		`  g) rolling zone back to generation, zone is pinned
+     there (no updates applied) till unpinned
+
+     receiver zones rollback --zone example.net --generation 1792339758190785000
+     receiver zones unpin --zone example.net`,
+	}
+
+	cmd.Example = strings.Join(examples, "\n\n")
+
+	listCmd := cmdReceiverZonesList{p: c.p, s: c}
+	cmd.AddCommand(listCmd.Command())
+
+	showCmd := cmdReceiverZonesShow{p: c.p, s: c}
+	cmd.AddCommand(showCmd.Command())
+
+	addCmd := cmdReceiverZonesAdd{p: c.p, s: c}
+	cmd.AddCommand(addCmd.Command())
+
+	removeCmd := cmdReceiverZonesRemove{p: c.p, s: c}
+	cmd.AddCommand(removeCmd.Command())
+
+	skippedCmd := cmdReceiverZonesSkipped{p: c.p, s: c}
+	cmd.AddCommand(skippedCmd.Command())
+
+	snapshotsCmd := cmdReceiverZonesSnapshots{p: c.p, s: c}
+	cmd.AddCommand(snapshotsCmd.Command())
+
+	rollbackCmd := cmdReceiverZonesRollback{p: c.p, s: c}
+	cmd.AddCommand(rollbackCmd.Command())
+
+	unpinCmd := cmdReceiverZonesUnpin{p: c.p, s: c}
+	cmd.AddCommand(unpinCmd.Command())
+
+	return cmd
+}
+
+// time is printed as "-" if not set
+func TimeAsString(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+type cmdReceiverZonesList struct {
+	p *TReceiverPlugin
+	s *cmdReceiverZones
+}
+
+func (c *cmdReceiverZonesList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "list"
+	cmd.Short = "Listing secondary zones"
+	cmd.Long = "Listing secondary zones with states"
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverZonesList) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (zones) (list)"
+
+	zones, err := c.p.GetClientZones("")
+	if err != nil {
+		c.p.G().L.Errorf("%s error getting zones, err:'%s'", id, err)
+		return err
+	}
+
+	fmt.Printf("%-32s %-10s %-10s %-12s %-10s %-8s %s\n", "ZONE", "LAYER", "STATE",
+		"SERIAL", "RECORDS", "SKIPPED", "LAST-TRANSFER")
+	for _, zone := range zones {
+		fmt.Printf("%-32s %-10s %-10s %-12d %-10d %-8d %s\n", zone.Zone, zone.Layer,
+			zone.State, zone.Serial, zone.Records, zone.Skipped,
+			TimeAsString(zone.LastTransfer))
+	}
+
+	return nil
+}
+
+type cmdReceiverZonesShow struct {
+	p *TReceiverPlugin
+	s *cmdReceiverZones
+}
+
+func (c *cmdReceiverZonesShow) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "show"
+	cmd.Short = "Showing secondary zone"
+	cmd.Long = "Showing secondary zone configuration, state and transfer status"
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverZonesShow) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (zones) (show)"
+
+	if len(c.s.zone) == 0 {
+		return fmt.Errorf("zone is not set")
+	}
+
+	zones, err := c.p.GetClientZones(c.s.zone)
+	if err != nil {
+		c.p.G().L.Errorf("%s error getting zone:'%s', err:'%s'", id, c.s.zone, err)
+		return err
+	}
+
+	for _, zone := range zones {
+		fmt.Printf("zone:           %s\n", zone.Zone)
+		fmt.Printf("layer:          %s\n", zone.Layer)
+		fmt.Printf("config:         %s\n", zone.Config.String())
+		fmt.Printf("state:          %s\n", zone.State)
+		fmt.Printf("serial:         %d\n", zone.Serial)
+		fmt.Printf("snapshot:       %s\n", TimeAsString(zone.Timestamp))
+		fmt.Printf("last-transfer:  %s\n", TimeAsString(zone.LastTransfer))
+		fmt.Printf("last-error:     %s\n", zone.LastError)
+		fmt.Printf("last-error-at:  %s\n", TimeAsString(zone.LastErrorTime))
+		fmt.Printf("records:        %d\n", zone.Records)
+		fmt.Printf("skipped:        %d\n", zone.Skipped)
+
+		if zone.Pinned != nil {
+			fmt.Printf("pinned:         generation:'%d' serial:'%d' at:'%s'\n",
+				zone.Pinned.Generation, zone.Pinned.Serial, TimeAsString(zone.Pinned.Pinned))
+		}
+
+		for _, primary := range zone.Primaries {
+			fmt.Printf("primary:        %s server:'%s' failures:'%d' latency:'%d' ms last-success:'%s' next-attempt:'%s' last-error:'%s'\n",
+				primary.Primary, primary.Server, primary.Failures, primary.Latency,
+				TimeAsString(primary.LastSuccess), TimeAsString(primary.NextAttempt),
+				primary.LastError)
+		}
+	}
+
+	return nil
+}
+
+type cmdReceiverZonesAdd struct {
+	p *TReceiverPlugin
+	s *cmdReceiverZones
+
+	primary     []string
+	allownotify []string
+	zonetype    string
+	refresh     int
+	disabled    bool
+}
+
+func (c *cmdReceiverZonesAdd) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "add"
+	cmd.Short = "Adding secondary zone"
+	cmd.Long = "Adding secondary zone into runtime zones overlay"
+
+	cmd.PersistentFlags().StringSliceVarP(&c.primary, "primary", "", nil,
+		"a list of primaries (or aliases)")
+	cmd.PersistentFlags().StringSliceVarP(&c.allownotify, "allow-notify", "", nil,
+		"a list of addresses to allow notify from")
+	cmd.PersistentFlags().StringVarP(&c.zonetype, "type", "", TransferTypeAXFR,
+		"type of zone: axfr, http or random")
+	cmd.PersistentFlags().IntVarP(&c.refresh, "refresh", "", 0,
+		"override SOA refresh in seconds")
+	cmd.PersistentFlags().BoolVarP(&c.disabled, "disabled", "", false,
+		"add zone as disabled")
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverZonesAdd) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (zones) (add)"
+
+	if len(c.s.zone) == 0 {
+		return fmt.Errorf("zone is not set")
+	}
+
+	enabled := !c.disabled
+
+	var request TZoneRequest
+	request.Zone = c.s.zone
+	request.Enabled = &enabled
+	request.Primary = c.primary
+	request.AllowNotify = c.allownotify
+	request.Type = &c.zonetype
+	if c.refresh > 0 {
+		request.Refresh = &c.refresh
+	}
+
+	config := request.Config()
+	c.p.G().L.Debugf("%s request to add zone:'%s' as %s dryrun:'%t'", id,
+		c.s.zone, config.String(), c.s.s.switches.Dryrun)
+
+	if c.s.s.switches.Dryrun {
+		c.p.G().L.Debugf("%s skip processing as dry-run set", id)
+		return nil
+	}
+
+	info, err := c.p.AddClientZone(&request)
+	if err != nil {
+		c.p.G().L.Errorf("%s error adding zone:'%s', err:'%s'", id, c.s.zone, err)
+		return err
+	}
+
+	fmt.Printf("zone:'%s' added as %s\n", info.Zone, info.Config.String())
+
+	return nil
+}
+
+type cmdReceiverZonesRemove struct {
+	p *TReceiverPlugin
+	s *cmdReceiverZones
+}
+
+func (c *cmdReceiverZonesRemove) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "remove"
+	cmd.Short = "Removing secondary zone"
+	cmd.Long = "Removing secondary zone added in runtime, its records are purged"
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverZonesRemove) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (zones) (remove)"
+
+	if len(c.s.zone) == 0 {
+		return fmt.Errorf("zone is not set")
+	}
+
+	c.p.G().L.Debugf("%s request to remove zone:'%s' dryrun:'%t'", id,
+		c.s.zone, c.s.s.switches.Dryrun)
+
+	if c.s.s.switches.Dryrun {
+		c.p.G().L.Debugf("%s skip processing as dry-run set", id)
+		return nil
+	}
+
+	if err := c.p.RemoveClientZone(c.s.zone); err != nil {
+		c.p.G().L.Errorf("%s error removing zone:'%s', err:'%s'", id, c.s.zone, err)
+		return err
+	}
+
+	fmt.Printf("zone:'%s' removed\n", c.s.zone)
+
+	return nil
+}
+
+type cmdReceiverZonesSkipped struct {
+	p *TReceiverPlugin
+	s *cmdReceiverZones
+
+	// reason to filter
+	reason string
+}
+
+func (c *cmdReceiverZonesSkipped) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "skipped"
+	cmd.Short = "Listing skipped records of zone"
+	cmd.Long = "Listing records of zone skipped during import with reasons"
+
+	cmd.PersistentFlags().StringVarP(&c.reason, "reason", "", "", "skip reason")
+
+	cmd.RunE = c.Run
+	return cmd
+}
 
+func (c *cmdReceiverZonesSkipped) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (zones) (skipped)"
+
+	if len(c.s.zone) == 0 {
+		return fmt.Errorf("zone is not set")
+	}
+
+	report, err := c.p.GetClientZoneSkipped(c.s.zone, c.reason)
+	if err != nil {
+		c.p.G().L.Errorf("%s error getting skipped records zone:'%s', err:'%s'",
+			id, c.s.zone, err)
+		return err
+	}
+
+	fmt.Printf("zone:           %s\n", report.Zone)
+	fmt.Printf("serial:         %d\n", report.Serial)
+	fmt.Printf("snapshot:       %s\n", TimeAsString(report.Timestamp))
+	for _, reason := range SkipReasons {
+		fmt.Printf("%-16s%d\n", fmt.Sprintf("%s:", reason), report.Counts[reason])
+	}
+
+	fmt.Printf("\n%-18s %s\n", "REASON", "RECORD")
+	for _, record := range report.Records {
+		fmt.Printf("%-18s %s\n", record.Reason, record.Record)
+	}
+
+	return nil
+}
+
+type cmdReceiverZonesSnapshots struct {
+	p *TReceiverPlugin
+	s *cmdReceiverZones
+}
+
+func (c *cmdReceiverZonesSnapshots) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "snapshots"
+	cmd.Short = "Listing snapshots generations of zone"
+	cmd.Long = "Listing snapshots generations of zone kept on disk"
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverZonesSnapshots) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (zones) (snapshots)"
+
+	if len(c.s.zone) == 0 {
+		return fmt.Errorf("zone is not set")
+	}
+
+	generations, err := c.p.GetClientZoneSnapshots(c.s.zone)
+	if err != nil {
+		c.p.G().L.Errorf("%s error getting generations zone:'%s', err:'%s'",
+			id, c.s.zone, err)
+		return err
+	}
+
+	fmt.Printf("%-20s %-12s %-26s %-10s %-8s %s\n", "GENERATION", "SERIAL", "CREATED",
+		"RECORDS", "SOURCE", "PINNED")
+	for _, g := range generations {
+		fmt.Printf("%-20d %-12d %-26s %-10d %-8s %t\n", g.Generation, g.Serial,
+			TimeAsString(g.Created), g.Records, g.Source, g.Pinned)
+	}
+
+	return nil
+}
+
+type cmdReceiverZonesRollback struct {
+	p *TReceiverPlugin
+	s *cmdReceiverZones
+
+	generation int64
+}
+
+func (c *cmdReceiverZonesRollback) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "rollback"
+	cmd.Short = "Rolling zone back to snapshot generation"
+	cmd.Long = "Syncing maps to snapshot generation of zone and pinning zone there"
+
+	cmd.PersistentFlags().Int64VarP(&c.generation, "generation", "", 0,
+		"snapshot generation to rollback")
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverZonesRollback) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (zones) (rollback)"
+
+	if len(c.s.zone) == 0 {
+		return fmt.Errorf("zone is not set")
+	}
+	if c.generation == 0 {
+		return fmt.Errorf("generation is not set")
+	}
+
+	c.p.G().L.Debugf("%s request to rollback zone:'%s' generation:'%d' dryrun:'%t'", id,
+		c.s.zone, c.generation, c.s.s.switches.Dryrun)
+
+	if c.s.s.switches.Dryrun {
+		c.p.G().L.Debugf("%s skip processing as dry-run set", id)
+		return nil
+	}
+
+	result, err := c.p.RollbackClientZone(c.s.zone, c.generation)
+	if err != nil {
+		c.p.G().L.Errorf("%s error rolling back zone:'%s', err:'%s'", id, c.s.zone, err)
+		return err
+	}
+
+	fmt.Printf("zone:'%s' rolled back to generation:'%d' and pinned %s\n", c.s.zone,
+		c.generation, result.AsString())
+
+	return nil
+}
+
+type cmdReceiverZonesUnpin struct {
+	p *TReceiverPlugin
+	s *cmdReceiverZones
+}
+
+func (c *cmdReceiverZonesUnpin) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "unpin"
+	cmd.Short = "Unpinning zone"
+	cmd.Long = "Unpinning zone rolled back, updates are applied again"
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverZonesUnpin) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (zones) (unpin)"
+
+	if len(c.s.zone) == 0 {
+		return fmt.Errorf("zone is not set")
+	}
+
+	c.p.G().L.Debugf("%s request to unpin zone:'%s' dryrun:'%t'", id,
+		c.s.zone, c.s.s.switches.Dryrun)
+
+	if c.s.s.switches.Dryrun {
+		c.p.G().L.Debugf("%s skip processing as dry-run set", id)
+		return nil
+	}
+
+	if err := c.p.UnpinClientZone(c.s.zone); err != nil {
+		c.p.G().L.Errorf("%s error unpinning zone:'%s', err:'%s'", id, c.s.zone, err)
+		return err
+	}
+
+	fmt.Printf("zone:'%s' unpinned\n", c.s.zone)
+
+	return nil
+}
+
+type cmdReceiverOverrides struct {
+	p *TReceiverPlugin
+	s *cmdReceiver
+}
+
+func (c *cmdReceiverOverrides) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "overrides"
+	cmd.Short = "Managing local records overrides via api"
+	cmd.Long = "Listing, adding and removing records overriding zones data"
+
+	var examples = []string{
+		`  a) listing all overrides
+
+     receiver overrides list`,
+
+		`  b) pinning name to address for an hour
+
+     receiver overrides add --record "www.example.net. 60 IN A 192.0.2.1" \
+        --expire 3600 --comment "incident"`,
+
+		`  c) removing override, transferred record is restored
+
+     receiver overrides remove --name www.example.net --type A`,
+	}
+
+	cmd.Example = strings.Join(examples, "\n\n")
+
+	listCmd := cmdReceiverOverridesList{p: c.p, s: c}
+	cmd.AddCommand(listCmd.Command())
+
+	addCmd := cmdReceiverOverridesAdd{p: c.p, s: c}
+	cmd.AddCommand(addCmd.Command())
+
+	removeCmd := cmdReceiverOverridesRemove{p: c.p, s: c}
+	cmd.AddCommand(removeCmd.Command())
+
+	return cmd
+}
+
+type cmdReceiverOverridesList struct {
+	p *TReceiverPlugin
+	s *cmdReceiverOverrides
+}
+
+func (c *cmdReceiverOverridesList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "list"
+	cmd.Short = "Listing overrides"
+	cmd.Long = "Listing local records overriding zones data"
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverOverridesList) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (overrides) (list)"
+
+	overrides, err := c.p.GetClientOverrides()
+	if err != nil {
+		c.p.G().L.Errorf("%s error getting overrides, err:'%s'", id, err)
+		return err
+	}
+
+	fmt.Printf("%-48s %-6s %-8s %-40s %-26s %s\n", "NAME", "TYPE", "TTL", "ADDRESS",
+		"EXPIRES", "COMMENT")
+	for _, o := range overrides {
+		fmt.Printf("%-48s %-6s %-8d %-40s %-26s %s\n", o.Name, o.Type, o.TTL, o.Address,
+			TimeAsString(o.Expires), o.Comment)
+	}
+
+	return nil
+}
+
+type cmdReceiverOverridesAdd struct {
+	p *TReceiverPlugin
+	s *cmdReceiverOverrides
+
+	record  string
+	expire  int
+	comment string
+}
+
+func (c *cmdReceiverOverridesAdd) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "add"
+	cmd.Short = "Adding override"
+	cmd.Long = "Adding (or replacing) local record overriding zones data"
+
+	cmd.PersistentFlags().StringVarP(&c.record, "record", "", "",
+		"record in zone file format")
+	cmd.PersistentFlags().IntVarP(&c.expire, "expire", "", 0,
+		"override lifetime in seconds, 0 is never")
+	cmd.PersistentFlags().StringVarP(&c.comment, "comment", "", "",
+		"a reason of override")
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverOverridesAdd) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (overrides) (add)"
+
+	request := TOverrideRequest{Record: c.record, Expire: c.expire, Comment: c.comment}
+
+	// validating request before sending
+	override, err := NewOverride(request.Record, request.Expire, request.Comment)
+	if err != nil {
+		c.p.G().L.Errorf("%s error parsing record:'%s', err:'%s'", id, c.record, err)
+		return err
+	}
+
+	c.p.G().L.Debugf("%s request to override:'%s' dryrun:'%t'", id,
+		override.RR().String(), c.s.s.switches.Dryrun)
+
+	if c.s.s.switches.Dryrun {
+		c.p.G().L.Debugf("%s skip processing as dry-run set", id)
+		return nil
+	}
+
+	if override, err = c.p.AddClientOverride(&request); err != nil {
+		c.p.G().L.Errorf("%s error adding override, err:'%s'", id, err)
+		return err
+	}
+
+	fmt.Printf("override:'%s' added as '%s' expires:'%s'\n", override.Key(),
+		override.RR().String(), TimeAsString(override.Expires))
+
+	return nil
+}
+
+type cmdReceiverOverridesRemove struct {
+	p *TReceiverPlugin
+	s *cmdReceiverOverrides
+
+	name  string
+	qtype string
+}
+
+func (c *cmdReceiverOverridesRemove) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "remove"
+	cmd.Short = "Removing override"
+	cmd.Long = "Removing override, transferred record (if any) is restored"
+
+	cmd.PersistentFlags().StringVarP(&c.name, "name", "", "", "name of record")
+	cmd.PersistentFlags().StringVarP(&c.qtype, "type", "", "A", "type of record")
+
+	cmd.RunE = c.Run
 	return cmd
 }
+
+func (c *cmdReceiverOverridesRemove) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (overrides) (remove)"
+
+	if len(c.name) == 0 {
+		return fmt.Errorf("name is not set")
+	}
+
+	c.p.G().L.Debugf("%s request to remove override name:'%s' type:'%s' dryrun:'%t'", id,
+		c.name, c.qtype, c.s.s.switches.Dryrun)
+
+	if c.s.s.switches.Dryrun {
+		c.p.G().L.Debugf("%s skip processing as dry-run set", id)
+		return nil
+	}
+
+	if err := c.p.RemoveClientOverride(c.name, c.qtype); err != nil {
+		c.p.G().L.Errorf("%s error removing override, err:'%s'", id, err)
+		return err
+	}
+
+	fmt.Printf("override:'%s' removed\n", OverrideKey(c.name, c.qtype))
+
+	return nil
+}
+
+type cmdReceiverQuarantine struct {
+	p *TReceiverPlugin
+	s *cmdReceiver
+
+	// zone to approve or reject
+	zone string
+}
+
+func (c *cmdReceiverQuarantine) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "quarantine"
+	cmd.Short = "Managing zones updates quarantined via api"
+	cmd.Long = "Listing, approving and rejecting zones updates violating guards"
+
+	cmd.PersistentFlags().StringVarP(&c.zone, "zone", "", "", "zone name")
+
+	var examples = []string{
+		`  a) listing zones updates quarantined with guards violated
+
+     receiver quarantine list`,
+
+		`  b) approving update of zone "example.net", it is applied
+     to maps right away
+
+     receiver quarantine approve --zone example.net`,
+
+		`  c) rejecting update, the same update (by serial) is not
+     quarantined again
+
+     receiver quarantine reject --zone example.net`,
+	}
+
+	cmd.Example = strings.Join(examples, "\n\n")
+
+	listCmd := cmdReceiverQuarantineList{p: c.p, s: c}
+	cmd.AddCommand(listCmd.Command())
+
+	approveCmd := cmdReceiverQuarantineApprove{p: c.p, s: c}
+	cmd.AddCommand(approveCmd.Command())
+
+	rejectCmd := cmdReceiverQuarantineReject{p: c.p, s: c}
+	cmd.AddCommand(rejectCmd.Command())
+
+	return cmd
+}
+
+type cmdReceiverQuarantineList struct {
+	p *TReceiverPlugin
+	s *cmdReceiverQuarantine
+}
+
+func (c *cmdReceiverQuarantineList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "list"
+	cmd.Short = "Listing quarantined updates"
+	cmd.Long = "Listing zones updates quarantined with guards violated"
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverQuarantineList) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (quarantine) (list)"
+
+	quarantine, err := c.p.GetClientQuarantine()
+	if err != nil {
+		c.p.G().L.Errorf("%s error getting quarantine, err:'%s'", id, err)
+		return err
+	}
+
+	fmt.Printf("%-32s %-24s %-16s %-8s %-8s %-8s %-26s %s\n", "ZONE", "SERIAL", "RECORDS",
+		"REMOVED", "CHANGED", "ADDED", "CREATED", "VIOLATIONS")
+	for _, q := range quarantine {
+		r := q.Report
+		fmt.Printf("%-32s %-24s %-16s %-8d %-8d %-8d %-26s %s\n", q.Zone,
+			fmt.Sprintf("%d -> %d", r.Current, r.Serial),
+			fmt.Sprintf("%d -> %d", r.Baseline, r.Records),
+			r.Removed, r.Changed, r.Added, TimeAsString(q.Created),
+			strings.Join(r.Violations, ","))
+	}
+
+	return nil
+}
+
+type cmdReceiverQuarantineApprove struct {
+	p *TReceiverPlugin
+	s *cmdReceiverQuarantine
+}
+
+func (c *cmdReceiverQuarantineApprove) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "approve"
+	cmd.Short = "Approving quarantined update"
+	cmd.Long = "Approving zone update quarantined, update is applied to maps"
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverQuarantineApprove) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (quarantine) (approve)"
+
+	zone := c.s.zone
+	if len(zone) == 0 {
+		return fmt.Errorf("zone is not set")
+	}
+
+	c.p.G().L.Debugf("%s request to approve zone:'%s' dryrun:'%t'", id,
+		zone, c.s.s.switches.Dryrun)
+
+	if c.s.s.switches.Dryrun {
+		c.p.G().L.Debugf("%s skip processing as dry-run set", id)
+		return nil
+	}
+
+	result, err := c.p.ApproveClientQuarantine(zone)
+	if err != nil {
+		c.p.G().L.Errorf("%s error approving zone:'%s', err:'%s'", id, zone, err)
+		return err
+	}
+
+	fmt.Printf("zone:'%s' update approved %s\n", zone, result.AsString())
+
+	return nil
+}
+
+type cmdReceiverQuarantineReject struct {
+	p *TReceiverPlugin
+	s *cmdReceiverQuarantine
+}
+
+func (c *cmdReceiverQuarantineReject) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "reject"
+	cmd.Short = "Rejecting quarantined update"
+	cmd.Long = "Rejecting zone update quarantined, update is dropped"
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverQuarantineReject) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (quarantine) (reject)"
+
+	zone := c.s.zone
+	if len(zone) == 0 {
+		return fmt.Errorf("zone is not set")
+	}
+
+	c.p.G().L.Debugf("%s request to reject zone:'%s' dryrun:'%t'", id,
+		zone, c.s.s.switches.Dryrun)
+
+	if c.s.s.switches.Dryrun {
+		c.p.G().L.Debugf("%s skip processing as dry-run set", id)
+		return nil
+	}
+
+	if err := c.p.RejectClientQuarantine(zone); err != nil {
+		c.p.G().L.Errorf("%s error rejecting zone:'%s', err:'%s'", id, zone, err)
+		return err
+	}
+
+	fmt.Printf("zone:'%s' update rejected\n", zone)
+
+	return nil
+}
+
+type cmdReceiverDiff struct {
+	p *TReceiverPlugin
+	s *cmdReceiver
+
+	zone string
+
+	// sources to compare and output format
+	from   string
+	to     string
+	format string
+}
+
+func (c *cmdReceiverDiff) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "diff"
+	cmd.Short = "Comparing zone data between sources via api"
+	cmd.Long = `
+Comparing zone data between two sources: "snapshot" (current
+blob), "generation:<id>" (snapshot generation), "file:<path>"
+(zone master file within include directory), "axfr[:<primary>]" (live transfer, via zone
+primaries if primary is not set) and "maps" (bpf maps contents)
+`
+
+	cmd.PersistentFlags().StringVarP(&c.zone, "zone", "", "", "zone name")
+	cmd.PersistentFlags().StringVarP(&c.from, "from", "", DiffSourceSnapshot,
+		"source to compare from")
+	cmd.PersistentFlags().StringVarP(&c.to, "to", "", DiffSourceMaps,
+		"source to compare to")
+	cmd.PersistentFlags().StringVarP(&c.format, "format", "", DiffFormatText,
+		"output format: text, json or ixfr")
+
+	var examples = []string{
+		`  a) comparing current snapshot of zone "example.net"
+     with bpf maps contents
+
+     receiver diff --zone example.net --from snapshot --to maps`,
+
+		`  b) comparing snapshot generation with live transfer
+     from primary as IXFR
+
+     receiver diff --zone example.net --from generation:1792339758190785000 \
+        --to axfr:[::1]:53 --format ixfr`,
+
+		`  c) comparing zone file with current snapshot in JSON
+
+     receiver diff --zone example.net --from file:/etc/zones/example.net \
+        --to snapshot --format json`,
+	}
+
+	cmd.Example = strings.Join(examples, "\n\n")
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverDiff) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (diff)"
+
+	if len(c.zone) == 0 {
+		return fmt.Errorf("zone is not set")
+	}
+	if err := ValidDiffFormat(c.format); err != nil {
+		return err
+	}
+	for _, source := range []string{c.from, c.to} {
+		if _, err := ParseDiffSource(source); err != nil {
+			return err
+		}
+	}
+
+	diff, err := c.p.GetClientZoneDiff(c.zone, c.from, c.to)
+	if err != nil {
+		c.p.G().L.Errorf("%s error making diff zone:'%s', err:'%s'", id, c.zone, err)
+		return err
+	}
+
+	if c.format == DiffFormatJSON {
+		out, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", out)
+		return nil
+	}
+
+	out, err := diff.Format(c.format)
+	if err != nil {
+		return err
+	}
+	fmt.Print(out)
+
+	return nil
+}
+
+// output formats of objects
+const (
+	ObjectsFormatText = "text"
+	ObjectsFormatJSON = "json"
+)
+
+type cmdReceiverObjects struct {
+	p *TReceiverPlugin
+	s *cmdReceiver
+
+	// output format: zone file text or json
+	format string
+}
+
+func (c *cmdReceiverObjects) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "objects"
+	cmd.Short = "Browsing bpf maps records via api"
+	cmd.Long = `
+Listing, getting, creating and removing records in bpf maps
+directly (not via zones data), records filters are regexp
+matched to name, ip address or network matched to data
+`
+
+	cmd.PersistentFlags().StringVarP(&c.format, "format", "", ObjectsFormatText,
+		"output format: text or json")
+
+	var examples = []string{
+		`  a) listing records of names matched regexp in network
+     192.0.2.0/24, the first 100 records
+
+     receiver objects list --filter "^www" --filter 192.0.2.0/24 --count 100`,
+
+		`  b) getting record of name and type
+
+     receiver objects get --name www.example.net --type AAAA --format json`,
+
+		`  c) creating and removing record
+
+     receiver objects create --record "www.example.net 300 IN A 192.0.2.1"
+     receiver objects remove --name www.example.net --type A`,
+
+		`  d) removing records matched filter (validating only
+     with dry-run)
+
+     receiver objects clean --filter '\.example\.net$' --dry-run`,
+
+		`  e) importing records of zone file into maps
+
+     receiver objects import --file /etc/zones/example.net --zone example.net`,
+	}
+
+	cmd.Example = strings.Join(examples, "\n\n")
+
+	listCmd := cmdReceiverObjectsList{p: c.p, s: c}
+	cmd.AddCommand(listCmd.Command())
+
+	getCmd := cmdReceiverObjectsGet{p: c.p, s: c}
+	cmd.AddCommand(getCmd.Command())
+
+	createCmd := cmdReceiverObjectsCreate{p: c.p, s: c}
+	cmd.AddCommand(createCmd.Command())
+
+	removeCmd := cmdReceiverObjectsRemove{p: c.p, s: c}
+	cmd.AddCommand(removeCmd.Command())
+
+	cleanCmd := cmdReceiverObjectsClean{p: c.p, s: c}
+	cmd.AddCommand(cleanCmd.Command())
+
+	importCmd := cmdReceiverObjectsImport{p: c.p, s: c}
+	cmd.AddCommand(importCmd.Command())
+
+	return cmd
+}
+
+// printing objects in format requested
+func (c *cmdReceiverObjects) Print(v interface{}, records []string) error {
+	switch c.format {
+	case ObjectsFormatJSON:
+		out, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s\n", out)
+	case ObjectsFormatText:
+		for _, r := range records {
+			fmt.Printf("%s\n", r)
+		}
+	default:
+		return fmt.Errorf("format:'%s' expected one of ['%s,%s']", c.format,
+			ObjectsFormatText, ObjectsFormatJSON)
+	}
+	return nil
+}
+
+type cmdReceiverObjectsList struct {
+	p *TReceiverPlugin
+	s *cmdReceiverObjects
+
+	filter TObjectFilter
+}
+
+func (c *cmdReceiverObjectsList) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "list"
+	cmd.Short = "Listing records"
+	cmd.Long = "Listing records in maps matched filters ordered by name"
+
+	cmd.PersistentFlags().StringSliceVarP(&c.filter.Names, "filter", "", nil,
+		"regexp, ip address or network")
+	cmd.PersistentFlags().IntVarP(&c.filter.Offset, "offset", "", 0,
+		"number of records to skip")
+	cmd.PersistentFlags().IntVarP(&c.filter.Count, "count", "", 0,
+		"max number of records, 0 is all")
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverObjectsList) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (objects) (list)"
+
+	if err := c.filter.Validate(); err != nil {
+		return err
+	}
+
+	page, err := c.p.GetClientObjects(&c.filter)
+	if err != nil {
+		c.p.G().L.Errorf("%s error listing objects, err:'%s'", id, err)
+		return err
+	}
+
+	if c.s.format == ObjectsFormatText {
+		fmt.Printf("; records:'%d' offset:'%d' total:'%d'\n", page.Count, page.Offset,
+			page.Total)
+	}
+
+	return c.s.Print(page, page.Records)
+}
+
+type cmdReceiverObjectsGet struct {
+	p *TReceiverPlugin
+	s *cmdReceiverObjects
+
+	name  string
+	qtype string
+}
+
+func (c *cmdReceiverObjectsGet) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "get"
+	cmd.Short = "Getting record"
+	cmd.Long = "Getting record of name and type from maps"
+
+	cmd.PersistentFlags().StringVarP(&c.name, "name", "", "", "name of record")
+	cmd.PersistentFlags().StringVarP(&c.qtype, "type", "", "A", "type of record")
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverObjectsGet) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (objects) (get)"
+
+	if len(c.name) == 0 {
+		return fmt.Errorf("name is not set")
+	}
+	if _, err := ObjectType(c.qtype); err != nil {
+		return err
+	}
+
+	page, err := c.p.GetClientObject(c.name, c.qtype)
+	if err != nil {
+		c.p.G().L.Errorf("%s error getting object name:'%s', err:'%s'", id, c.name, err)
+		return err
+	}
+
+	return c.s.Print(page, page.Records)
+}
+
+type cmdReceiverObjectsCreate struct {
+	p *TReceiverPlugin
+	s *cmdReceiverObjects
+
+	records []string
+}
+
+func (c *cmdReceiverObjectsCreate) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "create"
+	cmd.Short = "Creating records"
+	cmd.Long = "Creating records in maps, existing records with other data are conflicts"
+
+	cmd.PersistentFlags().StringSliceVarP(&c.records, "record", "", nil,
+		"record in zone file format")
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverObjectsCreate) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (objects) (create)"
+
+	if len(c.records) == 0 {
+		return fmt.Errorf("record is not set")
+	}
+
+	// validating records before sending
+	for _, raw := range c.records {
+		if _, err := dns.NewRR(raw); err != nil {
+			c.p.G().L.Errorf("%s error parsing record:'%s', err:'%s'", id, raw, err)
+			return err
+		}
+	}
+
+	request := TObjectsRequest{Records: c.records, Dryrun: c.s.s.switches.Dryrun}
+	c.p.G().L.Debugf("%s request to create objects:'%d' dryrun:'%t'", id,
+		len(request.Records), request.Dryrun)
+
+	result, err := c.p.CreateClientObjects(&request)
+	if err != nil {
+		c.p.G().L.Errorf("%s error creating objects, err:'%s'", id, err)
+		return err
+	}
+
+	return c.s.Print(result, []string{fmt.Sprintf("; objects %s", result.AsString())})
+}
+
+type cmdReceiverObjectsRemove struct {
+	p *TReceiverPlugin
+	s *cmdReceiverObjects
+
+	name  string
+	qtype string
+}
+
+func (c *cmdReceiverObjectsRemove) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "remove"
+	cmd.Short = "Removing record"
+	cmd.Long = "Removing record of name and type from maps"
+
+	cmd.PersistentFlags().StringVarP(&c.name, "name", "", "", "name of record")
+	cmd.PersistentFlags().StringVarP(&c.qtype, "type", "", "A", "type of record")
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverObjectsRemove) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (objects) (remove)"
+
+	if len(c.name) == 0 {
+		return fmt.Errorf("name is not set")
+	}
+	if _, err := ObjectType(c.qtype); err != nil {
+		return err
+	}
+
+	c.p.G().L.Debugf("%s request to remove object name:'%s' type:'%s' dryrun:'%t'", id,
+		c.name, c.qtype, c.s.s.switches.Dryrun)
+
+	result, err := c.p.RemoveClientObject(c.name, c.qtype, c.s.s.switches.Dryrun)
+	if err != nil {
+		c.p.G().L.Errorf("%s error removing object name:'%s', err:'%s'", id, c.name, err)
+		return err
+	}
+
+	return c.s.Print(result, []string{fmt.Sprintf("; objects %s", result.AsString())})
+}
+
+type cmdReceiverObjectsClean struct {
+	p *TReceiverPlugin
+	s *cmdReceiverObjects
+
+	filter TObjectFilter
+
+	// removing all records if no filter set
+	all bool
+}
+
+func (c *cmdReceiverObjectsClean) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "clean"
+	cmd.Short = "Removing records matched filters"
+	cmd.Long = "Removing records matched filters from maps, all records with --all"
+
+	cmd.PersistentFlags().StringSliceVarP(&c.filter.Names, "filter", "", nil,
+		"regexp, ip address or network")
+	cmd.PersistentFlags().BoolVarP(&c.all, "all", "", false,
+		"removing all records if filter is not set")
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverObjectsClean) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (objects) (clean)"
+
+	if len(c.filter.Names) == 0 && !c.all {
+		return fmt.Errorf("filter is not set, --all is required to clean all records")
+	}
+	if err := c.filter.ValidateClean(); err != nil {
+		return err
+	}
+
+	c.p.G().L.Debugf("%s request to clean objects filter:['%s'] dryrun:'%t'", id,
+		strings.Join(c.filter.Names, ","), c.s.s.switches.Dryrun)
+
+	result, err := c.p.CleanClientObjects(&c.filter, c.all, c.s.s.switches.Dryrun)
+	if err != nil {
+		c.p.G().L.Errorf("%s error cleaning objects, err:'%s'", id, err)
+		return err
+	}
+
+	return c.s.Print(result, []string{fmt.Sprintf("; objects %s", result.AsString())})
+}
+
+type cmdReceiverObjectsImport struct {
+	p *TReceiverPlugin
+	s *cmdReceiverObjects
+
+	// zone file and its origin
+	file string
+	zone string
+}
+
+func (c *cmdReceiverObjectsImport) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "import"
+	cmd.Short = "Importing records of zone file"
+	cmd.Long = "Importing A and AAAA records of zone file into maps, other are skipped"
+
+	cmd.PersistentFlags().StringVarP(&c.file, "file", "", "", "zone file")
+	cmd.PersistentFlags().StringVarP(&c.zone, "zone", "", "",
+		"zone name (origin), if zone file has no SOA")
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverObjectsImport) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (objects) (import)"
+
+	if len(c.file) == 0 {
+		return fmt.Errorf("file is not set")
+	}
+
+	rrs, err := ParseMasterFile(c.file, c.zone, "")
+	if err != nil {
+		c.p.G().L.Errorf("%s error parsing file:'%s', err:'%s'", id, c.file, err)
+		return err
+	}
+
+	var request TObjectsRequest
+	request.Dryrun = c.s.s.switches.Dryrun
+	for _, rr := range rrs {
+		if rr.Header().Rrtype == dns.TypeSOA {
+			continue
+		}
+		request.Records = append(request.Records, rr.String())
+	}
+
+	c.p.G().L.Debugf("%s request to import file:'%s' objects:'%d' dryrun:'%t'", id,
+		c.file, len(request.Records), request.Dryrun)
+
+	result, err := c.p.CreateClientObjects(&request)
+	if err != nil {
+		c.p.G().L.Errorf("%s error importing objects, err:'%s'", id, err)
+		return err
+	}
+
+	return c.s.Print(result, []string{fmt.Sprintf("; objects %s", result.AsString())})
+}
+
+// Running one-shot command (without server), summary is
+// printed in JSON, error is returned if any zone failed
+func (c *cmdReceiver) Oneshot(command string,
+	run func(ctx context.Context, result *TOneshotResult)) error {
+
+	id := fmt.Sprintf("(receiver) (%s)", command)
+
+	t0 := time.Now()
+
+	result := TOneshotResult{Command: command, Dryrun: c.switches.Dryrun,
+		Zones: []TOneshotZone{}}
+	run(context.Background(), &result)
+	result.Elapsed = time.Since(t0).Seconds()
+
+	fmt.Printf("%s\n", result.AsJSON())
+
+	if result.Failed > 0 {
+		err := fmt.Errorf("%s failed zones:'%d' of '%d'", command, result.Failed,
+			len(result.Zones))
+		c.p.G().L.Errorf("%s error processing, err:'%s'", id, err)
+		return err
+	}
+
+	return nil
+}
+
+type cmdReceiverFetch struct {
+	p *TReceiverPlugin
+	s *cmdReceiver
+
+	zone    string
+	primary string
+	output  string
+}
+
+func (c *cmdReceiverFetch) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "fetch"
+	cmd.Short = "Transferring zone into snapshot"
+	cmd.Long = `
+Transferring zone via configured or ad-hoc primary into
+snapshot file without server running, summary is printed
+in JSON
+`
+
+	cmd.PersistentFlags().StringVarP(&c.zone, "zone", "", "", "zone name")
+	cmd.PersistentFlags().StringVarP(&c.primary, "primary", "", "",
+		"primary server (or alias), zone primaries if not set")
+	cmd.PersistentFlags().StringVarP(&c.output, "output", "", "",
+		"snapshot file, snapshots directory if not set")
+
+	var examples = []string{
+		`  a) transferring zone "example.net" via its primaries into
+     snapshots directory
+
+     receiver fetch --zone example.net`,
+
+		`  b) transferring zone via ad-hoc primary into file
+
+     receiver fetch --zone example.net --primary "[::1]:53" --output /tmp/example.net.blob`,
+	}
+
+	cmd.Example = strings.Join(examples, "\n\n")
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverFetch) Run(cmd *cobra.Command, args []string) error {
+	if len(c.zone) == 0 {
+		return fmt.Errorf("zone is not set")
+	}
+
+	return c.s.Oneshot(OneshotFetch, func(ctx context.Context, result *TOneshotResult) {
+		result.Add(c.p.FetchZone(ctx, c.zone, c.primary, c.output, result.Dryrun))
+	})
+}
+
+type cmdReceiverCook struct {
+	p *TReceiverPlugin
+	s *cmdReceiver
+
+	zones []string
+	all   bool
+	file  string
+}
+
+func (c *cmdReceiverCook) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "cook"
+	cmd.Short = "Syncing zones snapshots into maps"
+	cmd.Long = `
+Syncing zones snapshots into pinned maps without server
+running, records of zones in maps are synced as IXFR (other
+zones data is kept), summary is printed in JSON
+`
+
+	cmd.PersistentFlags().StringSliceVarP(&c.zones, "zone", "", nil, "zone name")
+	cmd.PersistentFlags().BoolVarP(&c.all, "all", "", false,
+		"all configured zones having snapshots")
+	cmd.PersistentFlags().StringVarP(&c.file, "file", "", "",
+		"snapshot file of zone, snapshots directory if not set")
+
+	var examples = []string{
+		`  a) syncing snapshots of zones into maps
+
+     receiver cook --zone example.net --zone example.com`,
+
+		`  b) syncing all zones snapshots with dry-run
+
+     receiver cook --all --dry-run`,
+
+		`  c) syncing snapshot file of zone
+
+     receiver cook --zone example.net --file /tmp/example.net.blob`,
+	}
+
+	cmd.Example = strings.Join(examples, "\n\n")
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverCook) Run(cmd *cobra.Command, args []string) error {
+	zones := c.zones
+	if c.all {
+		zones = c.p.SnapshotZones()
+	}
+	if len(zones) == 0 {
+		return fmt.Errorf("zone is not set")
+	}
+	if len(c.file) > 0 && len(zones) > 1 {
+		return fmt.Errorf("file could be set for one zone only")
+	}
+
+	return c.s.Oneshot(OneshotCook, func(ctx context.Context, result *TOneshotResult) {
+		for _, zone := range zones {
+			result.Add(c.p.CookZone(ctx, zone, c.file, result.Dryrun))
+		}
+	})
+}
+
+type cmdReceiverImport struct {
+	p *TReceiverPlugin
+	s *cmdReceiver
+
+	zone string
+	file string
+}
+
+func (c *cmdReceiverImport) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "import"
+	cmd.Short = "Importing zone file into maps"
+	cmd.Long = `
+Importing zone file (rfc1035 master file) into pinned maps
+without server running, snapshot of zone is written, summary
+is printed in JSON
+`
+
+	cmd.PersistentFlags().StringVarP(&c.zone, "zone", "", "", "zone name")
+	cmd.PersistentFlags().StringVarP(&c.file, "file", "", "", "zone file")
+
+	var examples = []string{
+		`  a) importing zone file of "example.net"
+
+     receiver import --zone example.net --file /etc/zones/example.net`,
+	}
+
+	cmd.Example = strings.Join(examples, "\n\n")
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverImport) Run(cmd *cobra.Command, args []string) error {
+	if len(c.zone) == 0 {
+		return fmt.Errorf("zone is not set")
+	}
+	if len(c.file) == 0 {
+		return fmt.Errorf("file is not set")
+	}
+
+	return c.s.Oneshot(OneshotImport, func(ctx context.Context, result *TOneshotResult) {
+		result.Add(c.p.ImportZoneFile(ctx, c.zone, c.file, result.Dryrun))
+	})
+}
+
+type cmdReceiverOwnership struct {
+	p *TReceiverPlugin
+	s *cmdReceiver
+}
+
+func (c *cmdReceiverOwnership) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "ownership"
+	cmd.Short = "Showing owners of maps entries via api"
+	cmd.Long = `
+Showing zones (or manual records) owning maps entries and
+keys claimed by more than one owner
+`
+
+	var examples = []string{
+		`  a) listing keys claimed by more than one owner
+
+     receiver ownership conflicts`,
+
+		`  b) showing owner and claims of entry
+
+     receiver ownership get --name www.example.net --type A`,
+	}
+
+	cmd.Example = strings.Join(examples, "\n\n")
+
+	conflictsCmd := cmdReceiverOwnershipConflicts{p: c.p, s: c}
+	cmd.AddCommand(conflictsCmd.Command())
+
+	getCmd := cmdReceiverOwnershipGet{p: c.p, s: c}
+	cmd.AddCommand(getCmd.Command())
+
+	return cmd
+}
+
+func printOwnership(ownership []TOwnership) {
+	fmt.Printf("%-48s %-24s %s\n", "KEY", "OWNER", "CLAIMS")
+	for _, o := range ownership {
+		var claims []string
+		for _, claim := range o.Claims {
+			claims = append(claims, fmt.Sprintf("%s:'%s'", claim.Owner, claim.Record))
+		}
+		fmt.Printf("%-48s %-24s %s\n", o.Key, o.Owner, strings.Join(claims, ","))
+	}
+}
+
+type cmdReceiverOwnershipConflicts struct {
+	p *TReceiverPlugin
+	s *cmdReceiverOwnership
+}
+
+func (c *cmdReceiverOwnershipConflicts) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "conflicts"
+	cmd.Short = "Listing ownership conflicts"
+	cmd.Long = "Listing keys claimed by more than one owner"
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverOwnershipConflicts) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (ownership) (conflicts)"
+
+	conflicts, err := c.p.GetClientOwnershipConflicts()
+	if err != nil {
+		c.p.G().L.Errorf("%s error getting conflicts, err:'%s'", id, err)
+		return err
+	}
+
+	printOwnership(conflicts)
+	return nil
+}
+
+type cmdReceiverOwnershipGet struct {
+	p *TReceiverPlugin
+	s *cmdReceiverOwnership
+
+	name  string
+	qtype string
+}
+
+func (c *cmdReceiverOwnershipGet) Command() *cobra.Command {
+	cmd := &cobra.Command{}
+
+	cmd.Use = "get"
+	cmd.Short = "Showing owner of entry"
+	cmd.Long = "Showing owner of entry and all claims in order of precedence"
+
+	cmd.PersistentFlags().StringVarP(&c.name, "name", "", "", "record name")
+	cmd.PersistentFlags().StringVarP(&c.qtype, "type", "", "A", "record type")
+
+	cmd.RunE = c.Run
+	return cmd
+}
+
+func (c *cmdReceiverOwnershipGet) Run(cmd *cobra.Command, args []string) error {
+	id := "(receiver) (ownership) (get)"
+
+	if len(c.name) == 0 {
+		return fmt.Errorf("name is not set")
+	}
+
+	ownership, err := c.p.GetClientOwnership(c.name, c.qtype)
+	if err != nil {
+		c.p.G().L.Errorf("%s error getting owner, err:'%s'", id, err)
+		return err
+	}
+
+	printOwnership([]TOwnership{*ownership})
+	return nil
+}
@@ -0,0 +1,418 @@
+package receiver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// snapshot blob container: magic, format version, header
+// in JSON (zone, serial, source, created-at, records count),
+// gzip compressed zone text body and trailing sha256 checksum
+// of all preceding bytes, all lengths are big endian
+//
+//   magic[8] version[2] hlen[4] header[hlen] blen[4] body[blen] sha256[32]
+//
+// snapshots in plain text (written before container) are
+// still read and rewritten in container on next write
+
+const (
+	// magic of snapshot container
+	SnapshotMagic = "YADNSBLB"
+
+	// current snapshot container format version
+	SnapshotFormatVersion = 1
+
+	// suffix of corrupted snapshots quarantined
+	SnapshotCorruptedSuffix = "corrupted"
+
+	// max length of container header
+	SnapshotMaxHeader = 1 << 16
+
+	// snapshot corrupted (and quarantined) counter
+	// per zone
+	MetricSnapshotCorrupted = "receiver-snapshot-corrupted"
+)
+
+var (
+	// snapshot could not be read: checksum mismatch,
+	// truncated or could not be parsed
+	ErrSnapshotCorrupted = errors.New("snapshot corrupted")
+
+	// snapshot container version is not supported
+	ErrSnapshotVersion = errors.New("snapshot version not supported")
+)
+
+type TSnapshotHeader struct {
+	// container format version
+	Version int `json:"version"`
+
+	Zone   string `json:"zone"`
+	Serial uint32 `json:"serial"`
+
+	// zone type and transfer mode of snapshot
+	Source string `json:"source"`
+	Mode   string `json:"mode"`
+
+	// time snapshot is written
+	Created time.Time `json:"created"`
+
+	// number of records in body
+	Records int `json:"records"`
+}
+
+// Encoding snapshot header and zone text body into
+// container
+func EncodeSnapshot(header *TSnapshotHeader, body []byte) ([]byte, error) {
+	h, err := json.Marshal(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	if _, err = w.Write(body); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	b.WriteString(SnapshotMagic)
+	binary.Write(&b, binary.BigEndian, uint16(header.Version))
+	binary.Write(&b, binary.BigEndian, uint32(len(h)))
+	b.Write(h)
+	binary.Write(&b, binary.BigEndian, uint32(compressed.Len()))
+	b.Write(compressed.Bytes())
+
+	checksum := sha256.Sum256(b.Bytes())
+	b.Write(checksum[:])
+
+	return b.Bytes(), nil
+}
+
+func corrupted(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrSnapshotCorrupted, fmt.Sprintf(format, args...))
+}
+
+// reading header of container (without checksum
+// verification), header is nil for plain text
+func decodeSnapshotHeader(content []byte) (*TSnapshotHeader, int, error) {
+	if !bytes.HasPrefix(content, []byte(SnapshotMagic)) {
+		return nil, 0, nil
+	}
+
+	offset := len(SnapshotMagic)
+	if len(content) < offset+6 {
+		return nil, 0, corrupted("truncated header")
+	}
+
+	version := int(binary.BigEndian.Uint16(content[offset:]))
+	if version > SnapshotFormatVersion {
+		return nil, 0, fmt.Errorf("%w: version:'%d'", ErrSnapshotVersion, version)
+	}
+
+	hlen := int(binary.BigEndian.Uint32(content[offset+2:]))
+	offset += 6
+	if len(content) < offset+hlen {
+		return nil, 0, corrupted("truncated header")
+	}
+
+	var header TSnapshotHeader
+	if err := json.Unmarshal(content[offset:offset+hlen], &header); err != nil {
+		return nil, 0, corrupted("header, err:'%s'", err)
+	}
+
+	return &header, offset + hlen, nil
+}
+
+// Decoding container into header and zone text body, header
+// is nil and body is content itself for plain text snapshots
+func DecodeSnapshot(content []byte) (*TSnapshotHeader, []byte, error) {
+	if !bytes.HasPrefix(content, []byte(SnapshotMagic)) {
+		return nil, content, nil
+	}
+
+	if len(content) < len(SnapshotMagic)+sha256.Size {
+		return nil, nil, corrupted("truncated content")
+	}
+
+	// newer versions could have other layout, so version
+	// is checked before checksum
+	version := int(binary.BigEndian.Uint16(content[len(SnapshotMagic):]))
+	if version > SnapshotFormatVersion {
+		return nil, nil, fmt.Errorf("%w: version:'%d'", ErrSnapshotVersion, version)
+	}
+
+	data := content[:len(content)-sha256.Size]
+	checksum := sha256.Sum256(data)
+	if !bytes.Equal(checksum[:], content[len(data):]) {
+		return nil, nil, corrupted("checksum mismatch")
+	}
+
+	header, offset, err := decodeSnapshotHeader(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(data) < offset+4 {
+		return nil, nil, corrupted("truncated body")
+	}
+	blen := int(binary.BigEndian.Uint32(data[offset:]))
+	offset += 4
+	if len(data) != offset+blen {
+		return nil, nil, corrupted("body length:'%d' mismatch", blen)
+	}
+
+	r, err := gzip.NewReader(bytes.NewReader(data[offset:]))
+	if err != nil {
+		return nil, nil, corrupted("body, err:'%s'", err)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, corrupted("body, err:'%s'", err)
+	}
+
+	return header, body, nil
+}
+
+// Reading snapshot header of file, header is nil for
+// plain text snapshots
+func ReadSnapshotHeader(filename string) (*TSnapshotHeader, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	prefix := make([]byte, len(SnapshotMagic)+6)
+	n, err := io.ReadFull(f, prefix)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if !bytes.HasPrefix(prefix[:n], []byte(SnapshotMagic)) {
+		return nil, nil
+	}
+	if n < len(prefix) {
+		return nil, corrupted("truncated header")
+	}
+
+	hlen := int64(binary.BigEndian.Uint32(prefix[len(SnapshotMagic)+2:]))
+	if hlen > SnapshotMaxHeader {
+		return nil, corrupted("header length:'%d' exceeds max", hlen)
+	}
+	h := make([]byte, hlen)
+	if _, err := io.ReadFull(f, h); err != nil {
+		return nil, corrupted("truncated header")
+	}
+
+	header, _, err := decodeSnapshotHeader(append(prefix, h...))
+	return header, err
+}
+
+// Making header of plain text snapshot (written before
+// containers): zone is taken from SOA owner if snapshot
+// filename is of it (e.g. policy zones have root SOA owner
+// and are not resolved), header is nil if zone is not found
+func ReadPlainSnapshotHeader(filename string) (*TSnapshotHeader, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	rr, err := NewXFR(string(content))
+	if err != nil {
+		return nil, corrupted("records, err:'%s'", err)
+	}
+	if len(rr) == 0 || rr[0].Header().Rrtype != dns.TypeSOA {
+		return nil, nil
+	}
+
+	zone := RemoveDot(strings.ToLower(rr[0].Header().Name))
+	name := strings.TrimSuffix(filepath.Base(filename), fmt.Sprintf(".%s", DefaultSnapshotSuffix))
+	if len(zone) == 0 || Md5(zone) != name {
+		return nil, nil
+	}
+
+	var header TSnapshotHeader
+	header.Zone = zone
+	header.Serial = rr[0].(*dns.SOA).Serial
+	header.Records = len(rr)
+
+	return &header, nil
+}
+
+// Getting age of snapshot in seconds: container creation
+// time or file modification time for plain text snapshots,
+// 0 if file does not exist
+func SnapshotFileAge(filename string) float64 {
+	if header, err := ReadSnapshotHeader(filename); err == nil && header != nil {
+		return time.Since(header.Created).Seconds()
+	}
+	return GetFileAge(filename)
+}
+
+// Writing file atomically: content is written and synced
+// into temporary file in the same directory and renamed
+func WriteFileAtomic(filename string, content []byte, perm os.FileMode) error {
+	dir := filepath.Dir(filename)
+
+	f, err := os.CreateTemp(dir, fmt.Sprintf(".%s.*", filepath.Base(filename)))
+	if err != nil {
+		return err
+	}
+	temp := f.Name()
+	defer os.Remove(temp)
+
+	if _, err = f.Write(content); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(temp, perm); err != nil {
+		return err
+	}
+	if err = os.Rename(temp, filename); err != nil {
+		return err
+	}
+
+	// syncing directory to persist rename
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// Moving corrupted snapshot aside, so it is not read
+// again, returning quarantined file name
+func QuarantineSnapshotFile(p *TReceiverPlugin, filename string, zone string,
+	cause error) (string, error) {
+
+	id := "(snapshot) (quarantine)"
+
+	quarantined := fmt.Sprintf("%s.%s.%d", filename, SnapshotCorruptedSuffix,
+		time.Now().Unix())
+
+	p.G().L.Errorf("%s zone:'%s' snapshot:'%s' corrupted, moving to '%s', err:'%s'",
+		id, zone, filename, quarantined, cause)
+	p.PushMetric(MetricSnapshotCorrupted, []string{fmt.Sprintf("zone=%s", zone)}, 1)
+
+	if err := os.Rename(filename, quarantined); err != nil {
+		p.G().L.Errorf("%s error moving snapshot:'%s', err:'%s'", id, filename, err)
+		return "", err
+	}
+
+	return quarantined, nil
+}
+
+// Making snapshot container content: header and zone text
+// with SOA, records and SOA
+func (t *TSnapshotZone) Container() ([]byte, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", t.soa.String())
+
+	records := 2
+	for _, vv := range t.rrsets {
+		for _, v := range vv {
+			fmt.Fprintf(&b, "%s\n", v.String())
+			records++
+		}
+	}
+	fmt.Fprintf(&b, "%s\n", t.soa.String())
+
+	var header TSnapshotHeader
+	header.Version = SnapshotFormatVersion
+	header.Zone = t.zone
+	header.Serial, _ = t.Serial()
+	header.Created = time.Now()
+	header.Records = records
+
+	header.Source = TransferTypeAXFR
+	if t.p != nil && t.p.zones != nil {
+		if config, err := t.p.zones.GetConfig(t.zone); err == nil && len(config.Type) > 0 {
+			header.Source = config.Type
+		}
+	}
+	if t.imports != nil {
+		header.Mode = TransferModeAsString(t.imports.mode)
+	}
+
+	return EncodeSnapshot(&header, []byte(b.String()))
+}
+
+// Reading snapshot file in container (or plain text), file
+// corrupted is quarantined and error is returned
+func ReadSnapshotFile(p *TReceiverPlugin, filename string, zone string) (*TSnapshotZone, error) {
+	return readSnapshotFile(p, filename, zone, true)
+}
+
+// Reading snapshot file for read-only callers (e.g. diff),
+// corrupted snapshot is kept in place
+func PeekSnapshotFile(p *TReceiverPlugin, filename string, zone string) (*TSnapshotZone, error) {
+	return readSnapshotFile(p, filename, zone, false)
+}
+
+func readSnapshotFile(p *TReceiverPlugin, filename string, zone string,
+	quarantine bool) (*TSnapshotZone, error) {
+
+	id := "(snapshot) (read)"
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	header, body, err := DecodeSnapshot(content)
+	if err == nil && header == nil {
+		p.G().L.Debugf("%s zone:'%s' snapshot:'%s' is plain text, migrating on next write",
+			id, zone, filename)
+	}
+	if err == nil && header != nil && len(zone) > 0 && header.Zone != zone {
+		err = corrupted("zone:'%s' mismatch", header.Zone)
+	}
+
+	var rr []dns.RR
+	if err == nil {
+		if rr, err = NewXFR(string(body)); err != nil {
+			err = corrupted("records, err:'%s'", err)
+		}
+	}
+	if err == nil && header != nil && len(rr) != header.Records {
+		err = corrupted("records:'%d' expected:'%d'", len(rr), header.Records)
+	}
+
+	if err != nil {
+		if quarantine && errors.Is(err, ErrSnapshotCorrupted) {
+			QuarantineSnapshotFile(p, filename, zone, err)
+		}
+		return nil, err
+	}
+
+	if header != nil {
+		p.G().L.Debugf("%s zone:'%s' snapshot:'%s' version:'%d' serial:'%d' source:'%s' created:'%s'",
+			id, zone, filename, header.Version, header.Serial, header.Source,
+			TimeAsString(header.Created))
+	}
+
+	return NewSnapshotZoneFromRR(p, rr, zone)
+}
@@ -0,0 +1,126 @@
+package receiver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotContainer(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+	p.c.Options.Snapshots.Directory = t.TempDir()
+
+	zone := "example.net"
+	hosts := guardsHosts(5, 0)
+
+	snapshot, err := newGuardsSnapshot(p, 7, true, hosts)
+	if err != nil {
+		t.Fatalf("error making snapshot, err:'%s'", err)
+	}
+
+	// plain text snapshot as it was written before
+	// container
+	var legacy []string
+	legacy = append(legacy, snapshot.soa.String())
+	for _, rrset := range snapshot.rrsets {
+		for _, rr := range rrset {
+			legacy = append(legacy, rr.String())
+		}
+	}
+	legacy = append(legacy, snapshot.soa.String())
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		// changing written content: "none", "legacy",
+		// "flip" a byte, "truncate", "version" and
+		// "garbage" as plain text
+		change string
+
+		err         error
+		quarantined bool
+	}
+
+	var Tests = []TTest{
+		{"6c1d3e5f-7a8b-4c9d-8e0f-1a2b3c4d5e6f", true, "none", nil, false},
+		{"7d2e4f6a-8b9c-4d0e-9f1a-2b3c4d5e6f7a", true, "legacy", nil, false},
+		{"8e3f5a7b-9c0d-4e1f-8a2b-3c4d5e6f7a8b", true, "flip", ErrSnapshotCorrupted, true},
+		{"9f4a6b8c-0d1e-4f2a-9b3c-4d5e6f7a8b9c", true, "truncate", ErrSnapshotCorrupted, true},
+		{"0a5b7c9d-1e2f-4a3b-8c4d-5e6f7a8b9c0d", true, "version", ErrSnapshotVersion, false},
+		{"1b6c8d0e-2f3a-4b4c-9d5e-6f7a8b9c0d1e", true, "garbage", ErrSnapshotCorrupted, true},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		filename := GetSnapshotFilename(p, zone)
+		if err := snapshot.WriteSnapshotZone(false); err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error writing snapshot, err:'%s'", err))
+			continue
+		}
+
+		content, _ := os.ReadFile(filename)
+		switch test.change {
+		case "legacy":
+			content = []byte(strings.Join(legacy, "\n") + "\n")
+		case "flip":
+			content[len(content)/2] ^= 0xff
+		case "truncate":
+			content = content[:len(content)-10]
+		case "version":
+			content[len(SnapshotMagic)+1] = SnapshotFormatVersion + 1
+		case "garbage":
+			content = []byte("example.net. IN SOA broken\n")
+		}
+		os.WriteFile(filename, content, 0644)
+
+		if test.change == "none" {
+			header, err := ReadSnapshotHeader(filename)
+			if err != nil || header == nil || header.Zone != zone || header.Serial != 7 ||
+				header.Records != 7 || header.Version != SnapshotFormatVersion {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("unexpected header:'%+v' err:'%v'", header, err))
+				continue
+			}
+		}
+
+		read, err := NewSnapshotZoneFromFile(p, filename, zone)
+		if !errors.Is(err, test.err) {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error expected:'%v' got:'%v'", test.err, err))
+			continue
+		}
+
+		if err == nil && !read.Equal(snapshot) {
+			t.Error("\nUUID", test.uuid, "snapshot read differs from written")
+			continue
+		}
+
+		corrupted, _ := filepath.Glob(fmt.Sprintf("%s.%s.*", filename, SnapshotCorruptedSuffix))
+		if test.quarantined != (len(corrupted) > 0) || test.quarantined == Exists(filename) {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("quarantined expected:'%t' got:'%d'",
+				test.quarantined, len(corrupted)))
+			continue
+		}
+		for _, f := range corrupted {
+			os.Remove(f)
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+
+	// no temporary files are left
+	files, _ := filepath.Glob(fmt.Sprintf("%s/.*", p.c.Options.Snapshots.Directory))
+	if len(files) > 0 {
+		t.Error(fmt.Sprintf("temporary files left:['%s']", strings.Join(files, ",")))
+	}
+}
@@ -71,18 +71,16 @@ func (j *CookerWorker) CookIncrementZone(ctx context.Context,
 	id := "(cooker)"
 
 	if mode == CookerLock {
-		if _, ok := j.zones.locks[zone]; !ok {
-			return nil, fmt.Errorf("no lock for zone available")
-		}
-
 		t0 := time.Now()
 		j.p.G().L.Debugf("%s request to lock zone:'%s'...", id, zone)
-		j.zones.locks[zone].Lock()
-		defer j.zones.locks[zone].Unlock()
+		lock := j.zones.ZoneLock(zone)
+		lock.Lock()
+		defer lock.Unlock()
 		j.p.G().L.Debugf("%s zone:'%s' locked in '%s' OK", id, zone, time.Since(t0))
 	}
 
-	if _, ok := j.zones.zones[zone]; !ok {
+	state, ok := j.zones.GetState(zone)
+	if !ok {
 		return nil, fmt.Errorf("no zone available")
 	}
 
@@ -94,8 +92,6 @@ func (j *CookerWorker) CookIncrementZone(ctx context.Context,
 		return nil, err
 	}
 
-	state := j.zones.zones[zone]
-
 	sid := state.SnapshotID
 	snapshot := state.Snapshots[sid]
 
@@ -118,6 +114,10 @@ func (j *CookerWorker) CookIncrementZone(ctx context.Context,
 		return nil, err
 	}
 
+	if !j.options.Dryrun {
+		j.zones.CommitHTTPValidators(&snapshot)
+	}
+
 	return r, nil
 }
 
@@ -139,9 +139,11 @@ func (j *CookerWorker) Cook(ctx context.Context, index int, dryrun bool) error {
 	// if at least one zone is AXFR w need to sync all
 	// zones as AXFR (as in AXFR mode we need first
 	// create total rrset and sync it with bpf.Map
+	zones := states.States()
+
 	mode := TransferModeIXFR
 	counter := 0
-	for zone, state := range states.zones {
+	for zone, state := range zones {
 		sid := state.SnapshotID
 		if sid == -1 {
 			err := fmt.Errorf("no valid snapshot for zone:'%s' found", zone)
@@ -155,13 +157,13 @@ func (j *CookerWorker) Cook(ctx context.Context, index int, dryrun bool) error {
 			mode = TransferModeAXFR
 		}
 
-		j.p.G().L.Debugf("%s state [%d]/[%d] zone:'%s' as '%s'", id, counter, len(states.zones),
+		j.p.G().L.Debugf("%s state [%d]/[%d] zone:'%s' as '%s'", id, counter, len(zones),
 			zone, TransferModeAsString(imports.mode))
 
 		counter++
 	}
 
-	j.p.G().L.Debugf("%s map zones:'%d' state detected as '%s'", id, len(states.zones),
+	j.p.G().L.Debugf("%s map zones:'%d' state detected as '%s'", id, len(zones),
 		TransferModeAsString(mode))
 
 	var result *TSyncMapResult
@@ -180,14 +182,21 @@ func (j *CookerWorker) Cook(ctx context.Context, index int, dryrun bool) error {
 		snapshot.timestamp = time.Now()
 		snapshot.rrsets = make(map[string][]dns.RR)
 
-		// creating new snapshot with all AXFR rrsets
-		for _, state := range states.zones {
+		// creating new snapshot with all AXFR rrsets,
+		// policies replace zones rrsets
+		for _, state := range zones {
+			if state.IsPolicy() {
+				continue
+			}
 			sid := state.SnapshotID
 			snap := state.Snapshots[sid]
 			for k, v := range snap.rrsets {
 				snapshot.rrsets[k] = append(snapshot.rrsets[k], v...)
 			}
 		}
+		for k, v := range states.PolicyRRsets("") {
+			snapshot.rrsets[k] = v
+		}
 		snapshot.Dump(j.p, "axfr", DefaultDumpMaxRRsets)
 
 		if result, err = snapshot.SyncMap(mode, nil, j.options.Dryrun); err != nil {
@@ -195,13 +204,20 @@ func (j *CookerWorker) Cook(ctx context.Context, index int, dryrun bool) error {
 			return err
 		}
 
+		if !j.options.Dryrun {
+			for _, state := range zones {
+				snap := state.Snapshots[state.SnapshotID]
+				j.zones.CommitHTTPValidators(&snap)
+			}
+		}
+
 	case TransferModeIXFR:
 
 		result = new(TSyncMapResult)
 
 		// We have all states snapshots and actions ready
 		// need to apply all changes (IXFR modes)
-		for zone := range states.zones {
+		for zone := range zones {
 			r, err := j.CookIncrementZone(ctx, zone, CookerLock)
 			if err != nil {
 				j.p.G().L.Errorf("%s error syncing map zone:'%s', err:'%s'",
@@ -216,7 +232,7 @@ func (j *CookerWorker) Cook(ctx context.Context, index int, dryrun bool) error {
 		//j.monitor.PushIntMetric(MetricsCookerSyncRemoved, int64(result.Removed))
 	}
 
-	for zone, state := range states.zones {
+	for zone, state := range zones {
 		sid := state.SnapshotID
 		snapshot := state.Snapshots[sid]
 
@@ -235,7 +251,7 @@ func (j *CookerWorker) Cook(ctx context.Context, index int, dryrun bool) error {
 		// we need to run it
 
 		if j.verifier != nil {
-			options := VerifyOptions{Dryrun: false}
+			options := VerifyOptions{Dryrun: false, OnCook: true}
 			result, err := j.verifier.Verify(&options)
 			if err != nil {
 				j.p.G().L.Errorf("%s error verify snapshots and bpf maps, err:'%s'", id, err)
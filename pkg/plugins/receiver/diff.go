@@ -0,0 +1,475 @@
+package receiver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// zone diff compares two sources of zone data: current
+// snapshot blob, snapshot generation, zone master file,
+// live AXFR from primary and bpf maps contents. Sources
+// are set as "<type>[:<value>]", e.g. "snapshot",
+// "generation:1792339758190785000", "file:/etc/zones/db",
+// "axfr" (via zone primaries), "axfr:[::1]:53" or "maps". Zone
+// files are read only within http transfer include directory
+
+const (
+	DiffSourceSnapshot   = "snapshot"
+	DiffSourceGeneration = "generation"
+	DiffSourceFile       = "file"
+	DiffSourceAXFR       = "axfr"
+	DiffSourceMaps       = "maps"
+
+	// diff output formats
+	DiffFormatText = "text"
+	DiffFormatJSON = "json"
+	DiffFormatIXFR = "ixfr"
+
+	// per record changes
+	DiffAdded   = "added"
+	DiffRemoved = "removed"
+	DiffChanged = "changed"
+)
+
+var (
+	ErrDiffSource = errors.New("diff source not valid")
+
+	ErrDiffFormat = errors.New("diff format not valid")
+)
+
+type TDiffSource struct {
+	Type  string `json:"type"`
+	Value string `json:"value,omitempty"`
+}
+
+func (t *TDiffSource) String() string {
+	if len(t.Value) == 0 {
+		return t.Type
+	}
+	return fmt.Sprintf("%s:%s", t.Type, t.Value)
+}
+
+// Parsing diff source "<type>[:<value>]"
+func ParseDiffSource(source string) (*TDiffSource, error) {
+	var t TDiffSource
+
+	t.Type, t.Value, _ = strings.Cut(strings.TrimSpace(source), ":")
+
+	switch t.Type {
+	case DiffSourceSnapshot, DiffSourceMaps:
+		if len(t.Value) > 0 {
+			return nil, fmt.Errorf("%w: source:'%s' has no value", ErrDiffSource, t.Type)
+		}
+	case DiffSourceGeneration:
+		if _, err := strconv.ParseInt(t.Value, 10, 64); err != nil {
+			return nil, fmt.Errorf("%w: generation:'%s'", ErrDiffSource, t.Value)
+		}
+	case DiffSourceFile:
+		if len(t.Value) == 0 {
+			return nil, fmt.Errorf("%w: file is not set", ErrDiffSource)
+		}
+	case DiffSourceAXFR:
+	default:
+		return nil, fmt.Errorf("%w: source:'%s'", ErrDiffSource, source)
+	}
+
+	return &t, nil
+}
+
+func ValidDiffFormat(format string) error {
+	switch format {
+	case DiffFormatText, DiffFormatJSON, DiffFormatIXFR:
+		return nil
+	}
+	return fmt.Errorf("%w: format:'%s'", ErrDiffFormat, format)
+}
+
+// change of rrset (name and type), records are
+// in presentation format
+type TDiffRecord struct {
+	Change string   `json:"change"`
+	Key    string   `json:"key"`
+	From   []string `json:"from,omitempty"`
+	To     []string `json:"to,omitempty"`
+}
+
+type TZoneDiff struct {
+	Zone string `json:"zone"`
+
+	From string `json:"from"`
+	To   string `json:"to"`
+
+	// SOA of sources (if any), bpf maps have no SOA
+	FromSOA string `json:"from-soa,omitempty"`
+	ToSOA   string `json:"to-soa,omitempty"`
+
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+	Changed int `json:"changed"`
+
+	// per record counters as verifier reports
+	Result *TVerifyResult `json:"result"`
+
+	Records []TDiffRecord `json:"records"`
+}
+
+func (t *TZoneDiff) AsString() string {
+	var out []string
+
+	out = append(out, fmt.Sprintf("zone:'%s'", t.Zone))
+	out = append(out, fmt.Sprintf("from:'%s'", t.From))
+	out = append(out, fmt.Sprintf("to:'%s'", t.To))
+	out = append(out, fmt.Sprintf("added:'%d'", t.Added))
+	out = append(out, fmt.Sprintf("removed:'%d'", t.Removed))
+	out = append(out, fmt.Sprintf("changed:'%d'", t.Changed))
+
+	return strings.Join(out, ",")
+}
+
+// Formatting diff as text: "+" added, "-" removed
+// records, changed rrsets have both
+func (t *TZoneDiff) AsText() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "; zone:'%s' from:'%s' to:'%s'\n", t.Zone, t.From, t.To)
+	fmt.Fprintf(&b, "; added:'%d' removed:'%d' changed:'%d'\n", t.Added, t.Removed,
+		t.Changed)
+
+	for _, r := range t.Records {
+		fmt.Fprintf(&b, "; %s %s\n", r.Change, r.Key)
+		for _, rr := range r.From {
+			fmt.Fprintf(&b, "- %s\n", rr)
+		}
+		for _, rr := range r.To {
+			fmt.Fprintf(&b, "+ %s\n", rr)
+		}
+	}
+
+	return b.String()
+}
+
+// Formatting diff as IXFR (rfc1995) sequence: new SOA,
+// old SOA, deleted records, new SOA, added records and
+// new SOA, sources without SOA have synthetic one with
+// zero serial
+func (t *TZoneDiff) AsIXFR() (string, error) {
+	soa := func(s string) (string, error) {
+		if len(s) == 0 {
+			return NewSyntheticSOA(t.Zone, 0).String(), nil
+		}
+		rr, err := dns.NewRR(s)
+		if err != nil {
+			return "", err
+		}
+		return rr.String(), nil
+	}
+
+	from, err := soa(t.FromSOA)
+	if err != nil {
+		return "", err
+	}
+	to, err := soa(t.ToSOA)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n%s\n", to, from)
+	for _, r := range t.Records {
+		for _, rr := range r.From {
+			fmt.Fprintf(&b, "%s\n", rr)
+		}
+	}
+	fmt.Fprintf(&b, "%s\n", to)
+	for _, r := range t.Records {
+		for _, rr := range r.To {
+			fmt.Fprintf(&b, "%s\n", rr)
+		}
+	}
+	fmt.Fprintf(&b, "%s\n", to)
+
+	return b.String(), nil
+}
+
+// Formatting diff in format requested
+func (t *TZoneDiff) Format(format string) (string, error) {
+	switch format {
+	case DiffFormatText:
+		return t.AsText(), nil
+	case DiffFormatIXFR:
+		return t.AsIXFR()
+	}
+	return "", fmt.Errorf("%w: format:'%s'", ErrDiffFormat, format)
+}
+
+// rrsets with owner names in lower case, as bpf maps
+// keep names in lower case
+func normalizeRRsets(rrsets map[string][]dns.RR) map[string][]dns.RR {
+	out := make(map[string][]dns.RR)
+	for _, rrset := range rrsets {
+		for _, rr := range rrset {
+			r := dns.Copy(rr)
+			h := r.Header()
+			h.Name = strings.ToLower(h.Name)
+			key := fmt.Sprintf("%s-%s", h.Name, dns.Type(h.Rrtype).String())
+			out[key] = append(out[key], r)
+		}
+	}
+	return out
+}
+
+func rrsetStrings(rrset []dns.RR) []string {
+	var out []string
+	for _, rr := range rrset {
+		out = append(out, rr.String())
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Making diff between snapshots, changed set is detected
+// as zone state does on updates and record counters as
+// verifier does
+func DiffSnapshots(p *TReceiverPlugin, zone string, from *TSnapshotZone,
+	to *TSnapshotZone) (*TZoneDiff, error) {
+
+	s1 := &TSnapshotZone{p: p, zone: zone, rrsets: normalizeRRsets(from.rrsets),
+		timestamp: from.timestamp}
+	s2 := &TSnapshotZone{p: p, zone: zone, rrsets: normalizeRRsets(to.rrsets),
+		timestamp: to.timestamp}
+
+	var state TZoneState
+	changed, err := state.DetectChangedState(s1, s2)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier, err := NewVerifierWorker(p, p.zones)
+	if err != nil {
+		return nil, err
+	}
+	result, _, err := verifier.CompareSnapshots(s1.rrsets, s2.rrsets)
+	if err != nil {
+		return nil, err
+	}
+
+	var diff TZoneDiff
+	diff.Zone = zone
+	diff.Result = result
+	diff.Records = []TDiffRecord{}
+
+	if from.soa != nil {
+		diff.FromSOA = from.soa.String()
+	}
+	if to.soa != nil {
+		diff.ToSOA = to.soa.String()
+	}
+
+	keys := make(map[string]bool)
+	for _, change := range []int{ChangeRemove, ChangeCreate} {
+		for k := range changed.rrchanges[change] {
+			keys[k] = true
+		}
+	}
+
+	for k := range keys {
+		removed, rok := changed.rrchanges[ChangeRemove][k]
+		created, cok := changed.rrchanges[ChangeCreate][k]
+
+		record := TDiffRecord{Key: k, From: rrsetStrings(removed), To: rrsetStrings(created)}
+		switch {
+		case rok && cok:
+			record.Change = DiffChanged
+			diff.Changed++
+		case rok:
+			record.Change = DiffRemoved
+			diff.Removed++
+		default:
+			record.Change = DiffAdded
+			diff.Added++
+		}
+		diff.Records = append(diff.Records, record)
+	}
+
+	sort.Slice(diff.Records, func(i, j int) bool {
+		return diff.Records[i].Key < diff.Records[j].Key
+	})
+
+	return &diff, nil
+}
+
+// Transferring zone via primary set or via zone
+// primaries in preference order
+func (z *ZonesState) diffTransfer(zone string, primary string) ([]dns.RR, error) {
+	id := "(diff) (axfr)"
+
+	primaries := []string{primary}
+	if len(primary) == 0 {
+		config, err := z.GetConfig(zone)
+		if err != nil {
+			return nil, fmt.Errorf("zone:'%s', err:'%w'", zone, ErrZoneNotFound)
+		}
+		if primaries = z.PrimariesOrder(zone, config.Primary); len(primaries) == 0 {
+			return nil, fmt.Errorf("zone:'%s' has no primaries available", zone)
+		}
+	}
+
+	var err error
+	for _, primary := range primaries {
+		server := z.Primary(primary)
+
+		var keys []*TTsigKey
+		if keys, err = z.TsigKeys(zone, primary); err != nil {
+			return nil, err
+		}
+
+		opts := new(TransferOptions)
+		opts.Mode = TransferModeAXFR
+		if opts.TLS, err = z.TransferTLS(primary, server); err != nil {
+			z.p.G().L.Errorf("%s zone:'%s' primary:'%s' tls error, err:'%s'", id, zone,
+				server, err)
+			continue
+		}
+
+		var rr []dns.RR
+		transfer := func(key *TTsigKey) error {
+			var err error
+			opts.Tsig = key
+			rr, err = TransferZone(server, zone, opts)
+			return err
+		}
+
+		if _, err = WithTsigKeys(keys, transfer, nil); err == nil {
+			z.p.G().L.Debugf("%s transferred zone:'%s' via primary:'%s' rr:'%d'", id,
+				zone, server, len(rr))
+			return rr, nil
+		}
+
+		z.p.G().L.Errorf("%s error transferring zone:'%s' via primary:'%s', err:'%s'",
+			id, zone, server, err)
+	}
+
+	return nil, err
+}
+
+// Getting zone file name of diff source: zone files are read
+// only inside include directory (it should be configured),
+// relative names are taken within it
+func (z *ZonesState) DiffSourceFilename(value string) (string, error) {
+	base := z.p.L().HTTPTransfer.IncludeDirectory
+	if len(base) == 0 {
+		return "", fmt.Errorf("%w: file:'%s' include directory is not configured",
+			ErrDiffSource, value)
+	}
+
+	base = filepath.Clean(base)
+	filename := filepath.Clean(value)
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(base, filename)
+	}
+
+	rel, err := filepath.Rel(base, filename)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%w: file:'%s' is out of directory:'%s'",
+			ErrDiffSource, value, base)
+	}
+	return filename, nil
+}
+
+// Loading snapshot of zone from diff source
+func (z *ZonesState) LoadDiffSource(ctx context.Context, zone string,
+	source *TDiffSource) (*TSnapshotZone, error) {
+
+	id := "(diff) (source)"
+
+	z.p.G().L.Debugf("%s loading zone:'%s' source:'%s'", id, zone, source.String())
+
+	switch source.Type {
+	case DiffSourceSnapshot:
+		filename := GetSnapshotFilename(z.p, zone)
+		if !Exists(filename) {
+			return nil, fmt.Errorf("%w: zone:'%s' has no snapshot", ErrGenerationNotFound, zone)
+		}
+		return PeekSnapshotFile(z.p, filename, zone)
+
+	case DiffSourceGeneration:
+		generation, err := strconv.ParseInt(source.Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: generation:'%s'", ErrDiffSource, source.Value)
+		}
+		filename := GetGenerationFilename(z.p, zone, generation)
+		if !Exists(filename) {
+			return nil, fmt.Errorf("%w: generation:'%d'", ErrGenerationNotFound, generation)
+		}
+		return PeekSnapshotFile(z.p, filename, zone)
+
+	case DiffSourceFile:
+		filename, err := z.DiffSourceFilename(source.Value)
+		if err != nil {
+			return nil, err
+		}
+		return NewSnapshotZoneFromZoneFile(z.p, filename, zone)
+
+	case DiffSourceAXFR:
+		rr, err := z.diffTransfer(zone, source.Value)
+		if err != nil {
+			return nil, err
+		}
+		return NewSnapshotZoneFromRR(z.p, rr, zone)
+
+	case DiffSourceMaps:
+		rrs, err := NewObjects(z.p).ListRR()
+		if err != nil {
+			return nil, err
+		}
+
+		// maps keep records of all zones (and overrides),
+		// records of sub-zones are not the zone ones
+		return NewSnapshotZoneFromRR(z.p, z.ZoneRecords(zone, rrs), zone)
+	}
+
+	return nil, fmt.Errorf("%w: source:'%s'", ErrDiffSource, source.String())
+}
+
+// Making diff of zone between two sources
+func (z *ZonesState) DiffZone(ctx context.Context, zone string, from string,
+	to string) (*TZoneDiff, error) {
+
+	id := "(diff) (zone)"
+
+	s1, err := ParseDiffSource(from)
+	if err != nil {
+		return nil, err
+	}
+	s2, err := ParseDiffSource(to)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot1, err := z.LoadDiffSource(ctx, zone, s1)
+	if err != nil {
+		return nil, err
+	}
+	snapshot2, err := z.LoadDiffSource(ctx, zone, s2)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := DiffSnapshots(z.p, zone, snapshot1, snapshot2)
+	if err != nil {
+		return nil, err
+	}
+	diff.From = s1.String()
+	diff.To = s2.String()
+
+	z.p.G().L.Debugf("%s %s", id, diff.AsString())
+
+	return diff, nil
+}
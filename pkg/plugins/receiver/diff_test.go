@@ -0,0 +1,267 @@
+package receiver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseDiffSource(t *testing.T) {
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		source string
+		valid  bool
+		out    string
+	}
+
+	var Tests = []TTest{
+		{"0b1c2d3e-4f5a-4b6c-8d7e-9f0a1b2c3d4e", true, "snapshot", true, "snapshot"},
+		{"1c2d3e4f-5a6b-4c7d-9e8f-0a1b2c3d4e5f", true, "maps", true, "maps"},
+		{"2d3e4f5a-6b7c-4d8e-8f9a-1b2c3d4e5f6a", true, "generation:1792339758190785000", true,
+			"generation:1792339758190785000"},
+		{"3e4f5a6b-7c8d-4e9f-9a0b-2c3d4e5f6a7b", true, "generation:latest", false, ""},
+		{"4f5a6b7c-8d9e-4f0a-8b1c-3d4e5f6a7b8c", true, "axfr", true, "axfr"},
+		{"5a6b7c8d-9e0f-4a1b-9c2d-4e5f6a7b8c9d", true, "axfr:[::1]:53", true, "axfr:[::1]:53"},
+		{"6b7c8d9e-0f1a-4b2c-8d3e-5f6a7b8c9d0e", true, "file:", false, ""},
+		{"7c8d9e0f-1a2b-4c3d-9e4f-6a7b8c9d0e1f", true, "maps:all", false, ""},
+		{"8d9e0f1a-2b3c-4d4e-8f5a-7b8c9d0e1f2a", true, "blob", false, ""},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		source, err := ParseDiffSource(test.source)
+		if !test.valid {
+			if !errors.Is(err, ErrDiffSource) {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("expected error on source:'%s', got:'%v'",
+					test.source, err))
+				continue
+			}
+			fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+			continue
+		}
+
+		if err != nil || source.String() != test.out {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("source:'%s' expected:'%s' got:'%v' err:'%v'",
+				test.source, test.out, source, err))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
+
+func TestDiffZone(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+	p.c.Options.Snapshots.Directory = t.TempDir()
+	p.c.Cooker.Snapshots.Keep = 3
+	p.c.HTTPTransfer.IncludeDirectory = t.TempDir()
+	p.zones = NewZonesState(p)
+
+	zone := "example.net"
+
+	// snapshot: www-0..www-3 with 192.0.2.N
+	snapshot, err := newGuardsSnapshot(p, 10, true, guardsHosts(4, 0))
+	if err != nil {
+		t.Fatalf("error making snapshot, err:'%s'", err)
+	}
+	if err = snapshot.WriteSnapshotZone(false); err != nil {
+		t.Fatalf("error writing snapshot, err:'%s'", err)
+	}
+
+	generations, err := ListGenerations(p, zone)
+	if err != nil || len(generations) != 1 {
+		t.Fatalf("error listing generations:'%v', err:'%v'", generations, err)
+	}
+
+	// zone file: www-0 changed, www-3 removed, mail added
+	filename := filepath.Join(p.c.HTTPTransfer.IncludeDirectory, "example.net.zone")
+	content := []string{
+		"$ORIGIN example.net.",
+		"@ 3600 IN SOA ns1 hostmaster 11 3600 600 86400 300",
+		"WWW-0 300 IN A 198.51.100.1",
+		"www-1 300 IN A 192.0.2.2",
+		"www-2 300 IN A 192.0.2.3",
+		"mail 300 IN AAAA 2001:db8::25",
+	}
+	if err = os.WriteFile(filename, []byte(strings.Join(content, "\n")), 0644); err != nil {
+		t.Fatalf("error writing zone file, err:'%s'", err)
+	}
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		from string
+		to   string
+
+		added   int
+		removed int
+		changed int
+
+		// expected diff records as "<change> <key>"
+		records []string
+	}
+
+	var Tests = []TTest{
+		{"9e0f1a2b-3c4d-4e5f-9a6b-8c9d0e1f2a3b", true, "snapshot",
+			fmt.Sprintf("generation:%d", generations[0].Generation), 0, 0, 0, nil},
+		{"0f1a2b3c-4d5e-4f6a-8b7c-9d0e1f2a3b4c", true, "snapshot",
+			fmt.Sprintf("file:%s", filename), 1, 1, 1, []string{
+				"added mail.example.net.-AAAA",
+				"changed www-0.example.net.-A",
+				"removed www-3.example.net.-A",
+			}},
+		{"1a2b3c4d-5e6f-4a7b-9c8d-0e1f2a3b4c5d", true, fmt.Sprintf("file:%s", filename),
+			"snapshot", 1, 1, 1, []string{
+				"removed mail.example.net.-AAAA",
+				"changed www-0.example.net.-A",
+				"added www-3.example.net.-A",
+			}},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		diff, err := p.zones.DiffZone(context.Background(), zone, test.from, test.to)
+		if err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error making diff, err:'%s'", err))
+			continue
+		}
+
+		if diff.Added != test.added || diff.Removed != test.removed ||
+			diff.Changed != test.changed {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("diff expected added:'%d' removed:'%d' changed:'%d' got %s",
+				test.added, test.removed, test.changed, diff.AsString()))
+			continue
+		}
+
+		var records []string
+		for _, r := range diff.Records {
+			records = append(records, fmt.Sprintf("%s %s", r.Change, r.Key))
+		}
+		if strings.Join(records, ",") != strings.Join(test.records, ",") {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("diff records expected:'%v' got:'%v'",
+				test.records, records))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+
+	// ixfr: new SOA, old SOA, deletions, new SOA, additions, new SOA
+	diff, err := p.zones.DiffZone(context.Background(), zone, "snapshot",
+		fmt.Sprintf("file:%s", filename))
+	if err != nil {
+		t.Fatalf("error making diff, err:'%s'", err)
+	}
+	out, err := diff.AsIXFR()
+	if err != nil {
+		t.Fatalf("error formatting ixfr, err:'%s'", err)
+	}
+	rr, err := NewXFR(out)
+	if err != nil || len(rr) != 8 {
+		t.Fatalf("ixfr expected 8 records got:'%d', err:'%v'\n%s", len(rr), err, out)
+	}
+
+	// applying ixfr to snapshot makes it equal to zone file
+	_, mode, _, err := snapshot.Clone().ApplyIXFR(rr)
+	if err != nil || mode != TransferModeIXFR {
+		t.Fatalf("ixfr expected as incremental transfer, err:'%v'\n%s", err, out)
+	}
+
+	if _, err = p.zones.DiffZone(context.Background(), zone, "snapshot",
+		"generation:1"); !errors.Is(err, ErrGenerationNotFound) {
+		t.Fatalf("diff with unknown generation expected error, got:'%v'", err)
+	}
+}
+
+func TestDiffSourceFilename(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+	p.c.Options.Snapshots.Directory = t.TempDir()
+	p.zones = NewZonesState(p)
+
+	base := t.TempDir()
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		base     string
+		value    string
+		filename string
+	}
+
+	var Tests = []TTest{
+		// include directory is not configured
+		{"9e0f1a2b-3c4d-4e5f-8a6b-7c8d9e0f1a2b", true, "", "/etc/passwd", ""},
+		{"0f1a2b3c-4d5e-4f6a-9b7c-8d9e0f1a2b3c", true, base, "/etc/passwd", ""},
+		{"1a2b3c4d-5e6f-4a7b-8c8d-9e0f1a2b3c4d", true, base,
+			filepath.Join(base, "..", "db"), ""},
+		{"2b3c4d5e-6f7a-4b8c-9d9e-0f1a2b3c4d5e", true, base, "../db", ""},
+		{"3c4d5e6f-7a8b-4c9d-8e0f-1a2b3c4d5e6f", true, base, filepath.Join(base, "..db"),
+			filepath.Join(base, "..db")},
+		{"4d5e6f7a-8b9c-4d0e-9f1a-2b3c4d5e6f7a", true, base, "zones/db",
+			filepath.Join(base, "zones", "db")},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		p.c.HTTPTransfer.IncludeDirectory = test.base
+		filename, err := p.zones.DiffSourceFilename(test.value)
+		if len(test.filename) == 0 {
+			if !errors.Is(err, ErrDiffSource) {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("expected error on file:'%s', got:'%v'",
+					test.value, err))
+				continue
+			}
+			fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+			continue
+		}
+
+		if err != nil || filename != test.filename {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("file:'%s' expected:'%s' got:'%s' err:'%v'",
+				test.value, test.filename, filename, err))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+
+	// corrupted snapshot is kept in place on diff
+	uuid := "5e6f7a8b-9c0d-4e1f-8a2b-3c4d5e6f7a8b"
+	filename := GetSnapshotFilename(p, "example.net")
+	if err = os.WriteFile(filename, []byte("corrupted"), 0644); err != nil {
+		t.Fatalf("error writing snapshot, err:'%s'", err)
+	}
+	if _, err = p.zones.DiffZone(context.Background(), "example.net", "snapshot",
+		"snapshot"); err == nil || !Exists(filename) {
+		t.Error("\nUUID", uuid, fmt.Sprintf("snapshot is moved or read, err:'%v'", err))
+		return
+	}
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+}
@@ -0,0 +1,321 @@
+package receiver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// zones directory worker reads zones definitions from
+// yaml files placed in zones directory (one file per
+// zone) and watches directory changes via inotify
+
+const (
+	// zones files suffixes to read
+	DefaultZonesFileSuffix    = ".yaml"
+	DefaultZonesFileAltSuffix = ".yml"
+
+	// as we receive some events we wait for some
+	// time to collect the rest (e.g. editors make
+	// a number of renames and writes)
+	DefaultZonesDirectoryDelay = 500 * time.Millisecond
+
+	// poll timeout in milliseconds to check context
+	DefaultZonesDirectoryPoll = 1000
+
+	// inotify events we are interested in
+	DefaultZonesDirectoryEvents = unix.IN_CLOSE_WRITE | unix.IN_MOVED_TO |
+		unix.IN_MOVED_FROM | unix.IN_DELETE | unix.IN_CREATE
+)
+
+// zone definition in zones directory file, zone name
+// could be omitted, in this case it is derived from
+// file name
+type TConfigZoneFile struct {
+	// a name of zone
+	Zone string `json:"zone" yaml:"zone"`
+
+	// zone configuration as in secondary section
+	TConfigZone `yaml:",inline"`
+}
+
+type ZonesDirectoryWorker struct {
+	p *TReceiverPlugin
+
+	// zones state to configure
+	zones *ZonesState
+
+	// a list of directories to watch
+	directories []string
+
+	// the last valid zones configurations read
+	// from files: filename -> zone -> config
+	files map[string]map[string]TConfigZone
+}
+
+func NewZonesDirectoryWorker(p *TReceiverPlugin, zones *ZonesState) *ZonesDirectoryWorker {
+	var w ZonesDirectoryWorker
+	w.p = p
+	w.zones = zones
+	w.files = make(map[string]map[string]TConfigZone)
+
+	// zones directory could be the same for all adapters
+	var directories []string
+	if p.L().AxfrTransfer.Enabled {
+		directories = append(directories, p.L().AxfrTransfer.Zones.ZonesDirectory)
+	}
+	if p.L().HTTPTransfer.Enabled {
+		directories = append(directories, p.L().HTTPTransfer.Zones.ZonesDirectory)
+	}
+
+	for _, directory := range directories {
+		if len(directory) == 0 || StringInSlice(directory, w.directories) {
+			continue
+		}
+		w.directories = append(w.directories, directory)
+	}
+
+	return &w
+}
+
+// Reading zone definition file, the type of zone is used
+// to detect the adapter and it should be enabled
+func (w *ZonesDirectoryWorker) ReadZoneFile(filename string) (string, *TConfigZone, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var config TConfigZoneFile
+	if err = yaml.Unmarshal(content, &config); err != nil {
+		return "", nil, err
+	}
+
+	zone := config.Zone
+	if len(zone) == 0 {
+		base := filepath.Base(filename)
+		zone = strings.TrimSuffix(base, filepath.Ext(base))
+	}
+	zone = RemoveDot(strings.ToLower(zone))
+
+	if len(zone) == 0 {
+		return "", nil, fmt.Errorf("empty zone name")
+	}
+
+	if len(config.Primary) == 0 {
+		return "", nil, fmt.Errorf("zone:'%s' has no primary", zone)
+	}
+
+	if len(config.Type) == 0 {
+		config.Type = TransferTypeAXFR
+	}
+
+	switch config.Type {
+	case TransferTypeHTTP, TransferTypeRandom:
+		if !w.p.L().HTTPTransfer.Enabled {
+			return "", nil, fmt.Errorf("zone:'%s' type:'%s' adapter disabled", zone, config.Type)
+		}
+	default:
+		if !w.p.L().AxfrTransfer.Enabled {
+			return "", nil, fmt.Errorf("zone:'%s' type:'%s' adapter disabled", zone, config.Type)
+		}
+	}
+
+	return zone, &config.TConfigZone, nil
+}
+
+// Reading all directories files, if some file could not be
+// read its previous valid configuration is kept
+func (w *ZonesDirectoryWorker) Load() (map[string]TConfigZone, map[string]error) {
+	id := "(zones) (directory) (load)"
+
+	errors := make(map[string]error)
+	files := make(map[string]map[string]TConfigZone)
+
+	for _, directory := range w.directories {
+		entries, err := os.ReadDir(directory)
+		if err != nil {
+			errors[directory] = err
+			w.p.G().L.Errorf("%s error reading directory:'%s', err:'%s'", id, directory, err)
+
+			// keeping all previous configuration of the
+			// directory as it could not be read
+			for filename, zones := range w.files {
+				if filepath.Dir(filename) == filepath.Clean(directory) {
+					files[filename] = zones
+				}
+			}
+			continue
+		}
+
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || strings.HasPrefix(name, ".") {
+				continue
+			}
+
+			if !strings.HasSuffix(name, DefaultZonesFileSuffix) &&
+				!strings.HasSuffix(name, DefaultZonesFileAltSuffix) {
+				continue
+			}
+
+			filename := filepath.Join(directory, name)
+			zone, config, err := w.ReadZoneFile(filename)
+			if err != nil {
+				errors[filename] = err
+				w.p.G().L.Errorf("%s error reading zone file:'%s', err:'%s'", id, filename, err)
+
+				if zones, ok := w.files[filename]; ok {
+					files[filename] = zones
+				}
+				continue
+			}
+
+			files[filename] = map[string]TConfigZone{zone: *config}
+		}
+	}
+
+	// files are processed in sorted order to have
+	// duplicated zones errors stable
+	var filenames []string
+	for filename := range files {
+		filenames = append(filenames, filename)
+	}
+	sort.Strings(filenames)
+
+	configs := make(map[string]TConfigZone)
+	for _, filename := range filenames {
+		for zone, config := range files[filename] {
+			if _, ok := configs[zone]; ok {
+				err := fmt.Errorf("zone:'%s' has more than one configuration", zone)
+				errors[filename] = err
+				w.p.G().L.Errorf("%s error reading zone file:'%s', err:'%s'", id, filename, err)
+				delete(files, filename)
+				continue
+			}
+			configs[zone] = config
+		}
+	}
+
+	w.files = files
+
+	return configs, errors
+}
+
+// Reloading zones directories and applying changes
+// to zones state
+func (w *ZonesDirectoryWorker) Reload() {
+	id := "(zones) (directory) (reload)"
+
+	configs, errors := w.Load()
+
+	added, changed, removed := w.zones.SetLayerZones(ZonesLayerDirectory, configs)
+
+	w.p.G().L.Debugf("%s zones:'%d' errors:'%d' added:['%s'] changed:['%s'] removed:['%s']",
+		id, len(configs), len(errors), strings.Join(added, ","),
+		strings.Join(changed, ","), strings.Join(removed, ","))
+
+	w.zones.ReconfigureZones(changed, removed)
+}
+
+func (w *ZonesDirectoryWorker) Run(ctx context.Context) error {
+	id := "(zones) (directory) (worker)"
+
+	if len(w.directories) == 0 {
+		return nil
+	}
+
+	w.Reload()
+
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		w.p.G().L.Errorf("%s error initializing inotify, err:'%s'", id, err)
+		return nil
+	}
+	defer unix.Close(fd)
+
+	watches := 0
+	for _, directory := range w.directories {
+		if _, err := unix.InotifyAddWatch(fd, directory, DefaultZonesDirectoryEvents); err != nil {
+			w.p.G().L.Errorf("%s error watching directory:'%s', err:'%s'", id, directory, err)
+			continue
+		}
+		w.p.G().L.Debugf("%s watching directory:'%s'", id, directory)
+		watches++
+	}
+
+	if watches == 0 {
+		return nil
+	}
+
+	buf := make([]byte, unix.SizeofInotifyEvent*64+unix.PathMax)
+	fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+
+	for {
+		if ctx.Err() != nil {
+			w.p.G().L.Debugf("%s context stop on zones directory watcher", id)
+			return ctx.Err()
+		}
+
+		n, err := unix.Poll(fds, DefaultZonesDirectoryPoll)
+		if err != nil && err != unix.EINTR {
+			w.p.G().L.Errorf("%s error polling inotify, err:'%s'", id, err)
+			return err
+		}
+		if n <= 0 {
+			continue
+		}
+
+		// waiting for some time to collect all
+		// events of the change
+		time.Sleep(DefaultZonesDirectoryDelay)
+
+		changed := false
+		for {
+			n, err := unix.Read(fd, buf)
+			if err != nil || n <= 0 {
+				break
+			}
+
+			for _, name := range InotifyEventNames(buf[:n]) {
+				w.p.G().L.Debugf("%s event on file:'%s'", id, name)
+				changed = true
+			}
+		}
+
+		if changed {
+			w.Reload()
+		}
+	}
+}
+
+// Parsing inotify events buffer returning files names
+func InotifyEventNames(buf []byte) []string {
+	var names []string
+
+	offset := 0
+	for offset+unix.SizeofInotifyEvent <= len(buf) {
+		event := (*unix.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+
+		start := offset + unix.SizeofInotifyEvent
+		end := start + int(event.Len)
+		if end > len(buf) {
+			break
+		}
+
+		name := strings.TrimRight(string(buf[start:end]), "\x00")
+		names = append(names, name)
+
+		offset = end
+	}
+
+	return names
+}
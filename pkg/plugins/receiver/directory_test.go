@@ -0,0 +1,167 @@
+package receiver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestZonesDirectoryLoad(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+
+	directory := t.TempDir()
+
+	p.c.AxfrTransfer.Enabled = true
+	p.c.AxfrTransfer.Zones.ZonesDirectory = directory
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		// files to write (or remove if content is empty)
+		files map[string]string
+
+		// expected zones and files with errors
+		zones  []string
+		errors []string
+
+		// expected layer changes
+		added   []string
+		changed []string
+		removed []string
+	}
+
+	var Tests = []TTest{
+		{
+			"c0e7a3a2-6f0e-4c55-9c1a-7d1f0c0f4c1a",
+			true,
+			map[string]string{
+				"example.org.yaml": `
+enabled: true
+type: "axfr"
+primary: [ "[::1]:53" ]
+`,
+				"other.yaml": `
+zone: "example.net."
+enabled: true
+primary: [ "[::1]:53" ]
+refresh: 10
+`,
+				"readme.txt": `some text`,
+			},
+			[]string{"example.net", "example.org"},
+			[]string{},
+			[]string{"example.net", "example.org"},
+			[]string{},
+			[]string{},
+		},
+		{
+			"4d6f1b1e-2d3a-4a5b-8c7e-9f0a1b2c3d4e",
+			true,
+			map[string]string{
+				// invalid file keeps previous configuration
+				"example.org.yaml": `
+enabled: true
+primary: [ "[::1]:53"
+`,
+				"other.yaml": `
+zone: "example.net."
+enabled: true
+primary: [ "[::1]:53" ]
+refresh: 20
+`,
+				"noprimary.yaml": `
+enabled: true
+`,
+			},
+			[]string{"example.net", "example.org"},
+			[]string{"example.org.yaml", "noprimary.yaml"},
+			[]string{},
+			[]string{"example.net"},
+			[]string{},
+		},
+		{
+			"8a9b0c1d-3e4f-4a5b-9c6d-7e8f9a0b1c2d",
+			true,
+			map[string]string{
+				"example.org.yaml": "",
+				"noprimary.yaml":   "",
+				"duplicate.yaml": `
+zone: "example.net"
+enabled: true
+primary: [ "[::1]:53" ]
+`,
+			},
+			[]string{"example.net"},
+			[]string{"other.yaml"},
+			[]string{},
+			[]string{"example.net"},
+			[]string{"example.org"},
+		},
+	}
+
+	w := NewZonesDirectoryWorker(p, p.zones)
+	zones := NewZonesState(p)
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		for name, content := range test.files {
+			filename := filepath.Join(directory, name)
+			if len(content) == 0 {
+				os.Remove(filename)
+				continue
+			}
+			if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("error writing file, err:'%s'", err))
+				return
+			}
+		}
+
+		configs, errors := w.Load()
+
+		var names []string
+		for zone := range configs {
+			names = append(names, zone)
+		}
+		sort.Strings(names)
+
+		var files []string
+		for filename := range errors {
+			files = append(files, filepath.Base(filename))
+		}
+		sort.Strings(files)
+
+		if strings.Join(names, ",") != strings.Join(test.zones, ",") {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("zones expected:'%s' got:'%s'",
+				strings.Join(test.zones, ","), strings.Join(names, ",")))
+		}
+
+		if strings.Join(files, ",") != strings.Join(test.errors, ",") {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("errors expected:'%s' got:'%s'",
+				strings.Join(test.errors, ","), strings.Join(files, ",")))
+		}
+
+		added, changed, removed := zones.SetLayerZones(ZonesLayerDirectory, configs)
+		results := [][]string{added, changed, removed}
+		expected := [][]string{test.added, test.changed, test.removed}
+		for i := range results {
+			if strings.Join(results[i], ",") != strings.Join(expected[i], ",") {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("changes [%d] expected:'%s' got:'%s'",
+					i, strings.Join(expected[i], ","), strings.Join(results[i], ",")))
+			}
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
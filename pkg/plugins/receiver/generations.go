@@ -0,0 +1,414 @@
+package receiver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// snapshot generations are snapshot blobs written as zone
+// serial changes "<md5(zone)>.<generation>.yadns-xdp.blob"
+// next to the current blob, cooker snapshots "keep" defines
+// a number of generations per zone. Zone could be rolled
+// back to generation and it is pinned there: no updates
+// are applied till zone is unpinned
+
+const (
+	// file of zones pinned in snapshots directory
+	DefaultPinsFilename = "yadns-xdp.pins"
+)
+
+var (
+	ErrGenerationNotFound = errors.New("generation not found")
+
+	ErrZonePinned = errors.New("zone pinned")
+
+	ErrPinNotFound = errors.New("zone is not pinned")
+)
+
+type TSnapshotGeneration struct {
+	// generation id is write time in unix nanoseconds
+	Generation int64 `json:"generation"`
+
+	Zone   string `json:"zone"`
+	Serial uint32 `json:"serial"`
+
+	Source  string    `json:"source"`
+	Created time.Time `json:"created"`
+	Records int       `json:"records"`
+
+	// generation is zone pinned to
+	Pinned bool `json:"pinned"`
+}
+
+func GetGenerationFilename(p *TReceiverPlugin, zone string, generation int64) string {
+	path := p.L().Options.Snapshots.Directory
+	return fmt.Sprintf("%s/%s.%d.%s", path, Md5(zone), generation, DefaultSnapshotSuffix)
+}
+
+// Listing snapshot generations of zone, the latest
+// generation is the first
+func ListGenerations(p *TReceiverPlugin, zone string) ([]TSnapshotGeneration, error) {
+	id := "(generations) (list)"
+
+	path := p.L().Options.Snapshots.Directory
+	prefix := fmt.Sprintf("%s.", Md5(zone))
+	suffix := fmt.Sprintf(".%s", DefaultSnapshotSuffix)
+
+	files, err := filepath.Glob(fmt.Sprintf("%s/%s*%s", path, prefix, suffix))
+	if err != nil {
+		return nil, err
+	}
+
+	out := []TSnapshotGeneration{}
+	for _, filename := range files {
+		name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(filename), prefix), suffix)
+		generation, err := strconv.ParseInt(name, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		header, err := ReadSnapshotHeader(filename)
+		if err != nil || header == nil {
+			p.G().L.Errorf("%s error reading generation:'%s', err:'%v'", id, filename, err)
+			continue
+		}
+
+		out = append(out, TSnapshotGeneration{Generation: generation, Zone: header.Zone,
+			Serial: header.Serial, Source: header.Source, Created: header.Created,
+			Records: header.Records})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Generation > out[j].Generation
+	})
+
+	return out, nil
+}
+
+// Writing snapshot generation if zone serial is changed
+// since the latest generation and removing generations
+// over keep
+func (t *TSnapshotZone) WriteGeneration(content []byte, keep int) error {
+	id := "(generations) (write)"
+
+	if keep <= 0 {
+		return nil
+	}
+
+	generations, err := ListGenerations(t.p, t.zone)
+	if err != nil {
+		return err
+	}
+
+	serial, _ := t.Serial()
+	if len(generations) == 0 || generations[0].Serial != serial {
+		generation := time.Now().UnixNano()
+		filename := GetGenerationFilename(t.p, t.zone, generation)
+
+		t.p.G().L.Debugf("%s zone:'%s' serial:'%d' generation:'%s'", id, t.zone,
+			serial, filename)
+
+		if err = WriteFileAtomic(filename, content, 0644); err != nil {
+			return err
+		}
+		generations = append([]TSnapshotGeneration{{Generation: generation}}, generations...)
+	}
+
+	if len(generations) <= keep {
+		return nil
+	}
+
+	// generation zone is pinned to is kept
+	pin := t.p.zones.GetPin(t.zone)
+	for _, g := range generations[keep:] {
+		if pin != nil && pin.Generation == g.Generation {
+			continue
+		}
+		filename := GetGenerationFilename(t.p, t.zone, g.Generation)
+		t.p.G().L.Debugf("%s zone:'%s' removing outdated generation:'%s'", id, t.zone, filename)
+		if err = os.Remove(filename); err != nil {
+			t.p.G().L.Errorf("%s error removing generation:'%s', err:'%s'", id, filename, err)
+		}
+	}
+
+	return nil
+}
+
+// zone pinned to generation
+type TZonePin struct {
+	Zone       string `json:"zone"`
+	Generation int64  `json:"generation"`
+	Serial     uint32 `json:"serial"`
+
+	// time zone is pinned
+	Pinned time.Time `json:"pinned"`
+}
+
+type PinStore struct {
+	p *TReceiverPlugin
+
+	lock sync.Mutex
+
+	pins map[string]TZonePin
+}
+
+func NewPinStore(p *TReceiverPlugin) *PinStore {
+	var s PinStore
+	s.p = p
+	s.pins = make(map[string]TZonePin)
+	return &s
+}
+
+func (s *PinStore) filename() string {
+	path := s.p.L().Options.Snapshots.Directory
+	if len(path) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s", path, DefaultPinsFilename)
+}
+
+func (s *PinStore) Load() error {
+	id := "(pins) (load)"
+
+	filename := s.filename()
+	if len(filename) == 0 || !Exists(filename) {
+		return nil
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var list []TZonePin
+	if err = json.Unmarshal(content, &list); err != nil {
+		s.p.G().L.Errorf("%s error parsing pins:'%s', err:'%s'", id, filename, err)
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, pin := range list {
+		s.pins[pin.Zone] = pin
+	}
+	s.p.G().L.Debugf("%s pins:'%s' loaded:'%d'", id, filename, len(list))
+
+	return nil
+}
+
+// saving pins, should be called with lock held
+func (s *PinStore) save() error {
+	filename := s.filename()
+	if len(filename) == 0 {
+		return nil
+	}
+
+	list := []TZonePin{}
+	for _, pin := range s.pins {
+		list = append(list, pin)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Zone < list[j].Zone
+	})
+
+	content, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(filename, content, 0644)
+}
+
+func (s *PinStore) Get(zone string) *TZonePin {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if pin, ok := s.pins[zone]; ok {
+		return &pin
+	}
+	return nil
+}
+
+func (s *PinStore) Set(pin TZonePin) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.pins[pin.Zone] = pin
+	return s.save()
+}
+
+func (s *PinStore) Remove(zone string) (*TZonePin, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	pin, ok := s.pins[zone]
+	if !ok {
+		return nil, ErrPinNotFound
+	}
+	delete(s.pins, zone)
+	return &pin, s.save()
+}
+
+func (z *ZonesState) GetPin(zone string) *TZonePin {
+	if z == nil || z.pins == nil {
+		return nil
+	}
+	return z.pins.Get(zone)
+}
+
+// Listing snapshot generations of zone marking
+// the one zone is pinned to
+func (z *ZonesState) GetGenerations(zone string) ([]TSnapshotGeneration, error) {
+	if _, err := z.GetConfig(zone); err != nil {
+		return nil, fmt.Errorf("zone:'%s', err:'%w'", zone, ErrZoneNotFound)
+	}
+
+	generations, err := ListGenerations(z.p, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	if pin := z.GetPin(zone); pin != nil {
+		for i := range generations {
+			generations[i].Pinned = generations[i].Generation == pin.Generation
+		}
+	}
+
+	return generations, nil
+}
+
+// Reading snapshot of generation zone is pinned to
+func (z *ZonesState) PinnedSnapshot(zone string, pin *TZonePin) (*TSnapshotZone, error) {
+	filename := GetGenerationFilename(z.p, zone, pin.Generation)
+	if !Exists(filename) {
+		return nil, fmt.Errorf("%w: generation:'%d'", ErrGenerationNotFound, pin.Generation)
+	}
+	return ReadSnapshotFile(z.p, filename, zone)
+}
+
+// Rolling zone back to snapshot generation: maps are synced
+// with generation data and zone is pinned there
+func (z *ZonesState) RollbackZone(ctx context.Context, zone string,
+	generation int64) (*TSyncMapResult, error) {
+
+	id := "(generations) (rollback)"
+
+	config, err := z.GetConfig(zone)
+	if err != nil {
+		return nil, fmt.Errorf("zone:'%s', err:'%w'", zone, ErrZoneNotFound)
+	}
+
+	pin := &TZonePin{Zone: zone, Generation: generation, Pinned: time.Now()}
+	snapshot, err := z.PinnedSnapshot(zone, pin)
+	if err != nil {
+		return nil, err
+	}
+	pin.Serial, _ = snapshot.Serial()
+
+	lock := z.ZoneLock(zone)
+	lock.Lock()
+	defer lock.Unlock()
+
+	current := z.GetLastZoneSnapshot(zone)
+	if current == nil {
+		current = &TSnapshotZone{p: z.p, zone: zone, rrsets: make(map[string][]dns.RR)}
+	}
+
+	var state TZoneState
+	changed, err := state.DetectChangedState(current, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	state.Config = config
+	state.Zone = zone
+	state.SnapshotCount = DefaultSnapshotCount
+	state.Snapshots = make(map[int]TSnapshotZone)
+
+	snapshot.imports = &TImportActions{
+		mode:    TransferModeIXFR,
+		zone:    zone,
+		actions: changed.AsActions(),
+	}
+
+	state.SnapshotID = 0
+	state.Snapshots[state.SnapshotID] = *snapshot
+	state.State = state.DetectState(z.p, snapshot)
+
+	previous, exists := z.GetState(zone)
+	z.SetState(zone, state)
+
+	var options TConfigCooker
+	cooker, _ := NewCookerWorker(z.p, &options, z)
+
+	r, err := cooker.CookIncrementZone(ctx, zone, CookerNoLock)
+	if err != nil {
+		z.p.G().L.Errorf("%s error cooking zone:'%s', err:'%s'", id, zone, err)
+		z.DeleteState(zone)
+		if exists {
+			z.SetState(zone, previous)
+		}
+		return nil, err
+	}
+
+	if err = z.pins.Set(*pin); err != nil {
+		z.p.G().L.Errorf("%s error saving pin zone:'%s', err:'%s'", id, zone, err)
+		return r, err
+	}
+
+	// update quarantined (if any) is not actual
+	z.quarantine.Remove(zone)
+	z.PushSkipMetrics(zone, snapshot)
+
+	z.p.G().L.Infof("%s zone:'%s' rolled back to generation:'%d' serial:'%d' %s", id,
+		zone, generation, pin.Serial, r.AsString())
+
+	if err = snapshot.WriteSnapshotZone(options.Dryrun); err != nil {
+		z.p.G().L.Errorf("%s error writing blob for zone:'%s', err:'%s'", id, zone, err)
+		return r, err
+	}
+
+	return r, nil
+}
+
+// Unpinning zone, its updates are applied again on
+// next refresh (or notify)
+func (z *ZonesState) UnpinZone(zone string) (*TZonePin, error) {
+	id := "(generations) (unpin)"
+
+	pin, err := z.pins.Remove(zone)
+	if err != nil {
+		return nil, err
+	}
+
+	// http validators are dropped as zone data
+	// differs from the last response
+	z.lock.Lock()
+	for k := range z.validators {
+		if strings.HasPrefix(k, fmt.Sprintf("%s/", zone)) {
+			delete(z.validators, k)
+		}
+	}
+	z.lock.Unlock()
+
+	z.p.G().L.Infof("%s zone:'%s' unpinned from generation:'%d' serial:'%d'", id,
+		zone, pin.Generation, pin.Serial)
+
+	return pin, nil
+}
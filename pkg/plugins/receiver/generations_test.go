@@ -0,0 +1,111 @@
+package receiver
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSnapshotGenerations(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+	p.c.Options.Snapshots.Directory = t.TempDir()
+	p.c.Cooker.Snapshots.Keep = 3
+	p.zones = NewZonesState(p)
+
+	zone := "example.net"
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		// serial of snapshot written and pinning
+		// to the oldest generation
+		serial uint32
+		pin    bool
+
+		// expected generations serials
+		serials []uint32
+	}
+
+	var Tests = []TTest{
+		{"2c7d9e1f-3a4b-4c5d-8e6f-7a8b9c0d1e2f", true, 1, false, []uint32{1}},
+		{"3d8e0f2a-4b5c-4d6e-9f7a-8b9c0d1e2f3a", true, 1, false, []uint32{1}},
+		{"4e9f1a3b-5c6d-4e7f-8a8b-9c0d1e2f3a4b", true, 2, false, []uint32{2, 1}},
+		{"5f0a2b4c-6d7e-4f8a-9b9c-0d1e2f3a4b5c", true, 3, false, []uint32{3, 2, 1}},
+		{"6a1b3c5d-7e8f-4a9b-8c0d-1e2f3a4b5c6d", true, 4, true, []uint32{4, 3, 2}},
+		{"7b2c4d6e-8f9a-4b0c-9d1e-2f3a4b5c6d7e", true, 5, false, []uint32{5, 4, 3, 2}},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		snapshot, err := newGuardsSnapshot(p, test.serial, true, guardsHosts(3, 0))
+		if err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error making snapshot, err:'%s'", err))
+			continue
+		}
+		if err = snapshot.WriteSnapshotZone(false); err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error writing snapshot, err:'%s'", err))
+			continue
+		}
+
+		generations, err := ListGenerations(p, zone)
+		if err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error listing generations, err:'%s'", err))
+			continue
+		}
+
+		if test.pin {
+			oldest := generations[len(generations)-1]
+			if err = p.zones.pins.Set(TZonePin{Zone: zone, Generation: oldest.Generation,
+				Serial: oldest.Serial}); err != nil {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("error pinning zone, err:'%s'", err))
+				continue
+			}
+		}
+
+		var serials []uint32
+		for _, g := range generations {
+			serials = append(serials, g.Serial)
+		}
+
+		if fmt.Sprintf("%v", serials) != fmt.Sprintf("%v", test.serials) {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("generations expected:'%v' got:'%v'",
+				test.serials, serials))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+
+	// pins are persisted and pinned generation could be read
+	pins := NewPinStore(p)
+	if err := pins.Load(); err != nil {
+		t.Fatalf("error loading pins, err:'%s'", err)
+	}
+	pin := pins.Get(zone)
+	if pin == nil || pin.Serial != 2 {
+		t.Fatalf("pin expected serial:'2' got:'%+v'", pin)
+	}
+
+	snapshot, err := p.zones.PinnedSnapshot(zone, pin)
+	if err != nil {
+		t.Fatalf("error reading pinned generation, err:'%s'", err)
+	}
+	if serial, _ := snapshot.Serial(); serial != 2 {
+		t.Fatalf("pinned generation serial expected:'2' got:'%d'", serial)
+	}
+
+	if _, err := p.zones.UnpinZone(zone); err != nil || p.zones.GetPin(zone) != nil {
+		t.Fatalf("error unpinning zone, err:'%v'", err)
+	}
+	if _, err := p.zones.UnpinZone(zone); err != ErrPinNotFound {
+		t.Fatalf("unpinning zone not pinned expected error, got:'%v'", err)
+	}
+}
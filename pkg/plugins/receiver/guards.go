@@ -0,0 +1,443 @@
+package receiver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// mass change guards are checked before zone snapshot
+// is applied to maps: an update violating guards (e.g.
+// a broken primary serving almost empty zone) is kept
+// in quarantine and is not applied till operator approves
+// or rejects it
+
+const (
+	// zone has (1) or has not (0) update in quarantine
+	MetricQuarantined = "receiver-quarantined"
+
+	// monitor check for quarantined updates
+	MonitorQuarantine = "yadns-receiver-quarantine"
+)
+
+var (
+	// no update of zone is in quarantine
+	ErrQuarantineNotFound = errors.New("quarantine not found")
+
+	// zone update is violating guards and is
+	// kept in quarantine
+	ErrZoneQuarantined = errors.New("zone update quarantined")
+)
+
+type TConfigGuards struct {
+	// guards could be disabled
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// max percent of current zone records removed
+	// by update, 0 is not checked
+	MaxRemoved float64 `json:"max-removed" yaml:"max-removed"`
+
+	// max percent of current zone records changed
+	// (removed or replaced) by update, 0 is not checked
+	MaxChanged float64 `json:"max-changed" yaml:"max-changed"`
+
+	// min number of records in updated zone, 0 is
+	// not checked
+	MinRecords int `json:"min-records" yaml:"min-records"`
+
+	// updated zone should have SOA record
+	RequireSOA bool `json:"require-soa" yaml:"require-soa"`
+}
+
+// result of guards check of zone update against
+// the current (applied) zone snapshot
+type TGuardReport struct {
+	Zone string `json:"zone"`
+
+	// serials of current and updated snapshots
+	Current uint32 `json:"current-serial"`
+	Serial  uint32 `json:"serial"`
+
+	// records in current and updated snapshots
+	Baseline int `json:"baseline"`
+	Records  int `json:"records"`
+
+	// current records removed, replaced by other
+	// data and records added
+	Removed int `json:"removed"`
+	Changed int `json:"changed"`
+	Added   int `json:"added"`
+
+	// guards violated, empty if update is safe
+	Violations []string `json:"violations"`
+}
+
+func (r *TGuardReport) Violated() bool {
+	return len(r.Violations) > 0
+}
+
+func (r *TGuardReport) AsString() string {
+	var out []string
+
+	out = append(out, fmt.Sprintf("serial:'%d -> %d'", r.Current, r.Serial))
+	out = append(out, fmt.Sprintf("records:'%d -> %d'", r.Baseline, r.Records))
+	out = append(out, fmt.Sprintf("removed:'%d'", r.Removed))
+	out = append(out, fmt.Sprintf("changed:'%d'", r.Changed))
+	out = append(out, fmt.Sprintf("added:'%d'", r.Added))
+	if len(r.Violations) > 0 {
+		out = append(out, fmt.Sprintf("violations:['%s']", strings.Join(r.Violations, ",")))
+	}
+
+	return strings.Join(out, ",")
+}
+
+func percent(value int, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(value) * 100 / float64(total)
+}
+
+// Checking update snapshot against current snapshot, current
+// could be nil (no zone data applied yet), in this case only
+// records count and SOA are checked
+func CheckGuards(guards *TConfigGuards, current *TSnapshotZone,
+	snapshot *TSnapshotZone) *TGuardReport {
+
+	var report TGuardReport
+	report.Zone = snapshot.zone
+	report.Serial, _ = snapshot.Serial()
+	report.Records, _ = snapshot.Counters()
+
+	var state TZoneState
+	if current != nil {
+		report.Current, _ = current.Serial()
+		report.Baseline, _ = current.Counters()
+
+		for k, v := range current.rrsets {
+			w, ok := snapshot.rrsets[k]
+			if !ok {
+				report.Removed += len(v)
+				continue
+			}
+			if !state.DetectChangedRRset(v, w) {
+				report.Changed += len(v)
+			}
+		}
+		for k, w := range snapshot.rrsets {
+			if _, ok := current.rrsets[k]; !ok {
+				report.Added += len(w)
+			}
+		}
+	}
+
+	if guards == nil || !guards.Enabled {
+		return &report
+	}
+
+	if guards.RequireSOA {
+		if snapshot.soa == nil || snapshot.soa.Header().Rrtype != dns.TypeSOA {
+			report.Violations = append(report.Violations, "no SOA record")
+		}
+	}
+
+	if guards.MinRecords > 0 && report.Records < guards.MinRecords {
+		report.Violations = append(report.Violations,
+			fmt.Sprintf("records:'%d' less than min-records:'%d'", report.Records,
+				guards.MinRecords))
+	}
+
+	removed := percent(report.Removed, report.Baseline)
+	if guards.MaxRemoved > 0 && removed > guards.MaxRemoved {
+		report.Violations = append(report.Violations,
+			fmt.Sprintf("removed:'%2.2f%%' exceeds max-removed:'%2.2f%%'", removed,
+				guards.MaxRemoved))
+	}
+
+	changed := percent(report.Removed+report.Changed, report.Baseline)
+	if guards.MaxChanged > 0 && changed > guards.MaxChanged {
+		report.Violations = append(report.Violations,
+			fmt.Sprintf("changed:'%2.2f%%' exceeds max-changed:'%2.2f%%'", changed,
+				guards.MaxChanged))
+	}
+
+	return &report
+}
+
+// zone update kept in quarantine
+type TQuarantine struct {
+	Zone string `json:"zone"`
+
+	// time update is quarantined
+	Created time.Time `json:"created"`
+
+	Report TGuardReport `json:"report"`
+
+	// update snapshot waiting for approval
+	snapshot *TSnapshotZone
+}
+
+type QuarantineStore struct {
+	lock sync.Mutex
+
+	// quarantined updates per zone
+	updates map[string]*TQuarantine
+
+	// serials of rejected updates per zone, the
+	// same update is not quarantined again
+	rejected map[string]uint32
+}
+
+func NewQuarantineStore() *QuarantineStore {
+	var s QuarantineStore
+	s.updates = make(map[string]*TQuarantine)
+	s.rejected = make(map[string]uint32)
+	return &s
+}
+
+func (s *QuarantineStore) List() []TQuarantine {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var out []TQuarantine
+	for _, q := range s.updates {
+		out = append(out, *q)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Zone < out[j].Zone
+	})
+	return out
+}
+
+func (s *QuarantineStore) Get(zone string) *TQuarantine {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.updates[zone]
+}
+
+func (s *QuarantineStore) Set(q *TQuarantine) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.rejected, q.Zone)
+	s.updates[q.Zone] = q
+}
+
+func (s *QuarantineStore) Remove(zone string) *TQuarantine {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	q := s.updates[zone]
+	delete(s.updates, zone)
+	return q
+}
+
+func (s *QuarantineStore) Reject(zone string) *TQuarantine {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	q := s.updates[zone]
+	if q != nil {
+		s.rejected[zone] = q.Report.Serial
+	}
+	delete(s.updates, zone)
+	return q
+}
+
+func (s *QuarantineStore) Rejected(zone string, serial uint32) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	rejected, ok := s.rejected[zone]
+	return ok && rejected == serial
+}
+
+// Getting guards of zone: zone guards override
+// cooker guards
+func (z *ZonesState) Guards(config *TConfigZone) *TConfigGuards {
+	if config != nil && config.Guards != nil {
+		return config.Guards
+	}
+	return &z.p.L().Cooker.Guards
+}
+
+// Getting snapshot applied to maps: snapshot in memory
+// or its blob snapshot (if any)
+func (z *ZonesState) AppliedSnapshot(zone string) *TSnapshotZone {
+	if current := z.GetLastZoneSnapshot(zone); current != nil {
+		return current
+	}
+
+	filename := GetSnapshotFilename(z.p, zone)
+	if _, err := os.Stat(filename); err != nil {
+		return nil
+	}
+	current, err := NewSnapshotZoneFromFile(z.p, filename, zone)
+	if err != nil {
+		return nil
+	}
+	return current
+}
+
+// Checking zone update snapshot against guards, should be
+// called with zone lock held. Update violating guards is kept
+// in quarantine (replacing previous one) and true is returned,
+// safe update releases previous quarantined update
+func (z *ZonesState) Quarantine(zone string, config *TConfigZone,
+	snapshot *TSnapshotZone) bool {
+
+	id := "(guards) (quarantine)"
+
+	guards := z.Guards(config)
+	if !guards.Enabled {
+		return false
+	}
+
+	report := CheckGuards(guards, z.AppliedSnapshot(zone), snapshot)
+	report.Zone = zone
+
+	z.p.G().L.Debugf("%s zone:'%s' guards %s", id, zone, report.AsString())
+
+	tags := []string{fmt.Sprintf("zone=%s", zone)}
+	if !report.Violated() {
+		if q := z.quarantine.Remove(zone); q != nil {
+			z.p.G().L.Infof("%s zone:'%s' quarantined serial:'%d' superseded by serial:'%d'",
+				id, zone, q.Report.Serial, report.Serial)
+			z.p.PushMetric(MetricQuarantined, tags, 0)
+		}
+		return false
+	}
+
+	if z.quarantine.Rejected(zone, report.Serial) {
+		z.p.G().L.Debugf("%s zone:'%s' serial:'%d' rejected before, skipped", id,
+			zone, report.Serial)
+		return true
+	}
+
+	if q := z.quarantine.Get(zone); q != nil && q.Report.Serial == report.Serial {
+		z.p.G().L.Debugf("%s zone:'%s' serial:'%d' already quarantined", id,
+			zone, report.Serial)
+		return true
+	}
+
+	z.p.G().L.Errorf("%s zone:'%s' update quarantined, err:'%s'", id, zone,
+		strings.Join(report.Violations, ","))
+
+	z.quarantine.Set(&TQuarantine{Zone: zone, Created: time.Now(), Report: *report,
+		snapshot: snapshot})
+	z.p.PushMetric(MetricQuarantined, tags, 1)
+
+	return true
+}
+
+// Approving quarantined zone update: actions are recalculated
+// against the current snapshot and applied to maps
+func (z *ZonesState) ApproveQuarantine(ctx context.Context, zone string) (*TSyncMapResult, error) {
+	id := "(guards) (approve)"
+
+	lock := z.ZoneLock(zone)
+	lock.Lock()
+	defer lock.Unlock()
+
+	q := z.quarantine.Get(zone)
+	if q == nil {
+		return nil, ErrQuarantineNotFound
+	}
+
+	if pin := z.GetPin(zone); pin != nil {
+		return nil, fmt.Errorf("%w: generation:'%d'", ErrZonePinned, pin.Generation)
+	}
+
+	config, err := z.GetConfig(zone)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := *q.snapshot
+
+	// zone without snapshot in memory is created
+	// from scratch
+	current := z.GetLastZoneSnapshot(zone)
+	if current == nil {
+		current = &TSnapshotZone{p: z.p, zone: zone, rrsets: make(map[string][]dns.RR)}
+	}
+
+	var state TZoneState
+	changed, err := state.DetectChangedState(current, &snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	state.Config = config
+	state.Zone = zone
+	state.SnapshotCount = DefaultSnapshotCount
+	state.Snapshots = make(map[int]TSnapshotZone)
+
+	snapshot.timestamp = time.Now()
+	snapshot.imports = &TImportActions{
+		mode:    TransferModeIXFR,
+		zone:    zone,
+		actions: changed.AsActions(),
+	}
+
+	state.SnapshotID = 0
+	state.Snapshots[state.SnapshotID] = snapshot
+	state.State = state.DetectState(z.p, &snapshot)
+
+	// restoring current state if update could
+	// not be applied
+	previous, exists := z.GetState(zone)
+	z.SetState(zone, state)
+
+	var options TConfigCooker
+	cooker, _ := NewCookerWorker(z.p, &options, z)
+
+	r, err := cooker.CookIncrementZone(ctx, zone, CookerNoLock)
+	if err != nil {
+		z.p.G().L.Errorf("%s error cooking zone:'%s', err:'%s'", id, zone, err)
+		z.DeleteState(zone)
+		if exists {
+			z.SetState(zone, previous)
+		}
+		return nil, err
+	}
+
+	z.PushSkipMetrics(zone, &snapshot)
+	z.quarantine.Remove(zone)
+	z.p.PushMetric(MetricQuarantined, []string{fmt.Sprintf("zone=%s", zone)}, 0)
+
+	z.p.G().L.Infof("%s zone:'%s' quarantined serial:'%d' approved %s", id, zone,
+		q.Report.Serial, r.AsString())
+
+	if err = snapshot.WriteSnapshotZone(options.Dryrun); err != nil {
+		z.p.G().L.Errorf("%s error writing blob for zone:'%s', err:'%s'", id, zone, err)
+		return r, err
+	}
+
+	return r, nil
+}
+
+// Rejecting quarantined zone update, the same update
+// (by serial) is not quarantined again
+func (z *ZonesState) RejectQuarantine(zone string) error {
+	id := "(guards) (reject)"
+
+	q := z.quarantine.Reject(zone)
+	if q == nil {
+		return ErrQuarantineNotFound
+	}
+
+	z.p.PushMetric(MetricQuarantined, []string{fmt.Sprintf("zone=%s", zone)}, 0)
+	z.p.G().L.Infof("%s zone:'%s' quarantined serial:'%d' rejected", id, zone,
+		q.Report.Serial)
+
+	return nil
+}
@@ -0,0 +1,211 @@
+package receiver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// making zone snapshot "example.net" of serial and
+// addresses www-N 192.0.2.N
+func newGuardsSnapshot(p *TReceiverPlugin, serial uint32, soa bool,
+	hosts map[int]string) (*TSnapshotZone, error) {
+
+	var rr []dns.RR
+	if soa {
+		rr = append(rr, NewSyntheticSOA("example.net", serial))
+	}
+	for n, address := range hosts {
+		r, err := dns.NewRR(fmt.Sprintf("www-%d.example.net. 300 IN A %s", n, address))
+		if err != nil {
+			return nil, err
+		}
+		rr = append(rr, r)
+	}
+	if soa {
+		rr = append(rr, NewSyntheticSOA("example.net", serial))
+	}
+
+	return NewSnapshotZoneFromRR(p, rr, "example.net")
+}
+
+func guardsHosts(count int, changed int) map[int]string {
+	hosts := make(map[int]string)
+	for n := 0; n < count; n++ {
+		address := fmt.Sprintf("192.0.2.%d", n+1)
+		if n < changed {
+			address = fmt.Sprintf("198.51.100.%d", n+1)
+		}
+		hosts[n] = address
+	}
+	return hosts
+}
+
+func TestCheckGuards(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+
+	guards := &TConfigGuards{Enabled: true, MaxRemoved: 30, MaxChanged: 50,
+		MinRecords: 5, RequireSOA: true}
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		guards *TConfigGuards
+
+		// current zone records (0 is no current zone)
+		current int
+
+		// updated zone records, changed records
+		// and SOA
+		records int
+		changed int
+		soa     bool
+
+		removed    int
+		violations []string
+	}
+
+	var Tests = []TTest{
+		{"2a7b9c1d-3e4f-4a5b-8c6d-7e8f9a0b1c2d", true, guards, 10, 10, 2, true, 0, nil},
+		{"3b8c0d2e-4f5a-4b6c-9d7e-8f9a0b1c2d3e", true, guards, 10, 7, 0, true, 3, nil},
+		{"4c9d1e3f-5a6b-4c7d-8e8f-9a0b1c2d3e4f", true, guards, 10, 6, 0, true, 4,
+			[]string{"removed:'40.00%' exceeds max-removed:'30.00%'"}},
+		{"5d0e2f4a-6b7c-4d8e-9f9a-0b1c2d3e4f5a", true, guards, 10, 8, 4, true, 2,
+			[]string{"changed:'60.00%' exceeds max-changed:'50.00%'"}},
+		{"6e1f3a5b-7c8d-4e9f-8a0b-1c2d3e4f5a6b", true, guards, 10, 2, 0, false, 8,
+			[]string{"no SOA record", "records:'2' less than min-records:'5'",
+				"removed:'80.00%' exceeds max-removed:'30.00%'",
+				"changed:'80.00%' exceeds max-changed:'50.00%'"}},
+		{"7f2a4b6c-8d9e-4f0a-9b1c-2d3e4f5a6b7c", true, guards, 0, 3, 0, true, 0,
+			[]string{"records:'3' less than min-records:'5'"}},
+		{"8a3b5c7d-9e0f-4a1b-8c2d-3e4f5a6b7c8d", true, &TConfigGuards{MaxRemoved: 1},
+			10, 1, 0, true, 9, nil},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		var current *TSnapshotZone
+		if test.current > 0 {
+			if current, err = newGuardsSnapshot(p, 1, true, guardsHosts(test.current, 0)); err != nil {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("error making snapshot, err:'%s'", err))
+				continue
+			}
+		}
+
+		snapshot, err := newGuardsSnapshot(p, 2, test.soa, guardsHosts(test.records, test.changed))
+		if err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error making snapshot, err:'%s'", err))
+			continue
+		}
+
+		report := CheckGuards(test.guards, current, snapshot)
+		if report.Removed != test.removed || report.Changed != test.changed {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("report expected removed:'%d' changed:'%d' got %s",
+				test.removed, test.changed, report.AsString()))
+			continue
+		}
+
+		if strings.Join(report.Violations, ";") != strings.Join(test.violations, ";") {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("violations expected:['%s'] got:['%s']",
+				strings.Join(test.violations, ";"), strings.Join(report.Violations, ";")))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
+
+func TestQuarantine(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+	p.c.Options.Snapshots.Directory = t.TempDir()
+	p.c.Cooker.Guards = TConfigGuards{Enabled: true, MaxRemoved: 30}
+
+	p.zones = NewZonesState(p)
+	zone := "example.net"
+
+	current, err := newGuardsSnapshot(p, 1, true, guardsHosts(10, 0))
+	if err != nil {
+		t.Fatalf("error making snapshot, err:'%s'", err)
+	}
+	p.zones.zones[zone] = TZoneState{Zone: zone, SnapshotID: 0,
+		Snapshots: map[int]TSnapshotZone{0: *current}}
+
+	broken, _ := newGuardsSnapshot(p, 2, true, guardsHosts(2, 0))
+	fixed, _ := newGuardsSnapshot(p, 3, true, guardsHosts(9, 0))
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		// action: "update", "reject" or "approve"
+		action   string
+		snapshot *TSnapshotZone
+
+		// expected result and quarantined serial
+		// (0 if no quarantine)
+		result bool
+		serial uint32
+	}
+
+	var Tests = []TTest{
+		{"9b4c6d8e-0f1a-4b2c-9d3e-4f5a6b7c8d9e", true, "update", fixed, false, 0},
+		{"0c5d7e9f-1a2b-4c3d-8e4f-5a6b7c8d9e0f", true, "update", broken, true, 2},
+		{"1d6e8f0a-2b3c-4d4e-9f5a-6b7c8d9e0f1a", true, "update", broken, true, 2},
+		{"2e7f9a1b-3c4d-4e5f-8a6b-7c8d9e0f1a2b", true, "reject", nil, true, 0},
+		{"3f8a0b2c-4d5e-4f6a-9b7c-8d9e0f1a2b3c", true, "reject", nil, false, 0},
+		{"4a9b1c3d-5e6f-4a7b-8c8d-9e0f1a2b3c4d", true, "update", broken, true, 0},
+		{"5b0c2d4e-6f7a-4b8c-9d9e-0f1a2b3c4d5e", true, "approve", nil, false, 0},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		var result bool
+		switch test.action {
+		case "update":
+			result = p.zones.Quarantine(zone, nil, test.snapshot)
+		case "reject":
+			result = p.zones.RejectQuarantine(zone) == nil
+		case "approve":
+			_, err := p.zones.ApproveQuarantine(context.Background(), zone)
+			result = err != ErrQuarantineNotFound
+		}
+
+		if result != test.result {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("%s result expected:'%t' got:'%t'",
+				test.action, test.result, result))
+			continue
+		}
+
+		var serial uint32
+		if q := p.zones.quarantine.Get(zone); q != nil {
+			serial = q.Report.Serial
+		}
+		if serial != test.serial || len(p.zones.quarantine.List()) != map[bool]int{true: 1}[serial > 0] {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("quarantined serial expected:'%d' got:'%d'",
+				test.serial, serial))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
@@ -0,0 +1,406 @@
+package receiver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// http source fetches zone data from a list of endpoints,
+// endpoints are tried in order, the whole list is retried
+// with backoff, zone data could be compressed (gzip, zstd)
+// and requested conditionally (etag or last-modified)
+
+const (
+	// default http request timeout in seconds
+	DefaultHTTPTimeout = 30
+
+	// default number of retries over endpoints list
+	DefaultHTTPRetries = 2
+
+	// default max body size (decompressed) in bytes
+	DefaultHTTPMaxBodySize = 512 * 1024 * 1024
+
+	// http request methods supported: POST sends zone
+	// in json body, GET sends zone as "zone" query
+	HTTPMethodPost = "POST"
+	HTTPMethodGet  = "GET"
+)
+
+type TConfigHTTPClient struct {
+	// request method POST (default) or GET
+	Method string `json:"method" yaml:"method"`
+
+	// per request timeout in seconds
+	Timeout int `json:"timeout" yaml:"timeout"`
+
+	// number of retries over endpoints list and
+	// backoff between retries
+	Retries int      `json:"retries" yaml:"retries"`
+	Backoff TBackoff `json:"backoff" yaml:"backoff"`
+
+	// max (decompressed) body size in bytes
+	MaxBodySize int64 `json:"max-body-size" yaml:"max-body-size"`
+
+	// additional headers to send
+	Headers map[string]string `json:"headers" yaml:"headers"`
+
+	// authorization for endpoints
+	Auth TConfigHTTPAuth `json:"auth" yaml:"auth"`
+}
+
+type TConfigHTTPAuth struct {
+	// bearer token could be set inline or in file
+	Bearer     string `json:"bearer" yaml:"bearer"`
+	BearerFile string `json:"bearer-file" yaml:"bearer-file"`
+
+	// basic authorization
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+}
+
+// validators of the last response to make
+// conditional requests
+type THTTPValidators struct {
+	ETag         string `json:"etag"`
+	LastModified string `json:"last-modified"`
+}
+
+func (t *THTTPValidators) Empty() bool {
+	return t == nil || (len(t.ETag) == 0 && len(t.LastModified) == 0)
+}
+
+type THTTPResponse struct {
+	// endpoint responded
+	Endpoint string
+
+	// body decompressed, empty if not modified
+	Body []byte
+
+	// zone data is not modified since validators
+	NotModified bool
+
+	// validators of response
+	Validators THTTPValidators
+}
+
+// http status error, could be retried for some codes
+type THTTPStatusError struct {
+	Endpoint string
+	Code     int
+}
+
+func (t *THTTPStatusError) Error() string {
+	return fmt.Sprintf("endpoint:'%s' responded code:'%d' (%s)", t.Endpoint,
+		t.Code, http.StatusText(t.Code))
+}
+
+// client errors (except timeout and rate limit) are
+// not retried
+func IsHTTPRetryable(err error) bool {
+	var status *THTTPStatusError
+	if !errors.As(err, &status) {
+		return true
+	}
+	if status.Code == http.StatusRequestTimeout || status.Code == http.StatusTooManyRequests {
+		return true
+	}
+	return status.Code < 400 || status.Code > 499
+}
+
+type HTTPSource struct {
+	p *TReceiverPlugin
+
+	config *TConfigHTTPClient
+
+	client *http.Client
+}
+
+func NewHTTPSource(p *TReceiverPlugin, config *TConfigHTTPClient) *HTTPSource {
+	var s HTTPSource
+	s.p = p
+	s.config = config
+
+	timeout := DefaultHTTPTimeout
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+
+	s.client = &http.Client{Timeout: time.Duration(timeout) * time.Second}
+
+	return &s
+}
+
+// Creating request for zone with headers, authorization
+// and conditional validators (if any)
+func (s *HTTPSource) NewRequest(ctx context.Context, endpoint string, zone string,
+	validators *THTTPValidators) (*http.Request, error) {
+
+	method := strings.ToUpper(s.config.Method)
+	if len(method) == 0 {
+		method = HTTPMethodPost
+	}
+
+	var body io.Reader
+	switch method {
+	case HTTPMethodPost:
+		content, err := json.MarshalIndent(TZoneConfig{Zone: zone}, "", "   ")
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(content)
+
+	case HTTPMethodGet:
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		query := u.Query()
+		query.Set("zone", zone)
+		u.RawQuery = query.Encode()
+		endpoint = u.String()
+
+	default:
+		return nil, fmt.Errorf("http method:'%s' is not supported", s.config.Method)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("User-Agent", s.p.G().Runtime.GetUseragent())
+	req.Header.Set("Accept-Encoding", "gzip, zstd")
+	if method == HTTPMethodPost {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	for k, v := range s.config.Headers {
+		req.Header.Set(k, v)
+	}
+
+	auth := s.config.Auth
+	if len(auth.BearerFile) > 0 {
+		content, err := os.ReadFile(auth.BearerFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading bearer-file:'%s', err:'%s'", auth.BearerFile, err)
+		}
+		auth.Bearer = strings.TrimSpace(string(content))
+	}
+
+	if len(auth.Bearer) > 0 {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", auth.Bearer))
+	}
+	if len(auth.Username) > 0 {
+		req.SetBasicAuth(auth.Username, auth.Password)
+	}
+
+	if !validators.Empty() {
+		if len(validators.ETag) > 0 {
+			req.Header.Set("If-None-Match", validators.ETag)
+		}
+		if len(validators.LastModified) > 0 {
+			req.Header.Set("If-Modified-Since", validators.LastModified)
+		}
+	}
+
+	return req, nil
+}
+
+// Reading body w.r.t content encoding and max body size
+func (s *HTTPSource) ReadBody(resp *http.Response) ([]byte, error) {
+	max := int64(DefaultHTTPMaxBodySize)
+	if s.config.MaxBodySize > 0 {
+		max = s.config.MaxBodySize
+	}
+
+	var reader io.Reader = resp.Body
+
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		reader = gz
+	case "zstd":
+		zr, err := zstd.NewReader(resp.Body, zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderMaxMemory(uint64(max)+1))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		reader = zr
+	default:
+		return nil, fmt.Errorf("content encoding:'%s' is not supported", encoding)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(reader, max+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if int64(len(body)) > max {
+		return nil, fmt.Errorf("body size exceeds max-body-size:'%d'", max)
+	}
+
+	return body, nil
+}
+
+// Requesting zone data from endpoint, non 2xx responses
+// are errors (except not modified on conditional request)
+func (s *HTTPSource) Request(ctx context.Context, endpoint string, zone string,
+	validators *THTTPValidators) (*THTTPResponse, error) {
+
+	req, err := s.NewRequest(ctx, endpoint, zone, validators)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var response THTTPResponse
+	response.Endpoint = endpoint
+
+	// POST with If-None-Match is responded with 412
+	// if entity is not changed
+	conditional := !validators.Empty()
+	if conditional && (resp.StatusCode == http.StatusNotModified ||
+		resp.StatusCode == http.StatusPreconditionFailed) {
+		response.NotModified = true
+		response.Validators = *validators
+		return &response, nil
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		return nil, &THTTPStatusError{Endpoint: endpoint, Code: resp.StatusCode}
+	}
+
+	if response.Body, err = s.ReadBody(resp); err != nil {
+		return nil, err
+	}
+
+	if len(bytes.TrimSpace(response.Body)) == 0 {
+		return nil, fmt.Errorf("endpoint:'%s' responded empty body", endpoint)
+	}
+
+	response.Validators.ETag = resp.Header.Get("ETag")
+	response.Validators.LastModified = resp.Header.Get("Last-Modified")
+
+	return &response, nil
+}
+
+// Fetching zone data from endpoints: each endpoint is tried
+// in order, the list is retried with backoff
+func (s *HTTPSource) Fetch(ctx context.Context, endpoints []string, zone string,
+	validators *THTTPValidators) (*THTTPResponse, error) {
+
+	id := "(http) (source)"
+
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no endpoints defined for zone:'%s'", zone)
+	}
+
+	retries := DefaultHTTPRetries
+	if s.config.Retries > 0 {
+		retries = s.config.Retries
+	}
+
+	var err error
+	retryable := true
+	for retry := 0; retry <= retries && retryable; retry++ {
+		if retry > 0 {
+			interval := s.config.Backoff.Interval(retry)
+			s.p.G().L.Debugf("%s zone:'%s' retry [%d]/[%d] in '%s'", id, zone,
+				retry, retries, interval)
+
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(interval):
+			}
+		}
+
+		retryable = false
+		for _, endpoint := range endpoints {
+			t0 := time.Now()
+			s.p.G().L.Debugf("%s requesting zone:'%s' snapshot over endpoint:'%s' conditional:'%t'",
+				id, zone, endpoint, !validators.Empty())
+
+			var response *THTTPResponse
+			if response, err = s.Request(ctx, endpoint, zone, validators); err == nil {
+				s.p.G().L.Debugf("%s received zone:'%s' size:'%d' not-modified:'%t' finished in '%s'",
+					id, zone, len(response.Body), response.NotModified, time.Since(t0))
+				return response, nil
+			}
+
+			s.p.G().L.Errorf("%s error requesting zone:'%s' endpoint:'%s', err:'%s'",
+				id, zone, endpoint, err)
+
+			retryable = retryable || IsHTTPRetryable(err)
+
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("error fetching zone:'%s' from endpoints ['%s'], err:'%s'",
+		zone, strings.Join(endpoints, ","), err)
+}
+
+// Getting validators of the last response for zone
+// and endpoint (if any)
+func (z *ZonesState) GetHTTPValidators(zone string, endpoint string) *THTTPValidators {
+	if z == nil {
+		return nil
+	}
+
+	z.lock.RLock()
+	defer z.lock.RUnlock()
+
+	if v, ok := z.validators[fmt.Sprintf("%s/%s", zone, endpoint)]; ok {
+		return &v
+	}
+	return nil
+}
+
+func (z *ZonesState) SetHTTPValidators(zone string, endpoint string, validators THTTPValidators) {
+	if z == nil {
+		return
+	}
+
+	z.lock.Lock()
+	defer z.lock.Unlock()
+
+	z.validators[fmt.Sprintf("%s/%s", zone, endpoint)] = validators
+}
+
+// Keeping http validators of snapshot as it is applied
+// into maps
+func (z *ZonesState) CommitHTTPValidators(snapshot *TSnapshotZone) {
+	if snapshot == nil || snapshot.imports == nil || snapshot.imports.validated == nil {
+		return
+	}
+	validated := snapshot.imports.validated
+	z.SetHTTPValidators(snapshot.imports.zone, validated.Endpoint, validated.Validators)
+}
@@ -0,0 +1,375 @@
+package receiver
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+var TestHTTPZone = `example.net. 3600 IN SOA ns1.example.net. hostmaster.example.net. 10 3600 600 86400 300
+www.example.net. 300 IN A 192.0.2.1
+example.net. 3600 IN SOA ns1.example.net. hostmaster.example.net. 10 3600 600 86400 300
+`
+
+// making zstd frame with a single raw block, content
+// should be less than 256 bytes
+func NewTestZstdFrame(content []byte) []byte {
+	var b bytes.Buffer
+
+	// magic, single segment with one byte content size
+	b.Write([]byte{0x28, 0xb5, 0x2f, 0xfd, 0x20, byte(len(content))})
+
+	// last raw block with its size
+	header := uint32(len(content))<<3 | 1
+	b.Write([]byte{byte(header), byte(header >> 8), byte(header >> 16)})
+	b.Write(content)
+
+	return b.Bytes()
+}
+
+func TestHTTPSourceFetch(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+
+	etag := `"zone-v10"`
+
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	_, _ = gz.Write([]byte(TestHTTPZone))
+	_ = gz.Close()
+
+	var requests int32
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+
+		if r.Header.Get("Authorization") != "Bearer secret-token" ||
+			r.Header.Get("X-Cluster") != "test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/plain":
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			_, _ = w.Write([]byte(TestHTTPZone))
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			_, _ = w.Write(gzipped.Bytes())
+		case "/zstd":
+			w.Header().Set("Content-Encoding", "zstd")
+			_, _ = w.Write(NewTestZstdFrame([]byte(TestHTTPZone)))
+		case "/empty":
+			w.WriteHeader(http.StatusOK)
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	url := func(path string) string {
+		return fmt.Sprintf("%s%s", server.URL, path)
+	}
+
+	config := TConfigHTTPClient{
+		Timeout: 5,
+		Retries: 1,
+		Backoff: TBackoff{Initial: 1, Max: 1, Jitter: 0.01},
+		Headers: map[string]string{"X-Cluster": "test"},
+		Auth:    TConfigHTTPAuth{Bearer: "secret-token"},
+	}
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		endpoints  []string
+		validators *THTTPValidators
+		max        int64
+		bearer     string
+
+		// expected body, not modified flag and
+		// number of requests or error
+		body        string
+		notmodified bool
+		requests    int32
+		err         bool
+	}
+
+	var Tests = []TTest{
+		{
+			"6b1c2d3e-4f5a-4b6c-8d7e-9f0a1b2c3d4e",
+			true,
+			[]string{url("/plain")}, nil, 0, "",
+			TestHTTPZone, false, 1, false,
+		},
+		{
+			"7c2d3e4f-5a6b-4c7d-9e8f-0a1b2c3d4e5f",
+			true,
+			[]string{url("/plain")}, &THTTPValidators{ETag: etag}, 0, "",
+			"", true, 1, false,
+		},
+		{
+			"8d3e4f5a-6b7c-4d8e-8f9a-1b2c3d4e5f6a",
+			true,
+			[]string{url("/gzip")}, nil, 0, "",
+			TestHTTPZone, false, 1, false,
+		},
+		{
+			"9e4f5a6b-7c8d-4e9f-9a0b-2c3d4e5f6a7b",
+			true,
+			[]string{url("/zstd")}, nil, 0, "",
+			TestHTTPZone, false, 1, false,
+		},
+		{
+			// failing over to the next endpoint
+			"0f5a6b7c-8d9e-4f0a-8b1c-3d4e5f6a7b8c",
+			true,
+			[]string{url("/error"), url("/gzip")}, nil, 0, "",
+			TestHTTPZone, false, 2, false,
+		},
+		{
+			// all endpoints failed, list is retried
+			"1a6b7c8d-9e0f-4a1b-9c2d-4e5f6a7b8c9d",
+			true,
+			[]string{url("/error"), url("/empty")}, nil, 0, "",
+			"", false, 4, true,
+		},
+		{
+			// client errors are not retried
+			"2b7c8d9e-0f1a-4b2c-8d3e-5f6a7b8c9d0e",
+			true,
+			[]string{url("/forbidden")}, nil, 0, "",
+			"", false, 1, true,
+		},
+		{
+			"3c8d9e0f-1a2b-4c3d-9e4f-6a7b8c9d0e1f",
+			true,
+			[]string{url("/plain")}, nil, 0, "wrong-token",
+			"", false, 1, true,
+		},
+		{
+			"4d9e0f1a-2b3c-4d4e-8f5a-7b8c9d0e1f2a",
+			true,
+			[]string{url("/gzip")}, nil, 64, "",
+			"", false, 2, true,
+		},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		c := config
+		c.MaxBodySize = test.max
+		if len(test.bearer) > 0 {
+			c.Auth.Bearer = test.bearer
+		}
+
+		atomic.StoreInt32(&requests, 0)
+
+		source := NewHTTPSource(p, &c)
+		response, err := source.Fetch(context.Background(), test.endpoints,
+			"example.net", test.validators)
+
+		if n := atomic.LoadInt32(&requests); n != test.requests {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("requests expected:'%d' got:'%d'",
+				test.requests, n))
+			continue
+		}
+
+		if test.err {
+			if err == nil {
+				t.Error("\nUUID", test.uuid, "error expected")
+				continue
+			}
+			fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+			continue
+		}
+
+		if err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error fetching, err:'%s'", err))
+			continue
+		}
+
+		if string(response.Body) != test.body || response.NotModified != test.notmodified {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("body expected:'%s' not-modified:'%t' got:'%s' not-modified:'%t'",
+				test.body, test.notmodified, response.Body, response.NotModified))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
+
+func TestHTTPValidatorsCommit(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+	p.c.Options.Snapshots.Directory = t.TempDir()
+	p.zones = NewZonesState(p)
+
+	etag := `"zone-v10"`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		_, _ = w.Write([]byte(TestHTTPZone))
+	}))
+	defer server.Close()
+
+	zone := "example.net"
+	var config TConfigImporter
+	config.Zone = append(config.Zone, zone)
+	config.Endpoint = append(config.Endpoint, server.URL)
+
+	importer, err := NewImporterWorker(p, &config)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making importer, err:'%s'", err))
+		return
+	}
+
+	options := TZoneSnapshotOptions{Incremental: true, Source: SourceHTTP, Server: server.URL,
+		SnapshotMode: SnapshotMemoryExists}
+
+	// validators are not kept till snapshot is applied, so
+	// zone is requested again if snapshot is rejected
+	uuid := "4a7b8c9d-0e1f-4a2b-9c3d-4e5f6a7b8c9d"
+	for i := 0; i < 2; i++ {
+		snapshot, err := importer.GetZoneSnapshotHTTP(context.Background(), zone, &options)
+		if err != nil || snapshot == nil {
+			t.Error("\nUUID", uuid, fmt.Sprintf("snapshot expected [%d], err:'%v'", i, err))
+			return
+		}
+		if v := p.zones.GetHTTPValidators(zone, server.URL); v != nil {
+			t.Error("\nUUID", uuid, fmt.Sprintf("unexpected validators:'%v'", v))
+			return
+		}
+	}
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+
+	uuid = "5b8c9d0e-1f2a-4b3c-8d4e-5f6a7b8c9d0e"
+	snapshot, err := importer.GetZoneSnapshotHTTP(context.Background(), zone, &options)
+	if err != nil || snapshot == nil {
+		t.Error("\nUUID", uuid, fmt.Sprintf("snapshot expected, err:'%v'", err))
+		return
+	}
+	p.zones.CommitHTTPValidators(snapshot)
+
+	v := p.zones.GetHTTPValidators(zone, server.URL)
+	if v == nil || v.ETag != etag {
+		t.Error("\nUUID", uuid, fmt.Sprintf("unexpected validators:'%v'", v))
+		return
+	}
+	if snapshot, err = importer.GetZoneSnapshotHTTP(context.Background(), zone, &options); err != nil ||
+		snapshot != nil {
+		t.Error("\nUUID", uuid, fmt.Sprintf("not modified expected, err:'%v'", err))
+		return
+	}
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+}
+
+func TestHTTPEndpointsFailover(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+	p.c.Options.Snapshots.Directory = t.TempDir()
+	p.zones = NewZonesState(p)
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(TestHTTPZone))
+	}))
+	defer up.Close()
+
+	zone := "example.net"
+	var config TConfigImporter
+	config.Zone = append(config.Zone, zone)
+	config.Endpoint = append(config.Endpoint, down.URL)
+
+	p.L().HTTPTransfer.Client = TConfigHTTPClient{
+		Timeout: 5,
+		Retries: 1,
+		Backoff: TBackoff{Initial: 1, Max: 1, Jitter: 0.01},
+	}
+
+	importer, err := NewImporterWorker(p, &config)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making importer, err:'%s'", err))
+		return
+	}
+
+	type TTest struct {
+		uuid      string
+		enabled   bool
+		endpoints []string
+		endpoint  string
+		err       bool
+	}
+
+	tests := []TTest{
+		// single endpoint (as before) is down
+		{uuid: "6c9d0e1f-2a3b-4c4d-9e5f-6a7b8c9d0e1f", enabled: true,
+			endpoints: nil, err: true},
+		// next endpoint of zone serves snapshot
+		{uuid: "7d0e1f2a-3b4c-4d5e-8f6a-7b8c9d0e1f2a", enabled: true,
+			endpoints: []string{down.URL, up.URL}, endpoint: up.URL},
+	}
+
+	for _, test := range tests {
+		if !test.enabled {
+			continue
+		}
+
+		options := TZoneSnapshotOptions{Incremental: true, Source: SourceHTTP, Server: down.URL,
+			Endpoints: test.endpoints}
+
+		snapshot, err := importer.GetZoneSnapshotHTTP(context.Background(), zone, &options)
+		if test.err {
+			if err == nil {
+				t.Error("\nUUID", test.uuid, "error expected")
+				return
+			}
+			fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+			continue
+		}
+		if err != nil || snapshot == nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("snapshot expected, err:'%v'", err))
+			return
+		}
+		if v := snapshot.imports.validated; v == nil || v.Endpoint != test.endpoint {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("unexpected endpoint:'%v'", v))
+			return
+		}
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
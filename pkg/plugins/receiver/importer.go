@@ -2,9 +2,9 @@ package receiver
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/miekg/dns"
@@ -22,9 +22,9 @@ type ImporterWorker struct {
 
 	options *TConfigImporter
 
-	// locing state table within
-	// importer workers
-	lock sync.Mutex
+	// records skipped by the last filter zone
+	// call with their reasons
+	skips map[string]TSkippedRecord
 }
 
 func NewImporterWorker(p *TReceiverPlugin, options *TConfigImporter) (*ImporterWorker, error) {
@@ -74,6 +74,11 @@ const (
 	SkipByLength = 1001
 	SkipByCount  = 1002
 	SkipByType   = 1003
+	SkipByZone   = 1004
+
+	// policy trigger or action is not supported
+	SkipByTrigger = 1005
+	SkipByAction  = 1006
 )
 
 // we need filter zone got iva tranfer zone containing
@@ -82,11 +87,27 @@ func (j *ImporterWorker) FilterZone(rr []dns.RR, mode int) (map[string][]dns.RR,
 	id := "(importer) (filter)"
 
 	skips := make(map[int]int)
-	types := []int{SkipByLength, SkipByCount, SkipByType}
+	types := []int{SkipByLength, SkipByCount, SkipByType, SkipByZone}
 	for _, t := range types {
 		skips[t] = 0
 	}
 
+	j.skips = make(map[string]TSkippedRecord)
+	skip := func(r dns.RR, reason int) {
+		skips[reason]++
+		j.skips[r.String()] = NewSkippedRecord(r, reason)
+	}
+
+	// zone apex is defined by SOA record, records out of
+	// zone apex are skipped
+	var apex string
+	for _, r := range rr {
+		if r != nil && r.Header().Rrtype == dns.TypeSOA {
+			apex = r.Header().Name
+			break
+		}
+	}
+
 	rrsets := make(map[string][]dns.RR)
 	var soa dns.RR
 	for _, r := range rr {
@@ -106,31 +127,42 @@ func (j *ImporterWorker) FilterZone(rr []dns.RR, mode int) (map[string][]dns.RR,
 			name = fmt.Sprintf("%s%s.", name, j.options.Suffix)
 		}
 
-		// adding only ALLOWED types of RR
-		if h.Rrtype == dns.TypeA || h.Rrtype == dns.TypeAAAA {
-			if len(name) >= offloader.DefaultQnameMaxLength {
-				skips[SkipByLength]++
-				if skips[SkipByLength] < DefaultDumpMaxRRsets {
-					j.p.G().L.Errorf("%s skip qname:'%s' as length:'%d' exceeded qname max len of '%d'",
-						id, name, len(name), offloader.DefaultQnameMaxLength)
-				}
-				continue
-			}
-			key := fmt.Sprintf("%s-%s", name, dns.Type(h.Rrtype).String())
-			rrsets[key] = append(rrsets[key], r)
-		}
-
 		if h.Rrtype == dns.TypeSOA {
 			q := r
 			soa = q
+			continue
 		}
+
+		if len(apex) > 0 && !dns.IsSubDomain(apex, h.Name) {
+			skip(r, SkipByZone)
+			continue
+		}
+
+		// adding only ALLOWED types of RR
+		if h.Rrtype != dns.TypeA && h.Rrtype != dns.TypeAAAA {
+			skip(r, SkipByType)
+			continue
+		}
+
+		if len(name) >= offloader.DefaultQnameMaxLength {
+			skip(r, SkipByLength)
+			if skips[SkipByLength] < DefaultDumpMaxRRsets {
+				j.p.G().L.Errorf("%s skip qname:'%s' as length:'%d' exceeded qname max len of '%d'",
+					id, name, len(name), offloader.DefaultQnameMaxLength)
+			}
+			continue
+		}
+		key := fmt.Sprintf("%s-%s", name, dns.Type(h.Rrtype).String())
+		rrsets[key] = append(rrsets[key], r)
 	}
 
 	frrsets := make(map[string][]dns.RR)
 
 	for k, rrset := range rrsets {
 		if len(rrset) > 1 && mode == ImportFilterStrict {
-			skips[SkipByCount]++
+			for _, r := range rrset {
+				skip(r, SkipByCount)
+			}
 			if skips[SkipByCount] < DefaultDumpMaxRRsets {
 				j.p.G().L.Errorf("%s skip k:'%s'", id, k)
 			}
@@ -139,9 +171,9 @@ func (j *ImporterWorker) FilterZone(rr []dns.RR, mode int) (map[string][]dns.RR,
 		frrsets[k] = rrset
 	}
 
-	j.p.G().L.Debugf("%s filter in:'%d' -> out:'%d' skips bylength:'%d' bycount:'%d'",
-		id, len(rr), len(frrsets), skips[SkipByLength],
-		skips[SkipByCount])
+	j.p.G().L.Debugf("%s filter in:'%d' -> out:'%d' skips bylength:'%d' bycount:'%d' bytype:'%d' byzone:'%d'",
+		id, len(rr), len(frrsets), skips[SkipByLength], skips[SkipByCount],
+		skips[SkipByType], skips[SkipByZone])
 
 	return frrsets, soa
 }
@@ -159,6 +191,13 @@ func (j *ImporterWorker) GetZoneSnapshotAXFR(zone string, options *TZoneSnapshot
 	opts := new(TransferOptions)
 	opts.Mode = TransferModeAXFR
 	opts.Key = options.Key
+	opts.TLS = options.TLS
+
+	// TSIG failures are counted per zone and key
+	keys := options.Tsig
+	failed := func(key *TTsigKey, err error) {
+		j.p.zones.TsigFailure(zone, key, err)
+	}
 
 	var snapshot *TSnapshotZone
 	var actions *TSnapshotActions
@@ -183,7 +222,15 @@ func (j *ImporterWorker) GetZoneSnapshotAXFR(zone string, options *TZoneSnapshot
 			j.p.G().L.Debugf("%s snapshot zone:'%s' serial:'%d' is set, trying SOA request and IXFR",
 				id, zone, serial2)
 
-			if opts, err = RequestSOA(options.Server, zone); err != nil {
+			request := func(key *TTsigKey) error {
+				var err error
+				opts, err = RequestSOAWithOptions(options.Server, zone,
+					&TransferOptions{Tsig: key, TLS: options.TLS})
+				return err
+			}
+
+			var key *TTsigKey
+			if key, err = WithTsigKeys(keys, request, failed); err != nil {
 				j.p.G().L.Errorf("%s error SOA request zone:'%s' via server:'%s', err:'%s'",
 					id, zone, options.Server, err)
 				return nil, err
@@ -192,6 +239,10 @@ func (j *ImporterWorker) GetZoneSnapshotAXFR(zone string, options *TZoneSnapshot
 			j.p.G().L.Debugf("%s zone:'%s' authority SOA '%d %s %s'", id, zone,
 				opts.Serial, opts.Ns, opts.Mbox)
 			opts.Mode = TransferModeIXFR
+			opts.Key = options.Key
+
+			// the key succeeded is tried first on transfer
+			keys = PreferTsigKey(keys, key)
 
 			j.p.G().L.Debugf("%s zone:'%s' requested serial interval:'%d -> %d'",
 				id, zone, serial2, opts.Serial)
@@ -202,6 +253,10 @@ func (j *ImporterWorker) GetZoneSnapshotAXFR(zone string, options *TZoneSnapshot
 					j.p.G().L.Debugf("%s no any changes for zone:'%s' via primary:'%s' detected",
 						id, zone, options.Server)
 
+					if opts.Conn != nil {
+						opts.Conn.Close()
+					}
+
 					// beware snapshot could be nil	(as no any changes occured)
 					return nil, nil
 				}
@@ -216,8 +271,15 @@ func (j *ImporterWorker) GetZoneSnapshotAXFR(zone string, options *TZoneSnapshot
 		}
 	}
 
-	rr, err := TransferZone(options.Server, zone, opts)
-	if err != nil {
+	var rr []dns.RR
+	transfer := func(key *TTsigKey) error {
+		var err error
+		opts.Tsig = key
+		rr, err = TransferZone(options.Server, zone, opts)
+		return err
+	}
+
+	if _, err = WithTsigKeys(keys, transfer, failed); err != nil {
 		j.p.G().L.Errorf("%s error transfering zone:'%s', err:'%s'", id, zone, err)
 		return nil, err
 	}
@@ -240,7 +302,7 @@ func (j *ImporterWorker) GetZoneSnapshotAXFR(zone string, options *TZoneSnapshot
 		}
 	}
 
-	if incremental && snapshot == nil {
+	if snapshot == nil {
 
 		rrsets, soa := j.FilterZone(rr, ImportFilterLoosed)
 
@@ -252,6 +314,7 @@ func (j *ImporterWorker) GetZoneSnapshotAXFR(zone string, options *TZoneSnapshot
 		snapshot.zone = zone
 		snapshot.timestamp = time.Now()
 		snapshot.rrsets = rrsets
+		snapshot.skips = j.skips
 
 		snapshot.Dump(j.p, "axfr", DefaultDumpMaxRRsets)
 
@@ -277,6 +340,7 @@ func (j *ImporterWorker) GetZoneSnapshotAXFR(zone string, options *TZoneSnapshot
 		// replacing map rrset with new data of ixfr
 		// map[string][]dns.RR vs []dns.RR
 		snapshot.rrsets = rrsets
+		snapshot.skips = j.skips
 
 		snapshot.Dump(j.p, "axfr+fallback", DefaultDumpMaxRRsets)
 	}
@@ -295,15 +359,65 @@ func (j *ImporterWorker) GetZoneSnapshotHTTP(ctx context.Context, source string,
 
 	var snapshot *TSnapshotZone
 
+	// http response validators are kept as snapshot
+	// is applied for conditional requests
+	var validated *THTTPResponse
+
 	switch options.Source {
+	case SourceRandom:
+		snapshot, err = j.GetZoneSnapshotRandom(source, options)
+	case SourceRPZ:
+		snapshot, err = j.GetZoneSnapshotRPZ(source, options)
+		if err == nil && snapshot == nil {
+			// beware snapshot could be nil	(as no any changes occured)
+			return nil, nil
+		}
+	case SourceHosts, SourceJSON:
+		snapshot, validated, err = j.GetZoneSnapshotRecords(ctx, source, options)
+		if err == nil && snapshot == nil {
+			// beware snapshot could be nil	(as no any changes occured)
+			return nil, nil
+		}
+	case SourceDynamic:
+		snapshot, err = j.GetZoneSnapshotDynamic(source, options)
+		if err == nil && snapshot == nil {
+			// beware snapshot could be nil	(as no any changes occured)
+			return nil, nil
+		}
 	case SourceFile:
 		filename := strings.TrimPrefix(options.Server, "file:///")
 		j.p.G().L.Debugf("%s request snapshot zone:'%s' server:'%s' filename:'%s'", id,
 			source, options.Server, filename)
-		snapshot, err = NewSnapshotZoneFromFile(j.p, filename, source)
+		snapshot, err = NewSnapshotZoneFromZoneFile(j.p, filename, source)
 	case SourceHTTP:
-		snapshot, err = NewSnapshotZoneFromEndpoint(j.p, ctx,
-			[]string{options.Server}, source)
+		// conditional request is made only if we have
+		// zone snapshot in memory
+		var validators *THTTPValidators
+		if options.SnapshotMode == SnapshotMemoryExists {
+			validators = j.p.zones.GetHTTPValidators(source, options.Server)
+		}
+
+		client := NewHTTPSource(j.p, &j.p.L().HTTPTransfer.Client)
+
+		var response *THTTPResponse
+		response, err = client.Fetch(ctx, options.HTTPEndpoints(), source, validators)
+		if err != nil {
+			break
+		}
+
+		if response.NotModified {
+			j.p.G().L.Debugf("%s no any changes for zone:'%s' via endpoint:'%s' detected",
+				id, source, response.Endpoint)
+
+			// beware snapshot could be nil	(as no any changes occured)
+			return nil, nil
+		}
+
+		snapshot, err = NewSnapshotZone(j.p, string(response.Body), source)
+		if err == nil && snapshot.soa == nil {
+			err = fmt.Errorf("no SOA found in response of endpoint:'%s'", response.Endpoint)
+		}
+		validated = response
 	}
 	if err != nil {
 		j.p.G().L.Errorf("%s error import source:'%s' as snapshot via options:'%d', err:'%s'",
@@ -357,7 +471,11 @@ func (j *ImporterWorker) GetZoneSnapshotHTTP(ctx context.Context, source string,
 			mode = TransferModeAXFR
 		}
 
-		snapshot.imports = &TImportActions{mode: mode, zone: zone, actions: actions}
+		// validators are kept as snapshot is applied, so
+		// snapshot rejected (or failed) is requested again
+		snapshot.imports = &TImportActions{mode: mode, zone: zone, actions: actions,
+			validated: validated}
+
 		return snapshot, nil
 	}
 
@@ -369,7 +487,17 @@ type TUpdateZoneStateOptions struct {
 
 	Server string
 
+	// http endpoints tried in order (failover) by
+	// http sources, server is the first one
+	Endpoints []string
+
 	Key string
+
+	// named TSIG keys tried in order
+	Tsig []*TTsigKey
+
+	// TLS configuration for tls:// primaries
+	TLS *tls.Config
 }
 
 const (
@@ -389,16 +517,9 @@ func (j *ImporterWorker) UpdateZoneState(ctx context.Context, states *ZonesState
 	state.SnapshotCount = DefaultSnapshotCount
 	state.Snapshots = make(map[int]TSnapshotZone)
 
-	if _, ok := states.locks[zone]; !ok {
-		var lock sync.Mutex
-
-		j.lock.Lock()
-		states.locks[zone] = &lock
-		j.lock.Unlock()
-	}
-
-	states.locks[zone].Lock()
-	defer states.locks[zone].Unlock()
+	lock := states.ZoneLock(zone)
+	lock.Lock()
+	defer lock.Unlock()
 
 	// if we do not have any snapshot zone requested
 	// we need set snapshot mode
@@ -420,7 +541,7 @@ func (j *ImporterWorker) UpdateZoneState(ctx context.Context, states *ZonesState
 				id, startup, zone)
 
 			filename := GetSnapshotFilename(j.p, zone)
-			age := GetFileAge(filename)
+			age := SnapshotFileAge(filename)
 
 			j.p.G().L.Debugf("%s cold startup zone:'%s' snapshot:'%s' age:'%2.2f'",
 				id, zone, filename, age)
@@ -444,7 +565,7 @@ func (j *ImporterWorker) UpdateZoneState(ctx context.Context, states *ZonesState
 					state.Snapshots[state.SnapshotID] = *snapshot
 					state.Zone = zone
 
-					states.zones[zone] = state
+					states.SetState(zone, state)
 
 					return nil
 				}
@@ -456,21 +577,40 @@ func (j *ImporterWorker) UpdateZoneState(ctx context.Context, states *ZonesState
 		Incremental:  options.Incremental,
 		Source:       source,
 		Server:       options.Server,
+		Endpoints:    options.Endpoints,
 		Key:          options.Key,
+		Tsig:         options.Tsig,
+		TLS:          options.TLS,
 		SnapshotMode: mode,
+		Random:       config.Random,
+		RPZ:          config.RPZ,
 	}
 
 	var err error
 	var snapshot *TSnapshotZone
-	switch source {
-	case SourceHTTP, SourceFile:
+
+	// zone pinned to generation is not transferred, its
+	// generation is loaded if no snapshot in memory
+	pin := states.GetPin(zone)
+	switch {
+	case pin != nil:
+		if mode == SnapshotMemoryEmpty {
+			j.p.G().L.Debugf("%s zone:'%s' pinned to generation:'%d' serial:'%d'",
+				id, zone, pin.Generation, pin.Serial)
+			if snapshot, err = states.PinnedSnapshot(zone, pin); err == nil {
+				snapshot.imports = &TImportActions{mode: TransferModeAXFR, zone: zone}
+			}
+		}
+	case source == SourceAXFR:
+		snapshot, err = j.GetZoneSnapshotAXFR(zone, &opts)
+		err = PrimaryError(err)
+	default:
 		// checking if zone has file:// prefix
-		if strings.HasPrefix(opts.Server, "file://") {
+		if source == SourceHTTP && strings.HasPrefix(opts.Server, "file://") {
 			opts.Source = SourceFile
 		}
 		snapshot, err = j.GetZoneSnapshotHTTP(ctx, zone, &opts)
-	case SourceAXFR:
-		snapshot, err = j.GetZoneSnapshotAXFR(zone, &opts)
+		err = PrimaryError(err)
 	}
 
 	if err != nil {
@@ -479,6 +619,17 @@ func (j *ImporterWorker) UpdateZoneState(ctx context.Context, states *ZonesState
 		return err
 	}
 
+	// update violating guards is kept in quarantine and
+	// processed as no any changes in zone
+	if snapshot != nil && pin == nil && states.Quarantine(zone, config, snapshot) {
+		if states.GetLastZoneSnapshot(zone) == nil {
+			j.p.G().L.Errorf("%s error updating zone:'%s', err:'%s'", id, zone,
+				ErrZoneQuarantined)
+			return ErrZoneQuarantined
+		}
+		snapshot = nil
+	}
+
 	if snapshot != nil {
 		state.SnapshotID = (state.SnapshotID + 1) % state.SnapshotCount
 		state.Snapshots[state.SnapshotID] = *snapshot
@@ -491,7 +642,8 @@ func (j *ImporterWorker) UpdateZoneState(ctx context.Context, states *ZonesState
 		// snapshots
 		state.State = state.DetectState(j.p, snapshot)
 
-		states.zones[zone] = state
+		states.SetState(zone, state)
+		states.PushSkipMetrics(zone, snapshot)
 
 		j.p.G().L.Debugf("%s ixfr snapshot updated zone:'%s' rrsets:'%d'",
 			id, zone, len(snapshot.rrsets))
@@ -502,14 +654,14 @@ func (j *ImporterWorker) UpdateZoneState(ctx context.Context, states *ZonesState
 		// to push state into IXFR mode (incremental with zero
 		// changes)
 
-		if _, ok := states.zones[zone]; !ok {
+		state, ok := states.GetState(zone)
+		if !ok {
 			err = fmt.Errorf("no snapshot detected")
 			j.p.G().L.Errorf("%s error detecting current snapshot zone:'%s', err:'%s'",
 				id, zone, err)
 			return err
 		}
 
-		state := states.zones[zone]
 		sid := state.SnapshotID
 		if sid == -1 {
 			err := fmt.Errorf("no valid snapshot for zone:'%s' found", zone)
@@ -523,7 +675,7 @@ func (j *ImporterWorker) UpdateZoneState(ctx context.Context, states *ZonesState
 
 		snapshot.imports = imports
 		state.Snapshots[sid] = snapshot
-		states.zones[zone] = state
+		states.SetState(zone, state)
 
 		j.p.G().L.Debugf("%s axfr none changes via ixfr snapshot updated zone:'%s' rrsets:'%d'",
 			id, zone, len(snapshot.rrsets))
@@ -554,13 +706,16 @@ func (j *ImporterWorker) Import(ctx context.Context) error {
 
 	t := SourceFile
 	endpoint := ""
+	var endpoints []string
 	if len(j.options.Endpoint) > 0 {
 		t = SourceHTTP
 		endpoint = j.options.Endpoint[0]
+		endpoints = j.options.Endpoint
 	}
 	if len(j.options.Server) > 0 {
 		t = SourceAXFR
 		endpoint = j.options.Server
+		endpoints = nil
 	}
 
 	defaultconfig := CreateDefaultConfigZone([]string{j.options.Server})
@@ -574,6 +729,7 @@ func (j *ImporterWorker) Import(ctx context.Context) error {
 		options := TUpdateZoneStateOptions{
 			Incremental: incremental,
 			Server:      endpoint,
+			Endpoints:   endpoints,
 			Key:         j.options.Key,
 		}
 		if err = j.UpdateZoneState(ctx, states, t, source,
@@ -0,0 +1,487 @@
+package receiver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/yandex/yadns-controller/pkg/plugins/offloader"
+)
+
+// journal of maps mutations (write-ahead): mutations of maps
+// sync are staged first (sync logic sees staged state as maps
+// state), the batch of mutations is written into journal
+// directory, applied into maps and removed as committed.
+// Batches found in journal on startup are unfinished as
+// process stopped while applying them: they are replayed
+// (records of batch are written again) or rolled back (records
+// found in maps before batch are restored) w.r.t. recovery
+// policy. Mutations are idempotent, so batch could be applied
+// more than once
+
+const (
+	JournalReplay   = "replay"
+	JournalRollback = "rollback"
+
+	DefaultJournalRecovery = JournalReplay
+
+	// journal directory in snapshots directory
+	DefaultJournalDirectory = "journal"
+
+	// suffix of batch files in journal directory
+	DefaultJournalSuffix = "batch"
+
+	// batch pending longer is stale as it should be
+	// committed just after it is applied
+	DefaultJournalStale = 60 * time.Second
+
+	// number of batches pending in journal
+	MetricJournalPending = "receiver-journal-pending"
+
+	// batches recovered on startup w.r.t. recovery
+	// policy and failed to recover
+	MetricJournalRecovered = "receiver-journal-recovered"
+	MetricJournalFailed    = "receiver-journal-failed"
+
+	MonitorJournal = "yadns-receiver-journal"
+)
+
+var (
+	ErrJournalRecovery = errors.New("unknown journal recovery policy")
+
+	// key is removed in batch staged
+	ErrJournalStagedRemoved = errors.New("key removed in journal batch")
+)
+
+type TConfigJournal struct {
+	// journal of maps mutations could be disabled
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// unfinished batches recovery on startup: "replay"
+	// or "rollback", "replay" if empty
+	Recovery string `json:"recovery" yaml:"recovery"`
+
+	// journal directory, "journal" in snapshots
+	// directory if empty
+	Directory string `json:"directory" yaml:"directory"`
+}
+
+// Getting recovery policy with default applied
+func (t *TConfigJournal) GetRecovery() (string, error) {
+	switch t.Recovery {
+	case "":
+		return DefaultJournalRecovery, nil
+	case JournalReplay, JournalRollback:
+		return t.Recovery, nil
+	}
+	return "", fmt.Errorf("%w: '%s'", ErrJournalRecovery, t.Recovery)
+}
+
+// mutation of maps key, empty record means key removed
+// (as empty previous means key is absent before batch)
+type TJournalMutation struct {
+	Key      string `json:"key"`
+	Record   string `json:"record,omitempty"`
+	Previous string `json:"previous,omitempty"`
+}
+
+type TJournalBatch struct {
+	// batch id as unix nano time of its creation, batches
+	// are recovered in order of id
+	ID int64 `json:"id"`
+
+	Zone    string    `json:"zone"`
+	Mode    string    `json:"mode"`
+	Created time.Time `json:"created"`
+
+	Mutations []TJournalMutation `json:"mutations"`
+
+	// staged state of keys: record expected (nil if
+	// removed) and record found in maps before batch
+	records  map[string]dns.RR
+	previous map[string]dns.RR
+}
+
+func NewJournalBatch(zone string, mode int) *TJournalBatch {
+	var b TJournalBatch
+	b.Created = time.Now()
+	b.ID = b.Created.UnixNano()
+	b.Zone = zone
+	b.Mode = TransferModeAsString(mode)
+	b.records = make(map[string]dns.RR)
+	b.previous = make(map[string]dns.RR)
+	return &b
+}
+
+// Getting staged record of key, ok is false if key
+// is not touched by batch
+func (b *TJournalBatch) Staged(key string) (dns.RR, bool) {
+	rr, ok := b.records[key]
+	return rr, ok
+}
+
+// Staging record of key (nil if removed), previous is
+// kept as key is staged first time
+func (b *TJournalBatch) Stage(key string, rr dns.RR, previous dns.RR) {
+	if _, ok := b.records[key]; !ok {
+		b.previous[key] = previous
+	}
+	b.records[key] = rr
+}
+
+func rrString(rr dns.RR) string {
+	if rr == nil {
+		return ""
+	}
+	return rr.String()
+}
+
+// Building mutations of staged keys in keys order,
+// keys with records not changed are skipped
+func (b *TJournalBatch) Build() []TJournalMutation {
+	var keys []string
+	for k := range b.records {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	b.Mutations = nil
+	for _, k := range keys {
+		record := rrString(b.records[k])
+		previous := rrString(b.previous[k])
+		if record == previous {
+			continue
+		}
+		b.Mutations = append(b.Mutations, TJournalMutation{Key: k, Record: record,
+			Previous: previous})
+	}
+	return b.Mutations
+}
+
+func (b *TJournalBatch) AsString() string {
+	var out []string
+	out = append(out, fmt.Sprintf("id:'%d'", b.ID))
+	out = append(out, fmt.Sprintf("zone:'%s'", b.Zone))
+	out = append(out, fmt.Sprintf("mode:'%s'", b.Mode))
+	out = append(out, fmt.Sprintf("mutations:'%d'", len(b.Mutations)))
+	return strings.Join(out, ",")
+}
+
+type TJournalStatus struct {
+	Enabled  bool   `json:"enabled"`
+	Recovery string `json:"recovery"`
+
+	// batches found on startup recovered and
+	// failed to recover
+	Recovered int `json:"recovered"`
+	Failed    int `json:"failed"`
+
+	// batches committed since startup
+	Committed int64 `json:"committed"`
+
+	// batches pending in journal
+	Pending []TJournalBatch `json:"pending"`
+
+	Error string `json:"error,omitempty"`
+}
+
+type Journal struct {
+	p *TReceiverPlugin
+
+	lock sync.Mutex
+
+	recovered int
+	failed    int
+	committed int64
+	err       error
+}
+
+func NewJournal(p *TReceiverPlugin) *Journal {
+	var j Journal
+	j.p = p
+	return &j
+}
+
+func (t *TReceiverPlugin) Journal() *Journal {
+	if t.zones == nil {
+		return nil
+	}
+	return t.zones.journal
+}
+
+func (j *Journal) Enabled() bool {
+	return j.p.L().Cooker.Journal.Enabled
+}
+
+func (j *Journal) Directory() string {
+	if directory := j.p.L().Cooker.Journal.Directory; len(directory) > 0 {
+		return directory
+	}
+	path := j.p.L().Options.Snapshots.Directory
+	if len(path) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s", path, DefaultJournalDirectory)
+}
+
+func (j *Journal) filename(batch *TJournalBatch) string {
+	return fmt.Sprintf("%s/%d.%s", j.Directory(), batch.ID, DefaultJournalSuffix)
+}
+
+// Writing batch into journal before it is applied
+func (j *Journal) Begin(batch *TJournalBatch) error {
+	directory := j.Directory()
+	if len(directory) == 0 {
+		return fmt.Errorf("journal directory is not set")
+	}
+	if err := os.MkdirAll(directory, 0755); err != nil {
+		return err
+	}
+
+	content, err := json.MarshalIndent(batch, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(j.filename(batch), content, 0644)
+}
+
+// Removing batch from journal as it is applied
+func (j *Journal) Commit(batch *TJournalBatch) error {
+	if err := os.Remove(j.filename(batch)); err != nil {
+		return err
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	j.committed++
+
+	return nil
+}
+
+// Reading batches pending in journal in order of ids
+func (j *Journal) Pending() ([]*TJournalBatch, error) {
+	directory := j.Directory()
+	if len(directory) == 0 {
+		return nil, nil
+	}
+
+	files, err := filepath.Glob(fmt.Sprintf("%s/*.%s", directory, DefaultJournalSuffix))
+	if err != nil {
+		return nil, err
+	}
+
+	var out []*TJournalBatch
+	for _, filename := range files {
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			return out, err
+		}
+		var batch TJournalBatch
+		if err = json.Unmarshal(content, &batch); err != nil {
+			return out, fmt.Errorf("error parsing batch:'%s', err:'%w'", filename, err)
+		}
+		out = append(out, &batch)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].ID < out[j].ID
+	})
+
+	return out, nil
+}
+
+// Applying batch mutations into maps: records of batch are
+// written (or previous records are restored on rollback),
+// keys with no record are removed
+func (j *Journal) Apply(obj *Objects, rrmaps map[uint16]offloader.RRMap,
+	batch *TJournalBatch, rollback bool) error {
+
+	for _, m := range batch.Mutations {
+		target, other := m.Record, m.Previous
+		if rollback {
+			target, other = m.Previous, m.Record
+		}
+
+		if len(target) > 0 {
+			rr, err := dns.NewRR(target)
+			if err != nil {
+				return fmt.Errorf("error parsing k:'%s' record:'%s', err:'%w'", m.Key, target, err)
+			}
+			if _, err = writeOwnedRR(obj, rrmaps, rr, false); err != nil {
+				return fmt.Errorf("error writing k:'%s', err:'%w'", m.Key, err)
+			}
+			continue
+		}
+
+		rr, err := dns.NewRR(other)
+		if err != nil {
+			return fmt.Errorf("error parsing k:'%s' record:'%s', err:'%w'", m.Key, other, err)
+		}
+		rrmap := rrmaps[rr.Header().Rrtype]
+		if obj.ExistsDNSRR(rrmap, rr) == NoExists {
+			continue
+		}
+		if err = obj.UpdateDNSRR(ObjectRemove, rrmap, rr, false); err != nil {
+			return fmt.Errorf("error removing k:'%s', err:'%w'", m.Key, err)
+		}
+	}
+
+	return nil
+}
+
+// Writing batch staged into maps via journal: batch is
+// flushed into journal, applied and committed
+func (j *Journal) Write(obj *Objects, rrmaps map[uint16]offloader.RRMap,
+	batch *TJournalBatch) error {
+
+	id := "(journal) (write)"
+
+	if len(batch.Build()) == 0 {
+		return nil
+	}
+
+	if err := j.Begin(batch); err != nil {
+		j.p.G().L.Errorf("%s error writing batch %s, err:'%s'", id, batch.AsString(), err)
+		return err
+	}
+
+	if err := j.Apply(obj, rrmaps, batch, false); err != nil {
+		// batch is kept in journal and recovered on startup
+		j.p.G().L.Errorf("%s error applying batch %s, err:'%s'", id, batch.AsString(), err)
+		return err
+	}
+
+	if err := j.Commit(batch); err != nil {
+		j.p.G().L.Errorf("%s error committing batch %s, err:'%s'", id, batch.AsString(), err)
+		return err
+	}
+
+	j.p.G().L.Debugf("%s batch %s committed", id, batch.AsString())
+
+	return nil
+}
+
+// Recovering unfinished batches found in journal w.r.t.
+// recovery policy, batches are recovered in order (reversed
+// order on rollback), batches failed are kept in journal
+func (j *Journal) recover(obj *Objects, rrmaps map[uint16]offloader.RRMap,
+	batches []*TJournalBatch, recovery string) (int, int) {
+
+	id := "(journal) (recover)"
+
+	rollback := recovery == JournalRollback
+	if rollback {
+		sort.Slice(batches, func(i, j int) bool {
+			return batches[i].ID > batches[j].ID
+		})
+	}
+
+	recovered := 0
+	failed := 0
+	for _, batch := range batches {
+		if err := j.Apply(obj, rrmaps, batch, rollback); err != nil {
+			j.p.G().L.Errorf("%s error recovering batch %s recovery:'%s', err:'%s'", id,
+				batch.AsString(), recovery, err)
+			failed++
+			continue
+		}
+		if err := os.Remove(j.filename(batch)); err != nil {
+			j.p.G().L.Errorf("%s error removing batch %s, err:'%s'", id, batch.AsString(), err)
+			failed++
+			continue
+		}
+		j.p.G().L.Infof("%s batch %s created:'%s' recovered as '%s'", id, batch.AsString(),
+			TimeAsString(batch.Created), recovery)
+		recovered++
+	}
+
+	return recovered, failed
+}
+
+// Recovering journal on startup before zones are synced
+func (j *Journal) Recover() error {
+	id := "(journal) (recover)"
+
+	if !j.Enabled() {
+		return nil
+	}
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	recovery, err := j.p.L().Cooker.Journal.GetRecovery()
+	if err != nil {
+		j.err = err
+		return err
+	}
+
+	batches, err := j.Pending()
+	if err != nil {
+		j.p.G().L.Errorf("%s error reading journal, err:'%s'", id, err)
+		j.err = err
+		return err
+	}
+	if len(batches) == 0 {
+		return nil
+	}
+
+	snapshot := &TSnapshotZone{p: j.p}
+	rrmaps, err := snapshot.LoadMaps()
+	if err != nil {
+		j.p.G().L.Errorf("%s error loading pinned maps, err:'%s'", id, err)
+		j.err = err
+		j.failed += len(batches)
+		return err
+	}
+	defer snapshot.UnloadMaps(rrmaps)
+
+	j.recovered, j.failed = j.recover(NewObjects(j.p), rrmaps, batches, recovery)
+
+	j.p.PushMetric(MetricJournalRecovered, nil, float64(j.recovered))
+	j.p.PushMetric(MetricJournalFailed, nil, float64(j.failed))
+
+	if j.failed > 0 {
+		j.err = fmt.Errorf("batches failed to recover:'%d'", j.failed)
+		return j.err
+	}
+
+	return nil
+}
+
+func (j *Journal) Status() *TJournalStatus {
+	var status TJournalStatus
+
+	status.Enabled = j.Enabled()
+	status.Recovery, _ = j.p.L().Cooker.Journal.GetRecovery()
+
+	batches, err := j.Pending()
+
+	j.lock.Lock()
+	defer j.lock.Unlock()
+
+	status.Recovered = j.recovered
+	status.Failed = j.failed
+	status.Committed = j.committed
+
+	status.Pending = []TJournalBatch{}
+	for _, batch := range batches {
+		status.Pending = append(status.Pending, *batch)
+	}
+
+	if j.err != nil {
+		status.Error = j.err.Error()
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+
+	return &status
+}
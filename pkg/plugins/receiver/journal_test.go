@@ -0,0 +1,279 @@
+package receiver
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/miekg/dns"
+
+	"github.com/yandex/yadns-controller/pkg/plugins/offloader"
+)
+
+// in-memory map of A records instead of bpf pinned one
+type testRRMapA struct {
+	entries map[offloader.RRQname]offloader.RREntryA
+}
+
+func (m *testRRMapA) MapName() string      { return "test_rr_a" }
+func (m *testRRMapA) LoadPinnedMap() error { return nil }
+func (m *testRRMapA) Close() error         { return nil }
+
+func (m *testRRMapA) Remove(qname offloader.RRQname, qtype uint16) error {
+	if _, ok := m.entries[qname]; !ok {
+		return fmt.Errorf("key not found")
+	}
+	delete(m.entries, qname)
+	return nil
+}
+
+func (m *testRRMapA) Create(qname offloader.RRQname, qtype uint16, ttl uint32, ip netip.Addr) error {
+	if _, ok := m.entries[qname]; ok {
+		return fmt.Errorf("key exists")
+	}
+	return m.Update(qname, qtype, ttl, ip)
+}
+
+func (m *testRRMapA) Update(qname offloader.RRQname, qtype uint16, ttl uint32, ip netip.Addr) error {
+	m.entries[qname] = offloader.RREntryA{RRKey: offloader.RRKey{Qtype: qtype, Qname: qname},
+		RRValueA: offloader.RRValueA{Addr: ip.As4(), TTL: ttl}}
+	return nil
+}
+
+func (m *testRRMapA) Lookup(qname offloader.RRQname, qtype uint16) (offloader.RREntry, error) {
+	e, ok := m.entries[qname]
+	if !ok {
+		return nil, fmt.Errorf("key not found")
+	}
+	return e, nil
+}
+
+func (m *testRRMapA) Entries() ([]offloader.RREntry, error) {
+	var out []offloader.RREntry
+	for _, e := range m.entries {
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func (m *testRRMapA) get(name string) string {
+	qname, _ := PackName(name)
+	e, ok := m.entries[qname]
+	if !ok {
+		return ""
+	}
+	return e.IP().String()
+}
+
+func TestJournal(t *testing.T) {
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+	p.c.Options.Snapshots.Directory = t.TempDir()
+	p.c.Cooker.Journal = TConfigJournal{Enabled: true}
+	p.zones = NewZonesState(p)
+
+	journal := p.Journal()
+
+	rrmap := &testRRMapA{entries: make(map[offloader.RRQname]offloader.RREntryA)}
+	rrmaps := map[uint16]offloader.RRMap{dns.TypeA: rrmap}
+
+	rr := func(raw string) dns.RR {
+		r, _ := dns.NewRR(raw)
+		return r
+	}
+
+	// maps state before batch
+	if err = NewObjects(p).UpdateDNSRR(ObjectCreate, rrmap, rr("a.example.net. 300 IN A 10.0.0.1"),
+		false); err != nil {
+		t.Error(fmt.Sprintf("Error creating record, err:'%s'", err))
+		return
+	}
+
+	stage := func() *TJournalBatch {
+		batch := NewJournalBatch("example.net", TransferModeIXFR)
+		obj := NewObjects(p)
+		obj.Stage(batch)
+		obj.UpdateDNSRR(ObjectRemove, rrmap, rr("a.example.net. 300 IN A 10.0.0.1"), false)
+		obj.UpdateDNSRR(ObjectCreate, rrmap, rr("a.example.net. 300 IN A 10.0.0.2"), false)
+		obj.UpdateDNSRR(ObjectCreate, rrmap, rr("b.example.net. 300 IN A 10.0.0.3"), false)
+		obj.UpdateDNSRR(ObjectCreate, rrmap, rr("c.example.net. 300 IN A 10.0.0.4"), false)
+		obj.UpdateDNSRR(ObjectRemove, rrmap, rr("c.example.net. 300 IN A 10.0.0.4"), false)
+		return batch
+	}
+
+	uuid := "1d2e3f4a-5b6c-4d7e-8f9a-0b1c2d3e4f5a"
+	batch := stage()
+	obj := NewObjects(p)
+	obj.Stage(batch)
+	exists := []int{
+		obj.ExistsDNSRR(rrmap, rr("a.example.net. 300 IN A 10.0.0.2")),
+		obj.ExistsDNSRR(rrmap, rr("a.example.net. 300 IN A 10.0.0.1")),
+		obj.ExistsDNSRR(rrmap, rr("c.example.net. 300 IN A 10.0.0.4")),
+	}
+	if exists[0] != ExistsEqual || exists[1] != ExistsNotEqual || exists[2] != NoExists {
+		t.Error("\nUUID", uuid, fmt.Sprintf("unexpected staged state:'%v'", exists))
+		return
+	}
+	mutations := batch.Build()
+	if len(mutations) != 2 || mutations[0].Previous != "a.example.net.\t300\tIN\tA\t10.0.0.1" ||
+		len(mutations[1].Previous) > 0 || rrmap.get("a.example.net") != "10.0.0.1" ||
+		len(rrmap.entries) != 1 {
+		t.Error("\nUUID", uuid, fmt.Sprintf("unexpected mutations:'%v' map:'%v'", mutations,
+			rrmap.entries))
+		return
+	}
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+
+	uuid = "2e3f4a5b-6c7d-4e8f-9a0b-1c2d3e4f5a6b"
+	if err = journal.Write(NewObjects(p), rrmaps, batch); err != nil {
+		t.Error("\nUUID", uuid, fmt.Sprintf("error writing batch, err:'%s'", err))
+		return
+	}
+	pending, err := journal.Pending()
+	if err != nil || len(pending) != 0 || rrmap.get("a.example.net") != "10.0.0.2" ||
+		rrmap.get("b.example.net") != "10.0.0.3" || journal.Status().Committed != 1 {
+		t.Error("\nUUID", uuid, fmt.Sprintf("unexpected journal:'%v' map:'%v' err:'%v'", pending,
+			rrmap.entries, err))
+		return
+	}
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+
+	type TTest struct {
+		uuid     string
+		enabled  bool
+		recovery string
+		expected map[string]string
+	}
+
+	tests := []TTest{
+		{
+			uuid:     "3f4a5b6c-7d8e-4f9a-8b1c-2d3e4f5a6b7c",
+			enabled:  true,
+			recovery: JournalRollback,
+			expected: map[string]string{"a.example.net": "10.0.0.1", "b.example.net": ""},
+		},
+		{
+			uuid:     "4a5b6c7d-8e9f-4a0b-9c2d-3e4f5a6b7c8d",
+			enabled:  true,
+			recovery: JournalReplay,
+			expected: map[string]string{"a.example.net": "10.0.0.2", "b.example.net": "10.0.0.3"},
+		},
+	}
+
+	for _, test := range tests {
+		if !test.enabled {
+			continue
+		}
+
+		// batch is applied partially as process is stopped
+		rrmap.entries = make(map[offloader.RRQname]offloader.RREntryA)
+		NewObjects(p).UpdateDNSRR(ObjectCreate, rrmap, rr("a.example.net. 300 IN A 10.0.0.1"), false)
+
+		batch := stage()
+		batch.Build()
+		if err = journal.Begin(batch); err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error writing batch, err:'%s'", err))
+			return
+		}
+		NewObjects(p).UpdateDNSRR(ObjectCreate, rrmap, rr("b.example.net. 300 IN A 10.0.0.3"), false)
+
+		pending, err := journal.Pending()
+		if err != nil || len(pending) != 1 {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("unexpected pending:'%v' err:'%v'", pending, err))
+			return
+		}
+
+		recovered, failed := journal.recover(NewObjects(p), rrmaps, pending, test.recovery)
+		pending, _ = journal.Pending()
+		if recovered != 1 || failed != 0 || len(pending) != 0 {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("unexpected recovered:'%d' failed:'%d' pending:'%d'",
+				recovered, failed, len(pending)))
+			return
+		}
+
+		for name, address := range test.expected {
+			if rrmap.get(name) != address {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("unexpected name:'%s' address:'%s' != '%s'",
+					name, rrmap.get(name), address))
+				return
+			}
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
+
+func TestJournalOwnership(t *testing.T) {
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+	p.c.Options.Snapshots.Directory = t.TempDir()
+	p.zones = NewZonesState(p)
+
+	// journal directory could not be created
+	broken := filepath.Join(t.TempDir(), "broken")
+	if err = os.WriteFile(broken, nil, 0644); err != nil {
+		t.Error(fmt.Sprintf("Error writing file, err:'%s'", err))
+		return
+	}
+
+	rrmap := &testRRMapA{entries: make(map[offloader.RRQname]offloader.RREntryA)}
+	rrmaps := map[uint16]offloader.RRMap{dns.TypeA: rrmap}
+
+	r, _ := dns.NewRR("a.example.net. 300 IN A 10.0.0.1")
+	key := RecordKey(r)
+
+	snapshot := &TSnapshotZone{p: p, zone: "example.net",
+		rrsets: map[string][]dns.RR{key: {r}}}
+
+	var sa TSnapshotActions
+	sa.actions = make(map[int]map[int]map[string][]dns.RR)
+	sa.Add(0, SectionAddition, key, r)
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		directory string
+
+		// expected error, record in map and claim
+		err     bool
+		address string
+		claimed bool
+	}
+
+	var Tests = []TTest{
+		{"8f9a0b1c-2d3e-4f4a-9b5c-6d7e8f9a0b1c", true, broken, true, "", false},
+		{"9a0b1c2d-3e4f-4a5b-8c6d-7e8f9a0b1c2d", true, "", false, "10.0.0.1", true},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		p.c.Cooker.Journal = TConfigJournal{Enabled: true, Directory: test.directory}
+
+		_, err := snapshot.writeMap(TransferModeIXFR, &sa, false, rrmaps)
+		if (err != nil) != test.err {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error expected:'%t' got:'%v'", test.err, err))
+			continue
+		}
+
+		// claims are committed only as batch is written
+		if rrmap.get("a.example.net") != test.address || p.Owners().Claimed(key) != test.claimed {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("map:'%v' claimed:'%t' expected address:'%s' claimed:'%t'",
+				rrmap.entries, p.Owners().Claimed(key), test.address, test.claimed))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
@@ -0,0 +1,131 @@
+package receiver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// file sources are parsed as rfc1035 master files, the
+// same files that BIND or NSD load: $ORIGIN, $TTL, relative
+// names, "@", $GENERATE and $INCLUDE (restricted to base
+// directory) are supported
+
+const (
+	// synthesized SOA settings for zone files
+	// without SOA record
+	DefaultSyntheticSOATTL     = 3600
+	DefaultSyntheticSOARefresh = 3600
+	DefaultSyntheticSOARetry   = 600
+	DefaultSyntheticSOAExpire  = 86400
+	DefaultSyntheticSOAMinTTL  = 300
+
+	// ttl for records if no ttl and $TTL set
+	DefaultMasterFileTTL = 3600
+)
+
+// Creating synthetic SOA record for zone with serial
+func NewSyntheticSOA(zone string, serial uint32) *dns.SOA {
+	origin := Dot(strings.ToLower(zone))
+	return &dns.SOA{
+		Hdr: dns.RR_Header{Name: origin, Rrtype: dns.TypeSOA, Class: dns.ClassINET,
+			Ttl: DefaultSyntheticSOATTL},
+		Ns:      fmt.Sprintf("localhost.%s", origin),
+		Mbox:    fmt.Sprintf("hostmaster.%s", origin),
+		Serial:  serial,
+		Refresh: DefaultSyntheticSOARefresh,
+		Retry:   DefaultSyntheticSOARetry,
+		Expire:  DefaultSyntheticSOAExpire,
+		Minttl:  DefaultSyntheticSOAMinTTL,
+	}
+}
+
+// Parsing master file of zone, relative names are relative
+// to zone origin, $INCLUDE is allowed only if base directory
+// is set and included files are resolved inside it, if zone
+// has no SOA it is synthesized with serial of file
+// modification time, records are returned as AXFR-like list
+// (SOA is the first and the last record)
+func ParseMasterFile(filename string, zone string, base string) ([]dns.RR, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	origin := "."
+	if len(zone) > 0 {
+		origin = Dot(strings.ToLower(zone))
+	}
+
+	// included files are resolved relative to the file
+	// being parsed inside base directory
+	name := filename
+	if len(base) > 0 {
+		name = "."
+		if rel, err := filepath.Rel(base, filename); err == nil && !strings.HasPrefix(rel, "..") {
+			name = filepath.ToSlash(rel)
+		}
+	}
+
+	zp := dns.NewZoneParser(f, origin, name)
+	zp.SetDefaultTTL(DefaultMasterFileTTL)
+	if len(base) > 0 {
+		zp.SetIncludeAllowed(true)
+		zp.SetIncludeFS(os.DirFS(base))
+	}
+
+	var soa dns.RR
+	var records []dns.RR
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		if rr.Header().Rrtype == dns.TypeSOA {
+			if soa == nil {
+				soa = rr
+			}
+			continue
+		}
+		records = append(records, rr)
+	}
+
+	if err := zp.Err(); err != nil {
+		return nil, fmt.Errorf("error parsing file:'%s', err:'%s'", filename, err)
+	}
+
+	if soa == nil {
+		if len(zone) == 0 {
+			return nil, fmt.Errorf("file:'%s' has no SOA and zone is not set", filename)
+		}
+		soa = NewSyntheticSOA(zone, uint32(info.ModTime().Unix()))
+	}
+
+	out := []dns.RR{soa}
+	out = append(out, records...)
+	out = append(out, soa)
+
+	return out, nil
+}
+
+func NewSnapshotZoneFromZoneFile(p *TReceiverPlugin, filename string,
+	zone string) (*TSnapshotZone, error) {
+	id := "(snapshot) (zone) (file)"
+
+	base := p.L().HTTPTransfer.IncludeDirectory
+
+	p.G().L.Debugf("%s parsing zone:'%s' file:'%s' include-directory:'%s'", id,
+		zone, filename, base)
+
+	rr, err := ParseMasterFile(filename, zone, base)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSnapshotZoneFromRR(p, rr, zone)
+}
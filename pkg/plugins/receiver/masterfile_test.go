@@ -0,0 +1,170 @@
+package receiver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestParseMasterFile(t *testing.T) {
+
+	dir := t.TempDir()
+	base := filepath.Join(dir, "zones")
+	if err := os.MkdirAll(filepath.Join(base, "includes"), 0755); err != nil {
+		t.Fatalf("error creating directory, err:'%s'", err)
+	}
+
+	files := map[string]string{
+		"zones/includes/hosts.inc": "host1 IN A 192.0.2.11\n",
+		"secret.inc":               "secret IN A 192.0.2.99\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("error writing file, err:'%s'", err)
+		}
+	}
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		zone    string
+		content string
+		base    string
+
+		// expected records (without SOA) or error
+		// contained substring
+		records []string
+		soa     bool
+		err     string
+	}
+
+	var Tests = []TTest{
+		{
+			"8e3f4a5b-6c7d-4e8f-9a0b-1c2d3e4f5a6b",
+			true,
+			"example.net",
+			`$TTL 300
+@       IN SOA ns1 hostmaster 2024010101 3600 600 86400 300
+        IN NS  ns1
+ns1     IN A    192.0.2.1
+www     IN AAAA 2001:db8::1
+$ORIGIN sub.example.net.
+api 60  IN A    192.0.2.2
+`,
+			"",
+			[]string{
+				"api.sub.example.net.\t60\tIN\tA\t192.0.2.2",
+				"example.net.\t300\tIN\tNS\tns1.example.net.",
+				"ns1.example.net.\t300\tIN\tA\t192.0.2.1",
+				"www.example.net.\t300\tIN\tAAAA\t2001:db8::1",
+			},
+			true, "",
+		},
+		{
+			// no SOA, SOA is synthesized
+			"9f4a5b6c-7d8e-4f9a-8b1c-2d3e4f5a6b7c",
+			true,
+			"example.net",
+			`$GENERATE 1-3 host$ 60 IN A 192.0.2.$`,
+			"",
+			[]string{
+				"host1.example.net.\t60\tIN\tA\t192.0.2.1",
+				"host2.example.net.\t60\tIN\tA\t192.0.2.2",
+				"host3.example.net.\t60\tIN\tA\t192.0.2.3",
+			},
+			false, "",
+		},
+		{
+			"0a5b6c7d-8e9f-4a0b-9c2d-3e4f5a6b7c8d",
+			true,
+			"example.net",
+			"$INCLUDE includes/hosts.inc\n",
+			base,
+			[]string{"host1.example.net.\t3600\tIN\tA\t192.0.2.11"},
+			false, "",
+		},
+		{
+			// include out of base directory
+			"1b6c7d8e-9f0a-4b1c-8d3e-4f5a6b7c8d9e",
+			true,
+			"example.net",
+			"$INCLUDE ../secret.inc\n",
+			base,
+			nil, false, "failed to open",
+		},
+		{
+			// include is not allowed without base directory
+			"2c7d8e9f-0a1b-4c2d-9e4f-5a6b7c8d9e0f",
+			true,
+			"example.net",
+			"$INCLUDE includes/hosts.inc\n",
+			"",
+			nil, false, "not allowed",
+		},
+		{
+			"3d8e9f0a-1b2c-4d3e-8f5a-6b7c8d9e0f1a",
+			true,
+			"example.net",
+			"www IN A 192.0.2.1\nbad IN A 192.0.2\n",
+			"",
+			nil, false, "line: 2",
+		},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		filename := filepath.Join(base, fmt.Sprintf("%s.zone", test.uuid))
+		if err := os.WriteFile(filename, []byte(test.content), 0644); err != nil {
+			t.Fatalf("error writing file, err:'%s'", err)
+		}
+
+		rr, err := ParseMasterFile(filename, test.zone, test.base)
+		if len(test.err) > 0 {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("error expected:'%s' got:'%v'", test.err, err))
+				continue
+			}
+			fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+			continue
+		}
+
+		if err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error parsing, err:'%s'", err))
+			continue
+		}
+
+		first, ok1 := rr[0].(*dns.SOA)
+		_, ok2 := rr[len(rr)-1].(*dns.SOA)
+		if !ok1 || !ok2 || first.Hdr.Name != Dot(test.zone) {
+			t.Error("\nUUID", test.uuid, "SOA is expected as the first and the last records")
+			continue
+		}
+		if test.soa != (first.Serial == 2024010101) {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("unexpected SOA serial:'%d'", first.Serial))
+			continue
+		}
+
+		var records []string
+		for _, r := range rr[1 : len(rr)-1] {
+			records = append(records, r.String())
+		}
+		sort.Strings(records)
+
+		if strings.Join(records, "\n") != strings.Join(test.records, "\n") {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("records expected:\n%s\ngot:\n%s",
+				strings.Join(test.records, "\n"), strings.Join(records, "\n")))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
@@ -1,6 +1,13 @@
 package receiver
 
 import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yandex/yadns-controller/pkg/plugins/metrics"
 	"github.com/yandex/yadns-controller/pkg/plugins/monitor"
 )
 
@@ -8,6 +15,16 @@ const (
 	// metric counter for example plugin
 	MetricNameCounter = "counter"
 
+	// primaries health metrics: primary is up (1) or
+	// down (0), total failures count and the last
+	// transfer latency in ms
+	MetricPrimaryUp       = "receiver-primary-up"
+	MetricPrimaryFailures = "receiver-primary-failures"
+	MetricPrimaryLatency  = "receiver-primary-latency"
+
+	// TSIG verification failures per zone and key
+	MetricTsigFailures = "receiver-tsig-failures"
+
 	// monitor class for check
 	MonitorClass = "receiver"
 )
@@ -16,4 +33,197 @@ func (t *TReceiverPlugin) Monitor(m *monitor.TMonitorPlugin) {
 
 	// all active and passive monitoring checks should be
 	// set here: controlling some timers
+	m.AddConfig(monitor.CheckConfig{ID: "yadns-receiver-primaries",
+		F: t.PrimariesMonitor})
+	m.AddConfig(monitor.CheckConfig{ID: MonitorQuarantine,
+		F: t.QuarantineMonitor})
+	m.AddConfig(monitor.CheckConfig{ID: MonitorJournal,
+		F: t.JournalMonitor})
+}
+
+// pushing receiver metric into metrics plugin (if any)
+func (t *TReceiverPlugin) PushMetric(name string, tags []string, value float64) {
+	if t.P() == nil {
+		return
+	}
+	m, ok := t.P().M().(*metrics.TMetricsPlugin)
+	if !ok || m == nil {
+		return
+	}
+
+	tags = append([]string{fmt.Sprintf("name=%s", name), "type=receiver"}, tags...)
+	m.Push(metrics.MetricsCounter, tags, value)
+}
+
+// primaries check is CRIT if some zone has all primaries
+// failed and WARN if some of zone primaries failed
+func (t *TReceiverPlugin) PrimariesMonitor(ctx context.Context,
+	m *monitor.TMonitorPlugin) (*monitor.Check, error) {
+
+	tid := "yadns-receiver-primaries"
+	id := fmt.Sprintf("(monitor) (%s)", tid)
+
+	if t.zones == nil {
+		check := &monitor.Check{
+			ID: tid, Class: MonitorClass,
+			Message: "zones state is not ready",
+			Code:    monitor.Ok,
+		}
+		return check, nil
+	}
+
+	var failed []string
+	var degraded []string
+
+	for zone, config := range t.zones.GetZonesConfigs() {
+		if !config.Enabled || len(config.Primary) == 0 {
+			continue
+		}
+
+		down := 0
+		for _, health := range t.zones.GetPrimariesHealth(zone, config.Primary) {
+			if !health.Healthy() {
+				down++
+			}
+		}
+
+		if down == len(config.Primary) {
+			failed = append(failed, zone)
+			continue
+		}
+		if down > 0 {
+			degraded = append(degraded, zone)
+		}
+	}
+
+	sort.Strings(failed)
+	sort.Strings(degraded)
+
+	t.G().L.Debugf("%s zones failed:['%s'] degraded:['%s']", id,
+		strings.Join(failed, ","), strings.Join(degraded, ","))
+
+	code := monitor.Ok
+	message := "all primaries are OK"
+
+	if len(degraded) > 0 {
+		code = monitor.Warn
+		message = fmt.Sprintf("zones with failed primaries:['%s']",
+			strings.Join(degraded, ","))
+	}
+
+	if len(failed) > 0 {
+		code = monitor.Crit
+		message = fmt.Sprintf("zones with all primaries failed:['%s']",
+			strings.Join(failed, ","))
+	}
+
+	check := &monitor.Check{
+		ID: tid, Class: MonitorClass,
+		Message: message,
+		Code:    code,
+	}
+
+	return check, nil
+}
+
+// quarantine check is CRIT if some zone has update
+// violating guards waiting for operator
+func (t *TReceiverPlugin) QuarantineMonitor(ctx context.Context,
+	m *monitor.TMonitorPlugin) (*monitor.Check, error) {
+
+	tid := MonitorQuarantine
+	id := fmt.Sprintf("(monitor) (%s)", tid)
+
+	if t.zones == nil {
+		check := &monitor.Check{
+			ID: tid, Class: MonitorClass,
+			Message: "zones state is not ready",
+			Code:    monitor.Ok,
+		}
+		return check, nil
+	}
+
+	var zones []string
+	for _, q := range t.zones.quarantine.List() {
+		zones = append(zones, fmt.Sprintf("%s:%d", q.Zone, q.Report.Serial))
+	}
+
+	t.G().L.Debugf("%s zones quarantined:['%s']", id, strings.Join(zones, ","))
+
+	code := monitor.Ok
+	message := "no zones updates quarantined"
+
+	if len(zones) > 0 {
+		code = monitor.Crit
+		message = fmt.Sprintf("zones updates quarantined:['%s']",
+			strings.Join(zones, ","))
+	}
+
+	check := &monitor.Check{
+		ID: tid, Class: MonitorClass,
+		Message: message,
+		Code:    code,
+	}
+
+	return check, nil
+}
+
+// journal check is CRIT if some batches failed to recover
+// or batch is pending too long (failed to apply), WARN if
+// unfinished batches are recovered on startup
+func (t *TReceiverPlugin) JournalMonitor(ctx context.Context,
+	m *monitor.TMonitorPlugin) (*monitor.Check, error) {
+
+	tid := MonitorJournal
+	id := fmt.Sprintf("(monitor) (%s)", tid)
+
+	journal := t.Journal()
+	if journal == nil || !journal.Enabled() {
+		check := &monitor.Check{
+			ID: tid, Class: MonitorClass,
+			Message: "journal is not enabled",
+			Code:    monitor.Ok,
+		}
+		return check, nil
+	}
+
+	status := journal.Status()
+
+	var stale []string
+	for _, batch := range status.Pending {
+		if time.Since(batch.Created) > DefaultJournalStale {
+			stale = append(stale, fmt.Sprintf("%s:%d", batch.Zone, batch.ID))
+		}
+	}
+
+	t.G().L.Debugf("%s pending:'%d' stale:['%s'] recovered:'%d' failed:'%d' committed:'%d'",
+		id, len(status.Pending), strings.Join(stale, ","), status.Recovered, status.Failed,
+		status.Committed)
+
+	t.PushMetric(MetricJournalPending, nil, float64(len(status.Pending)))
+
+	code := monitor.Ok
+	message := fmt.Sprintf("journal committed batches:'%d'", status.Committed)
+
+	switch {
+	case status.Failed > 0 || len(status.Error) > 0:
+		code = monitor.Crit
+		message = fmt.Sprintf("journal recovery failed batches:'%d', err:'%s'",
+			status.Failed, status.Error)
+	case len(stale) > 0:
+		code = monitor.Crit
+		message = fmt.Sprintf("journal batches pending:['%s']", strings.Join(stale, ","))
+	case status.Recovered > 0:
+		code = monitor.Warn
+		message = fmt.Sprintf("journal batches recovered on startup:'%d' as '%s'",
+			status.Recovered, status.Recovery)
+	}
+
+	check := &monitor.Check{
+		ID: tid, Class: MonitorClass,
+		Message: message,
+		Code:    code,
+	}
+
+	return check, nil
 }
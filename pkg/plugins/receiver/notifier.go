@@ -201,13 +201,9 @@ func (j *NotifierJob) execute(p *TReceiverPlugin, ctx context.Context,
 
 		zone := j.Zone
 
-		if _, ok := j.States.locks[zone]; !ok {
-			var lock sync.Mutex
-			j.States.locks[zone] = &lock
-		}
-
-		j.States.locks[zone].Lock()
-		defer j.States.locks[zone].Unlock()
+		lock := j.States.ZoneLock(zone)
+		lock.Lock()
+		defer lock.Unlock()
 
 		// checking if some snapshot exists in memory
 		snapshot := j.States.GetLastZoneSnapshot(zone)
@@ -219,6 +215,18 @@ func (j *NotifierJob) execute(p *TReceiverPlugin, ctx context.Context,
 			return result
 		}
 
+		// IXFR is applied to copy as update could be
+		// kept in quarantine
+		snapshot = snapshot.Clone()
+
+		if pin := j.States.GetPin(zone); pin != nil {
+			err := fmt.Errorf("%w: generation:'%d'", ErrZonePinned, pin.Generation)
+			p.G().L.Debugf("%s worker:'%d' zone:'%s' notify skipped, err:'%s'",
+				id, index, zone, err)
+			result.Error = err
+			return result
+		}
+
 		serial, err := snapshot.Serial()
 		if err != nil {
 			err := fmt.Errorf("zone:'%s' snapshot serial missed", zone)
@@ -257,33 +265,81 @@ func (j *NotifierJob) execute(p *TReceiverPlugin, ctx context.Context,
 			return result
 		}
 
-		// T.B.D. we use first primary server at least now
-		primary := conf.Primary[0]
-
 		opts := new(TransferOptions)
 		if j.Serial > 0 {
 			opts.Mode = TransferModeIXFR
 			opts.Serial = serial
 		}
-		if j.Serial == 0 {
+		if j.Serial == 0 || snapshot.soa == nil {
 			opts.Mode = TransferModeAXFR
 		}
 
-		opts.Ns = snapshot.soa.(*dns.SOA).Ns
+		if snapshot.soa != nil {
+			opts.Ns = snapshot.soa.(*dns.SOA).Ns
+
+			// OMG, mailbox, :)
+			opts.Mbox = snapshot.soa.(*dns.SOA).Mbox
+		}
+
+		// primaries are tried in preference order
+		// skipping ones in backoff
+		primaries := j.States.PrimariesOrder(zone, conf.Primary)
+		if len(primaries) == 0 {
+			err := fmt.Errorf("zone:'%s' all primaries ['%s'] are in backoff", zone,
+				strings.Join(conf.Primary, ","))
+			p.G().L.Errorf("%s worker:'%d' zone:'%s' could not be processed, err:'%s'",
+				id, index, zone, err)
+			result.Error = err
+			return result
+		}
+
+		var rr []dns.RR
+		for _, primary := range primaries {
+			server := j.States.Primary(primary)
+
+			p.G().L.Debugf("%s worker:'%d' requesting ixfr zone:'%s' serial:'%d' ns:'%s' mbox:'%s' via primary:'%s'",
+				id, index, zone, opts.Serial, opts.Ns, opts.Mbox, server)
 
-		// OMG, mailbox, :)
-		opts.Mbox = snapshot.soa.(*dns.SOA).Mbox
+			var keys []*TTsigKey
+			if keys, err = j.States.TsigKeys(zone, primary); err != nil {
+				p.G().L.Errorf("%s worker:'%d' zone:'%s' tsig keys error, err:'%s'",
+					id, index, zone, err)
+				break
+			}
+
+			if opts.TLS, err = j.States.TransferTLS(primary, server); err != nil {
+				p.G().L.Errorf("%s worker:'%d' zone:'%s' tls error, err:'%s'",
+					id, index, zone, err)
+				j.States.SetPrimaryStatus(zone, primary, server, 0, err)
+				continue
+			}
+
+			transfer := func(key *TTsigKey) error {
+				var err error
+				opts.Tsig = key
+				rr, err = TransferZone(server, zone, opts)
+				return err
+			}
+			failed := func(key *TTsigKey, err error) {
+				j.States.TsigFailure(zone, key, err)
+			}
 
-		p.G().L.Debugf("%s worker:'%d' requesting ixfr zone:'%s' serial:'%d' ns:'%s' mbox:'%s' via primary:'%s'",
-			id, index, zone, opts.Serial, opts.Ns, opts.Mbox, primary)
+			// do we need request SOA to ensure serial from notify?
+			// now we skip this step
+			t1 := time.Now()
+			_, err = WithTsigKeys(keys, transfer, failed)
+			j.States.SetPrimaryStatus(zone, primary, server, time.Since(t1), err)
+			j.States.SetTransferStatus(zone, err)
+			if err == nil {
+				break
+			}
+
+			p.G().L.Errorf("%s worker:'%d' error on notify processing zone:'%s' via primary:'%s', err:'%s'",
+				id, index, zone, server, err)
+		}
 
-		// do we need request SOA to ensure serial from notify?
-		// now we skip this step
-		rr, err := TransferZone(primary, zone, opts)
 		if err != nil {
 			result.Error = fmt.Errorf("error transferring zone:'%s', err:'%s'", zone, err)
-			p.G().L.Errorf("%s worker:'%d' error on notify processing zone:'%s', err:'%s'",
-				id, index, zone, err)
 			return result
 		}
 		p.G().L.Debugf("%s worker:'%d' transferred zone:'%s' as rrsets:'%d'", id, index, zone, len(rr))
@@ -311,6 +367,14 @@ func (j *NotifierJob) execute(p *TReceiverPlugin, ctx context.Context,
 		}
 		actions.Dump(p)
 
+		snapshot.soa = soa
+		if j.States.Quarantine(zone, conf, snapshot) {
+			p.G().L.Errorf("%s worker:'%d' zone:'%s' update not applied, err:'%s'",
+				id, index, zone, ErrZoneQuarantined)
+			result.Error = ErrZoneQuarantined
+			return result
+		}
+
 		// we have to create new zone state and replace by
 		// (taken from UpdateZoneState)
 		var state TZoneState
@@ -339,7 +403,8 @@ func (j *NotifierJob) execute(p *TReceiverPlugin, ctx context.Context,
 		state.Zone = zone
 		state.State = state.DetectState(p, snapshot)
 
-		j.States.zones[zone] = state
+		j.States.SetState(zone, state)
+		j.States.PushSkipMetrics(zone, snapshot)
 
 		p.G().L.Debugf("%s ixfr snapshot zone:'%s' updated rrsets:'%d'",
 			id, zone, len(snapshot.rrsets))
@@ -478,10 +543,12 @@ func (j *NotifierWorker) Worker(ctx context.Context, wg *sync.WaitGroup,
 		return
 	}
 
+	// TSIG signed requests (updates) are verified
+	// with keys loaded
 	server := &dns.Server{
 		PacketConn: p,
 		Net:        "udp",
-		TsigSecret: nil,
+		TsigSecret: j.zones.keyring.Secrets(),
 		ReusePort:  options.soreuseport,
 		UDPSize:    options.udpbuffer,
 	}
@@ -589,7 +656,7 @@ func (j *NotifierWorker) MatchRequest(r *dns.Msg) (*TMatchedRequest, error) {
 			soa := rr.(*dns.SOA)
 			name := RemoveDot(rr.Header().Name)
 
-			if _, ok := j.zones.zones[name]; !ok {
+			if _, ok := j.zones.GetState(name); !ok {
 				return nil, fmt.Errorf("not corrent notify")
 			}
 
@@ -607,7 +674,7 @@ func (j *NotifierWorker) MatchRequest(r *dns.Msg) (*TMatchedRequest, error) {
 			return nil, fmt.Errorf("not SOA request")
 		}
 		name := RemoveDot(rr.Name)
-		if _, ok := j.zones.zones[name]; !ok {
+		if _, ok := j.zones.GetState(name); !ok {
 			return nil, fmt.Errorf("not corrent notify")
 		}
 
@@ -624,6 +691,12 @@ func (j *NotifierWorker) MatchRequest(r *dns.Msg) (*TMatchedRequest, error) {
 func (j *NotifierWorker) Handle(w dns.ResponseWriter, r *dns.Msg) {
 	id := "(notifier) (handler)"
 
+	// dynamic zones updates are handled separately
+	if r.Opcode == dns.OpcodeUpdate {
+		j.HandleUpdate(w, r)
+		return
+	}
+
 	// need something to answer
 	m := new(dns.Msg)
 	m.SetReply(r)
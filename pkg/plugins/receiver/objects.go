@@ -1,9 +1,11 @@
 package receiver
 
 import (
+	"errors"
 	"fmt"
 	"net/netip"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -13,11 +15,68 @@ import (
 )
 
 type TObjectFilter struct {
-	// a filter for operations "clean", "list"
+	// a filter for operations "clean", "list": regexp
+	// matched to qname, ip address or network (cidr)
+	// matched to record data, all of them should match
 	Names []string
 
-	// max number of rr sets to return
-	Count int
+	// paging: number of rr sets to skip and max
+	// number of rr sets to return
+	Offset int
+	Count  int
+}
+
+var (
+	ErrObjectNotFound = errors.New("object not found")
+
+	ErrObjectFilter = errors.New("object filter not valid")
+
+	ErrObjectExists = errors.New("object exists with other data")
+)
+
+// Validating filter: each name should be ip address,
+// network or regexp
+func (t *TObjectFilter) Validate() error {
+	for _, f := range t.Names {
+		if _, _, err := ParseObjectFilter(f); err != nil {
+			return err
+		}
+	}
+	if t.Offset < 0 || t.Count < 0 {
+		return fmt.Errorf("%w: offset:'%d' count:'%d'", ErrObjectFilter, t.Offset, t.Count)
+	}
+	return nil
+}
+
+// Validating filter of clean: records listed (to be released
+// by owners) and records removed should be the same set, so
+// paging is not allowed
+func (t *TObjectFilter) ValidateClean() error {
+	if t.Offset != 0 || t.Count != 0 {
+		return fmt.Errorf("%w: paging offset:'%d' count:'%d' is not allowed on clean",
+			ErrObjectFilter, t.Offset, t.Count)
+	}
+	return t.Validate()
+}
+
+// Parsing filter as network (ip address is a network
+// with a single address) or as regexp
+func ParseObjectFilter(f string) (*netip.Prefix, *regexp.Regexp, error) {
+	if prefix, err := netip.ParsePrefix(f); err == nil {
+		prefix = prefix.Masked()
+		return &prefix, nil, nil
+	}
+
+	if ip, err := netip.ParseAddr(f); err == nil {
+		prefix := netip.PrefixFrom(ip, ip.BitLen())
+		return &prefix, nil, nil
+	}
+
+	re, err := regexp.Compile(f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: filter:'%s', err:'%s'", ErrObjectFilter, f, err)
+	}
+	return nil, re, nil
 }
 
 // objects implements logics to read, update, remove
@@ -30,6 +89,18 @@ type Objects struct {
 
 	// filter to list data
 	Filter TObjectFilter
+
+	// filters parsed
+	filters map[string]objectMatch
+
+	// journal batch staging mutations (if any)
+	stage *TJournalBatch
+}
+
+type objectMatch struct {
+	prefix *netip.Prefix
+	re     *regexp.Regexp
+	err    error
 }
 
 func NewObjects(p *TReceiverPlugin) *Objects {
@@ -48,14 +119,27 @@ func (o *Objects) MatchFilter(e offloader.RREntry, filter TObjectFilter) bool {
 	// of RR (ip4 or ip6) and regexp to the left
 
 	for _, f := range filter.Names {
-		// it could be network with ://, ip address
-		// or regexp
-		if strings.Contains(f, "::") {
-			// T.B.D network convering and matching
-			continue
+		// it could be network, ip address or regexp,
+		// filter not valid matches nothing
+		m, ok := o.filters[f]
+		if !ok {
+			if o.filters == nil {
+				o.filters = make(map[string]objectMatch)
+			}
+			m.prefix, m.re, m.err = ParseObjectFilter(f)
+			o.filters[f] = m
+		}
+		if m.err != nil {
+			return false
 		}
+		prefix, re := m.prefix, m.re
 
-		re := regexp.MustCompile(f)
+		if prefix != nil {
+			if !prefix.Contains(e.IP().Unmap()) {
+				return false
+			}
+			continue
+		}
 
 		name, err := UnpackName(e.Qname())
 		// not matching if unpack with error
@@ -67,9 +151,6 @@ func (o *Objects) MatchFilter(e offloader.RREntry, filter TObjectFilter) bool {
 		if !re.Match([]byte(name)) {
 			return false
 		}
-
-		// T.B.D all other cases
-
 	}
 	return true
 }
@@ -113,6 +194,31 @@ func (o *Objects) RemoveRR(raw string) error {
 	return o.UpdateRR(ObjectRemove, raw)
 }
 
+// Loading pinned map of records type, map should be
+// closed by caller
+func (o *Objects) RRMap(qtype uint16) (offloader.RRMap, error) {
+	id := "(objects) (map)"
+
+	var rrmap offloader.RRMap
+	switch qtype {
+	case dns.TypeA:
+		rrmap = &offloader.RRMapA{PinPath: o.p.L().PinPath}
+	case dns.TypeAAAA:
+		rrmap = &offloader.RRMapAAAA{PinPath: o.p.L().PinPath}
+	default:
+		return nil, fmt.Errorf("unexpected dns type:'%s' expected one of ['A,AAAA']",
+			dns.TypeToString[qtype])
+	}
+
+	if err := rrmap.LoadPinnedMap(); err != nil {
+		o.p.G().L.Errorf("%s error loading pinned map:'%s', err:'%s'", id, rrmap.MapName(), err)
+		return nil, err
+	}
+	o.p.G().L.Debugf("%s loaded pinned map:'%s':OK", id, rrmap.MapName())
+
+	return rrmap, nil
+}
+
 func (o *Objects) UpdateRR(mode int, raw string) error {
 	id := "(objects) (update) (rr)"
 	o.p.G().L.Debugf("%s request create raw:'%s'", id, raw)
@@ -128,33 +234,57 @@ func (o *Objects) UpdateRR(mode int, raw string) error {
 	qtype := rr.Header().Rrtype
 
 	switch qtype {
-	case dns.TypeA:
-		var rrmap offloader.RRMapA
-		rrmap.PinPath = o.p.L().PinPath
-		if err = rrmap.LoadPinnedMap(); err != nil {
-			o.p.G().L.Errorf("%s error loading pinned map:'%s', err:'%s'", id, rrmap.MapName(), err)
-			return err
-		}
-		defer rrmap.Close()
-		o.p.G().L.Debugf("%s loaded pinned map:'%s':OK", id, rrmap.MapName())
-
-		err = o.UpdateDNSRR(mode, &rrmap, rr, true)
-	case dns.TypeAAAA:
-		var rrmap offloader.RRMapAAAA
-		rrmap.PinPath = o.p.L().PinPath
-		if err = rrmap.LoadPinnedMap(); err != nil {
-			o.p.G().L.Errorf("%s error loading pinned map:'%s', err:'%s'", id, rrmap.MapName(), err)
+	case dns.TypeA, dns.TypeAAAA:
+		rrmap, err := o.RRMap(qtype)
+		if err != nil {
 			return err
 		}
 		defer rrmap.Close()
-		o.p.G().L.Debugf("%s loaded pinned map:'%s':OK", id, rrmap.MapName())
 
-		err = o.UpdateDNSRR(mode, &rrmap, rr, true)
+		return o.UpdateDNSRR(mode, rrmap, rr, true)
 	}
 
 	return err
 }
 
+// Getting record of name and type from map
+func (o *Objects) GetRR(name string, qtype uint16) (dns.RR, error) {
+	rrmap, err := o.RRMap(qtype)
+	if err != nil {
+		return nil, err
+	}
+	defer rrmap.Close()
+
+	qname, err := PackName(strings.ToLower(name))
+	if err != nil {
+		return nil, err
+	}
+
+	ttl, ip, err := o.LookupGenericRR(rrmap, qname, qtype)
+	if err != nil {
+		return nil, fmt.Errorf("%w: name:'%s' type:'%s'", ErrObjectNotFound, name,
+			dns.TypeToString[qtype])
+	}
+
+	raw := fmt.Sprintf("%s %d IN %s %s", Dot(strings.ToLower(name)), ttl,
+		dns.TypeToString[qtype], ip.String())
+	return dns.NewRR(raw)
+}
+
+// Removing record of name and type from map
+func (o *Objects) RemoveNameRR(name string, qtype uint16) error {
+	rr, err := o.GetRR(name, qtype)
+	if err != nil {
+		return err
+	}
+
+	if o.Dryrun {
+		return nil
+	}
+
+	return o.RemoveRR(rr.String())
+}
+
 type ConvertRR struct {
 	qname offloader.RRQname
 	qtype uint16
@@ -204,8 +334,53 @@ func (o *Objects) ConvertDNSRR(rr dns.RR) (*ConvertRR, error) {
 	return &conv, nil
 }
 
+// Staging mutations into journal batch instead of maps,
+// lookups see staged state over maps state
+func (o *Objects) Stage(batch *TJournalBatch) {
+	o.stage = batch
+}
+
+// Staging record of rr key, record found in maps is kept
+// as previous one as key is staged first time
+func (o *Objects) StageDNSRR(mode int, rrmap offloader.RRMap, rr dns.RR) error {
+	id := "(objects) (stage) (dns rr)"
+
+	key := RecordKey(rr)
+
+	var previous dns.RR
+	if _, staged := o.stage.Staged(key); !staged {
+		conv, err := o.ConvertDNSRR(rr)
+		if err != nil {
+			o.p.G().L.Errorf("%s error converting record, err:'%s'", id, err)
+			return err
+		}
+		if ttl, ip, err := o.LookupGenericRR(rrmap, conv.qname, conv.qtype); err == nil {
+			raw := fmt.Sprintf("%s %d IN %s %s", dns.Fqdn(rr.Header().Name), ttl,
+				dns.TypeToString[conv.qtype], ip.String())
+			if previous, err = dns.NewRR(raw); err != nil {
+				o.p.G().L.Errorf("%s error parsing raw:'%s', err:'%s'", id, raw, err)
+				return err
+			}
+		}
+	}
+
+	switch mode {
+	case ObjectCreate:
+		o.stage.Stage(key, dns.Copy(rr), previous)
+	case ObjectRemove:
+		o.stage.Stage(key, nil, previous)
+	}
+
+	return nil
+}
+
 func (o *Objects) UpdateDNSRR(mode int, rrmap offloader.RRMap, rr dns.RR, dump bool) error {
 	id := "(objects) (update) (dns rr)"
+
+	if o.stage != nil {
+		return o.StageDNSRR(mode, rrmap, rr)
+	}
+
 	conv, err := o.ConvertDNSRR(rr)
 	if err != nil {
 		o.p.G().L.Errorf("%s error converting record, err:'%s'", id, err)
@@ -283,6 +458,19 @@ func (o *Objects) LookupDNSRR(rrmap offloader.RRMap, rr dns.RR) (*ConvertRR, uin
 		return nil, 0, netip.Addr{}, err
 	}
 
+	if o.stage != nil {
+		if staged, ok := o.stage.Staged(RecordKey(rr)); ok {
+			if staged == nil {
+				return conv, 0, netip.Addr{}, ErrJournalStagedRemoved
+			}
+			sconv, err := o.ConvertDNSRR(staged)
+			if err != nil {
+				return conv, 0, netip.Addr{}, err
+			}
+			return conv, sconv.ttl, sconv.ip, nil
+		}
+	}
+
 	ttl, ip, err := o.LookupGenericRR(rrmap, conv.qname, conv.qtype)
 	return conv, ttl, ip, err
 }
@@ -327,6 +515,14 @@ func (o *Objects) UpdateGenericRR(mode int, rrmap offloader.RRMap, conv *Convert
 
 // we listing all supported types, A, AAAA for now
 func (o *Objects) ListRR() ([]dns.RR, error) {
+	rrs, _, err := o.ListPageRR()
+	return rrs, err
+}
+
+// Listing records matched filter ordered by name and
+// type, page of records is returned (if offset or count
+// set) and total number of records matched
+func (o *Objects) ListPageRR() ([]dns.RR, int, error) {
 
 	id := "(objects) (list) (rr)"
 	o.p.G().L.Debugf("%s request listing", id)
@@ -339,7 +535,7 @@ func (o *Objects) ListRR() ([]dns.RR, error) {
 
 	var outA []dns.RR
 	if outA, _, err = o.IterateGenericMapRR(ObjectList, &rrmapA); err != nil {
-		return outA, err
+		return outA, 0, err
 	}
 
 	var rrmapAAAA offloader.RRMapAAAA
@@ -347,13 +543,35 @@ func (o *Objects) ListRR() ([]dns.RR, error) {
 
 	var outAAAA []dns.RR
 	if outAAAA, _, err = o.IterateGenericMapRR(ObjectList, &rrmapAAAA); err != nil {
-		return outAAAA, err
+		return outAAAA, 0, err
 	}
 
 	o.p.G().L.Debugf("%s finished in '%s'", id, time.Since(t0))
 
 	outA = append(outA, outAAAA...)
-	return outA, err
+
+	// map entries are iterated in hash order, sorting
+	// records to make pages stable
+	sort.Slice(outA, func(i, j int) bool {
+		hi, hj := outA[i].Header(), outA[j].Header()
+		if hi.Name != hj.Name {
+			return hi.Name < hj.Name
+		}
+		return hi.Rrtype < hj.Rrtype
+	})
+
+	total := len(outA)
+	if o.Filter.Offset > 0 {
+		if o.Filter.Offset >= len(outA) {
+			return []dns.RR{}, total, err
+		}
+		outA = outA[o.Filter.Offset:]
+	}
+	if o.Filter.Count > 0 && len(outA) > o.Filter.Count {
+		outA = outA[:o.Filter.Count]
+	}
+
+	return outA, total, err
 }
 
 func (o *Objects) IterateGenericMapRR(mode int, rrmap offloader.RRMap) ([]dns.RR, int, error) {
@@ -381,15 +599,13 @@ func (o *Objects) IterateGenericMapRR(mode int, rrmap offloader.RRMap) ([]dns.RR
 	o.p.G().L.Debugf("%s loaded from bpf map:'%s' count:'%d", id, rrmap.MapName(), len(entries))
 
 	max := 5
+	matched := 0
 	for i, e := range entries {
 
-		if o.Filter.Count > 0 && i >= o.Filter.Count {
-			continue
-		}
-
 		if !o.MatchFilter(e, o.Filter) {
 			continue
 		}
+		matched++
 
 		if i < max {
 			o.p.G().L.Debugf("%s [%d]/[%d] %s", id, i, len(entries),
@@ -398,23 +614,24 @@ func (o *Objects) IterateGenericMapRR(mode int, rrmap offloader.RRMap) ([]dns.RR
 
 		switch mode {
 		case ObjectList:
-			qname, err := UnpackName(e.Qname())
-			if err != nil {
-				o.p.G().L.Errorf("%s error unpacking data %s", id, e.AsRawString())
-				return out, 0, err
-			}
-
-			raw := fmt.Sprintf("%s %d IN %s %s", Dot(qname), e.QTTL(),
-				dns.TypeToString[e.Qtype()], e.Qdata())
-			rr, err := dns.NewRR(raw)
+			rr, err := o.EntryRR(e)
 			if err != nil {
-				o.p.G().L.Errorf("%s error parsing raw:'%s', err:'%s'", id, raw, err)
 				return out, 0, err
 			}
 			out = append(out, rr)
 		case ObjectClean:
 			if o.Dryrun {
-				o.p.G().L.Errorf("%s error on clean as dry-run set", id)
+				continue
+			}
+			// removal is staged into journal batch (if any)
+			if o.stage != nil {
+				rr, err := o.EntryRR(e)
+				if err != nil {
+					return out, 0, err
+				}
+				if err = o.StageDNSRR(ObjectRemove, rrmap, rr); err != nil {
+					return out, 0, err
+				}
 				continue
 			}
 			if err = rrmap.Remove(e.Qname(), e.Qtype()); err != nil {
@@ -424,9 +641,30 @@ func (o *Objects) IterateGenericMapRR(mode int, rrmap offloader.RRMap) ([]dns.RR
 		}
 	}
 
-	o.p.G().L.Debugf("%s recevied entries:'%d'", id, len(entries))
+	o.p.G().L.Debugf("%s recevied entries:'%d' matched:'%d' dryrun:'%t'", id, len(entries),
+		matched, o.Dryrun)
 
-	return out, len(entries), err
+	return out, matched, err
+}
+
+// Making record of map entry
+func (o *Objects) EntryRR(e offloader.RREntry) (dns.RR, error) {
+	id := "(objects) (entry) (rr)"
+
+	qname, err := UnpackName(e.Qname())
+	if err != nil {
+		o.p.G().L.Errorf("%s error unpacking data %s", id, e.AsRawString())
+		return nil, err
+	}
+
+	raw := fmt.Sprintf("%s %d IN %s %s", Dot(qname), e.QTTL(),
+		dns.TypeToString[e.Qtype()], e.Qdata())
+	rr, err := dns.NewRR(raw)
+	if err != nil {
+		o.p.G().L.Errorf("%s error parsing raw:'%s', err:'%s'", id, raw, err)
+		return nil, err
+	}
+	return rr, nil
 }
 
 func (o *Objects) CleanRR() (int, error) {
@@ -542,16 +780,92 @@ func UnpackName(pqname offloader.RRQname) (string, error) {
 	return string(qname), nil
 }
 
-func (o *Objects) ImportRR() error {
+type TObjectsResult struct {
+	Created int `json:"created"`
+	Removed int `json:"removed"`
+
+	// records not placed into maps (unsupported types)
+	// and records already in maps with the same data
+	Skipped int `json:"skipped"`
+	Existed int `json:"existed"`
+
+	Dryrun bool `json:"dryrun"`
+}
+
+func (t *TObjectsResult) AsString() string {
+	var out []string
+
+	out = append(out, fmt.Sprintf("created:'%d'", t.Created))
+	out = append(out, fmt.Sprintf("removed:'%d'", t.Removed))
+	out = append(out, fmt.Sprintf("skipped:'%d'", t.Skipped))
+	out = append(out, fmt.Sprintf("existed:'%d'", t.Existed))
+	out = append(out, fmt.Sprintf("dryrun:'%t'", t.Dryrun))
+
+	return strings.Join(out, ",")
+}
+
+// Importing records into maps, records of types not
+// supported by maps are skipped, records existing with
+// other data are conflicts, on dry-run records are only
+// validated
+func (o *Objects) ImportRR(rrs []dns.RR) (*TObjectsResult, error) {
 
 	id := "(objects) (import) (rr)"
-	o.p.G().L.Debugf("%s request to import RR", id)
+	o.p.G().L.Debugf("%s request to import RR count:'%d' dryrun:'%t'", id, len(rrs), o.Dryrun)
 
 	t0 := time.Now()
 
-	// T.B.D.
+	var result TObjectsResult
+	result.Dryrun = o.Dryrun
 
-	o.p.G().L.Debugf("%s finished in '%s'", id, time.Since(t0))
+	rrmaps := make(map[uint16]offloader.RRMap)
+	defer func() {
+		for _, rrmap := range rrmaps {
+			rrmap.Close()
+		}
+	}()
 
-	return nil
+	for _, rr := range rrs {
+		qtype := rr.Header().Rrtype
+		if qtype != dns.TypeA && qtype != dns.TypeAAAA {
+			result.Skipped++
+			continue
+		}
+
+		if _, err := o.ConvertDNSRR(rr); err != nil {
+			o.p.G().L.Errorf("%s error converting rr:'%s', err:'%s'", id, rr.String(), err)
+			return &result, err
+		}
+
+		rrmap, ok := rrmaps[qtype]
+		if !ok {
+			var err error
+			if rrmap, err = o.RRMap(qtype); err != nil {
+				return &result, err
+			}
+			rrmaps[qtype] = rrmap
+		}
+
+		switch o.ExistsDNSRR(rrmap, rr) {
+		case ExistsEqual:
+			result.Existed++
+			continue
+		case ExistsNotEqual:
+			return &result, fmt.Errorf("%w: rr:'%s'", ErrObjectExists, rr.String())
+		}
+
+		if o.Dryrun {
+			result.Created++
+			continue
+		}
+
+		if err := o.UpdateDNSRR(ObjectCreate, rrmap, rr, false); err != nil {
+			return &result, err
+		}
+		result.Created++
+	}
+
+	o.p.G().L.Debugf("%s finished in '%s' %s", id, time.Since(t0), result.AsString())
+
+	return &result, nil
 }
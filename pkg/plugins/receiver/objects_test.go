@@ -1,9 +1,14 @@
 package receiver
 
 import (
+	"errors"
 	"fmt"
+	"net/netip"
+	"strings"
 	"testing"
 
+	"github.com/miekg/dns"
+
 	"github.com/yandex/yadns-controller/pkg/plugins/offloader"
 )
 
@@ -205,3 +210,114 @@ func TestPackName(t *testing.T) {
 	}
 
 }
+
+func TestMatchFilter(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+
+	entry := func(name string, address string) offloader.RREntry {
+		qname, _ := PackName(name)
+		ip := netip.MustParseAddr(address)
+		if ip.Is4() {
+			return offloader.RREntryA{RRKey: offloader.RRKey{Qtype: dns.TypeA, Qname: qname},
+				RRValueA: offloader.RRValueA{Addr: ip.As4(), TTL: 300}}
+		}
+		return offloader.RREntryAAAA{RRKey: offloader.RRKey{Qtype: dns.TypeAAAA, Qname: qname},
+			RRValueAAAA: offloader.RRValueAAAA{Addr: ip.As16(), TTL: 300}}
+	}
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		name    string
+		address string
+		filter  []string
+
+		matched bool
+	}
+
+	var Tests = []TTest{
+		{"4a0e1b2c-5d6f-4a7b-8c9d-0e1f2a3b4c5d", true, "www.example.net", "192.0.2.1",
+			nil, true},
+		{"5b1f2c3d-6e7a-4b8c-9d0e-1f2a3b4c5d6e", true, "www.example.net", "192.0.2.1",
+			[]string{"^www\\."}, true},
+		{"6c2a3d4e-7f8b-4c9d-8e0f-2a3b4c5d6e7f", true, "mail.example.net", "192.0.2.1",
+			[]string{"^www\\."}, false},
+		{"7d3b4e5f-8a9c-4d0e-9f1a-3b4c5d6e7f8a", true, "www.example.net", "192.0.2.1",
+			[]string{"192.0.2.0/24"}, true},
+		{"8e4c5f6a-9b0d-4e1f-8a2b-4c5d6e7f8a9b", true, "www.example.net", "198.51.100.1",
+			[]string{"192.0.2.0/24"}, false},
+		{"9f5d6a7b-0c1e-4f2a-9b3c-5d6e7f8a9b0c", true, "www.example.net", "192.0.2.1",
+			[]string{"192.0.2.1"}, true},
+		{"0a6e7b8c-1d2f-4a3b-8c4d-6e7f8a9b0c1d", true, "www.example.net", "192.0.2.1",
+			[]string{"192.0.2.2"}, false},
+		{"1b7f8c9d-2e3a-4b4c-9d5e-7f8a9b0c1d2e", true, "www.example.net", "2001:db8::1",
+			[]string{"2001:db8::/32", "example"}, true},
+		{"2c8a9d0e-3f4b-4c5d-8e6f-8a9b0c1d2e3f", true, "www.example.net", "2001:db8::1",
+			[]string{"192.0.2.0/24"}, false},
+		{"3d9b0e1f-4a5c-4d6e-9f7a-9b0c1d2e3f4a", true, "www.example.net", "2001:db8::1",
+			[]string{"2001:db8::/32", "^mail"}, false},
+		{"4e0c1f2a-5b6d-4e7f-8a8b-0c1d2e3f4a5b", true, "www.example.net", "192.0.2.1",
+			[]string{"("}, false},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		obj := NewObjects(p)
+		filter := TObjectFilter{Names: test.filter}
+
+		matched := obj.MatchFilter(entry(test.name, test.address), filter)
+		if matched != test.matched {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("name:'%s' address:'%s' filter:['%s'] expected:'%t' got:'%t'",
+				test.name, test.address, strings.Join(test.filter, ","), test.matched, matched))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+
+	filter := TObjectFilter{Names: []string{"("}}
+	if err := filter.Validate(); !errors.Is(err, ErrObjectFilter) {
+		t.Errorf("filter not valid expected error, got:'%v'", err)
+	}
+}
+
+func TestValidateClean(t *testing.T) {
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		filter TObjectFilter
+		err    bool
+	}
+
+	var Tests = []TTest{
+		{"4b5c6d7e-8f9a-4b0c-9d1e-3f4a5b6c7d8e", true, TObjectFilter{Names: []string{`\.example\.net$`}}, false},
+		{"5c6d7e8f-9a0b-4c1d-8e2f-4a5b6c7d8e9f", true, TObjectFilter{Names: []string{"192.0.2.0/24"}, Count: 10}, true},
+		{"6d7e8f9a-0b1c-4d2e-9f3a-5b6c7d8e9f0a", true, TObjectFilter{Offset: 5}, true},
+		{"7e8f9a0b-1c2d-4e3f-8a4b-6c7d8e9f0a1b", true, TObjectFilter{Names: []string{"("}}, true},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		err := test.filter.ValidateClean()
+		if (err != nil) != test.err || (err != nil && !errors.Is(err, ErrObjectFilter)) {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error expected:'%t' got:'%v'", test.err, err))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
@@ -0,0 +1,319 @@
+package receiver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// one-shot commands run without server: "fetch" transfers
+// zone into snapshot, "cook" syncs snapshots into pinned
+// maps and "import" syncs zone file into pinned maps. Zone
+// records are synced as IXFR against records of zone found
+// in maps, so other zones data is kept untouched
+
+const (
+	OneshotFetch  = "fetch"
+	OneshotCook   = "cook"
+	OneshotImport = "import"
+)
+
+type TOneshotZone struct {
+	Zone   string `json:"zone"`
+	Serial uint32 `json:"serial"`
+
+	// number of records in snapshot and snapshot
+	// file read or written
+	Records  int    `json:"records"`
+	Snapshot string `json:"snapshot,omitempty"`
+
+	// records synced into maps
+	Created int `json:"created"`
+	Removed int `json:"removed"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// summary of one-shot command printed for scripting
+type TOneshotResult struct {
+	Command string `json:"command"`
+	Dryrun  bool   `json:"dryrun"`
+
+	Zones []TOneshotZone `json:"zones"`
+
+	// elapsed time in seconds
+	Elapsed float64 `json:"elapsed"`
+
+	// failed zones count
+	Failed int `json:"failed"`
+}
+
+func (t *TOneshotResult) AsJSON() []byte {
+	body, _ := json.MarshalIndent(t, "", "  ")
+	return body
+}
+
+// Adding zone result, err (if any) is kept as error
+// of zone
+func (t *TOneshotResult) Add(zone *TOneshotZone, err error) {
+	if err != nil {
+		zone.Error = err.Error()
+		t.Failed++
+	}
+	t.Zones = append(t.Zones, *zone)
+}
+
+// Getting zones state for one-shot commands: overlay,
+// overrides and pins are loaded and journal is recovered
+// as on server start
+func (t *TReceiverPlugin) OneshotZones() *ZonesState {
+	id := "(oneshot) (zones)"
+
+	if t.zones != nil {
+		return t.zones
+	}
+
+	t.zones = NewZonesState(t)
+	if err := t.zones.LoadOverlay(); err != nil {
+		t.G().L.Errorf("%s error loading zones overlay, err:'%s'", id, err)
+	}
+	if err := t.zones.overrides.Load(); err != nil {
+		t.G().L.Errorf("%s error loading overrides, err:'%s'", id, err)
+	}
+	if err := t.zones.pins.Load(); err != nil {
+		t.G().L.Errorf("%s error loading pins, err:'%s'", id, err)
+	}
+	if err := t.zones.owners.Load(); err != nil {
+		t.G().L.Errorf("%s error loading owners, err:'%s'", id, err)
+	}
+	if err := t.zones.journal.Recover(); err != nil {
+		t.G().L.Errorf("%s error recovering journal, err:'%s'", id, err)
+	}
+
+	return t.zones
+}
+
+func newOneshotZone(snapshot *TSnapshotZone) *TOneshotZone {
+	var out TOneshotZone
+	out.Zone = snapshot.zone
+	out.Serial, _ = snapshot.Serial()
+	for _, rrset := range snapshot.rrsets {
+		out.Records += len(rrset)
+	}
+	return &out
+}
+
+// Syncing zone snapshot into maps: actions are detected
+// as diff between records of zone in maps and snapshot
+func (z *ZonesState) SyncZoneSnapshot(ctx context.Context, zone string,
+	snapshot *TSnapshotZone, dryrun bool) (*TSyncMapResult, error) {
+
+	id := "(oneshot) (sync)"
+
+	// zone rolled back is not updated till unpinned
+	if pin := z.GetPin(zone); pin != nil {
+		return nil, fmt.Errorf("%w: zone:'%s' generation:'%d'", ErrZonePinned, zone,
+			pin.Generation)
+	}
+
+	lock := z.ZoneLock(zone)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// maps keep records of all zones (and overrides),
+	// records of sub-zones are not changed by zone
+	rrs, err := NewObjects(z.p).ListRR()
+	if err != nil {
+		z.p.G().L.Errorf("%s error reading maps zone:'%s', err:'%s'", id, zone, err)
+		return nil, err
+	}
+	current, err := NewSnapshotZoneFromRR(z.p, z.ZoneRecords(zone, rrs), zone)
+	if err != nil {
+		return nil, err
+	}
+
+	var state TZoneState
+	changed, err := state.DetectChangedState(current, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	state.Zone = zone
+	state.Config, _ = z.GetConfig(zone)
+	state.SnapshotCount = DefaultSnapshotCount
+	state.Snapshots = make(map[int]TSnapshotZone)
+
+	snapshot.imports = &TImportActions{
+		mode:    TransferModeIXFR,
+		zone:    zone,
+		actions: changed.AsActions(),
+	}
+
+	state.SnapshotID = 0
+	state.Snapshots[state.SnapshotID] = *snapshot
+	state.State = state.DetectState(z.p, snapshot)
+	z.SetState(zone, state)
+
+	options := TConfigCooker{Dryrun: dryrun}
+	cooker, _ := NewCookerWorker(z.p, &options, z)
+
+	r, err := cooker.CookIncrementZone(ctx, zone, CookerNoLock)
+	if err != nil {
+		z.p.G().L.Errorf("%s error cooking zone:'%s', err:'%s'", id, zone, err)
+		return nil, err
+	}
+
+	z.p.G().L.Debugf("%s zone:'%s' synced %s dryrun:'%t'", id, zone, r.AsString(), dryrun)
+
+	return r, nil
+}
+
+// Transferring zone via primary (ad-hoc or zone primaries
+// in order) into snapshot file, snapshot is written into
+// snapshots directory if output is not set
+func (t *TReceiverPlugin) FetchZone(ctx context.Context, zone string, primary string,
+	output string, dryrun bool) (*TOneshotZone, error) {
+
+	id := "(oneshot) (fetch)"
+	z := t.OneshotZones()
+
+	primaries := []string{primary}
+	if len(primary) == 0 {
+		config, err := z.GetConfig(zone)
+		if err != nil {
+			return &TOneshotZone{Zone: zone}, fmt.Errorf("zone:'%s', err:'%w'", zone,
+				ErrZoneNotFound)
+		}
+		primaries = config.Primary
+	}
+
+	var err error
+	var snapshot *TSnapshotZone
+	for _, primary := range primaries {
+		server := z.Primary(primary)
+
+		var keys []*TTsigKey
+		if keys, err = z.TsigKeys(zone, primary); err != nil {
+			break
+		}
+
+		var options TZoneSnapshotOptions
+		options.Server = server
+		options.Tsig = keys
+		if options.TLS, err = z.TransferTLS(primary, server); err != nil {
+			t.G().L.Errorf("%s zone:'%s' primary:'%s' tls error, err:'%s'", id, zone,
+				server, err)
+			continue
+		}
+
+		var config TConfigImporter
+		config.Zone = append(config.Zone, zone)
+		config.Server = server
+
+		importer, _ := NewImporterWorker(t, &config)
+		if snapshot, err = importer.GetZoneSnapshotAXFR(zone, &options); err == nil {
+			break
+		}
+
+		t.G().L.Errorf("%s error transferring zone:'%s' via primary:'%s', err:'%s'", id,
+			zone, server, err)
+	}
+
+	if snapshot == nil {
+		if err == nil {
+			err = fmt.Errorf("zone:'%s' has no primaries", zone)
+		}
+		return &TOneshotZone{Zone: zone}, err
+	}
+
+	out := newOneshotZone(snapshot)
+	out.Snapshot = output
+	if len(output) == 0 {
+		out.Snapshot = GetSnapshotFilename(t, zone)
+	}
+
+	t.G().L.Debugf("%s zone:'%s' serial:'%d' records:'%d' snapshot:'%s' dryrun:'%t'", id,
+		zone, out.Serial, out.Records, out.Snapshot, dryrun)
+
+	if dryrun {
+		return out, nil
+	}
+
+	if len(output) == 0 {
+		return out, snapshot.WriteSnapshotZone(false)
+	}
+
+	content, err := snapshot.Container()
+	if err != nil {
+		return out, err
+	}
+	return out, WriteFileAtomic(output, content, 0644)
+}
+
+// Cooking zone snapshot file into maps, snapshot is read
+// from snapshots directory if file is not set
+func (t *TReceiverPlugin) CookZone(ctx context.Context, zone string, filename string,
+	dryrun bool) (*TOneshotZone, error) {
+
+	z := t.OneshotZones()
+
+	if len(filename) == 0 {
+		filename = GetSnapshotFilename(t, zone)
+	}
+
+	snapshot, err := ReadSnapshotFile(t, filename, zone)
+	if err != nil {
+		return &TOneshotZone{Zone: zone, Snapshot: filename}, err
+	}
+
+	out := newOneshotZone(snapshot)
+	out.Snapshot = filename
+
+	r, err := z.SyncZoneSnapshot(ctx, zone, snapshot, dryrun)
+	if err != nil {
+		return out, err
+	}
+	out.Created = r.Created
+	out.Removed = r.Removed
+
+	return out, nil
+}
+
+// Importing zone file into maps, snapshot of zone is
+// written into snapshots directory
+func (t *TReceiverPlugin) ImportZoneFile(ctx context.Context, zone string, filename string,
+	dryrun bool) (*TOneshotZone, error) {
+
+	z := t.OneshotZones()
+
+	snapshot, err := NewSnapshotZoneFromZoneFile(t, filename, zone)
+	if err != nil {
+		return &TOneshotZone{Zone: zone}, err
+	}
+
+	out := newOneshotZone(snapshot)
+	out.Snapshot = GetSnapshotFilename(t, zone)
+
+	r, err := z.SyncZoneSnapshot(ctx, zone, snapshot, dryrun)
+	if err != nil {
+		return out, err
+	}
+	out.Created = r.Created
+	out.Removed = r.Removed
+
+	return out, snapshot.WriteSnapshotZone(dryrun)
+}
+
+// zones of snapshots in snapshots directory, zones
+// configured are checked only
+func (t *TReceiverPlugin) SnapshotZones() []string {
+	var out []string
+	for zone := range t.OneshotZones().GetZonesConfigs() {
+		if Exists(GetSnapshotFilename(t, zone)) {
+			out = append(out, zone)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
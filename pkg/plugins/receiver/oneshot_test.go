@@ -0,0 +1,88 @@
+package receiver
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestOneshotCook(t *testing.T) {
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		zone   string
+		file   string
+		failed int
+	}
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+	p.c.Options.Snapshots.Directory = t.TempDir()
+	p.zones = NewZonesState(p)
+
+	var Tests = []TTest{
+		{"4a5b6c7d-8e9f-4a0b-9c1d-2e3f4a5b6c7d", true, "example.net", "", 1},
+		{"5b6c7d8e-9f0a-4b1c-8d2e-3f4a5b6c7d8e", true, "example.net",
+			filepath.Join(t.TempDir(), "missing.blob"), 1},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		result := TOneshotResult{Command: OneshotCook, Dryrun: true}
+		result.Add(p.CookZone(context.Background(), test.zone, test.file, true))
+
+		if result.Failed != test.failed || len(result.Zones) != 1 ||
+			result.Zones[0].Zone != test.zone || len(result.Zones[0].Error) == 0 {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("unexpected result:'%s'",
+				result.AsJSON()))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
+
+func TestZoneRecords(t *testing.T) {
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+	p.c.Options.Snapshots.Directory = t.TempDir()
+	p.c.AxfrTransfer.Enabled = true
+	p.c.AxfrTransfer.Zones.Secondary = map[string]TConfigZone{
+		"example.com":     CreateDefaultConfigZone([]string{"[::1]:53"}),
+		"sub.example.com": CreateDefaultConfigZone([]string{"[::1]:53"}),
+	}
+	p.zones = NewZonesState(p)
+
+	var rrs []dns.RR
+	for _, raw := range []string{
+		"www.example.com. 300 IN A 192.0.2.1",
+		"www.sub.example.com. 300 IN A 192.0.2.2",
+		"www.example.org. 300 IN A 192.0.2.3",
+	} {
+		rr, _ := dns.NewRR(raw)
+		rrs = append(rrs, rr)
+	}
+
+	// records of sub-zone are not the parent zone ones
+	uuid := "6c7d8e9f-0a1b-4c2d-8e3f-4a5b6c7d8e9f"
+	records := p.zones.ZoneRecords("example.com", rrs)
+	if len(records) != 1 || records[0].Header().Name != "www.example.com." {
+		t.Error("\nUUID", uuid, fmt.Sprintf("unexpected records:'%v'", records))
+		return
+	}
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+}
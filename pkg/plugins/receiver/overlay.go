@@ -0,0 +1,225 @@
+package receiver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/miekg/dns"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// runtime zones overlay: zones added or changed via api
+// are placed in runtime layer and persisted in overlay
+// file, overlay is read as controller starts
+
+var (
+	// zone already has configuration
+	ErrZoneExists = errors.New("zone already exists")
+
+	// zone is not configured
+	ErrZoneNotFound = errors.New("zone not found")
+
+	// zone is defined out of runtime layer and could
+	// not be removed via api
+	ErrZoneNotRuntime = errors.New("zone is not defined in runtime layer")
+)
+
+// partial zone configuration update, only set
+// fields are changed
+type TZonePatch struct {
+	Enabled     *bool    `json:"enabled,omitempty"`
+	Primary     []string `json:"primary,omitempty"`
+	AllowNotify []string `json:"allow-notify,omitempty"`
+	Refresh     *int     `json:"refresh,omitempty"`
+	Type        *string  `json:"type,omitempty"`
+}
+
+func (t *TZonePatch) Apply(config *TConfigZone) {
+	if t.Enabled != nil {
+		config.Enabled = *t.Enabled
+	}
+	if t.Primary != nil {
+		config.Primary = append([]string{}, t.Primary...)
+	}
+	if t.AllowNotify != nil {
+		config.AllowNotify = append([]string{}, t.AllowNotify...)
+	}
+	if t.Refresh != nil {
+		config.Refresh = *t.Refresh
+	}
+	if t.Type != nil {
+		config.Type = *t.Type
+	}
+}
+
+// Normalizing zone name as it is used in zones state
+func ZoneName(zone string) (string, error) {
+	name := RemoveDot(strings.ToLower(strings.TrimSpace(zone)))
+	if len(name) == 0 {
+		return "", fmt.Errorf("empty zone name")
+	}
+	if _, ok := dns.IsDomainName(name); !ok {
+		return "", fmt.Errorf("zone:'%s' is not a domain name", zone)
+	}
+	return name, nil
+}
+
+// Validating zone configuration before it is
+// placed into runtime layer
+func ValidateConfigZone(zone string, config *TConfigZone) error {
+	if len(config.Primary) == 0 {
+		return fmt.Errorf("zone:'%s' has no primary", zone)
+	}
+
+	if len(config.Type) == 0 {
+		config.Type = TransferTypeAXFR
+	}
+
+	types := []string{TransferTypeAXFR, TransferTypeHTTP, TransferTypeRandom}
+	if !StringInSlice(config.Type, types) {
+		return fmt.Errorf("zone:'%s' type:'%s' is not supported, expected one of ['%s']",
+			zone, config.Type, strings.Join(types, ","))
+	}
+
+	return nil
+}
+
+func (z *ZonesState) LoadOverlay() error {
+	id := "(zones) (overlay) (load)"
+
+	filename := z.p.L().Options.ZonesOverlay
+	if len(filename) == 0 || !Exists(filename) {
+		return nil
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		z.p.G().L.Errorf("%s error reading overlay:'%s', err:'%s'", id, filename, err)
+		return err
+	}
+
+	var configs map[string]TConfigZone
+	if err = yaml.Unmarshal(content, &configs); err != nil {
+		z.p.G().L.Errorf("%s error parsing overlay:'%s', err:'%s'", id, filename, err)
+		return err
+	}
+
+	z.SetLayerZones(ZonesLayerRuntime, configs)
+
+	z.p.G().L.Debugf("%s overlay:'%s' zones:'%d' loaded", id, filename, len(configs))
+
+	return nil
+}
+
+func (z *ZonesState) SaveOverlay() error {
+	id := "(zones) (overlay) (save)"
+
+	filename := z.p.L().Options.ZonesOverlay
+	if len(filename) == 0 {
+		z.p.G().L.Debugf("%s no overlay file configured, runtime zones are not persisted", id)
+		return nil
+	}
+
+	content, err := yaml.Marshal(z.GetLayerZones(ZonesLayerRuntime))
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+
+	// writing temporary file and renaming it
+	// to have overlay always consistent
+	temp := fmt.Sprintf("%s.tmp", filename)
+	if err = os.WriteFile(temp, content, 0644); err != nil {
+		z.p.G().L.Errorf("%s error writing overlay:'%s', err:'%s'", id, temp, err)
+		return err
+	}
+
+	if err = os.Rename(temp, filename); err != nil {
+		z.p.G().L.Errorf("%s error renaming overlay:'%s', err:'%s'", id, filename, err)
+		return err
+	}
+
+	return nil
+}
+
+// Updating runtime layer zones, applying changes to
+// zones state and saving overlay file
+func (z *ZonesState) updateRuntimeZones(update func(configs map[string]TConfigZone) error) error {
+	z.overlay.Lock()
+	defer z.overlay.Unlock()
+
+	configs := z.GetLayerZones(ZonesLayerRuntime)
+	if err := update(configs); err != nil {
+		return err
+	}
+
+	_, changed, removed := z.SetLayerZones(ZonesLayerRuntime, configs)
+	z.ReconfigureZones(changed, removed)
+
+	return z.SaveOverlay()
+}
+
+func (z *ZonesState) AddRuntimeZone(zone string, config TConfigZone) error {
+	if err := ValidateConfigZone(zone, &config); err != nil {
+		return err
+	}
+
+	return z.updateRuntimeZones(func(configs map[string]TConfigZone) error {
+		if _, err := z.GetConfig(zone); err == nil {
+			return fmt.Errorf("zone:'%s', err:'%w'", zone, ErrZoneExists)
+		}
+		configs[zone] = config
+		return nil
+	})
+}
+
+// Patching zone configuration, if zone is defined in
+// other layer its configuration is copied into runtime
+// layer and overrides it
+func (z *ZonesState) PatchRuntimeZone(zone string, patch *TZonePatch) (*TConfigZone, error) {
+	var config TConfigZone
+
+	err := z.updateRuntimeZones(func(configs map[string]TConfigZone) error {
+		current, err := z.GetConfig(zone)
+		if err != nil {
+			return fmt.Errorf("zone:'%s', err:'%w'", zone, ErrZoneNotFound)
+		}
+
+		config = *current
+		patch.Apply(&config)
+
+		if err := ValidateConfigZone(zone, &config); err != nil {
+			return err
+		}
+
+		configs[zone] = config
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// Removing zone from runtime layer, if zone is also
+// defined in other layer, its configuration is restored
+func (z *ZonesState) RemoveRuntimeZone(zone string) error {
+	return z.updateRuntimeZones(func(configs map[string]TConfigZone) error {
+		if _, ok := configs[zone]; !ok {
+			if _, err := z.GetConfig(zone); err != nil {
+				return fmt.Errorf("zone:'%s', err:'%w'", zone, ErrZoneNotFound)
+			}
+			return fmt.Errorf("zone:'%s', err:'%w'", zone, ErrZoneNotRuntime)
+		}
+		delete(configs, zone)
+		return nil
+	})
+}
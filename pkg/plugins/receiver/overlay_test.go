@@ -0,0 +1,188 @@
+package receiver
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRuntimeZones(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+
+	p.c.AxfrTransfer.Enabled = true
+	p.c.AxfrTransfer.Zones.Secondary = map[string]TConfigZone{
+		"example.org": CreateDefaultConfigZone([]string{"[::1]:53"}),
+	}
+	p.c.Options.ZonesOverlay = filepath.Join(t.TempDir(), "zones.overlay.yaml")
+
+	const (
+		ActionAdd    = "add"
+		ActionPatch  = "patch"
+		ActionRemove = "remove"
+	)
+
+	disabled := false
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		action string
+		zone   string
+		config TConfigZone
+		patch  TZonePatch
+
+		// expected error (if any)
+		err error
+
+		// expected zone layer and enabled state
+		// after action (empty if removed)
+		layer       string
+		zoneenabled bool
+	}
+
+	var Tests = []TTest{
+		{
+			"0b5c4a8e-1f2d-4e3a-9b8c-7d6e5f4a3b2c",
+			true,
+			ActionAdd, "example.net",
+			CreateDefaultConfigZone([]string{"[::1]:53"}), TZonePatch{},
+			nil, ZonesLayerRuntime, true,
+		},
+		{
+			"1c6d5b9f-2a3e-4f4b-8c9d-8e7f6a5b4c3d",
+			true,
+			ActionAdd, "example.org",
+			CreateDefaultConfigZone([]string{"[::1]:53"}), TZonePatch{},
+			ErrZoneExists, ZonesLayerConfig, true,
+		},
+		{
+			"2d7e6c0a-3b4f-4a5c-9d0e-9f8a7b6c5d4e",
+			true,
+			ActionAdd, "example.com",
+			CreateDefaultConfigZone([]string{}), TZonePatch{},
+			fmt.Errorf("no primary"), "", false,
+		},
+		{
+			"3e8f7d1b-4c5a-4b6d-8e1f-0a9b8c7d6e5f",
+			true,
+			ActionPatch, "example.org",
+			TConfigZone{}, TZonePatch{Enabled: &disabled},
+			nil, ZonesLayerRuntime, false,
+		},
+		{
+			"4f9a8e2c-5d6b-4c7e-9f2a-1b0c9d8e7f6a",
+			true,
+			ActionRemove, "example.org",
+			TConfigZone{}, TZonePatch{},
+			nil, ZonesLayerConfig, true,
+		},
+		{
+			"5a0b9f3d-6e7c-4d8f-8a3b-2c1d0e9f8a7b",
+			true,
+			ActionRemove, "example.org",
+			TConfigZone{}, TZonePatch{},
+			ErrZoneNotRuntime, ZonesLayerConfig, true,
+		},
+		{
+			"6b1c0a4e-7f8d-4e9a-9b4c-3d2e1f0a9b8c",
+			true,
+			ActionRemove, "example.ru",
+			TConfigZone{}, TZonePatch{},
+			ErrZoneNotFound, "", false,
+		},
+	}
+
+	zones := NewZonesState(p)
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		var err error
+		switch test.action {
+		case ActionAdd:
+			err = zones.AddRuntimeZone(test.zone, test.config)
+		case ActionPatch:
+			_, err = zones.PatchRuntimeZone(test.zone, &test.patch)
+		case ActionRemove:
+			err = zones.RemoveRuntimeZone(test.zone)
+		}
+
+		if (err == nil) != (test.err == nil) {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error expected:'%v' got:'%v'", test.err, err))
+			continue
+		}
+		sentinels := []error{ErrZoneExists, ErrZoneNotFound, ErrZoneNotRuntime}
+		for _, sentinel := range sentinels {
+			if test.err == sentinel && !errors.Is(err, sentinel) {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("error expected:'%v' got:'%v'", test.err, err))
+			}
+		}
+		config, err := zones.GetConfig(test.zone)
+		if len(test.layer) == 0 {
+			if err == nil {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("zone:'%s' expected to be absent", test.zone))
+			}
+			fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+			continue
+		}
+
+		if err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("zone:'%s' not found, err:'%s'", test.zone, err))
+			continue
+		}
+
+		layer := zones.GetZoneLayer(test.zone)
+		if layer != test.layer || config.Enabled != test.zoneenabled {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("zone:'%s' expected layer:'%s' enabled:'%t' got layer:'%s' enabled:'%t'",
+				test.zone, test.layer, test.zoneenabled, layer, config.Enabled))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+
+	// overlay file should keep runtime zones
+	loaded := NewZonesState(p)
+	if err := loaded.LoadOverlay(); err != nil {
+		t.Error(fmt.Sprintf("error loading overlay, err:'%s'", err))
+		return
+	}
+
+	if loaded.GetZoneLayer("example.net") != ZonesLayerRuntime {
+		t.Error(fmt.Sprintf("zone:'%s' expected in overlay", "example.net"))
+	}
+
+	// zone info is read while zone is locked (e.g. by cooker
+	// syncing zone) and zone lock is taken while zones lock
+	lock := loaded.ZoneLock("example.net")
+	lock.Lock()
+	defer lock.Unlock()
+
+	done := make(chan error)
+	go func() {
+		loaded.lock.RLock()
+		loaded.ZoneLock("example.org")
+		loaded.lock.RUnlock()
+
+		_, err := loaded.GetZoneInfo("example.net")
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Error(fmt.Sprintf("error getting zone info, err:'%s'", err))
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("zone info is blocked by zone lock")
+	}
+}
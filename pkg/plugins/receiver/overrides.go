@@ -0,0 +1,557 @@
+package receiver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/yandex/yadns-controller/pkg/plugins/offloader"
+)
+
+// overrides are local records pinned regardless of zones
+// data transferred: they take precedence over zones records
+// on map sync, IXFR actions for overridden names are skipped
+// (override record is written instead), overrides could expire and then transferred data (if any)
+// is restored in maps
+
+const (
+	// interval to check overrides expiration
+	DefaultOverridesInterval = 10 * time.Second
+
+	// default override record ttl
+	DefaultOverrideTTL = 60
+)
+
+var (
+	// override for name and type is not found
+	ErrOverrideNotFound = errors.New("override not found")
+)
+
+type TOverride struct {
+	// fqdn and type (A or AAAA) of record
+	Name string `json:"name" yaml:"name"`
+	Type string `json:"type" yaml:"type"`
+
+	// record ttl and address
+	TTL     uint32 `json:"ttl" yaml:"ttl"`
+	Address string `json:"address" yaml:"address"`
+
+	// expiration time, override never expires
+	// if not set
+	Expires time.Time `json:"expires,omitempty" yaml:"expires,omitempty"`
+
+	// a reason of override (e.g. incident)
+	Comment string `json:"comment,omitempty" yaml:"comment,omitempty"`
+
+	Created time.Time `json:"created" yaml:"created"`
+}
+
+// Making override key, the same as rrsets keys
+func OverrideKey(name string, qtype string) string {
+	return fmt.Sprintf("%s-%s", Dot(strings.ToLower(name)), strings.ToUpper(qtype))
+}
+
+// Creating override from record text, expire is
+// override lifetime in seconds (0 is never)
+func NewOverride(record string, expire int, comment string) (*TOverride, error) {
+	rr, err := dns.NewRR(record)
+	if err != nil {
+		return nil, err
+	}
+	if rr == nil {
+		return nil, fmt.Errorf("empty record")
+	}
+
+	var o TOverride
+	o.Name = strings.ToLower(rr.Header().Name)
+	o.Type = dns.Type(rr.Header().Rrtype).String()
+	o.TTL = rr.Header().Ttl
+	o.Comment = comment
+	o.Created = time.Now().UTC()
+
+	switch v := rr.(type) {
+	case *dns.A:
+		o.Address = v.A.String()
+	case *dns.AAAA:
+		o.Address = v.AAAA.String()
+	default:
+		return nil, fmt.Errorf("record type:'%s' is not supported, expected one of ['A,AAAA']", o.Type)
+	}
+
+	if expire > 0 {
+		o.Expires = o.Created.Add(time.Duration(expire) * time.Second)
+	}
+
+	return &o, o.Validate()
+}
+
+func (o *TOverride) Key() string {
+	return OverrideKey(o.Name, o.Type)
+}
+
+func (o *TOverride) Validate() error {
+	if _, ok := dns.IsDomainName(o.Name); !ok || len(o.Name) == 0 {
+		return fmt.Errorf("override name:'%s' is not a domain name", o.Name)
+	}
+
+	addr, err := netip.ParseAddr(o.Address)
+	if err != nil {
+		return fmt.Errorf("override name:'%s' address:'%s', err:'%w'", o.Name, o.Address, err)
+	}
+
+	switch strings.ToUpper(o.Type) {
+	case "A":
+		if !addr.Is4() {
+			return fmt.Errorf("override name:'%s' address:'%s' is not IPv4", o.Name, o.Address)
+		}
+	case "AAAA":
+		if !addr.Is6() || addr.Is4In6() {
+			return fmt.Errorf("override name:'%s' address:'%s' is not IPv6", o.Name, o.Address)
+		}
+	default:
+		return fmt.Errorf("override name:'%s' type:'%s' is not supported", o.Name, o.Type)
+	}
+
+	return nil
+}
+
+func (o *TOverride) Expired(now time.Time) bool {
+	return !o.Expires.IsZero() && !now.Before(o.Expires)
+}
+
+func (o *TOverride) RR() dns.RR {
+	ttl := o.TTL
+	if ttl == 0 {
+		ttl = DefaultOverrideTTL
+	}
+	rr, _ := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", Dot(o.Name), ttl,
+		strings.ToUpper(o.Type), o.Address))
+	return rr
+}
+
+type OverrideStore struct {
+	p *TReceiverPlugin
+
+	lock sync.RWMutex
+
+	// file to persist overrides (if any)
+	filename string
+
+	// overrides by key
+	overrides map[string]TOverride
+}
+
+func NewOverrideStore(p *TReceiverPlugin, filename string) *OverrideStore {
+	var s OverrideStore
+	s.p = p
+	s.filename = filename
+	s.overrides = make(map[string]TOverride)
+	return &s
+}
+
+// Loading overrides from file, invalid overrides are
+// reported and skipped
+func (s *OverrideStore) Load() error {
+	id := "(overrides) (load)"
+
+	if len(s.filename) == 0 || !Exists(s.filename) {
+		return nil
+	}
+
+	content, err := os.ReadFile(s.filename)
+	if err != nil {
+		s.p.G().L.Errorf("%s error reading overrides:'%s', err:'%s'", id, s.filename, err)
+		return err
+	}
+
+	var list []TOverride
+	if err = yaml.Unmarshal(content, &list); err != nil {
+		s.p.G().L.Errorf("%s error parsing overrides:'%s', err:'%s'", id, s.filename, err)
+		return err
+	}
+
+	overrides := make(map[string]TOverride)
+	for _, o := range list {
+		o.Name = Dot(strings.ToLower(o.Name))
+		o.Type = strings.ToUpper(o.Type)
+		if err := o.Validate(); err != nil {
+			s.p.G().L.Errorf("%s error override in:'%s', err:'%s'", id, s.filename, err)
+			continue
+		}
+		overrides[o.Key()] = o
+	}
+
+	s.lock.Lock()
+	s.overrides = overrides
+	s.lock.Unlock()
+
+	s.p.G().L.Debugf("%s overrides:'%s' loaded:'%d'", id, s.filename, len(overrides))
+
+	return nil
+}
+
+// Saving overrides into file (if set), should be called
+// with lock held
+func (s *OverrideStore) save() error {
+	id := "(overrides) (save)"
+
+	if len(s.filename) == 0 {
+		s.p.G().L.Debugf("%s no overrides file configured, overrides are not persisted", id)
+		return nil
+	}
+
+	content, err := yaml.Marshal(s.list())
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(s.filename), 0755); err != nil {
+		return err
+	}
+
+	temp := fmt.Sprintf("%s.tmp", s.filename)
+	if err = os.WriteFile(temp, content, 0644); err != nil {
+		s.p.G().L.Errorf("%s error writing overrides:'%s', err:'%s'", id, temp, err)
+		return err
+	}
+
+	if err = os.Rename(temp, s.filename); err != nil {
+		s.p.G().L.Errorf("%s error renaming overrides:'%s', err:'%s'", id, s.filename, err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *OverrideStore) list() []TOverride {
+	out := []TOverride{}
+	for _, o := range s.overrides {
+		out = append(out, o)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Key() < out[j].Key()
+	})
+	return out
+}
+
+// Listing all overrides sorted by key
+func (s *OverrideStore) List() []TOverride {
+	if s == nil {
+		return []TOverride{}
+	}
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.list()
+}
+
+// Setting (or replacing) override and persisting it
+func (s *OverrideStore) Set(o TOverride) error {
+	o.Name = Dot(strings.ToLower(o.Name))
+	o.Type = strings.ToUpper(o.Type)
+	if err := o.Validate(); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.overrides[o.Key()] = o
+	return s.save()
+}
+
+// Getting override by key
+func (s *OverrideStore) Get(key string) (*TOverride, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	o, ok := s.overrides[key]
+	if !ok {
+		return nil, false
+	}
+	return &o, true
+}
+
+// Reverting override of key to previous one, override
+// is removed if previous is nil
+func (s *OverrideStore) Revert(key string, previous *TOverride) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if previous == nil {
+		delete(s.overrides, key)
+	} else {
+		s.overrides[key] = *previous
+	}
+	return s.save()
+}
+
+// Removing override by name and type
+func (s *OverrideStore) Remove(name string, qtype string) (*TOverride, error) {
+	key := OverrideKey(name, qtype)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	o, ok := s.overrides[key]
+	if !ok {
+		return nil, fmt.Errorf("override:'%s', err:'%w'", key, ErrOverrideNotFound)
+	}
+	delete(s.overrides, key)
+
+	return &o, s.save()
+}
+
+// Removing overrides expired, returning them
+func (s *OverrideStore) Expire(now time.Time) ([]TOverride, error) {
+	if s == nil {
+		return nil, nil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var expired []TOverride
+	for key, o := range s.overrides {
+		if o.Expired(now) {
+			expired = append(expired, o)
+			delete(s.overrides, key)
+		}
+	}
+
+	if len(expired) == 0 {
+		return nil, nil
+	}
+
+	return expired, s.save()
+}
+
+// Getting active (not expired) overrides records by key
+func (s *OverrideStore) Records() map[string]dns.RR {
+	out := make(map[string]dns.RR)
+	if s == nil {
+		return out
+	}
+
+	now := time.Now()
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for key, o := range s.overrides {
+		if o.Expired(now) {
+			continue
+		}
+		out[key] = o.RR()
+	}
+	return out
+}
+
+// Making rrsets with overrides applied: overridden keys
+// are replaced with override records and overrides not
+// in rrsets are added
+func (s *OverrideStore) ApplyRRsets(rrsets map[string][]dns.RR) map[string][]dns.RR {
+	records := s.Records()
+	if len(records) == 0 {
+		return rrsets
+	}
+
+	out := make(map[string][]dns.RR)
+	for k, v := range rrsets {
+		if _, ok := records[k]; ok {
+			continue
+		}
+		out[k] = v
+	}
+	for k, r := range records {
+		out[k] = []dns.RR{r}
+	}
+	return out
+}
+
+// Removing actions of overridden keys, they should not
+// be changed by IXFR
+func (s *OverrideStore) FilterActions(sa *TSnapshotActions) *TSnapshotActions {
+	records := s.Records()
+	if len(records) == 0 || sa == nil {
+		return sa
+	}
+
+	keys := make(map[string][]dns.RR)
+	for k, r := range records {
+		keys[k] = []dns.RR{r}
+	}
+	return sa.Without(keys)
+}
+
+// Getting overrides records of keys touched by actions,
+// they are written as IXFR actions are skipped
+func (s *OverrideStore) Touched(sa *TSnapshotActions) map[string]dns.RR {
+	out := make(map[string]dns.RR)
+	records := s.Records()
+	if len(records) == 0 || sa == nil {
+		return out
+	}
+
+	for _, sections := range sa.actions {
+		for _, actions := range sections {
+			for k := range actions {
+				if rr, ok := records[k]; ok {
+					out[k] = rr
+				}
+			}
+		}
+	}
+	return out
+}
+
+// Getting overrides store of plugin, nil if zones state
+// is not ready (e.g. in command line tools)
+func (t *TReceiverPlugin) Overrides() *OverrideStore {
+	if t.zones == nil {
+		return nil
+	}
+	return t.zones.overrides
+}
+
+// Getting record transferred for key (if any), rrsets with
+// more than one record are not placed into maps, policies
+// take precedence over zones records
+func (z *ZonesState) TransferredRR(key string) dns.RR {
+	return z.UnderlyingRR("", key)
+}
+
+// Calling fn with pinned map of override record type
+func (z *ZonesState) withOverrideMap(o *TOverride,
+	fn func(obj *Objects, rrmaps map[uint16]offloader.RRMap) error) error {
+
+	obj := NewObjects(z.p)
+	obj.Dryrun = z.p.L().Cooker.Dryrun
+
+	qtype := o.RR().Header().Rrtype
+	rrmap, err := obj.RRMap(qtype)
+	if err != nil {
+		return err
+	}
+	defer rrmap.Close()
+
+	return fn(obj, map[uint16]offloader.RRMap{qtype: rrmap})
+}
+
+// Writing override record into maps right now
+func (z *ZonesState) ApplyOverride(o *TOverride) error {
+	return z.withOverrideMap(o, func(obj *Objects, rrmaps map[uint16]offloader.RRMap) error {
+		return z.applyOverride(obj, rrmaps, o)
+	})
+}
+
+// Writing override record, record of key served already
+// is replaced
+func (z *ZonesState) applyOverride(obj *Objects, rrmaps map[uint16]offloader.RRMap,
+	o *TOverride) error {
+
+	id := "(overrides) (apply)"
+
+	rr := o.RR()
+	z.p.G().L.Debugf("%s override %s", id, rr.String())
+
+	_, err := writeOwnedRR(obj, rrmaps, rr, obj.Dryrun)
+	return err
+}
+
+// Restoring transferred record of override removed (or
+// expired) or removing override record from maps
+func (z *ZonesState) RestoreOverride(o *TOverride) error {
+	return z.withOverrideMap(o, func(obj *Objects, rrmaps map[uint16]offloader.RRMap) error {
+		return z.restoreOverride(obj, rrmaps, o)
+	})
+}
+
+func (z *ZonesState) restoreOverride(obj *Objects, rrmaps map[uint16]offloader.RRMap,
+	o *TOverride) error {
+
+	id := "(overrides) (restore)"
+
+	if rr := z.TransferredRR(o.Key()); rr != nil {
+		z.p.G().L.Debugf("%s override:'%s' restored as %s", id, o.Key(), rr.String())
+		_, err := writeOwnedRR(obj, rrmaps, rr, obj.Dryrun)
+		return err
+	}
+
+	z.p.G().L.Debugf("%s override:'%s' removed", id, o.Key())
+
+	rr := o.RR()
+	rrmap := rrmaps[rr.Header().Rrtype]
+	if obj.Dryrun || obj.ExistsDNSRR(rrmap, rr) == NoExists {
+		return nil
+	}
+	return obj.UpdateDNSRR(ObjectRemove, rrmap, rr, false)
+}
+
+// Setting override and writing it into maps, override
+// stored is reverted if maps are not written
+func (z *ZonesState) SetOverride(o *TOverride) error {
+	id := "(overrides) (set)"
+
+	previous, _ := z.overrides.Get(o.Key())
+	if err := z.overrides.Set(*o); err != nil {
+		return err
+	}
+
+	if err := z.ApplyOverride(o); err != nil {
+		if rerr := z.overrides.Revert(o.Key(), previous); rerr != nil {
+			z.p.G().L.Errorf("%s error reverting override:'%s', err:'%s'", id,
+				o.Key(), rerr)
+		}
+		return err
+	}
+	return nil
+}
+
+func (z *ZonesState) RemoveOverride(name string, qtype string) (*TOverride, error) {
+	o, err := z.overrides.Remove(name, qtype)
+	if err != nil {
+		return nil, err
+	}
+	return o, z.RestoreOverride(o)
+}
+
+// Expiring overrides periodically restoring records
+// transferred
+func (z *ZonesState) RunOverrides(ctx context.Context) error {
+	id := "(overrides) (worker)"
+
+	timer := time.NewTicker(DefaultOverridesInterval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			expired, err := z.overrides.Expire(time.Now())
+			if err != nil {
+				z.p.G().L.Errorf("%s error saving overrides, err:'%s'", id, err)
+			}
+			for _, o := range expired {
+				z.p.G().L.Debugf("%s override:'%s' expired at '%s'", id, o.Key(),
+					TimeAsString(o.Expires))
+				if err := z.RestoreOverride(&o); err != nil {
+					z.p.G().L.Errorf("%s error restoring override:'%s', err:'%s'", id,
+						o.Key(), err)
+				}
+			}
+		case <-ctx.Done():
+			z.p.G().L.Debugf("%s context stop on overrides", id)
+			return ctx.Err()
+		}
+	}
+}
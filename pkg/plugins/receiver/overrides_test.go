@@ -0,0 +1,172 @@
+package receiver
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/yandex/yadns-controller/pkg/plugins/offloader"
+)
+
+func TestOverrides(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		// override record, expire and error expected
+		record string
+		expire int
+		err    bool
+
+		// expected to be expired in a hour
+		expired bool
+	}
+
+	var Tests = []TTest{
+		{"1a2b3c4d-5e6f-4a7b-8c9d-0e1f2a3b4c5d", true, "www.example.net. 60 IN A 192.0.2.1", 0, false, false},
+		{"2b3c4d5e-6f7a-4b8c-9d0e-1f2a3b4c5d6e", true, "WWW.example.net. 60 IN AAAA 2001:db8::1", 60, false, true},
+		{"3c4d5e6f-7a8b-4c9d-8e1f-2a3b4c5d6e7f", true, "www.example.net. 60 IN TXT \"text\"", 0, true, false},
+		{"4d5e6f7a-8b9c-4d0e-9f2a-3b4c5d6e7f8a", true, "www.example.net. 60 IN AAAA ::ffff:192.0.2.1", 0, true, false},
+	}
+
+	filename := filepath.Join(t.TempDir(), "overrides.yaml")
+	store := NewOverrideStore(p, filename)
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		o, err := NewOverride(test.record, test.expire, "test")
+		if (err != nil) != test.err {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error expected:'%t' got:'%v'", test.err, err))
+			continue
+		}
+		if err != nil {
+			fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+			continue
+		}
+
+		if err := store.Set(*o); err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error setting override, err:'%s'", err))
+			continue
+		}
+
+		// overrides should survive reload
+		loaded := NewOverrideStore(p, filename)
+		if err := loaded.Load(); err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error loading overrides, err:'%s'", err))
+			continue
+		}
+		if _, ok := loaded.Records()[o.Key()]; !ok {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("override:'%s' is not loaded", o.Key()))
+			continue
+		}
+
+		if o.Expired(time.Now().Add(time.Hour)) != test.expired {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("override:'%s' expired expected:'%t'",
+				o.Key(), test.expired))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+
+	// overridden rrset is replaced, others are kept
+	a, _ := dns.NewRR("www.example.net. 300 IN A 192.0.2.100")
+	b, _ := dns.NewRR("mail.example.net. 300 IN A 192.0.2.25")
+	rrsets := map[string][]dns.RR{
+		OverrideKey("www.example.net", "A"):  {a},
+		OverrideKey("mail.example.net", "A"): {b},
+	}
+	applied := store.ApplyRRsets(rrsets)
+	if len(applied) != 3 || applied[OverrideKey("www.example.net", "A")][0].(*dns.A).A.String() != "192.0.2.1" {
+		t.Error(fmt.Sprintf("overrides are not applied to rrsets:'%v'", applied))
+	}
+
+	var sa TSnapshotActions
+	sa.actions = make(map[int]map[int]map[string][]dns.RR)
+	sa.Add(0, SectionAddition, OverrideKey("www.example.net", "A"), a)
+	sa.Add(0, SectionAddition, OverrideKey("mail.example.net", "A"), b)
+	filtered := store.FilterActions(&sa)
+	if len(filtered.actions[0][SectionAddition]) != 1 {
+		t.Error(fmt.Sprintf("overridden actions are not filtered:'%v'", filtered.actions))
+	}
+
+	expired, err := store.Expire(time.Now().Add(time.Hour))
+	if err != nil || len(expired) != 1 || len(store.List()) != 1 {
+		t.Error(fmt.Sprintf("expected one override expired, got:'%d' err:'%v'", len(expired), err))
+	}
+
+	if _, err := store.Remove("www.example.net", "A"); err != nil {
+		t.Error(fmt.Sprintf("error removing override, err:'%s'", err))
+	}
+	if _, err := store.Remove("www.example.net", "A"); !errors.Is(err, ErrOverrideNotFound) {
+		t.Error(fmt.Sprintf("expected not found error, got:'%v'", err))
+	}
+}
+
+func TestOverrideMaps(t *testing.T) {
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+	p.c.Options.Snapshots.Directory = t.TempDir()
+	p.c.PinPath = t.TempDir()
+	p.zones = NewZonesState(p)
+
+	rrmap := &testRRMapA{entries: make(map[offloader.RRQname]offloader.RREntryA)}
+	rrmaps := map[uint16]offloader.RRMap{dns.TypeA: rrmap}
+
+	// record served (transferred) already
+	served, _ := dns.NewRR("www.example.net. 300 IN A 192.0.2.1")
+	var snapshot TSnapshotZone
+	snapshot.rrsets = map[string][]dns.RR{RecordKey(served): {served}}
+	p.zones.SetState("example.net", TZoneState{Zone: "example.net",
+		Snapshots: map[int]TSnapshotZone{0: snapshot}})
+	NewObjects(p).UpdateDNSRR(ObjectCreate, rrmap, served, false)
+
+	o, _ := NewOverride("www.example.net. 60 IN A 192.0.2.20", 0, "")
+
+	uuid := "5e6f7a8b-9c0d-4e1f-8a3b-4c5d6e7f8a9b"
+	if err = p.zones.applyOverride(NewObjects(p), rrmaps, o); err != nil ||
+		rrmap.get("www.example.net") != "192.0.2.20" {
+		t.Error("\nUUID", uuid, fmt.Sprintf("override is not written:'%s' err:'%v'",
+			rrmap.get("www.example.net"), err))
+		return
+	}
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+
+	uuid = "6f7a8b9c-0d1e-4f2a-9b4c-5d6e7f8a9b0c"
+	if err = p.zones.restoreOverride(NewObjects(p), rrmaps, o); err != nil ||
+		rrmap.get("www.example.net") != "192.0.2.1" {
+		t.Error("\nUUID", uuid, fmt.Sprintf("record is not restored:'%s' err:'%v'",
+			rrmap.get("www.example.net"), err))
+		return
+	}
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+
+	// maps could not be written, override is not kept
+	uuid = "7a8b9c0d-1e2f-4a3b-8c5d-6e7f8a9b0c1d"
+	if err = p.zones.SetOverride(o); err == nil {
+		t.Error("\nUUID", uuid, "expected error writing maps")
+		return
+	}
+	if _, ok := p.zones.overrides.Get(o.Key()); ok {
+		t.Error("\nUUID", uuid, "override is kept as maps are not written")
+		return
+	}
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+}
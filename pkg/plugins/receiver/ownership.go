@@ -0,0 +1,839 @@
+package receiver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/miekg/dns"
+	"github.com/yandex/yadns-controller/pkg/plugins/offloader"
+)
+
+// ownership index keeps owners claiming map entries by key
+// (qname and type): zones synced and records created via
+// objects api ("@manual"). Entry in maps is the record of
+// owner of the highest precedence: overrides, policy zones,
+// zones in configured precedence order (then by name) and
+// manual records (if not configured). AXFR sync of zone
+// removes entries owned by zone only, entry released is
+// restored from other owner (if any). Keys claimed by more
+// than one owner are reported as conflicts
+
+const (
+	// owner of override records
+	OwnerOverride = "@override"
+
+	// owner of records created via objects api
+	OwnerManual = "@manual"
+
+	// file of manual records claims in snapshots
+	// directory
+	DefaultOwnersFilename = "yadns-xdp.owners"
+
+	// number of keys claimed by more than one owner
+	MetricOwnershipConflicts = "receiver-ownership-conflicts"
+)
+
+var (
+	// no owner of key found
+	ErrOwnerNotFound = errors.New("owner not found")
+)
+
+type TConfigOwnership struct {
+	// zones (and "@manual") in order of precedence, zones
+	// not listed follow in name order and then "@manual"
+	Precedence []string `json:"precedence" yaml:"precedence"`
+}
+
+type TOwnerClaim struct {
+	Owner  string `json:"owner"`
+	Record string `json:"record"`
+}
+
+// owner of key and all claims in order of precedence
+type TOwnership struct {
+	Key    string        `json:"key"`
+	Owner  string        `json:"owner"`
+	Record string        `json:"record"`
+	Claims []TOwnerClaim `json:"claims"`
+
+	// key is claimed by more than one owner
+	Conflict bool `json:"conflict"`
+}
+
+type OwnershipIndex struct {
+	p *TReceiverPlugin
+
+	lock sync.RWMutex
+
+	// claims by key and owner
+	claims map[string]map[string]dns.RR
+
+	// keys claimed by owner
+	owned map[string]map[string]bool
+}
+
+func NewOwnershipIndex(p *TReceiverPlugin) *OwnershipIndex {
+	var o OwnershipIndex
+	o.p = p
+	o.claims = make(map[string]map[string]dns.RR)
+	o.owned = make(map[string]map[string]bool)
+	return &o
+}
+
+// Getting ownership index of plugin, nil if zones state
+// is not ready (e.g. in command line tools)
+func (t *TReceiverPlugin) Owners() *OwnershipIndex {
+	if t.zones == nil {
+		return nil
+	}
+	return t.zones.owners
+}
+
+// Making claims of rrsets: rrsets with more than one
+// record are never placed into maps
+func OwnedRecords(rrsets map[string][]dns.RR) map[string]dns.RR {
+	out := make(map[string]dns.RR)
+	for k, rrset := range rrsets {
+		if len(rrset) == 1 {
+			out[k] = rrset[0]
+		}
+	}
+	return out
+}
+
+func RecordKey(rr dns.RR) string {
+	h := rr.Header()
+	return fmt.Sprintf("%s-%s", strings.ToLower(h.Name), dns.Type(h.Rrtype).String())
+}
+
+func (o *OwnershipIndex) filename() string {
+	path := o.p.L().Options.Snapshots.Directory
+	if len(path) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s", path, DefaultOwnersFilename)
+}
+
+// Loading manual records claims, zones claims are made
+// as zones are synced (or seeded from zones snapshots
+// loaded, see Seed)
+func (o *OwnershipIndex) Load() error {
+	id := "(ownership) (load)"
+
+	filename := o.filename()
+	if len(filename) == 0 || !Exists(filename) {
+		return nil
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var records []string
+	if err = json.Unmarshal(content, &records); err != nil {
+		o.p.G().L.Errorf("%s error parsing owners:'%s', err:'%s'", id, filename, err)
+		return err
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	for _, record := range records {
+		rr, err := dns.NewRR(record)
+		if err != nil || rr == nil {
+			o.p.G().L.Errorf("%s error parsing record:'%s', err:'%v'", id, record, err)
+			continue
+		}
+		o.set(OwnerManual, RecordKey(rr), rr)
+	}
+	o.p.G().L.Debugf("%s owners:'%s' loaded:'%d'", id, filename, len(records))
+
+	return nil
+}
+
+// saving manual records claims, should be called
+// with lock held
+func (o *OwnershipIndex) save() error {
+	filename := o.filename()
+	if len(filename) == 0 {
+		return nil
+	}
+
+	records := []string{}
+	for k := range o.owned[OwnerManual] {
+		records = append(records, o.claims[k][OwnerManual].String())
+	}
+	sort.Strings(records)
+
+	content, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(filename, content, 0644)
+}
+
+// setting claim, should be called with lock held,
+// nil record releases key
+func (o *OwnershipIndex) set(owner string, key string, rr dns.RR) {
+	if rr == nil {
+		if claims, ok := o.claims[key]; ok {
+			delete(claims, owner)
+			if len(claims) == 0 {
+				delete(o.claims, key)
+			}
+		}
+		if keys, ok := o.owned[owner]; ok {
+			delete(keys, key)
+			if len(keys) == 0 {
+				delete(o.owned, owner)
+			}
+		}
+		return
+	}
+
+	if _, ok := o.claims[key]; !ok {
+		o.claims[key] = make(map[string]dns.RR)
+	}
+	o.claims[key][owner] = rr
+
+	if _, ok := o.owned[owner]; !ok {
+		o.owned[owner] = make(map[string]bool)
+	}
+	o.owned[owner][key] = true
+}
+
+// Claiming records created via objects api
+func (o *OwnershipIndex) Claim(rrs []dns.RR) error {
+	if o == nil {
+		return nil
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	for _, rr := range rrs {
+		o.set(OwnerManual, RecordKey(rr), rr)
+	}
+	return o.save()
+}
+
+// Releasing keys of records removed via objects api
+func (o *OwnershipIndex) Release(keys []string) error {
+	if o == nil {
+		return nil
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	for _, k := range keys {
+		o.set(OwnerManual, k, nil)
+	}
+	return o.save()
+}
+
+// Getting keys claimed by owner
+func (o *OwnershipIndex) Keys(owner string) []string {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	var out []string
+	for k := range o.owned[owner] {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Seeding claims of zones having no claims yet from their
+// current snapshots: zones claims are kept in memory only,
+// so on startup zones restored from snapshots (and not
+// synced yet) claim their records as being in maps already
+func (z *ZonesState) SeedOwners() {
+	o := z.owners
+	if o == nil {
+		return
+	}
+
+	for zone, state := range z.States() {
+		current, ok := state.Snapshots[state.SnapshotID]
+		if !ok || state.IsPolicy() {
+			continue
+		}
+
+		o.lock.Lock()
+		if len(o.owned[zone]) == 0 {
+			for k, rr := range OwnedRecords(current.rrsets) {
+				o.set(zone, k, rr)
+			}
+		}
+		o.lock.Unlock()
+	}
+}
+
+// Getting records claimed by owner
+func (o *OwnershipIndex) Records(owner string) map[string]dns.RR {
+	out := make(map[string]dns.RR)
+	if o == nil {
+		return out
+	}
+
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	for k := range o.owned[owner] {
+		out[k] = o.claims[k][owner]
+	}
+	return out
+}
+
+// Resolving records expected in maps w.r.t ownership: claims
+// of current zones snapshots (but policies) and manual ones
+// are resolved per key (overrides included), owners of keys
+// are returned as well
+func (z *ZonesState) ResolveOwned() (map[string]dns.RR, map[string]string, error) {
+	claims := make(map[string]map[string]dns.RR)
+	for zone, state := range z.States() {
+		current, ok := state.Snapshots[state.SnapshotID]
+		if !ok {
+			return nil, nil, fmt.Errorf("zone:'%s' state not found", zone)
+		}
+		if state.IsPolicy() {
+			continue
+		}
+		claims[zone] = OwnedRecords(current.rrsets)
+	}
+	claims[OwnerManual] = z.owners.Records(OwnerManual)
+
+	plan := z.owners.Plan(claims, true)
+	return plan.Write, plan.Owners, nil
+}
+
+// Getting owners in order of precedence (but overrides
+// taking precedence over all)
+func (z *ZonesState) OwnersPrecedence() []string {
+	var out []string
+	seen := make(map[string]bool)
+
+	add := func(owner string) {
+		if !seen[owner] {
+			seen[owner] = true
+			out = append(out, owner)
+		}
+	}
+
+	for _, zone := range z.PolicyZones() {
+		add(zone)
+	}
+
+	names := make(map[string]string)
+	var zones []string
+	for zone := range z.States() {
+		names[strings.TrimSuffix(strings.ToLower(zone), ".")] = zone
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+
+	for _, owner := range z.p.L().Cooker.Ownership.Precedence {
+		if owner == OwnerManual {
+			add(owner)
+			continue
+		}
+		if zone, ok := names[strings.TrimSuffix(strings.ToLower(owner), ".")]; ok {
+			add(zone)
+		}
+	}
+
+	for _, zone := range zones {
+		add(zone)
+	}
+	add(OwnerManual)
+
+	return out
+}
+
+// ordering claims of key w.r.t precedence, owners unknown
+// (e.g. zones removed) follow in name order
+func orderClaims(claims map[string]dns.RR, ranks map[string]int) []TOwnerClaim {
+	var owners []string
+	for owner := range claims {
+		owners = append(owners, owner)
+	}
+
+	rank := func(owner string) int {
+		if r, ok := ranks[owner]; ok {
+			return r
+		}
+		return len(ranks)
+	}
+
+	sort.Slice(owners, func(i, j int) bool {
+		ri, rj := rank(owners[i]), rank(owners[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return owners[i] < owners[j]
+	})
+
+	var out []TOwnerClaim
+	for _, owner := range owners {
+		out = append(out, TOwnerClaim{Owner: owner, Record: claims[owner].String()})
+	}
+	return out
+}
+
+func (o *OwnershipIndex) ranks() map[string]int {
+	out := make(map[string]int)
+	for i, owner := range o.p.zones.OwnersPrecedence() {
+		out[owner] = i
+	}
+	return out
+}
+
+// resolving owner of key claims, override takes
+// precedence over all
+func (o *OwnershipIndex) resolve(key string, claims map[string]dns.RR,
+	overrides map[string]dns.RR, ranks map[string]int) (string, dns.RR) {
+
+	if rr, ok := overrides[key]; ok {
+		return OwnerOverride, rr
+	}
+	ordered := orderClaims(claims, ranks)
+	if len(ordered) == 0 {
+		return "", nil
+	}
+	owner := ordered[0].Owner
+	return owner, claims[owner]
+}
+
+// plan of maps sync w.r.t ownership
+type TOwnershipPlan struct {
+	// claims of synced owners by owner and key,
+	// nil record releases key
+	claims map[string]map[string]dns.RR
+
+	// all owner claims are replaced
+	full bool
+
+	// records of keys touched to write (owner record)
+	// and to remove (no owner left)
+	Write  map[string]dns.RR
+	Remove map[string]dns.RR
+
+	// owners of keys touched
+	Owners map[string]string
+
+	// keys claimed by synced owners, but owned
+	// by others
+	Skipped map[string]string
+}
+
+// Planning sync of owners claims: if full all claims of
+// owners are replaced with claims given, otherwise only keys
+// given are changed
+func (o *OwnershipIndex) Plan(claims map[string]map[string]dns.RR, full bool) *TOwnershipPlan {
+	var plan TOwnershipPlan
+	plan.claims = claims
+	plan.full = full
+	plan.Write = make(map[string]dns.RR)
+	plan.Remove = make(map[string]dns.RR)
+	plan.Owners = make(map[string]string)
+	plan.Skipped = make(map[string]string)
+
+	if o == nil {
+		return &plan
+	}
+
+	ranks := o.ranks()
+	overrides := o.p.Overrides().Records()
+
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	touched := make(map[string]bool)
+	for owner, records := range claims {
+		for k := range records {
+			touched[k] = true
+		}
+		if full {
+			for k := range o.owned[owner] {
+				touched[k] = true
+			}
+		}
+	}
+
+	for k := range touched {
+		current := make(map[string]dns.RR)
+		for owner, rr := range o.claims[k] {
+			current[owner] = rr
+		}
+
+		// record of synced owners placed into maps
+		var previous dns.RR
+		for owner := range claims {
+			if rr, ok := current[owner]; ok {
+				previous = rr
+			}
+		}
+
+		for owner, records := range claims {
+			rr, ok := records[k]
+			if !ok && !full {
+				continue
+			}
+			if rr == nil {
+				delete(current, owner)
+				continue
+			}
+			current[owner] = rr
+		}
+
+		owner, rr := o.resolve(k, current, overrides, ranks)
+		plan.Owners[k] = owner
+
+		if rr == nil {
+			if previous != nil {
+				plan.Remove[k] = previous
+			}
+			continue
+		}
+		plan.Write[k] = rr
+
+		for synced, records := range claims {
+			if synced != owner && records[k] != nil {
+				plan.Skipped[k] = owner
+			}
+		}
+	}
+
+	return &plan
+}
+
+// Committing claims of plan into index
+func (o *OwnershipIndex) Commit(plan *TOwnershipPlan) {
+	if o == nil || plan == nil {
+		return
+	}
+
+	o.lock.Lock()
+	for owner, records := range plan.claims {
+		if plan.full {
+			for k := range o.owned[owner] {
+				if _, ok := records[k]; !ok {
+					o.set(owner, k, nil)
+				}
+			}
+		}
+		for k, rr := range records {
+			o.set(owner, k, rr)
+		}
+	}
+	o.lock.Unlock()
+
+	o.PushMetrics()
+}
+
+// Getting ownership of key, override (if any) is the owner
+func (o *OwnershipIndex) Get(key string) (*TOwnership, error) {
+	ranks := o.ranks()
+	overrides := o.p.Overrides().Records()
+
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	claims := o.claims[key]
+	owner, rr := o.resolve(key, claims, overrides, ranks)
+	if rr == nil {
+		return nil, fmt.Errorf("%w: key:'%s'", ErrOwnerNotFound, key)
+	}
+
+	out := TOwnership{Key: key, Owner: owner, Record: rr.String(),
+		Claims: orderClaims(claims, ranks), Conflict: len(claims) > 1}
+	if out.Claims == nil {
+		out.Claims = []TOwnerClaim{}
+	}
+	return &out, nil
+}
+
+// Getting keys claimed by more than one owner
+func (o *OwnershipIndex) Conflicts() []TOwnership {
+	ranks := o.ranks()
+	overrides := o.p.Overrides().Records()
+
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	out := []TOwnership{}
+	for k, claims := range o.claims {
+		if len(claims) < 2 {
+			continue
+		}
+		owner, rr := o.resolve(k, claims, overrides, ranks)
+		out = append(out, TOwnership{Key: k, Owner: owner, Record: rr.String(),
+			Claims: orderClaims(claims, ranks), Conflict: true})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+func (o *OwnershipIndex) PushMetrics() {
+	o.lock.RLock()
+	conflicts := 0
+	for _, claims := range o.claims {
+		if len(claims) > 1 {
+			conflicts++
+		}
+	}
+	o.lock.RUnlock()
+
+	o.p.PushMetric(MetricOwnershipConflicts, nil, float64(conflicts))
+}
+
+// Getting owners having claims
+func (o *OwnershipIndex) OwnerNames() []string {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	var out []string
+	for owner := range o.owned {
+		out = append(out, owner)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Checking if key is claimed by any owner
+func (o *OwnershipIndex) Claimed(key string) bool {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	_, ok := o.claims[key]
+	return ok
+}
+
+// Planning IXFR actions of zone: keys touched by actions are
+// claimed w.r.t zone rrsets with actions applied
+func (o *OwnershipIndex) PlanActions(zone string, rrsets map[string][]dns.RR,
+	sa *TSnapshotActions) *TOwnershipPlan {
+
+	records := make(map[string]dns.RR)
+	if sa != nil {
+		for _, sections := range sa.actions {
+			for _, actions := range sections {
+				for k := range actions {
+					records[k] = nil
+					if rrset := rrsets[k]; len(rrset) == 1 {
+						records[k] = rrset[0]
+					}
+				}
+			}
+		}
+	}
+	return o.Plan(map[string]map[string]dns.RR{zone: records}, false)
+}
+
+// Removing actions of keys owned by others than zone
+func (t *TOwnershipPlan) FilterActions(zone string, sa *TSnapshotActions) *TSnapshotActions {
+	keys := make(map[string][]dns.RR)
+	for k, owner := range t.Owners {
+		if len(owner) > 0 && owner != zone {
+			keys[k] = nil
+		}
+	}
+	if len(keys) == 0 {
+		return sa
+	}
+	return sa.Without(keys)
+}
+
+// Getting records of keys owned by others than zone
+// to restore in maps
+func (t *TOwnershipPlan) Restore(zone string) map[string]dns.RR {
+	out := make(map[string]dns.RR)
+	for k, owner := range t.Owners {
+		if len(owner) > 0 && owner != zone {
+			out[k] = t.Write[k]
+		}
+	}
+	return out
+}
+
+// Writing record into maps unless equal one exists,
+// returns true if record is changed
+func writeOwnedRR(obj *Objects, rrmaps map[uint16]offloader.RRMap, rr dns.RR,
+	dryrun bool) (bool, error) {
+
+	rrmap := rrmaps[rr.Header().Rrtype]
+	var err error
+
+	switch obj.ExistsDNSRR(rrmap, rr) {
+	case ExistsEqual:
+		return false, nil
+	case NoExists:
+		if !dryrun {
+			err = obj.UpdateDNSRR(ObjectCreate, rrmap, rr, false)
+		}
+	case ExistsNotEqual:
+		if !dryrun {
+			err = obj.UpdateDNSRR(ObjectRemove, rrmap, rr, false)
+			if err == nil {
+				err = obj.UpdateDNSRR(ObjectCreate, rrmap, rr, false)
+			}
+		}
+	}
+	return true, err
+}
+
+// Partitioning maps records by all zones known: configured
+// ones and ones having state
+func (z *ZonesState) PartitionZones(rrs []dns.RR,
+	zones ...string) (map[string]map[string]dns.RR, []dns.RR) {
+
+	for name := range z.GetZonesConfigs() {
+		zones = append(zones, name)
+	}
+	for name := range z.States() {
+		zones = append(zones, name)
+	}
+	return PartitionRR(rrs, zones)
+}
+
+// Getting maps records of zone, records of its sub-zones
+// (configured separately) are not included
+func (z *ZonesState) ZoneRecords(zone string, rrs []dns.RR) []dns.RR {
+	partitions, _ := z.PartitionZones(rrs, zone)
+
+	var out []dns.RR
+	for _, rr := range partitions[zone] {
+		out = append(out, rr)
+	}
+	return out
+}
+
+// Getting maps records which could be removed by sync of
+// zone if not claimed: records of zone but its sub-zones
+// (as they are synced on their own) or, for all zones
+// (empty zone), records of zones having state and records
+// not matching any zone
+func (z *ZonesState) OrphanCandidates(zone string, rrs []dns.RR) []dns.RR {
+	if len(zone) > 0 {
+		return z.ZoneRecords(zone, rrs)
+	}
+
+	states := z.States()
+	partitions, unmatched := z.PartitionZones(rrs)
+
+	out := unmatched
+	for name, records := range partitions {
+		if _, ok := states[name]; !ok {
+			continue
+		}
+		for _, rr := range records {
+			out = append(out, rr)
+		}
+	}
+	return out
+}
+
+// Syncing maps in AXFR mode w.r.t ownership: entries owned
+// by zone (or all zones for snapshot merged) are replaced,
+// entries of other owners are kept and entries not claimed
+// by any owner within zone (but its sub-zones) or within all
+// zones having state are removed. Plan is returned to be
+// committed as maps mutations are written
+func (t *TSnapshotZone) SyncOwnedMap(obj *Objects, rrmaps map[uint16]offloader.RRMap,
+	owners *OwnershipIndex, dryrun bool) (*TSyncMapResult, *TOwnershipPlan, error) {
+
+	id := fmt.Sprintf("(ownership) (sync) %s", DryrunString(dryrun))
+
+	var result TSyncMapResult
+
+	// zones loaded but not synced yet keep their
+	// records in maps
+	t.p.zones.SeedOwners()
+
+	claims := make(map[string]map[string]dns.RR)
+	if len(t.zone) > 0 {
+		claims[t.zone] = OwnedRecords(t.rrsets)
+	} else {
+		for zone, state := range t.p.zones.States() {
+			snapshot := state.Snapshots[state.SnapshotID]
+			claims[zone] = OwnedRecords(snapshot.rrsets)
+		}
+		for _, owner := range owners.OwnerNames() {
+			if _, ok := claims[owner]; !ok && owner != OwnerManual {
+				claims[owner] = make(map[string]dns.RR)
+			}
+		}
+	}
+
+	plan := owners.Plan(claims, true)
+
+	overrides := t.p.Overrides().Records()
+	if len(t.zone) == 0 {
+		for k, rr := range overrides {
+			plan.Write[k] = rr
+		}
+	}
+
+	rrs, err := obj.ListRR()
+	if err != nil {
+		t.p.G().L.Errorf("%s error listing map zone:'%s', err:'%s'", id, t.zone, err)
+		return nil, nil, err
+	}
+	for _, rr := range t.p.zones.OrphanCandidates(t.zone, rrs) {
+		k := RecordKey(rr)
+		if _, ok := plan.Owners[k]; ok {
+			continue
+		}
+		if _, ok := overrides[k]; ok || owners.Claimed(k) {
+			continue
+		}
+		plan.Remove[k] = rr
+	}
+
+	for k, rr := range plan.Remove {
+		if !dryrun {
+			if err = obj.UpdateDNSRR(ObjectRemove, rrmaps[rr.Header().Rrtype], rr, false); err != nil {
+				t.p.G().L.Errorf("%s error removing k:'%s', err:'%s'", id, k, err)
+				continue
+			}
+		}
+		result.Removed++
+	}
+
+	for k, rr := range plan.Write {
+		changed, err := writeOwnedRR(obj, rrmaps, rr, dryrun)
+		if err != nil {
+			t.p.G().L.Errorf("%s error writing k:'%s', err:'%s'", id, k, err)
+			continue
+		}
+		if changed {
+			result.Created++
+		}
+	}
+
+	for k, owner := range plan.Skipped {
+		t.p.G().L.Debugf("%s zone:'%s' k:'%s' skipped as owned by '%s'", id, t.zone, k, owner)
+	}
+
+	t.p.G().L.Debugf("%s zone:'%s' synced map %s skipped:'%d'", id, t.zone,
+		result.AsString(), len(plan.Skipped))
+
+	return &result, plan, nil
+}
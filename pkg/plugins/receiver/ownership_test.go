@@ -0,0 +1,287 @@
+package receiver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestOwnershipPlan(t *testing.T) {
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+	p.c.Options.Snapshots.Directory = t.TempDir()
+	p.c.Cooker.Ownership.Precedence = []string{"example.com"}
+	p.zones = NewZonesState(p)
+	p.zones.zones["example.net"] = TZoneState{Zone: "example.net"}
+	p.zones.zones["example.com"] = TZoneState{Zone: "example.com"}
+
+	owners := p.Owners()
+
+	record := func(s string) dns.RR {
+		rr, _ := dns.NewRR(s)
+		return rr
+	}
+
+	key := "www.example.org.-A"
+	net := record("www.example.org. 300 IN A 192.0.2.1")
+	com := record("www.example.org. 300 IN A 192.0.2.2")
+	manual := record("www.example.org. 300 IN A 192.0.2.3")
+	other := record("ns.example.net. 300 IN A 192.0.2.4")
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		owner  string
+		claims map[string]dns.RR
+		full   bool
+
+		// expected owner of key, record written and
+		// record removed
+		expected string
+		write    dns.RR
+		remove   dns.RR
+		skipped  bool
+	}
+
+	var Tests = []TTest{
+		// the first zone claims key and owns it
+		{"1a2b3c4d-5e6f-4a7b-8c9d-0e1f2a3b4c5d", true, "example.net",
+			map[string]dns.RR{key: net, "ns.example.net.-A": other}, true,
+			"example.net", net, nil, false},
+		// zone of higher precedence takes it over
+		{"2b3c4d5e-6f7a-4b8c-9d0e-1f2a3b4c5d6e", true, "example.com",
+			map[string]dns.RR{key: com}, true, "example.com", com, nil, false},
+		// zone of lower precedence changes record, it is skipped
+		{"3c4d5e6f-7a8b-4c9d-8e0f-2a3b4c5d6e7f", true, "example.net",
+			map[string]dns.RR{key: manual, "ns.example.net.-A": other}, true,
+			"example.com", com, nil, true},
+		// zone releases key, record of the other zone is restored
+		{"4d5e6f7a-8b9c-4d0e-9f1a-3b4c5d6e7f8a", true, "example.com",
+			map[string]dns.RR{}, true, "example.net", manual, nil, false},
+		// the last owner releases key, record is removed
+		{"5e6f7a8b-9c0d-4e1f-8a2b-4c5d6e7f8a9b", true, "example.net",
+			map[string]dns.RR{key: nil}, false, "", nil, manual, false},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		plan := owners.Plan(map[string]map[string]dns.RR{test.owner: test.claims}, test.full)
+
+		_, skipped := plan.Skipped[key]
+		if plan.Owners[key] != test.expected || skipped != test.skipped ||
+			fmt.Sprint(plan.Write[key]) != fmt.Sprint(test.write) ||
+			fmt.Sprint(plan.Remove[key]) != fmt.Sprint(test.remove) {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("unexpected plan owner:'%s' write:'%v' remove:'%v' skipped:'%t'",
+				plan.Owners[key], plan.Write[key], plan.Remove[key], skipped))
+			continue
+		}
+
+		owners.Commit(plan)
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+
+	// other zone keys are kept on full sync of zone
+	uuid := "6f7a8b9c-0d1e-4f2a-9b3c-5d6e7f8a9b0c"
+	if keys := owners.Keys("example.net"); len(keys) != 1 || keys[0] != "ns.example.net.-A" {
+		t.Error("\nUUID", uuid, fmt.Sprintf("unexpected keys:'%v'", keys))
+		return
+	}
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+}
+
+func TestOwnershipManual(t *testing.T) {
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+	p.c.Options.Snapshots.Directory = t.TempDir()
+	p.zones = NewZonesState(p)
+	p.zones.zones["example.net"] = TZoneState{Zone: "example.net"}
+
+	uuid := "7a8b9c0d-1e2f-4a3b-8c4d-6e7f8a9b0c1d"
+
+	manual, _ := dns.NewRR("www.example.net. 60 IN A 192.0.2.10")
+	zone, _ := dns.NewRR("www.example.net. 300 IN A 192.0.2.1")
+	key := RecordKey(manual)
+
+	if err = p.Owners().Claim([]dns.RR{manual}); err != nil {
+		t.Error("\nUUID", uuid, fmt.Sprintf("error claiming, err:'%s'", err))
+		return
+	}
+
+	// manual claims are persisted
+	owners := NewOwnershipIndex(p)
+	if err = owners.Load(); err != nil || !owners.Claimed(key) {
+		t.Error("\nUUID", uuid, fmt.Sprintf("manual claim is not loaded, err:'%v'", err))
+		return
+	}
+	p.zones.owners = owners
+
+	// zone takes precedence over manual records, key
+	// is reported as conflict
+	owners.Commit(owners.Plan(map[string]map[string]dns.RR{
+		"example.net": {key: zone}}, true))
+
+	conflicts := owners.Conflicts()
+	if len(conflicts) != 1 || conflicts[0].Owner != "example.net" ||
+		len(conflicts[0].Claims) != 2 || conflicts[0].Claims[1].Owner != OwnerManual {
+		t.Error("\nUUID", uuid, fmt.Sprintf("unexpected conflicts:'%v'", conflicts))
+		return
+	}
+
+	// override takes precedence over all
+	o, _ := NewOverride("www.example.net. 30 IN A 192.0.2.20", 0, "")
+	if err = p.zones.overrides.Set(*o); err != nil {
+		t.Error("\nUUID", uuid, fmt.Sprintf("error setting override, err:'%s'", err))
+		return
+	}
+	ownership, err := owners.Get(key)
+	if err != nil || ownership.Owner != OwnerOverride || !ownership.Conflict {
+		t.Error("\nUUID", uuid, fmt.Sprintf("unexpected ownership:'%v' err:'%v'", ownership, err))
+		return
+	}
+
+	if err = owners.Release([]string{key}); err != nil || len(owners.Conflicts()) != 0 {
+		t.Error("\nUUID", uuid, fmt.Sprintf("manual claim is not released, err:'%v'", err))
+		return
+	}
+
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+}
+
+func TestResolveOwned(t *testing.T) {
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+	p.c.Options.Snapshots.Directory = t.TempDir()
+	p.c.Cooker.Ownership.Precedence = []string{"example.com"}
+	p.zones = NewZonesState(p)
+
+	uuid := "8b9c0d1e-2f3a-4b4c-9d5e-7f8a9b0c1d2e"
+
+	record := func(s string) dns.RR {
+		rr, _ := dns.NewRR(s)
+		return rr
+	}
+
+	// both zones claim the same key
+	key := "www.example.org.-A"
+	states := map[string]dns.RR{
+		"example.net": record("www.example.org. 300 IN A 192.0.2.1"),
+		"example.com": record("www.example.org. 300 IN A 192.0.2.2"),
+	}
+	for zone, rr := range states {
+		var snapshot TSnapshotZone
+		snapshot.rrsets = map[string][]dns.RR{key: {rr}}
+		p.zones.SetState(zone, TZoneState{Zone: zone, Snapshots: map[int]TSnapshotZone{0: snapshot}})
+	}
+
+	manual := record("manual.example.org. 60 IN A 192.0.2.10")
+	if err = p.Owners().Claim([]dns.RR{manual}); err != nil {
+		t.Error("\nUUID", uuid, fmt.Sprintf("error claiming, err:'%s'", err))
+		return
+	}
+
+	expected, owners, err := p.zones.ResolveOwned()
+	if err != nil || len(expected) != 2 || owners[key] != "example.com" ||
+		expected[key].String() != states["example.com"].String() ||
+		owners[RecordKey(manual)] != OwnerManual {
+		t.Error("\nUUID", uuid, fmt.Sprintf("unexpected records:'%v' owners:'%v' err:'%v'",
+			expected, owners, err))
+		return
+	}
+
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+}
+
+func TestOwnershipRestart(t *testing.T) {
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+	p.c.Options.Snapshots.Directory = t.TempDir()
+	p.c.AxfrTransfer.Enabled = true
+	p.c.AxfrTransfer.Zones.Secondary = map[string]TConfigZone{
+		"example.net":     CreateDefaultConfigZone([]string{"[::1]:53"}),
+		"sub.example.net": CreateDefaultConfigZone([]string{"[::1]:53"}),
+	}
+	p.zones = NewZonesState(p)
+
+	record := func(s string) dns.RR {
+		rr, _ := dns.NewRR(s)
+		return rr
+	}
+
+	parent := record("www.example.net. 300 IN A 192.0.2.1")
+	child := record("www.sub.example.net. 300 IN A 192.0.2.2")
+	other := record("www.example.org. 300 IN A 192.0.2.3")
+	rrs := []dns.RR{parent, child, other}
+
+	// the child zone is restored from snapshot on
+	// startup, claims are not synced yet
+	uuid := "9c0d1e2f-3a4b-4c5d-8e6f-8a9b0c1d2e3f"
+	var snapshot TSnapshotZone
+	snapshot.rrsets = map[string][]dns.RR{RecordKey(child): {child}}
+	p.zones.SetState("sub.example.net", TZoneState{Zone: "sub.example.net",
+		Snapshots: map[int]TSnapshotZone{0: snapshot}})
+
+	p.zones.SeedOwners()
+	if !p.Owners().Claimed(RecordKey(child)) {
+		t.Error("\nUUID", uuid, "zone restored does not claim its records")
+		return
+	}
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		zone     string
+		expected []string
+	}
+
+	var Tests = []TTest{
+		// sub-zone records are not removed by parent
+		{"0d1e2f3a-4b5c-4d6e-9f7a-9b0c1d2e3f4a", true, "example.net",
+			[]string{RecordKey(parent)}},
+		{"1e2f3a4b-5c6d-4e7f-8a8b-0c1d2e3f4a5b", true, "sub.example.net",
+			[]string{RecordKey(child)}},
+		// zones without state (parent) keep their records
+		{"2f3a4b5c-6d7e-4f8a-9b9c-1d2e3f4a5b6c", true, "",
+			[]string{RecordKey(other), RecordKey(child)}},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		var keys []string
+		for _, rr := range p.zones.OrphanCandidates(test.zone, rrs) {
+			keys = append(keys, RecordKey(rr))
+		}
+		sort.Strings(keys)
+
+		if strings.Join(keys, ",") != strings.Join(test.expected, ",") {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("orphans expected:'%v' got:'%v'",
+				test.expected, keys))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
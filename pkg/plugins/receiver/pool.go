@@ -3,6 +3,7 @@ package receiver
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -90,6 +91,23 @@ const (
 	// has file:// prefix
 	TransferTypeHTTP = "http"
 
+	// synthetic zone generated with random
+	// records (http adapter)
+	TransferTypeRandom = "random"
+
+	// response policy zone transferred via AXFR
+	// or read from file:// (http adapter)
+	TransferTypeRPZ = "rpz"
+
+	// records sources (not zones) fetched from
+	// file:// or http(s):// (http adapter)
+	TransferTypeHosts = "hosts"
+	TransferTypeJSON  = "json"
+
+	// dynamic zone updated by DNS UPDATE, zone is
+	// loaded from snapshot (http adapter)
+	TransferTypeDynamic = "dynamic"
+
 	// by default we do not use TSIG
 	DefaultTSIGKey = ""
 )
@@ -109,10 +127,22 @@ func (j *CollectorJob) execute(p *TReceiverPlugin, ctx context.Context,
 	case ClassJobTransfer:
 
 		zone := job.Zone
-		for _, primary := range job.Config.Primary {
 
-			p.G().L.Debugf("%s worker:'%d' importing zone:'%s' via primary:'%s'",
-				id, index, zone, primary)
+		// primaries are tried in preference order, the ones
+		// failed recently are skipped till backoff expires
+		primaries := job.Zones.PrimariesOrder(zone, job.Config.Primary)
+		if len(primaries) == 0 {
+			err := fmt.Errorf("zone:'%s' all primaries ['%s'] are in backoff", zone,
+				strings.Join(job.Config.Primary, ","))
+			p.G().L.Debugf("%s worker:'%d' skip zone:'%s', err:'%s'", id, index, zone, err)
+			result.Error = err
+			return result
+		}
+
+		for i, primary := range primaries {
+
+			p.G().L.Debugf("%s worker:'%d' importing zone:'%s' via primary:'%s' [%d]/[%d]",
+				id, index, zone, primary, i, len(primaries))
 
 			// need resolve primary (if is has some alias)
 			server := job.Zones.Primary(primary)
@@ -128,6 +158,26 @@ func (j *CollectorJob) execute(p *TReceiverPlugin, ctx context.Context,
 				t = SourceHTTP
 				opts.Endpoint = append(opts.Endpoint, server)
 
+			case TransferTypeRandom:
+				t = SourceRandom
+				opts.Endpoint = append(opts.Endpoint, server)
+
+			case TransferTypeRPZ:
+				t = SourceRPZ
+				opts.Endpoint = append(opts.Endpoint, server)
+
+			case TransferTypeHosts:
+				t = SourceHosts
+				opts.Endpoint = append(opts.Endpoint, server)
+
+			case TransferTypeJSON:
+				t = SourceJSON
+				opts.Endpoint = append(opts.Endpoint, server)
+
+			case TransferTypeDynamic:
+				t = SourceDynamic
+				opts.Endpoint = append(opts.Endpoint, server)
+
 			case TransferTypeAXFR:
 				t = SourceAXFR
 				opts.Server = server
@@ -142,20 +192,86 @@ func (j *CollectorJob) execute(p *TReceiverPlugin, ctx context.Context,
 				return result
 			}
 
+			// TSIG keys are defined per zone or primary
+			keys, err := job.Zones.TsigKeys(zone, primary)
+			if err != nil {
+				result.Error = err
+				p.G().L.Errorf("%s error importing zone:'%s' via primary:'%s' err:'%s'",
+					id, zone, server, err)
+				return result
+			}
+
+			// tls:// primaries are requested over TLS
+			tlsconfig, err := job.Zones.TransferTLS(primary, server)
+			if err != nil {
+				result.Error = err
+				job.Zones.SetPrimaryStatus(zone, primary, server, 0, err)
+				p.G().L.Errorf("%s error importing zone:'%s' via primary:'%s' err:'%s'",
+					id, zone, server, err)
+				continue
+			}
+
 			options := TUpdateZoneStateOptions{
 				Incremental: p.L().Options.Incremental,
 				Server:      server,
 				Key:         DefaultTSIGKey,
+				Tsig:        keys,
+				TLS:         tlsconfig,
 			}
 
+			// http sources fail over (with retries) via the
+			// endpoints of this and next primaries
+			failover := false
+			switch job.Config.Type {
+			case TransferTypeHTTP, TransferTypeHosts, TransferTypeJSON:
+				for _, next := range primaries[i:] {
+					options.Endpoints = append(options.Endpoints, job.Zones.Primary(next))
+				}
+				failover = true
+			}
+
+			t1 := time.Now()
 			err = importer.UpdateZoneState(ctx, job.Zones, t, zone,
 				&defaultconfig, &options)
+			job.Zones.SetTransferStatus(zone, err)
+
+			// local errors (e.g. guards) are not failures of
+			// primary and are not fixed by next primary
+			if err != nil && !IsPrimaryError(err) {
+				p.G().L.Errorf("%s error updating snapshot zone:'%s' source:'%s', err:'%s'",
+					id, zone, server, err)
+				result.Error = err
+				break
+			}
+
+			job.Zones.SetPrimaryStatus(zone, primary, server, time.Since(t1), err)
+			if err != nil && failover {
+				p.G().L.Errorf("%s error updating snapshot endpoints:['%s'], err:'%s'",
+					id, strings.Join(options.Endpoints, ","), err)
+
+				// all endpoints are tried, next primaries
+				// are failed as well
+				for _, next := range primaries[i+1:] {
+					job.Zones.SetPrimaryStatus(zone, next, job.Zones.Primary(next), 0, err)
+				}
+				result.Error = err
+				break
+			}
 			if err != nil {
 				p.G().L.Errorf("%s error updating snapshot source:'%s', err:'%s'",
 					id, server, err)
+
+				// failing over to the next primary (if any)
 				result.Error = err
-				return result
+				continue
 			}
+
+			result.Error = nil
+			break
+		}
+
+		if result.Error != nil {
+			return result
 		}
 	}
 
@@ -244,6 +360,13 @@ func (t *CollectorTransferPool) TransferJob(zone string,
 	job.Config = v
 	job.Zones = t.zones
 
+	// IXFR requires SOA of snapshot, zone without it
+	// is transferred in full
+	if mode == TransferModeIXFR && soa == nil {
+		t.p.G().L.Debugf("%s zone:'%s' has no SOA, requesting AXFR", id, zone)
+		mode = TransferModeAXFR
+	}
+
 	var options TransferOptions
 	options.Mode = mode
 	options.Key = DefaultEmptyTSIG
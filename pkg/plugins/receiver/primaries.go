@@ -0,0 +1,211 @@
+package receiver
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// primaries health is tracked per zone and primary, transfer
+// jobs choose primaries in preference order (as configured)
+// skipping ones in backoff, each failure increases backoff
+// interval exponentially with some jitter
+
+const (
+	// default backoff settings, intervals in seconds
+	DefaultBackoffInitial = 5
+	DefaultBackoffMax     = 600
+	DefaultBackoffJitter  = 0.2
+)
+
+type TPrimaryHealth struct {
+	// primary as configured (could be alias) and
+	// resolved server address
+	Primary string `json:"primary"`
+	Server  string `json:"server"`
+
+	// the last successful transfer and its latency in ms
+	LastSuccess time.Time `json:"last-success"`
+	Latency     int64     `json:"latency"`
+
+	// the last failure time and error
+	LastFailure time.Time `json:"last-failure"`
+	LastError   string    `json:"last-error"`
+
+	// consecutive failures (reset on success) and
+	// total failures count
+	Failures      int   `json:"failures"`
+	TotalFailures int64 `json:"total-failures"`
+
+	// primary is not used till next attempt time
+	NextAttempt time.Time `json:"next-attempt"`
+}
+
+// error of primary transfer (connection, transfer, etc),
+// other errors (e.g. guards or local snapshots) are not
+// failures of primary
+type TPrimaryError struct {
+	Err error
+}
+
+func (e *TPrimaryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TPrimaryError) Unwrap() error {
+	return e.Err
+}
+
+func PrimaryError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &TPrimaryError{Err: err}
+}
+
+func IsPrimaryError(err error) bool {
+	var e *TPrimaryError
+	return errors.As(err, &e)
+}
+
+// primary is healthy if its the last attempt succeeded
+func (t *TPrimaryHealth) Healthy() bool {
+	return t.Failures == 0
+}
+
+// Calculating backoff interval for number of consecutive
+// failures w.r.t backoff configuration
+func (t *TBackoff) Interval(failures int) time.Duration {
+	initial := float64(DefaultBackoffInitial)
+	if t.Initial > 0 {
+		initial = float64(t.Initial)
+	}
+
+	max := float64(DefaultBackoffMax)
+	if t.Max > 0 {
+		max = float64(t.Max)
+	}
+
+	jitter := DefaultBackoffJitter
+	if t.Jitter > 0 {
+		jitter = t.Jitter
+	}
+
+	if failures <= 0 {
+		return 0
+	}
+
+	interval := math.Min(max, initial*math.Pow(2, float64(failures-1)))
+
+	// jitter spreads attempts in interval
+	// [interval*(1-jitter), interval*(1+jitter)]
+	interval = interval * (1 + jitter*(2*rand.Float64()-1))
+
+	return time.Duration(interval * float64(time.Second))
+}
+
+// Ordering zone primaries by preference (as configured) and
+// skipping ones waiting for the next attempt
+func (z *ZonesState) PrimariesOrder(zone string, primaries []string) []string {
+	z.lock.RLock()
+	defer z.lock.RUnlock()
+
+	var out []string
+	now := time.Now()
+	for _, primary := range primaries {
+		if health, ok := z.health[zone][primary]; ok {
+			if now.Before(health.NextAttempt) {
+				continue
+			}
+		}
+		out = append(out, primary)
+	}
+
+	return out
+}
+
+// Getting backoff of zone primaries w.r.t. zone transfer
+// type: zones transferred via endpoints (http, random, etc)
+// use http transfer backoff
+func (z *ZonesState) PrimaryBackoff(zone string) TBackoff {
+	config, err := z.GetConfig(zone)
+	if err == nil && len(config.Type) > 0 && config.Type != TransferTypeAXFR {
+		return z.p.L().HTTPTransfer.Backoff
+	}
+	return z.p.L().AxfrTransfer.Transfer.Backoff
+}
+
+// Recording the result of transfer attempt from
+// zone primary, err is nil on success
+func (z *ZonesState) SetPrimaryStatus(zone string, primary string, server string,
+	latency time.Duration, err error) {
+
+	id := "(zones) (primary) (health)"
+
+	backoff := z.PrimaryBackoff(zone)
+
+	z.lock.Lock()
+
+	if _, ok := z.health[zone]; !ok {
+		z.health[zone] = make(map[string]*TPrimaryHealth)
+	}
+	if _, ok := z.health[zone][primary]; !ok {
+		z.health[zone][primary] = &TPrimaryHealth{Primary: primary}
+	}
+
+	health := z.health[zone][primary]
+	health.Server = server
+
+	if err == nil {
+		health.LastSuccess = time.Now()
+		health.Latency = latency.Milliseconds()
+		health.Failures = 0
+		health.NextAttempt = time.Time{}
+	}
+
+	if err != nil {
+		health.LastFailure = time.Now()
+		health.LastError = err.Error()
+		health.Failures++
+		health.TotalFailures++
+
+		health.NextAttempt = time.Now().Add(backoff.Interval(health.Failures))
+	}
+
+	h := *health
+	z.lock.Unlock()
+
+	if err != nil {
+		z.p.G().L.Errorf("%s zone:'%s' primary:'%s' failures:'%d' next attempt:'%s', err:'%s'",
+			id, zone, primary, h.Failures, h.NextAttempt.Format(time.RFC3339), err)
+	}
+
+	tags := []string{fmt.Sprintf("zone=%s", zone), fmt.Sprintf("primary=%s", primary)}
+
+	up := float64(0)
+	if h.Healthy() {
+		up = 1
+		z.p.PushMetric(MetricPrimaryLatency, tags, float64(h.Latency))
+	}
+	z.p.PushMetric(MetricPrimaryUp, tags, up)
+	z.p.PushMetric(MetricPrimaryFailures, tags, float64(h.TotalFailures))
+}
+
+// Getting a copy of zone primaries health in
+// order of zone configuration
+func (z *ZonesState) GetPrimariesHealth(zone string, primaries []string) []TPrimaryHealth {
+	z.lock.RLock()
+	defer z.lock.RUnlock()
+
+	var out []TPrimaryHealth
+	for _, primary := range primaries {
+		if health, ok := z.health[zone][primary]; ok {
+			out = append(out, *health)
+			continue
+		}
+		out = append(out, TPrimaryHealth{Primary: primary})
+	}
+	return out
+}
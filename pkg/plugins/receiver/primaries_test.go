@@ -0,0 +1,226 @@
+package receiver
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackoffInterval(t *testing.T) {
+
+	type TTest struct {
+		uuid     string
+		enabled  bool
+		backoff  TBackoff
+		failures int
+		min      time.Duration
+		max      time.Duration
+	}
+
+	var Tests = []TTest{
+		{
+			"7c2d1b5f-8a9e-4f0b-8c5d-4e3f2a1b0c9d",
+			true,
+			TBackoff{Initial: 5, Max: 600, Jitter: 0.2},
+			0, 0, 0,
+		},
+		{
+			"8d3e2c6a-9b0f-4a1c-9d6e-5f4a3b2c1d0e",
+			true,
+			TBackoff{Initial: 5, Max: 600, Jitter: 0.2},
+			1, 4 * time.Second, 6 * time.Second,
+		},
+		{
+			"9e4f3d7b-0c1a-4b2d-8e7f-6a5b4c3d2e1f",
+			true,
+			TBackoff{Initial: 5, Max: 600, Jitter: 0.2},
+			4, 32 * time.Second, 48 * time.Second,
+		},
+		{
+			"0f5a4e8c-1d2b-4c3e-9f8a-7b6c5d4e3f2a",
+			true,
+			TBackoff{Initial: 5, Max: 600, Jitter: 0.2},
+			30, 480 * time.Second, 720 * time.Second,
+		},
+		{
+			"1a6b5f9d-2e3c-4d4f-8a9b-8c7d6e5f4a3b",
+			true,
+			TBackoff{},
+			2, 8 * time.Second, 12 * time.Second,
+		},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		// checking jitter bounds for some tries
+		for i := 0; i < 100; i++ {
+			interval := test.backoff.Interval(test.failures)
+			if interval < test.min || interval > test.max {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("interval:'%s' expected in ['%s', '%s']",
+					interval, test.min, test.max))
+				break
+			}
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
+
+func TestPrimariesOrder(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+
+	zone := "example.net"
+	primaries := []string{"primary1", "primary2", "primary3"}
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		// primary and transfer result
+		primary string
+		err     error
+
+		// expected primaries order
+		order []string
+	}
+
+	var Tests = []TTest{
+		{
+			"2b7c6a0e-3f4d-4e5a-9b0c-9d8e7f6a5b4c",
+			true,
+			"primary1", nil,
+			[]string{"primary1", "primary2", "primary3"},
+		},
+		{
+			"3c8d7b1f-4a5e-4f6b-8c1d-0e9f8a7b6c5d",
+			true,
+			"primary1", fmt.Errorf("connection refused"),
+			[]string{"primary2", "primary3"},
+		},
+		{
+			"4d9e8c2a-5b6f-4a7c-9d2e-1f0a9b8c7d6e",
+			true,
+			"primary3", fmt.Errorf("timeout"),
+			[]string{"primary2"},
+		},
+		{
+			"5e0f9d3b-6c7a-4b8d-8e3f-2a1b0c9d8e7f",
+			true,
+			"primary2", fmt.Errorf("timeout"),
+			[]string{},
+		},
+	}
+
+	zones := NewZonesState(p)
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		zones.SetPrimaryStatus(zone, test.primary, test.primary, time.Millisecond, test.err)
+
+		order := zones.PrimariesOrder(zone, primaries)
+		if strings.Join(order, ",") != strings.Join(test.order, ",") {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("order expected:'%s' got:'%s'",
+				strings.Join(test.order, ","), strings.Join(order, ",")))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+
+	health := zones.GetPrimariesHealth(zone, primaries)
+	if len(health) != len(primaries) || health[0].Failures != 1 || health[0].TotalFailures != 1 {
+		t.Error(fmt.Sprintf("unexpected primaries health:'%v'", health))
+	}
+}
+
+func TestPrimaryBackoff(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+
+	p.c.AxfrTransfer.Enabled = true
+	p.c.AxfrTransfer.Transfer.Backoff = TBackoff{Initial: 10, Max: 10}
+	p.c.AxfrTransfer.Zones.Secondary = map[string]TConfigZone{
+		"example.net": CreateDefaultConfigZone([]string{"[::1]:53"}),
+	}
+
+	config := CreateDefaultConfigZone([]string{"http://[::1]/zone"})
+	config.Type = TransferTypeHTTP
+	p.c.HTTPTransfer.Enabled = true
+	p.c.HTTPTransfer.Backoff = TBackoff{Initial: 300, Max: 300}
+	p.c.HTTPTransfer.Zones.Secondary = map[string]TConfigZone{"example.org": config}
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		zone    string
+		initial int
+	}
+
+	var Tests = []TTest{
+		{"8a1b2c3d-4e5f-4a6b-9c7d-8e9f0a1b2c3d", true, "example.net", 10},
+		{"9b2c3d4e-5f6a-4b7c-8d8e-9f0a1b2c3d4e", true, "example.org", 300},
+	}
+
+	zones := NewZonesState(p)
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		if backoff := zones.PrimaryBackoff(test.zone); backoff.Initial != test.initial {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("backoff expected:'%d' got:'%d'",
+				test.initial, backoff.Initial))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
+
+func TestPrimaryError(t *testing.T) {
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		err     error
+		primary bool
+	}
+
+	var Tests = []TTest{
+		{"0c3d4e5f-6a7b-4c8d-9e9f-0a1b2c3d4e5f", true, PrimaryError(fmt.Errorf("connection refused")), true},
+		{"1d4e5f6a-7b8c-4d9e-8f0a-1b2c3d4e5f6a", true,
+			fmt.Errorf("zone:'example.net', err:'%w'", PrimaryError(fmt.Errorf("timeout"))), true},
+		{"2e5f6a7b-8c9d-4e0f-9a1b-2c3d4e5f6a7b", true, ErrZoneQuarantined, false},
+		{"3f6a7b8c-9d0e-4f1a-8b2c-3d4e5f6a7b8c", true, PrimaryError(nil), false},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		if IsPrimaryError(test.err) != test.primary {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("primary error expected:'%t' err:'%v'",
+				test.primary, test.err))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
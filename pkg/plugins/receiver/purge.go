@@ -0,0 +1,369 @@
+package receiver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// zones removed from configuration (or disabled) are detected
+// via snapshots on disk: snapshot of zone not configured is an
+// orphan. As orphan is detected for longer than grace period
+// its records are purged from maps and zone snapshot (with its
+// generations) is moved into archive directory. Zone returned
+// into configuration within grace period is kept untouched
+
+const (
+	// interval to check orphans snapshots
+	DefaultPurgeInterval = 60 * time.Second
+
+	// default grace period in seconds
+	DefaultPurgeGrace = 3600
+
+	// archive directory in snapshots directory
+	DefaultPurgeArchive = "archive"
+
+	// file of orphans detected in snapshots directory
+	DefaultOrphansFilename = "yadns-xdp.orphans"
+
+	// number of orphans zones pending purge
+	MetricOrphans = "receiver-orphans"
+
+	// number of orphans zones purged
+	MetricOrphansPurged = "receiver-orphans-purged"
+)
+
+type TConfigPurge struct {
+	// purge of zones removed could be disabled
+	Enabled bool `json:"enabled" yaml:"enabled"`
+
+	// seconds zone should be absent in configuration
+	// before its records are purged
+	Grace int `json:"grace" yaml:"grace"`
+
+	// directory to archive snapshots of zones purged,
+	// "archive" in snapshots directory if empty
+	Archive string `json:"archive" yaml:"archive"`
+}
+
+// zone snapshot found on disk with zone absent in
+// configuration
+type TOrphanZone struct {
+	Zone     string `json:"zone"`
+	Snapshot string `json:"snapshot"`
+	Serial   uint32 `json:"serial"`
+	Records  int    `json:"records"`
+
+	// time orphan is detected first
+	Detected time.Time `json:"detected"`
+}
+
+type OrphansStore struct {
+	p *TReceiverPlugin
+
+	lock sync.Mutex
+
+	orphans map[string]TOrphanZone
+}
+
+func NewOrphansStore(p *TReceiverPlugin) *OrphansStore {
+	var s OrphansStore
+	s.p = p
+	s.orphans = make(map[string]TOrphanZone)
+	return &s
+}
+
+func (s *OrphansStore) filename() string {
+	path := s.p.L().Options.Snapshots.Directory
+	if len(path) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s", path, DefaultOrphansFilename)
+}
+
+func (s *OrphansStore) Load() error {
+	id := "(purge) (load)"
+
+	filename := s.filename()
+	if len(filename) == 0 || !Exists(filename) {
+		return nil
+	}
+
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var list []TOrphanZone
+	if err = json.Unmarshal(content, &list); err != nil {
+		s.p.G().L.Errorf("%s error parsing orphans:'%s', err:'%s'", id, filename, err)
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for _, orphan := range list {
+		s.orphans[orphan.Zone] = orphan
+	}
+	s.p.G().L.Debugf("%s orphans:'%s' loaded:'%d'", id, filename, len(list))
+
+	return nil
+}
+
+// saving orphans, should be called with lock held
+func (s *OrphansStore) save() error {
+	filename := s.filename()
+	if len(filename) == 0 {
+		return nil
+	}
+
+	list := s.list()
+	content, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(filename, content, 0644)
+}
+
+func (s *OrphansStore) list() []TOrphanZone {
+	list := []TOrphanZone{}
+	for _, orphan := range s.orphans {
+		list = append(list, orphan)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Zone < list[j].Zone
+	})
+	return list
+}
+
+func (s *OrphansStore) List() []TOrphanZone {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.list()
+}
+
+// Replacing orphans with ones detected, time of detection
+// is kept for orphans known
+func (s *OrphansStore) Update(detected []TOrphanZone) ([]TOrphanZone, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	orphans := make(map[string]TOrphanZone)
+	for _, orphan := range detected {
+		if known, ok := s.orphans[orphan.Zone]; ok {
+			orphan.Detected = known.Detected
+		}
+		orphans[orphan.Zone] = orphan
+	}
+	s.orphans = orphans
+
+	return s.list(), s.save()
+}
+
+func (s *OrphansStore) Remove(zone string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.orphans, zone)
+	return s.save()
+}
+
+// Detecting snapshots of zones not configured (and absent
+// in zones state), generations are skipped and zone of plain
+// text snapshots (having no header) is taken from SOA owner
+func (z *ZonesState) DetectOrphans(now time.Time) ([]TOrphanZone, error) {
+	id := "(purge) (detect)"
+
+	path := z.p.L().Options.Snapshots.Directory
+	suffix := fmt.Sprintf(".%s", DefaultSnapshotSuffix)
+
+	files, err := filepath.Glob(fmt.Sprintf("%s/*%s", path, suffix))
+	if err != nil {
+		return nil, err
+	}
+
+	configs := z.GetZonesConfigs()
+
+	var out []TOrphanZone
+	for _, filename := range files {
+		name := strings.TrimSuffix(filepath.Base(filename), suffix)
+		if strings.Contains(name, ".") {
+			continue
+		}
+
+		header, err := ReadSnapshotHeader(filename)
+		if err == nil && header == nil {
+			header, err = ReadPlainSnapshotHeader(filename)
+		}
+		if err != nil || header == nil {
+			z.p.G().L.Debugf("%s skipping snapshot:'%s' with no zone detected, err:'%v'", id,
+				filename, err)
+			continue
+		}
+
+		if config, ok := configs[header.Zone]; ok && config.Enabled {
+			continue
+		}
+		if z.GetLastZoneSnapshot(header.Zone) != nil {
+			continue
+		}
+
+		out = append(out, TOrphanZone{Zone: header.Zone, Snapshot: filename,
+			Serial: header.Serial, Records: header.Records, Detected: now})
+	}
+
+	return out, nil
+}
+
+func (z *ZonesState) ArchiveDirectory() string {
+	if archive := z.p.L().Cooker.Snapshots.Purge.Archive; len(archive) > 0 {
+		return archive
+	}
+	return fmt.Sprintf("%s/%s", z.p.L().Options.Snapshots.Directory, DefaultPurgeArchive)
+}
+
+// Moving zone snapshot and its generations into archive
+// directory "<archive>/<zone>.<unix time>"
+func (z *ZonesState) ArchiveSnapshots(zone string, now time.Time) (string, error) {
+	path := z.p.L().Options.Snapshots.Directory
+
+	files, err := filepath.Glob(fmt.Sprintf("%s/%s.*%s", path, Md5(zone),
+		DefaultSnapshotSuffix))
+	if err != nil {
+		return "", err
+	}
+
+	archive := fmt.Sprintf("%s/%s.%d", z.ArchiveDirectory(), zone, now.Unix())
+	if err = os.MkdirAll(archive, 0755); err != nil {
+		return "", err
+	}
+
+	for _, filename := range files {
+		if err = os.Rename(filename, filepath.Join(archive, filepath.Base(filename))); err != nil {
+			return archive, err
+		}
+	}
+
+	return archive, nil
+}
+
+// Purging orphan zone: records of zone snapshot are removed
+// from maps (entries owned by others are kept) and snapshots
+// are archived
+func (z *ZonesState) PurgeOrphan(orphan *TOrphanZone, now time.Time) (*TSyncMapResult, error) {
+	id := "(purge) (orphan)"
+
+	dryrun := z.p.L().Cooker.Dryrun
+
+	snapshot, err := ReadSnapshotFile(z.p, orphan.Snapshot, orphan.Zone)
+	if err != nil {
+		z.p.G().L.Errorf("%s error reading snapshot:'%s' zone:'%s', err:'%s'", id,
+			orphan.Snapshot, orphan.Zone, err)
+		return nil, err
+	}
+
+	lock := z.ZoneLock(orphan.Zone)
+	lock.Lock()
+	defer lock.Unlock()
+
+	r, err := snapshot.PurgeMap(dryrun)
+	if err != nil {
+		z.p.G().L.Errorf("%s error purging zone:'%s' records, err:'%s'", id, orphan.Zone, err)
+		return nil, err
+	}
+
+	z.p.G().L.Infof("%s zone:'%s' serial:'%d' detected:'%s' purged records %s dryrun:'%t'",
+		id, orphan.Zone, orphan.Serial, TimeAsString(orphan.Detected), r.AsString(), dryrun)
+
+	if dryrun {
+		return r, nil
+	}
+
+	archive, err := z.ArchiveSnapshots(orphan.Zone, now)
+	if err != nil {
+		z.p.G().L.Errorf("%s error archiving zone:'%s' snapshots, err:'%s'", id,
+			orphan.Zone, err)
+		return r, err
+	}
+	z.p.G().L.Infof("%s zone:'%s' snapshots archived into '%s'", id, orphan.Zone, archive)
+
+	// pin of zone (if any) is not actual
+	if _, err := z.pins.Remove(orphan.Zone); err == nil {
+		z.p.G().L.Debugf("%s zone:'%s' unpinned", id, orphan.Zone)
+	}
+
+	return r, z.orphans.Remove(orphan.Zone)
+}
+
+// Checking orphans: detecting them and purging ones
+// absent in configuration longer than grace period
+func (z *ZonesState) CheckOrphans(now time.Time) error {
+	id := "(purge) (check)"
+
+	grace := z.p.L().Cooker.Snapshots.Purge.Grace
+	if grace <= 0 {
+		grace = DefaultPurgeGrace
+	}
+
+	detected, err := z.DetectOrphans(now)
+	if err != nil {
+		z.p.G().L.Errorf("%s error detecting orphans, err:'%s'", id, err)
+		return err
+	}
+
+	orphans, err := z.orphans.Update(detected)
+	if err != nil {
+		z.p.G().L.Errorf("%s error saving orphans, err:'%s'", id, err)
+	}
+
+	purged := 0
+	for _, orphan := range orphans {
+		deadline := orphan.Detected.Add(time.Duration(grace) * time.Second)
+		if now.Before(deadline) {
+			z.p.G().L.Debugf("%s zone:'%s' orphan is purged after '%s'", id, orphan.Zone,
+				TimeAsString(deadline))
+			continue
+		}
+
+		if _, err := z.PurgeOrphan(&orphan, now); err != nil {
+			continue
+		}
+		purged++
+	}
+
+	z.p.PushMetric(MetricOrphans, nil, float64(len(orphans)-purged))
+	z.p.PushMetric(MetricOrphansPurged, nil, float64(purged))
+
+	return nil
+}
+
+// Checking orphans periodically
+func (z *ZonesState) RunPurge(ctx context.Context) error {
+	id := "(purge) (worker)"
+
+	if err := z.orphans.Load(); err != nil {
+		z.p.G().L.Errorf("%s error loading orphans, err:'%s'", id, err)
+	}
+
+	timer := time.NewTicker(DefaultPurgeInterval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			z.CheckOrphans(time.Now())
+		case <-ctx.Done():
+			z.p.G().L.Debugf("%s context stop on purge", id)
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package receiver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOrphans(t *testing.T) {
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+	p.c.Options.Snapshots.Directory = t.TempDir()
+	p.c.Cooker.Snapshots.Keep = 3
+	p.c.Cooker.Snapshots.Purge = TConfigPurge{Enabled: true, Grace: 60}
+	p.c.AxfrTransfer.Enabled = true
+	p.c.AxfrTransfer.Zones.Secondary = map[string]TConfigZone{
+		"example.net": CreateDefaultConfigZone([]string{"[::1]:53"}),
+	}
+	p.zones = NewZonesState(p)
+
+	snapshot, err := newGuardsSnapshot(p, 2024010101, true, guardsHosts(3, 0))
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making snapshot, err:'%s'", err))
+		return
+	}
+	if err = snapshot.WriteSnapshotZone(false); err != nil {
+		t.Error(fmt.Sprintf("Error writing snapshot, err:'%s'", err))
+		return
+	}
+
+	now := time.Now()
+
+	uuid := "8b9c0d1e-2f3a-4b4c-9d5e-7f8a9b0c1d2e"
+	orphans, err := p.zones.DetectOrphans(now)
+	if err != nil || len(orphans) != 0 {
+		t.Error("\nUUID", uuid, fmt.Sprintf("unexpected orphans of zone configured:'%v' err:'%v'",
+			orphans, err))
+		return
+	}
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+
+	// zone is removed from configuration
+	p.c.AxfrTransfer.Zones.Secondary = map[string]TConfigZone{}
+
+	uuid = "9c0d1e2f-3a4b-4c5d-8e6f-8a9b0c1d2e3f"
+	if err = p.zones.CheckOrphans(now); err != nil {
+		t.Error("\nUUID", uuid, fmt.Sprintf("error checking orphans, err:'%s'", err))
+		return
+	}
+	orphans = p.zones.orphans.List()
+	if len(orphans) != 1 || orphans[0].Zone != "example.net" || orphans[0].Records == 0 {
+		t.Error("\nUUID", uuid, fmt.Sprintf("unexpected orphans:'%v'", orphans))
+		return
+	}
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+
+	// orphan detection time is kept between checks and
+	// restarts, snapshot is kept within grace period
+	uuid = "0d1e2f3a-4b5c-4d6e-9f7a-9b0c1d2e3f4a"
+	store := NewOrphansStore(p)
+	if err = store.Load(); err != nil {
+		t.Error("\nUUID", uuid, fmt.Sprintf("error loading orphans, err:'%s'", err))
+		return
+	}
+	p.zones.orphans = store
+	if err = p.zones.CheckOrphans(now.Add(30 * time.Second)); err != nil {
+		t.Error("\nUUID", uuid, fmt.Sprintf("error checking orphans, err:'%s'", err))
+		return
+	}
+	orphans = store.List()
+	if len(orphans) != 1 || !orphans[0].Detected.Equal(now) ||
+		!Exists(GetSnapshotFilename(p, "example.net")) {
+		t.Error("\nUUID", uuid, fmt.Sprintf("unexpected orphans:'%v'", orphans))
+		return
+	}
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+
+	// snapshots are archived
+	uuid = "1e2f3a4b-5c6d-4e7f-8a8b-0c1d2e3f4a5b"
+	archive, err := p.zones.ArchiveSnapshots("example.net", now)
+	if err != nil || Exists(GetSnapshotFilename(p, "example.net")) ||
+		!Exists(filepath.Join(archive, filepath.Base(GetSnapshotFilename(p, "example.net")))) {
+		t.Error("\nUUID", uuid, fmt.Sprintf("snapshot is not archived into:'%s' err:'%v'",
+			archive, err))
+		return
+	}
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+
+	// zone returned into configuration is not orphan
+	uuid = "2f3a4b5c-6d7e-4f8a-9b9c-1d2e3f4a5b6c"
+	if err = snapshot.WriteSnapshotZone(false); err != nil {
+		t.Error("\nUUID", uuid, fmt.Sprintf("error writing snapshot, err:'%s'", err))
+		return
+	}
+	p.c.AxfrTransfer.Zones.Secondary = map[string]TConfigZone{
+		"example.net": CreateDefaultConfigZone([]string{"[::1]:53"}),
+	}
+	if err = p.zones.CheckOrphans(now.Add(time.Hour)); err != nil || len(store.List()) != 0 {
+		t.Error("\nUUID", uuid, fmt.Sprintf("unexpected orphans:'%v' err:'%v'", store.List(), err))
+		return
+	}
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+
+	// plain text snapshots (written before containers) are
+	// detected via SOA owner, owner not of filename is skipped
+	uuid = "3a4b5c6d-7e8f-4a9b-8c0d-2e3f4a5b6c7d"
+	soa := "legacy.net. 3600 IN SOA ns.legacy.net. hostmaster.legacy.net. 7 3600 600 86400 60"
+	plain := fmt.Sprintf("%s\nwww.legacy.net. 300 IN A 192.0.2.1\n%s\n", soa, soa)
+	if err = os.WriteFile(GetSnapshotFilename(p, "legacy.net"), []byte(plain), 0644); err != nil {
+		t.Error("\nUUID", uuid, fmt.Sprintf("error writing snapshot, err:'%s'", err))
+		return
+	}
+	if err = os.WriteFile(GetSnapshotFilename(p, "other.net"), []byte(plain), 0644); err != nil {
+		t.Error("\nUUID", uuid, fmt.Sprintf("error writing snapshot, err:'%s'", err))
+		return
+	}
+	orphans, err = p.zones.DetectOrphans(now)
+	if err != nil || len(orphans) != 1 || orphans[0].Zone != "legacy.net" ||
+		orphans[0].Serial != 7 || orphans[0].Records != 3 {
+		t.Error("\nUUID", uuid, fmt.Sprintf("unexpected orphans:'%v' err:'%v'", orphans, err))
+		return
+	}
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+}
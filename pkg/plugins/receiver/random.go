@@ -0,0 +1,301 @@
+package receiver
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// random zone source generates seeded synthetic A/AAAA
+// records for load and capacity testing: generation N of
+// zone is always the same for the same seed and options,
+// each refresh makes the next generation with some churn
+// (adds, deletes and changes) to exercise IXFR-like diffs
+
+const (
+	// default random zone settings
+	DefaultRandomCount         = 1000
+	DefaultRandomNameLengthMin = 8
+	DefaultRandomNameLengthMax = 16
+	DefaultRandomTTLMin        = 60
+	DefaultRandomTTLMax        = 3600
+
+	// name length distributions
+	RandomDistributionUniform = "uniform"
+	RandomDistributionNormal  = "normal"
+)
+
+type TRandomRange struct {
+	Min int `json:"min" yaml:"min"`
+	Max int `json:"max" yaml:"max"`
+}
+
+type TRandomNameLength struct {
+	TRandomRange `yaml:",inline"`
+
+	// distribution of label length: uniform or
+	// normal (centered in range)
+	Distribution string `json:"distribution" yaml:"distribution"`
+}
+
+type TRandomChurn struct {
+	// fractions of records count added, deleted and
+	// changed per refresh (generation)
+	Adds    float64 `json:"adds" yaml:"adds"`
+	Deletes float64 `json:"deletes" yaml:"deletes"`
+	Changes float64 `json:"changes" yaml:"changes"`
+}
+
+type TConfigRandom struct {
+	// seed of generator
+	Seed int64 `json:"seed" yaml:"seed"`
+
+	// number of names generated
+	Count int `json:"count" yaml:"count"`
+
+	// length of generated label
+	NameLength TRandomNameLength `json:"name-length" yaml:"name-length"`
+
+	// ttl range of records
+	TTL TRandomRange `json:"ttl" yaml:"ttl"`
+
+	// churn per refresh
+	Churn TRandomChurn `json:"churn" yaml:"churn"`
+
+	// fraction of names with more than one address
+	MultiAddress float64 `json:"multi-address" yaml:"multi-address"`
+
+	// fraction of AAAA names, the rest are A
+	AAAA float64 `json:"aaaa" yaml:"aaaa"`
+}
+
+type tRandomEntry struct {
+	label string
+	rtype uint16
+	ttl   uint32
+	addrs []net.IP
+}
+
+type RandomZone struct {
+	zone   string
+	config TConfigRandom
+
+	entries []*tRandomEntry
+	labels  map[string]bool
+}
+
+func NewRandomZone(zone string, config *TConfigRandom) *RandomZone {
+	var r RandomZone
+	r.zone = Dot(strings.ToLower(zone))
+
+	if config != nil {
+		r.config = *config
+	}
+
+	c := &r.config
+	if c.Count <= 0 {
+		c.Count = DefaultRandomCount
+	}
+	if c.NameLength.Min <= 0 {
+		c.NameLength.Min = DefaultRandomNameLengthMin
+	}
+	if c.NameLength.Max < c.NameLength.Min {
+		c.NameLength.Max = int(math.Max(float64(c.NameLength.Min), DefaultRandomNameLengthMax))
+	}
+	if c.TTL.Min <= 0 {
+		c.TTL.Min = DefaultRandomTTLMin
+	}
+	if c.TTL.Max < c.TTL.Min {
+		c.TTL.Max = int(math.Max(float64(c.TTL.Min), DefaultRandomTTLMax))
+	}
+
+	return &r
+}
+
+func (r *RandomZone) between(rng *rand.Rand, v TRandomRange) int {
+	return v.Min + rng.Intn(v.Max-v.Min+1)
+}
+
+func (r *RandomZone) labelLength(rng *rand.Rand) int {
+	v := r.config.NameLength
+	if v.Distribution != RandomDistributionNormal {
+		return r.between(rng, v.TRandomRange)
+	}
+
+	mean := float64(v.Min+v.Max) / 2
+	sd := float64(v.Max-v.Min) / 6
+	length := int(math.Round(rng.NormFloat64()*sd + mean))
+	return int(math.Min(float64(v.Max), math.Max(float64(v.Min), float64(length))))
+}
+
+// Generating unique label with letters and digits, the
+// first symbol is a letter
+func (r *RandomZone) label(rng *rand.Rand) string {
+	letters := "abcdefghijklmnopqrstuvwxyz"
+	symbols := "abcdefghijklmnopqrstuvwxyz0123456789"
+
+	for i := 0; ; i++ {
+		length := r.labelLength(rng)
+
+		var b strings.Builder
+		b.WriteByte(letters[rng.Intn(len(letters))])
+		for j := 1; j < length; j++ {
+			b.WriteByte(symbols[rng.Intn(len(symbols))])
+		}
+
+		label := b.String()
+
+		// short labels space could be exhausted
+		if i > 16 {
+			label = fmt.Sprintf("%s-%d", label, len(r.labels))
+		}
+
+		if !r.labels[label] {
+			r.labels[label] = true
+			return label
+		}
+	}
+}
+
+func (r *RandomZone) addresses(rng *rand.Rand, rtype uint16) []net.IP {
+	count := 1
+	if rng.Float64() < r.config.MultiAddress {
+		count = 2 + rng.Intn(3)
+	}
+
+	var out []net.IP
+	for i := 0; i < count; i++ {
+		var ip net.IP
+		if rtype == dns.TypeAAAA {
+			ip = make(net.IP, net.IPv6len)
+			ip[0] = 0xfd
+			for j := 1; j < net.IPv6len; j++ {
+				ip[j] = byte(rng.Intn(256))
+			}
+		} else {
+			ip = net.IPv4(10, byte(rng.Intn(256)), byte(rng.Intn(256)), byte(rng.Intn(256))).To4()
+		}
+		out = append(out, ip)
+	}
+
+	return out
+}
+
+func (r *RandomZone) entry(rng *rand.Rand) *tRandomEntry {
+	var e tRandomEntry
+	e.label = r.label(rng)
+	e.rtype = dns.TypeA
+	if rng.Float64() < r.config.AAAA {
+		e.rtype = dns.TypeAAAA
+	}
+	e.ttl = uint32(r.between(rng, r.config.TTL))
+	e.addrs = r.addresses(rng, e.rtype)
+	return &e
+}
+
+// Applying churn of one generation step: deleting, changing
+// addresses and adding records
+func (r *RandomZone) churn(rng *rand.Rand) {
+	count := float64(len(r.entries))
+
+	deletes := int(math.Round(count * r.config.Churn.Deletes))
+	for i := 0; i < deletes && len(r.entries) > 0; i++ {
+		k := rng.Intn(len(r.entries))
+		delete(r.labels, r.entries[k].label)
+		r.entries[k] = r.entries[len(r.entries)-1]
+		r.entries = r.entries[:len(r.entries)-1]
+	}
+
+	changes := int(math.Round(count * r.config.Churn.Changes))
+	for i := 0; i < changes && len(r.entries) > 0; i++ {
+		e := r.entries[rng.Intn(len(r.entries))]
+		e.addrs = r.addresses(rng, e.rtype)
+	}
+
+	adds := int(math.Round(count * r.config.Churn.Adds))
+	for i := 0; i < adds; i++ {
+		r.entries = append(r.entries, r.entry(rng))
+	}
+}
+
+// Generating zone records of generation (starting from 1)
+// as AXFR-like list with SOA serial set to generation
+func (r *RandomZone) Generate(generation uint32) []dns.RR {
+	if generation == 0 {
+		generation = 1
+	}
+
+	r.entries = nil
+	r.labels = make(map[string]bool)
+
+	rng := rand.New(rand.NewSource(r.config.Seed))
+	for i := 0; i < r.config.Count; i++ {
+		r.entries = append(r.entries, r.entry(rng))
+	}
+
+	for step := uint32(1); step < generation; step++ {
+		r.churn(rand.New(rand.NewSource(r.config.Seed + int64(step)*7919)))
+	}
+
+	soa := &dns.SOA{
+		Hdr:     dns.RR_Header{Name: r.zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: 3600},
+		Ns:      fmt.Sprintf("ns1.%s", r.zone),
+		Mbox:    fmt.Sprintf("hostmaster.%s", r.zone),
+		Serial:  generation,
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  300,
+	}
+
+	var records []dns.RR
+	for _, e := range r.entries {
+		name := fmt.Sprintf("%s.%s", e.label, r.zone)
+		header := dns.RR_Header{Name: name, Rrtype: e.rtype, Class: dns.ClassINET, Ttl: e.ttl}
+		for _, ip := range e.addrs {
+			if e.rtype == dns.TypeAAAA {
+				records = append(records, &dns.AAAA{Hdr: header, AAAA: ip})
+				continue
+			}
+			records = append(records, &dns.A{Hdr: header, A: ip})
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].String() < records[j].String()
+	})
+
+	out := []dns.RR{soa}
+	out = append(out, records...)
+	out = append(out, soa)
+
+	return out
+}
+
+// Getting next generation of random zone: the generation
+// is the next one after zone snapshot serial (if any)
+func (j *ImporterWorker) GetZoneSnapshotRandom(zone string,
+	options *TZoneSnapshotOptions) (*TSnapshotZone, error) {
+
+	id := "(importer) (random) (snapshot)"
+
+	generation := uint32(1)
+	if blob, err := NewSnapshotZoneFromSnapshot(j.p, zone); err == nil {
+		if serial, err := blob.Serial(); err == nil {
+			generation = serial + 1
+		}
+	}
+
+	random := NewRandomZone(zone, options.Random)
+	rr := random.Generate(generation)
+
+	j.p.G().L.Debugf("%s zone:'%s' seed:'%d' generation:'%d' rr:'%d'", id, zone,
+		random.config.Seed, generation, len(rr))
+
+	return NewSnapshotZoneFromRR(j.p, rr, zone)
+}
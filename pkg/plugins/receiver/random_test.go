@@ -0,0 +1,146 @@
+package receiver
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// grouping random zone records (without SOA) by name
+func RandomZoneNames(rr []dns.RR) map[string][]string {
+	out := make(map[string][]string)
+	for _, r := range rr {
+		if r.Header().Rrtype == dns.TypeSOA {
+			continue
+		}
+		name := r.Header().Name
+		out[name] = append(out[name], r.String())
+	}
+	return out
+}
+
+func TestRandomZoneGenerate(t *testing.T) {
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		config     TConfigRandom
+		generation uint32
+
+		// expected names count and names added, deleted
+		// and changed against previous generation
+		names   int
+		adds    int
+		deletes int
+		changes int
+	}
+
+	churn := TRandomChurn{Adds: 0.05, Deletes: 0.02, Changes: 0.03}
+
+	var Tests = []TTest{
+		{
+			"5e0a1b2c-3d4e-4f5a-8b6c-7d8e9f0a1b2c",
+			true,
+			TConfigRandom{Seed: 1, Count: 100},
+			1, 100, 0, 0, 0,
+		},
+		{
+			"6f1b2c3d-4e5f-4a6b-9c7d-8e9f0a1b2c3d",
+			true,
+			TConfigRandom{Seed: 2, Count: 1000, Churn: churn, AAAA: 0.5},
+			2, 1030, 50, 20, 30,
+		},
+		{
+			"7a2c3d4e-5f6a-4b7c-8d8e-9f0a1b2c3d4e",
+			true,
+			TConfigRandom{Seed: 3, Count: 200, Churn: churn, MultiAddress: 1},
+			5, 226, 11, 4, 7,
+		},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		random := NewRandomZone("example.net", &test.config)
+		rr := random.Generate(test.generation)
+
+		// generation should be the same for the same seed
+		again := NewRandomZone("example.net", &test.config).Generate(test.generation)
+		if len(rr) != len(again) {
+			t.Error("\nUUID", test.uuid, "generation is not deterministic")
+			continue
+		}
+		for i := range rr {
+			if rr[i].String() != again[i].String() {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("generation is not deterministic '%s' != '%s'",
+					rr[i].String(), again[i].String()))
+				break
+			}
+		}
+
+		soa, ok := rr[0].(*dns.SOA)
+		if !ok || soa.Serial != test.generation || rr[len(rr)-1].Header().Rrtype != dns.TypeSOA {
+			t.Error("\nUUID", test.uuid, "SOA with generation serial expected")
+			continue
+		}
+
+		names := RandomZoneNames(rr)
+		if len(names) != test.names {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("names expected:'%d' got:'%d'",
+				test.names, len(names)))
+			continue
+		}
+
+		failed := false
+		for name, records := range names {
+			label := strings.TrimSuffix(name, ".example.net.")
+			if len(label) < DefaultRandomNameLengthMin || len(label) > DefaultRandomNameLengthMax {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("name:'%s' length is out of range", name))
+				failed = true
+				break
+			}
+			if test.config.MultiAddress == 1 && len(records) < 2 {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("name:'%s' expected multi address", name))
+				failed = true
+				break
+			}
+		}
+		if failed {
+			continue
+		}
+
+		if test.generation > 1 {
+			previous := RandomZoneNames(NewRandomZone("example.net", &test.config).Generate(test.generation - 1))
+
+			adds, deletes, changes := 0, 0, 0
+			for name, records := range names {
+				if _, ok := previous[name]; !ok {
+					adds++
+					continue
+				}
+				if strings.Join(records, ",") != strings.Join(previous[name], ",") {
+					changes++
+				}
+			}
+			for name := range previous {
+				if _, ok := names[name]; !ok {
+					deletes++
+				}
+			}
+
+			// changes could hit the same name twice
+			if adds != test.adds || deletes != test.deletes || changes > test.changes || changes == 0 {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("churn expected adds:'%d' deletes:'%d' changes:'%d' got:'%d' '%d' '%d'",
+					test.adds, test.deletes, test.changes, adds, deletes, changes))
+				continue
+			}
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
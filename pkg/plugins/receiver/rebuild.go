@@ -0,0 +1,302 @@
+package receiver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// zones states could be rebuilt on startup from pinned maps
+// (still served by XDP) if snapshots are lost or stale: maps
+// records are partitioned into configured zones by suffix and
+// compared with zone snapshot (current one even if it is too
+// old or generations in order). Snapshot matching maps records
+// is the last known state of zone: it is placed into memory and
+// rewritten, so IXFR continues from its serial. Zones having no
+// snapshot matching maps (e.g. snapshots directory is lost) get
+// no state, as maps keep no SOA to continue from: they are
+// transferred as usual (AXFR) and the transfer is synced with
+// records kept in maps w.r.t ownership (see SyncOwnedMap)
+
+const (
+	// zones rebuilt from maps as recoverable (or not)
+	MetricRebuildZones = "receiver-rebuild-zones"
+
+	RebuildSourceSnapshot   = "snapshot"
+	RebuildSourceGeneration = "generation"
+)
+
+type TRebuildZone struct {
+	Zone string `json:"zone"`
+
+	// records of zone found in maps
+	Records int `json:"records"`
+
+	// zone state is recovered with serial of snapshot
+	// (or generation) matching maps records
+	Recoverable bool   `json:"recoverable"`
+	Serial      uint32 `json:"serial,omitempty"`
+	Source      string `json:"source,omitempty"`
+
+	Reason string `json:"reason,omitempty"`
+}
+
+type TRebuildResult struct {
+	Zones []TRebuildZone `json:"zones"`
+
+	// records of maps not matching any zone configured
+	Unmatched int `json:"unmatched"`
+}
+
+func (t *TRebuildResult) AsString() string {
+	recovered := 0
+	for _, zone := range t.Zones {
+		if zone.Recoverable {
+			recovered++
+		}
+	}
+
+	var out []string
+	out = append(out, fmt.Sprintf("zones:'%d'", len(t.Zones)))
+	out = append(out, fmt.Sprintf("recovered:'%d'", recovered))
+	out = append(out, fmt.Sprintf("unmatched:'%d'", t.Unmatched))
+	return strings.Join(out, ",")
+}
+
+// Partitioning records into zones by suffix, the longest
+// zone matched is used (as zones could be nested), records
+// not matching any zone are returned separately
+func PartitionRR(rrs []dns.RR, zones []string) (map[string]map[string]dns.RR, []dns.RR) {
+	sorted := append([]string{}, zones...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if len(sorted[i]) != len(sorted[j]) {
+			return len(sorted[i]) > len(sorted[j])
+		}
+		return sorted[i] < sorted[j]
+	})
+
+	out := make(map[string]map[string]dns.RR)
+	for _, zone := range zones {
+		out[zone] = make(map[string]dns.RR)
+	}
+
+	var unmatched []dns.RR
+	for _, rr := range rrs {
+		matched := false
+		for _, zone := range sorted {
+			if dns.IsSubDomain(Dot(zone), rr.Header().Name) {
+				out[zone][RecordKey(rr)] = rr
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			unmatched = append(unmatched, rr)
+		}
+	}
+
+	return out, unmatched
+}
+
+// Comparing records of snapshot placed into maps with ones
+// found in maps, keys overridden or created via objects api
+// are skipped
+func (z *ZonesState) MatchMapsRecords(snapshot *TSnapshotZone, records map[string]dns.RR) error {
+	skipped := make(map[string]bool)
+	for k := range z.p.Overrides().Records() {
+		skipped[k] = true
+	}
+	for _, k := range z.owners.Keys(OwnerManual) {
+		skipped[k] = true
+	}
+
+	expected := make(map[string]dns.RR)
+	for _, rr := range OwnedRecords(snapshot.rrsets) {
+		if t := rr.Header().Rrtype; t != dns.TypeA && t != dns.TypeAAAA {
+			continue
+		}
+		expected[RecordKey(rr)] = rr
+	}
+
+	missed, differ, unexpected := 0, 0, 0
+	for k, rr := range expected {
+		if skipped[k] {
+			continue
+		}
+		found, ok := records[k]
+		switch {
+		case !ok:
+			missed++
+		case !dns.IsDuplicate(rr, found) || rr.Header().Ttl != found.Header().Ttl:
+			differ++
+		}
+	}
+	for k := range records {
+		if _, ok := expected[k]; !ok && !skipped[k] {
+			unexpected++
+		}
+	}
+
+	if missed+differ+unexpected > 0 {
+		return fmt.Errorf("maps records missed:'%d' differ:'%d' unexpected:'%d'",
+			missed, differ, unexpected)
+	}
+	return nil
+}
+
+// Finding the last known snapshot of zone matching maps
+// records: current snapshot (regardless of its age) and
+// generations from the newest one
+func (z *ZonesState) RecoverZoneSnapshot(zone string,
+	records map[string]dns.RR) (*TSnapshotZone, *TRebuildZone) {
+
+	id := "(rebuild) (zone)"
+
+	out := &TRebuildZone{Zone: zone, Records: len(records), Reason: "no snapshot found"}
+
+	type candidate struct {
+		source   string
+		filename string
+	}
+
+	candidates := []candidate{{RebuildSourceSnapshot, GetSnapshotFilename(z.p, zone)}}
+	generations, _ := ListGenerations(z.p, zone)
+	for _, g := range generations {
+		candidates = append(candidates, candidate{RebuildSourceGeneration,
+			GetGenerationFilename(z.p, zone, g.Generation)})
+	}
+
+	for _, c := range candidates {
+		if !Exists(c.filename) {
+			continue
+		}
+
+		snapshot, err := ReadSnapshotFile(z.p, c.filename, zone)
+		if err != nil {
+			z.p.G().L.Debugf("%s zone:'%s' error reading %s:'%s', err:'%s'", id, zone,
+				c.source, c.filename, err)
+			out.Reason = err.Error()
+			continue
+		}
+
+		serial, err := snapshot.Serial()
+		if err != nil {
+			out.Reason = err.Error()
+			continue
+		}
+
+		if err = z.MatchMapsRecords(snapshot, records); err != nil {
+			z.p.G().L.Debugf("%s zone:'%s' %s:'%s' serial:'%d' does not match maps, err:'%s'",
+				id, zone, c.source, c.filename, serial, err)
+			out.Reason = fmt.Sprintf("%s serial:'%d' %s", c.source, serial, err)
+			continue
+		}
+
+		out.Recoverable = true
+		out.Serial = serial
+		out.Source = c.source
+		out.Reason = ""
+
+		return snapshot, out
+	}
+
+	return nil, out
+}
+
+// Rebuilding zones states from pinned maps, zones having
+// snapshot valid (or pinned, or already in memory) are
+// processed as usual
+func (z *ZonesState) RebuildFromMaps() (*TRebuildResult, error) {
+	id := "(rebuild) (maps)"
+
+	var result TRebuildResult
+
+	var zones []string
+	configs := z.GetZonesConfigs()
+	for zone, config := range configs {
+		if config.Enabled {
+			zones = append(zones, zone)
+		}
+	}
+	sort.Strings(zones)
+
+	rrs, err := NewObjects(z.p).ListRR()
+	if err != nil {
+		z.p.G().L.Errorf("%s error listing maps, err:'%s'", id, err)
+		return nil, err
+	}
+
+	partitions, unmatched := PartitionRR(rrs, zones)
+	result.Unmatched = len(unmatched)
+
+	for _, zone := range zones {
+		filename := GetSnapshotFilename(z.p, zone)
+		if Exists(filename) && ValidateSnapshotZoneFile(z.p, filename, zone) {
+			continue
+		}
+		if z.GetPin(zone) != nil || z.GetLastZoneSnapshot(zone) != nil {
+			continue
+		}
+
+		snapshot, out := z.RecoverZoneSnapshot(zone, partitions[zone])
+		result.Zones = append(result.Zones, *out)
+
+		if snapshot == nil {
+			z.p.G().L.Infof("%s zone:'%s' records:'%d' could not be recovered, reason:'%s'",
+				id, zone, out.Records, out.Reason)
+			continue
+		}
+
+		z.RestoreZoneState(zone, snapshot)
+
+		// snapshot is rewritten as valid, so IXFR is
+		// requested from its serial
+		if err := snapshot.WriteSnapshotZone(false); err != nil {
+			z.p.G().L.Errorf("%s error writing zone:'%s' snapshot, err:'%s'", id, zone, err)
+		}
+
+		z.p.G().L.Infof("%s zone:'%s' records:'%d' recovered via %s serial:'%d'", id, zone,
+			out.Records, out.Source, out.Serial)
+	}
+
+	counts := make(map[bool]int)
+	for _, zone := range result.Zones {
+		counts[zone.Recoverable]++
+	}
+	for _, recoverable := range []bool{true, false} {
+		tags := []string{fmt.Sprintf("recoverable=%t", recoverable)}
+		z.p.PushMetric(MetricRebuildZones, tags, float64(counts[recoverable]))
+	}
+
+	z.p.G().L.Debugf("%s rebuilt %s", id, result.AsString())
+
+	return &result, nil
+}
+
+// Placing snapshot of zone into memory as its records
+// are in maps already (as on cold startup)
+func (z *ZonesState) RestoreZoneState(zone string, snapshot *TSnapshotZone) {
+	lock := z.ZoneLock(zone)
+	lock.Lock()
+	defer lock.Unlock()
+
+	var state TZoneState
+	state.Zone = zone
+	state.Config, _ = z.GetConfig(zone)
+	state.State = ZoneStateDirty
+	state.SnapshotCount = DefaultSnapshotCount
+	state.Snapshots = make(map[int]TSnapshotZone)
+
+	snapshot.imports = &TImportActions{
+		mode:    TransferModeNONE,
+		zone:    zone,
+		actions: nil,
+	}
+
+	state.SnapshotID = 0
+	state.Snapshots[state.SnapshotID] = *snapshot
+
+	z.SetState(zone, state)
+}
@@ -0,0 +1,213 @@
+package receiver
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestPartitionRR(t *testing.T) {
+	var rrs []dns.RR
+	for _, raw := range []string{
+		"www.example.net. 300 IN A 192.0.2.1",
+		"example.net. 300 IN AAAA 2001:db8::1",
+		"www.sub.example.net. 300 IN A 192.0.2.2",
+		"www.example.org. 300 IN A 192.0.2.3",
+	} {
+		rr, _ := dns.NewRR(raw)
+		rrs = append(rrs, rr)
+	}
+
+	uuid := "5b6c7d8e-9f0a-4b1c-8d3e-4f5a6b7c8d9e"
+	partitions, unmatched := PartitionRR(rrs, []string{"example.net", "sub.example.net"})
+	if len(partitions["example.net"]) != 2 || len(partitions["sub.example.net"]) != 1 ||
+		len(unmatched) != 1 || unmatched[0].Header().Name != "www.example.org." {
+		t.Error("\nUUID", uuid, fmt.Sprintf("unexpected partitions:'%v' unmatched:'%v'",
+			partitions, unmatched))
+		return
+	}
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+}
+
+func TestRecoverZoneSnapshot(t *testing.T) {
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+	p.c.Options.Snapshots.Directory = t.TempDir()
+	p.c.Cooker.Snapshots.Keep = 3
+	p.c.AxfrTransfer.Enabled = true
+	p.c.AxfrTransfer.Zones.Secondary = map[string]TConfigZone{
+		"example.net": CreateDefaultConfigZone([]string{"[::1]:53"}),
+	}
+	p.zones = NewZonesState(p)
+
+	// maps keep records of serial 1, snapshot of serial 2
+	// is written but not applied
+	records := make(map[string]dns.RR)
+	for _, serial := range []uint32{2024010101, 2024010102} {
+		changed := 0
+		if serial == 2024010102 {
+			changed = 1
+		}
+		snapshot, err := newGuardsSnapshot(p, serial, true, guardsHosts(3, changed))
+		if err != nil {
+			t.Error(fmt.Sprintf("Error making snapshot, err:'%s'", err))
+			return
+		}
+		if err = snapshot.WriteSnapshotZone(false); err != nil {
+			t.Error(fmt.Sprintf("Error writing snapshot, err:'%s'", err))
+			return
+		}
+		if changed == 0 {
+			for _, rr := range OwnedRecords(snapshot.rrsets) {
+				records[RecordKey(rr)] = rr
+			}
+		}
+	}
+
+	type TTest struct {
+		uuid        string
+		enabled     bool
+		records     map[string]dns.RR
+		recoverable bool
+		serial      uint32
+		source      string
+	}
+
+	unexpected := make(map[string]dns.RR)
+	for k, rr := range records {
+		unexpected[k] = rr
+	}
+	extra, _ := dns.NewRR("extra.example.net. 300 IN A 192.0.2.100")
+	unexpected[RecordKey(extra)] = extra
+
+	tests := []TTest{
+		{
+			uuid:        "6c7d8e9f-0a1b-4c2d-9e4f-5a6b7c8d9e0f",
+			enabled:     true,
+			records:     records,
+			recoverable: true,
+			serial:      2024010101,
+			source:      RebuildSourceGeneration,
+		},
+		// no snapshot matches, zone is transferred
+		{
+			uuid:        "7d8e9f0a-1b2c-4d3e-8f5a-6b7c8d9e0f1a",
+			enabled:     true,
+			records:     unexpected,
+			recoverable: false,
+		},
+		{
+			uuid:        "9f0a1b2c-3d4e-4f5a-8b7c-8d9e0f1a2b3c",
+			enabled:     true,
+			records:     map[string]dns.RR{},
+			recoverable: false,
+		},
+	}
+
+	for _, test := range tests {
+		if !test.enabled {
+			continue
+		}
+
+		snapshot, out := p.zones.RecoverZoneSnapshot("example.net", test.records)
+		if out.Recoverable != test.recoverable || (snapshot != nil) != test.recoverable ||
+			out.Serial != test.serial || out.Source != test.source {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("unexpected rebuild:'%+v'", out))
+			return
+		}
+
+		if snapshot != nil {
+			p.zones.RestoreZoneState("example.net", snapshot)
+			last := p.zones.GetLastZoneSnapshot("example.net")
+			if last == nil {
+				t.Error("\nUUID", test.uuid, "zone state is not restored")
+				return
+			}
+			if serial, _ := last.Serial(); serial != test.serial {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("unexpected serial:'%d'", serial))
+				return
+			}
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
+
+func TestRebuildUpdate(t *testing.T) {
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+	p.c.Options.Snapshots.Directory = t.TempDir()
+	p.c.AxfrTransfer.Enabled = true
+	p.c.AxfrTransfer.Zones.Secondary = map[string]TConfigZone{
+		"example.net": CreateDefaultConfigZone([]string{"[::1]:53"}),
+	}
+	p.zones = NewZonesState(p)
+
+	pool := NewCollectorTransferPool(p, &CollectorTransferOptions{Count: 4})
+	pool.zones = p.zones
+
+	// snapshots directory is lost, maps keep records only
+	records := make(map[string]dns.RR)
+	rr, _ := dns.NewRR("www.example.net. 300 IN A 192.0.2.1")
+	records[RecordKey(rr)] = rr
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		// zone state restored without SOA
+		restored bool
+	}
+
+	tests := []TTest{
+		{"a01b2c3d-4e5f-4a6b-8c7d-9e0f1a2b3c4d", true, false},
+		{"b12c3d4e-5f6a-4b7c-9d8e-0f1a2b3c4d5e", true, true},
+	}
+
+	for _, test := range tests {
+		if !test.enabled {
+			continue
+		}
+
+		p.zones.DeleteState("example.net")
+		snapshot, out := p.zones.RecoverZoneSnapshot("example.net", records)
+		if snapshot != nil || out.Recoverable {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("unexpected rebuild:'%+v'", out))
+			return
+		}
+
+		if test.restored {
+			var snapshot TSnapshotZone
+			snapshot.p = p
+			snapshot.zone = "example.net"
+			snapshot.rrsets = map[string][]dns.RR{RecordKey(rr): {rr}}
+			p.zones.RestoreZoneState("example.net", &snapshot)
+		}
+
+		if err = p.zones.Update(pool); err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error updating zones, err:'%s'", err))
+			return
+		}
+
+		select {
+		case job := <-pool.jobs:
+			if job.Zone != "example.net" || job.Options.Mode != TransferModeAXFR {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("unexpected job zone:'%s' mode:'%s'",
+					job.Zone, TransferModeAsString(job.Options.Mode)))
+				return
+			}
+		default:
+			t.Error("\nUUID", test.uuid, "no transfer job requested")
+			return
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
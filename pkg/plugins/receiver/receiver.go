@@ -79,7 +79,90 @@ func (t *TReceiverPlugin) Run(ctx context.Context, overrides *plugins.OverrideOp
 
 	t.zones = NewZonesState(t)
 
+	// zones added in runtime via api are kept
+	// in overlay file
+	if err := t.zones.LoadOverlay(); err != nil {
+		t.G().L.Errorf("%s error loading zones overlay, err:'%s'", id, err)
+	}
+
+	// local records overriding zones data, expired
+	// ones are removed periodically
+	if err := t.zones.overrides.Load(); err != nil {
+		t.G().L.Errorf("%s error loading overrides, err:'%s'", id, err)
+	}
+
+	// zones pinned to snapshot generations are
+	// not updated till unpinned
+	if err := t.zones.pins.Load(); err != nil {
+		t.G().L.Errorf("%s error loading pins, err:'%s'", id, err)
+	}
+
+	// records created via objects api are owned
+	// and kept on zones sync
+	if err := t.zones.owners.Load(); err != nil {
+		t.G().L.Errorf("%s error loading owners, err:'%s'", id, err)
+	}
+
+	// batches of maps mutations unfinished on stop
+	// are replayed or rolled back before any sync
+	if err := t.zones.journal.Recover(); err != nil {
+		t.G().L.Errorf("%s error recovering journal, err:'%s'", id, err)
+	}
+
+	// zones definitions could be placed in zones directory
+	// of axfr or http transfer, reading and watching them
+	directory := NewZonesDirectoryWorker(t, t.zones)
+
 	transfer := t.L().AxfrTransfer
+	catalogs := NewCatalogWorker(t, t.zones)
+
+	// zones with snapshots lost or too old are rebuilt
+	// from maps to continue IXFR instead of AXFR, zones
+	// of directories and catalogs are loaded first as
+	// maps records are partitioned by all zones
+	if t.L().Options.Snapshots.RebuildOnStartup {
+		directory.Reload()
+		if transfer.Enabled {
+			catalogs.Refresh()
+		}
+		if _, err := t.zones.RebuildFromMaps(); err != nil {
+			t.G().L.Errorf("%s error rebuilding zones from maps, err:'%s'", id, err)
+		}
+	}
+
+	w.Go(func() error {
+		defer t.G().L.Debugf("%s overrides worker stopped", id)
+
+		return t.zones.RunOverrides(ctx)
+	})
+
+	// policy names are registered in offloader hits
+	// map and their hits are exported
+	w.Go(func() error {
+		defer t.G().L.Debugf("%s policy hits worker stopped", id)
+
+		return t.zones.RunPolicyHits(ctx)
+	})
+
+	// zones removed from configuration are detected
+	// via snapshots and purged after grace period
+	if t.L().Cooker.Snapshots.Purge.Enabled {
+		w.Go(func() error {
+			defer t.G().L.Debugf("%s purge worker stopped", id)
+
+			return t.zones.RunPurge(ctx)
+		})
+	}
+
+	// zones definitions of directories are watched
+	if len(directory.directories) > 0 {
+		w.Go(func() error {
+			defer t.G().L.Debugf("%s zones directory watcher stopped", id)
+
+			return directory.Run(ctx)
+		})
+	}
+
 	if transfer.Enabled {
 		context, cancel := context.WithCancel(ctx)
 		w.Go(func() error {
@@ -92,6 +175,14 @@ func (t *TReceiverPlugin) Run(ctx context.Context, overrides *plugins.OverrideOp
 			return t.TransferPoolRun(context)
 		})
 
+		// member zones could be defined in catalog
+		// zones, transferring and applying them
+		w.Go(func() error {
+			defer t.G().L.Debugf("%s catalogs worker stopped", id)
+
+			return catalogs.Run(ctx)
+		})
+
 		// periodic state update state for
 		// transfer zones
 		w.Go(func() error {
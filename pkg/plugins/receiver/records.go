@@ -0,0 +1,319 @@
+package receiver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"hash/crc32"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// records sources are not dns zones: "hosts" is /etc/hosts
+// style file and "json" is a list of records in JSON (or
+// YAML) format, both are fetched from file:// or http(s)://
+// and placed into synthetic zone. Content changes are detected
+// by content hash (or by records if hash is not known, e.g. on
+// restart) and SOA serial is time based (or incremented) to be
+// monotonic, so changes are applied as for other zones
+
+const (
+	// default ttl of records if not set
+	DefaultRecordsTTL = 300
+)
+
+type TRecordsEntry struct {
+	// name of record, relative names are relative
+	// to zone
+	Name string `json:"name" yaml:"name"`
+
+	// type of record, A (or AAAA) by default
+	// w.r.t addresses
+	Type string `json:"type" yaml:"type"`
+
+	TTL uint32 `json:"ttl" yaml:"ttl"`
+
+	// addresses (or record data for other types)
+	Addresses []string `json:"addresses" yaml:"addresses"`
+}
+
+// content digest of snapshot with serial
+type TContentDigest struct {
+	Serial uint32
+	Digest uint32
+}
+
+// Getting hash of records source content to detect changes,
+// it is not used as SOA serial as hash is not monotonic
+func ContentDigest(content []byte) uint32 {
+	return crc32.ChecksumIEEE(content)
+}
+
+// Getting SOA serial of records source changed: unix time
+// or previous serial incremented if time is not greater
+// (in serial arithmetic, e.g. changed within a second)
+func NextSerial(previous uint32, now time.Time) uint32 {
+	serial := uint32(now.Unix())
+	if previous != 0 && int32(serial-previous) <= 0 {
+		return previous + 1
+	}
+	return serial
+}
+
+// Getting content digest of zone snapshot of serial, digest
+// of other serial (e.g. snapshot rejected) is not known
+func (z *ZonesState) GetContentDigest(zone string, serial uint32) (uint32, bool) {
+	if z == nil {
+		return 0, false
+	}
+
+	z.lock.RLock()
+	defer z.lock.RUnlock()
+
+	d, ok := z.digests[zone]
+	if !ok || d.Serial != serial {
+		return 0, false
+	}
+	return d.Digest, true
+}
+
+func (z *ZonesState) SetContentDigest(zone string, serial uint32, digest uint32) {
+	if z == nil {
+		return
+	}
+
+	z.lock.Lock()
+	defer z.lock.Unlock()
+
+	z.digests[zone] = TContentDigest{Serial: serial, Digest: digest}
+}
+
+// Making fqdn of record name: names with trailing dot or
+// in zone are absolute, other are relative to zone
+func RecordName(zone string, name string) string {
+	origin := Dot(strings.ToLower(zone))
+	fqdn := Dot(strings.ToLower(name))
+	if strings.HasSuffix(name, ".") || dns.IsSubDomain(origin, fqdn) {
+		return fqdn
+	}
+	return fmt.Sprintf("%s.%s", strings.ToLower(name), origin)
+}
+
+func recordsAXFR(zone string, serial uint32, records []dns.RR) []dns.RR {
+	soa := NewSyntheticSOA(zone, serial)
+
+	out := []dns.RR{soa}
+	out = append(out, records...)
+	out = append(out, soa)
+	return out
+}
+
+// Parsing /etc/hosts style content "address name [aliases]"
+// with comments into AXFR-like list of records with SOA
+// serial given
+func ParseHosts(zone string, content []byte, serial uint32) ([]dns.RR, error) {
+	var records []dns.RR
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	line := 0
+	for scanner.Scan() {
+		line++
+
+		text := scanner.Text()
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line:'%d' has no names", line)
+		}
+
+		addr, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line:'%d' address:'%s', err:'%w'", line, fields[0], err)
+		}
+
+		qtype := "AAAA"
+		if addr.Is4() {
+			qtype = "A"
+		}
+
+		for _, name := range fields[1:] {
+			rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", RecordName(zone, name),
+				DefaultRecordsTTL, qtype, addr.String()))
+			if err != nil {
+				return nil, fmt.Errorf("line:'%d' name:'%s', err:'%w'", line, name, err)
+			}
+			records = append(records, rr)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return recordsAXFR(zone, serial, records), nil
+}
+
+// Parsing list of records entries in JSON (or YAML) into
+// AXFR-like list of records, records of types other than
+// A/AAAA are reported as skipped by zone filter
+func ParseRecordsJSON(zone string, content []byte, serial uint32) ([]dns.RR, error) {
+	var entries []TRecordsEntry
+	if err := yaml.Unmarshal(content, &entries); err != nil {
+		return nil, err
+	}
+
+	var records []dns.RR
+	for i, entry := range entries {
+		if len(entry.Name) == 0 {
+			return nil, fmt.Errorf("entry:'%d' has no name", i)
+		}
+
+		ttl := entry.TTL
+		if ttl == 0 {
+			ttl = DefaultRecordsTTL
+		}
+
+		for _, address := range entry.Addresses {
+			qtype := strings.ToUpper(entry.Type)
+			if len(qtype) == 0 {
+				qtype = "A"
+				if addr, err := netip.ParseAddr(address); err == nil && !addr.Is4() {
+					qtype = "AAAA"
+				}
+			}
+
+			rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", RecordName(zone, entry.Name),
+				ttl, qtype, address))
+			if err != nil {
+				return nil, fmt.Errorf("entry:'%d' name:'%s', err:'%w'", i, entry.Name, err)
+			}
+			if rr == nil {
+				return nil, fmt.Errorf("entry:'%d' name:'%s' has empty record", i, entry.Name)
+			}
+			records = append(records, rr)
+		}
+	}
+
+	return recordsAXFR(zone, serial, records), nil
+}
+
+func sameSkips(s1 *TSnapshotZone, s2 *TSnapshotZone) bool {
+	if len(s1.skips) != len(s2.skips) {
+		return false
+	}
+	for k := range s1.skips {
+		if _, ok := s2.skips[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Getting snapshot of records source fetched from file:// or
+// http(s):// endpoint, snapshot is nil if content is not changed
+// (not modified response, the same content hash or records), response is
+// returned to keep its validators as snapshot is accepted
+func (j *ImporterWorker) GetZoneSnapshotRecords(ctx context.Context, zone string,
+	options *TZoneSnapshotOptions) (*TSnapshotZone, *THTTPResponse, error) {
+
+	id := "(importer) (records) (snapshot)"
+
+	var content []byte
+	var response *THTTPResponse
+	var err error
+
+	if strings.HasPrefix(options.Server, "file://") {
+		filename := strings.TrimPrefix(options.Server, "file:///")
+		j.p.G().L.Debugf("%s request records zone:'%s' filename:'%s'", id, zone, filename)
+
+		if content, err = os.ReadFile(filename); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		var validators *THTTPValidators
+		if options.SnapshotMode == SnapshotMemoryExists {
+			validators = j.p.zones.GetHTTPValidators(zone, options.Server)
+		}
+
+		client := NewHTTPSource(j.p, &j.p.L().HTTPTransfer.Client)
+		if response, err = client.Fetch(ctx, options.HTTPEndpoints(), zone, validators); err != nil {
+			return nil, nil, err
+		}
+
+		if response.NotModified {
+			j.p.G().L.Debugf("%s no any changes for zone:'%s' via endpoint:'%s' detected",
+				id, zone, response.Endpoint)
+			return nil, nil, nil
+		}
+		content = response.Body
+	}
+
+	digest := ContentDigest(content)
+
+	var current *TSnapshotZone
+	var previous uint32
+	known := false
+	if options.SnapshotMode == SnapshotMemoryExists && j.p.zones != nil {
+		current = j.p.zones.GetLastZoneSnapshot(zone)
+	}
+	if current != nil {
+		previous, _ = current.Serial()
+
+		var d uint32
+		if d, known = j.p.zones.GetContentDigest(zone, previous); known && d == digest {
+			j.p.G().L.Debugf("%s no any changes for zone:'%s' content digest:'%d' detected",
+				id, zone, digest)
+			return nil, nil, nil
+		}
+	}
+
+	serial := NextSerial(previous, time.Now())
+
+	var rr []dns.RR
+	switch options.Source {
+	case SourceHosts:
+		rr, err = ParseHosts(zone, content, serial)
+	case SourceJSON:
+		rr, err = ParseRecordsJSON(zone, content, serial)
+	default:
+		err = fmt.Errorf("source:'%d' is not records source", options.Source)
+	}
+	if err != nil {
+		j.p.G().L.Errorf("%s error parsing records zone:'%s', err:'%s'", id, zone, err)
+		return nil, nil, err
+	}
+
+	j.p.G().L.Debugf("%s zone:'%s' bytes:'%d' rr:'%d' serial:'%d'", id, zone,
+		len(content), len(rr), serial)
+
+	snapshot, err := NewSnapshotZoneFromRR(j.p, rr, zone)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// digest is not known (e.g. on restart), content is
+	// not changed if records are the same
+	if current != nil && !known && current.Equal(snapshot) && sameSkips(current, snapshot) {
+		j.p.G().L.Debugf("%s no any changes for zone:'%s' records detected", id, zone)
+		j.p.zones.SetContentDigest(zone, previous, digest)
+		return nil, nil, nil
+	}
+
+	// digest is valid only if snapshot of serial is applied
+	if j.p.zones != nil {
+		j.p.zones.SetContentDigest(zone, serial, digest)
+	}
+
+	return snapshot, response, nil
+}
@@ -0,0 +1,217 @@
+package receiver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordsSources(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+	p.zones = NewZonesState(p)
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		source  int
+		content string
+
+		// expected error, records as "name/type" and
+		// records skipped
+		err     bool
+		records []string
+		skipped int
+	}
+
+	var Tests = []TTest{
+		{
+			"5d0e1f2a-3b4c-4d5e-8f6a-7b8c9d0e1f2a",
+			true,
+			SourceHosts,
+			`# inventory
+192.0.2.1   web1 web1-alias
+2001:db8::1 web1
+192.0.2.2   db.example.net.   # absolute
+192.0.2.3   other.example.org.
+`,
+			false,
+			[]string{"db.example.net./A", "web1-alias.example.net./A",
+				"web1.example.net./A", "web1.example.net./AAAA"},
+			1,
+		},
+		{
+			"6e1f2a3b-4c5d-4e6f-9a7b-8c9d0e1f2a3b",
+			true,
+			SourceHosts,
+			`192.0.2.300 broken`,
+			true, nil, 0,
+		},
+		{
+			"7f2a3b4c-5d6e-4f7a-8b8c-9d0e1f2a3b4c",
+			true,
+			SourceJSON,
+			`[
+  {"name": "api", "ttl": 60, "addresses": ["192.0.2.10", "2001:db8::10"]},
+  {"name": "mx.example.net.", "type": "A", "addresses": ["192.0.2.11"]},
+  {"name": "txt", "type": "TXT", "addresses": ["\"text\""]}
+]`,
+			false,
+			[]string{"api.example.net./A", "api.example.net./AAAA", "mx.example.net./A"},
+			1,
+		},
+		{
+			// yaml is accepted as well
+			"8a3b4c5d-6e7f-4a8b-9c9d-0e1f2a3b4c5d",
+			true,
+			SourceJSON,
+			`- name: cache
+  addresses: [ "192.0.2.20" ]
+`,
+			false,
+			[]string{"cache.example.net./A"},
+			0,
+		},
+	}
+
+	dir := t.TempDir()
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		filename := filepath.Join(dir, test.uuid)
+		if err := os.WriteFile(filename, []byte(test.content), 0644); err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error writing source, err:'%s'", err))
+			continue
+		}
+
+		var importer ImporterWorker
+		importer.p = p
+
+		options := TZoneSnapshotOptions{
+			Source:       test.source,
+			Server:       fmt.Sprintf("file:///%s", filename),
+			SnapshotMode: SnapshotMemoryEmpty,
+		}
+
+		snapshot, _, err := importer.GetZoneSnapshotRecords(context.Background(), "example.net", &options)
+		if (err != nil) != test.err {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error expected:'%t' got:'%v'", test.err, err))
+			continue
+		}
+		if err != nil {
+			fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+			continue
+		}
+
+		var got []string
+		for k := range snapshot.rrsets {
+			i := strings.LastIndex(k, "-")
+			got = append(got, fmt.Sprintf("%s/%s", k[:i], k[i+1:]))
+		}
+		sort.Strings(got)
+
+		if strings.Join(got, " ") != strings.Join(test.records, " ") {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("records expected:'%s' got:'%s'",
+				strings.Join(test.records, " "), strings.Join(got, " ")))
+			continue
+		}
+
+		if _, skipped := snapshot.Counters(); skipped != test.skipped {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("skipped expected:'%d' got:'%d'",
+				test.skipped, skipped))
+			continue
+		}
+
+		serial, _ := snapshot.Serial()
+		if serial == ContentDigest([]byte(test.content)) {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("serial:'%d' is content hash", serial))
+			continue
+		}
+
+		// the same content is not changed (digest known and
+		// digest not known as on restart)
+		p.zones.zones["example.net"] = TZoneState{Zone: "example.net", SnapshotID: 0,
+			Snapshots: map[int]TSnapshotZone{0: *snapshot}}
+		options.SnapshotMode = SnapshotMemoryExists
+
+		unchanged, _, err := importer.GetZoneSnapshotRecords(context.Background(), "example.net", &options)
+		if err != nil || unchanged != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("expected no changes, err:'%v'", err))
+			continue
+		}
+
+		delete(p.zones.digests, "example.net")
+		unchanged, _, err = importer.GetZoneSnapshotRecords(context.Background(), "example.net", &options)
+		if err != nil || unchanged != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("expected no changes of records, err:'%v'", err))
+			continue
+		}
+
+		// changed content (comment added) gets greater serial
+		if err := os.WriteFile(filename, []byte(test.content+"\n#\n"), 0644); err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error writing source, err:'%s'", err))
+			continue
+		}
+
+		changed, _, err := importer.GetZoneSnapshotRecords(context.Background(), "example.net", &options)
+		if err != nil || changed == nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("expected changes, err:'%v'", err))
+			continue
+		}
+		if next, _ := changed.Serial(); int32(next-serial) <= 0 {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("serial:'%d' is not greater than:'%d'", next, serial))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
+
+func TestNextSerial(t *testing.T) {
+
+	now := time.Unix(1700000000, 0)
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		previous uint32
+		serial   uint32
+	}
+
+	var Tests = []TTest{
+		{"7a8b9c0d-1e2f-4a3b-8c4d-5e6f7a8b9c0d", true, 0, 1700000000},
+		{"8b9c0d1e-2f3a-4b4c-9d5e-6f7a8b9c0d1e", true, 1600000000, 1700000000},
+		// changed within a second or previous serial is
+		// ahead of time (e.g. content hash serial)
+		{"9c0d1e2f-3a4b-4c5d-8e6f-7a8b9c0d1e2f", true, 1700000000, 1700000001},
+		{"0d1e2f3a-4b5c-4d6e-9f7a-8b9c0d1e2f3a", true, 1800000000, 1800000001},
+		// time is greater in serial arithmetic (wrapped)
+		{"1e2f3a4b-5c6d-4e7f-8a8b-9c0d1e2f3a4b", true, 4294967295, 1700000000},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		if serial := NextSerial(test.previous, now); serial != test.serial {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("serial expected:'%d' got:'%d'", test.serial, serial))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
@@ -0,0 +1,400 @@
+package receiver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// verifier repair policy defines what is done with differences
+// found between zones snapshots and maps: "off" ignores them,
+// "report" records them only, "repair-missing" creates records
+// missed in maps and "repair-all" also replaces differing and
+// removes unexpected records. Repairs are applied via IXFR sync
+// of maps, capped per verify cycle and recorded in metrics and
+// audit log (json lines). Keys of manual records (created via
+// objects api) are reported only, never repaired. Verify on
+// cook uses its own policy ("repair-all" if empty, as it has
+// always repaired all differences found)
+
+const (
+	RepairOff     = "off"
+	RepairReport  = "report"
+	RepairMissing = "repair-missing"
+	RepairAll     = "repair-all"
+
+	DefaultRepairPolicy = RepairReport
+
+	// default policy of verify on cook
+	DefaultRepairOnCookPolicy = RepairAll
+
+	// default max number of records repaired per cycle
+	DefaultRepairMax = 1000
+
+	// audit log of repairs in snapshots directory
+	DefaultRepairAuditFilename = "yadns-xdp.repairs"
+
+	// kinds of differences
+	RepairKindMissed     = "missed"
+	RepairKindDiffer     = "differ"
+	RepairKindUnexpected = "unexpected"
+
+	// differences repaired or reported per kind
+	MetricVerifierRepairs = "receiver-verifier-repairs"
+
+	// differences deferred to the next cycle as
+	// repairs cap is reached
+	MetricVerifierRepairsDeferred = "receiver-verifier-repairs-deferred"
+)
+
+var (
+	ErrRepairPolicy = errors.New("unknown repair policy")
+
+	RepairPolicies = []string{RepairOff, RepairReport, RepairMissing, RepairAll}
+
+	RepairKinds = []string{RepairKindMissed, RepairKindDiffer, RepairKindUnexpected}
+)
+
+type TConfigRepair struct {
+	// repair policy: "off", "report", "repair-missing"
+	// or "repair-all", "report" if empty
+	Policy string `json:"policy" yaml:"policy"`
+
+	// repair policy of verify on cook, "repair-all"
+	// if empty
+	OnCook string `json:"on-cook" yaml:"on-cook"`
+
+	// max number of records repaired per verify cycle,
+	// the rest is deferred to the next one
+	Max int `json:"max" yaml:"max"`
+
+	// audit log file of repairs, the snapshots directory
+	// file is used if empty
+	Audit string `json:"audit" yaml:"audit"`
+}
+
+func ValidRepairPolicy(policy string) bool {
+	for _, p := range RepairPolicies {
+		if p == policy {
+			return true
+		}
+	}
+	return false
+}
+
+// Getting repair policy and max repairs per cycle
+// with defaults applied
+func (t *TConfigRepair) Get() (string, int, error) {
+	return t.get(t.Policy, DefaultRepairPolicy)
+}
+
+// Getting repair policy of verify on cook and max
+// repairs with defaults applied
+func (t *TConfigRepair) GetOnCook() (string, int, error) {
+	return t.get(t.OnCook, DefaultRepairOnCookPolicy)
+}
+
+func (t *TConfigRepair) get(policy string, defaults string) (string, int, error) {
+	if len(policy) == 0 {
+		policy = defaults
+	}
+	if !ValidRepairPolicy(policy) {
+		return RepairOff, 0, fmt.Errorf("%w: '%s'", ErrRepairPolicy, policy)
+	}
+
+	max := t.Max
+	if max <= 0 {
+		max = DefaultRepairMax
+	}
+
+	return policy, max, nil
+}
+
+// record of audit log
+type TRepairRecord struct {
+	Time   time.Time `json:"time"`
+	Policy string    `json:"policy"`
+	Kind   string    `json:"kind"`
+	Key    string    `json:"key"`
+
+	// record found in maps and record expected
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+
+	Dryrun  bool `json:"dryrun"`
+	Applied bool `json:"applied"`
+
+	// record is selected to repair by policy
+	repair bool
+}
+
+type TRepairResult struct {
+	Policy string `json:"policy"`
+	Dryrun bool   `json:"dryrun"`
+
+	// differences found by verifier
+	Found int `json:"found"`
+
+	// differences repaired (or to be repaired on
+	// dryrun), reported only and deferred to the
+	// next cycle as cap is reached
+	Repaired int `json:"repaired"`
+	Reported int `json:"reported"`
+	Deferred int `json:"deferred"`
+
+	// records of maps sync
+	Created int `json:"created"`
+	Removed int `json:"removed"`
+
+	Records []TRepairRecord `json:"-"`
+
+	// changes selected to repair
+	changed *TChangedSetZone
+}
+
+func (t *TRepairResult) AsString() string {
+	var out []string
+
+	out = append(out, fmt.Sprintf("policy:'%s'", t.Policy))
+	out = append(out, fmt.Sprintf("found:'%d'", t.Found))
+	out = append(out, fmt.Sprintf("repaired:'%d'", t.Repaired))
+	out = append(out, fmt.Sprintf("reported:'%d'", t.Reported))
+	out = append(out, fmt.Sprintf("deferred:'%d'", t.Deferred))
+	out = append(out, fmt.Sprintf("dryrun:'%t'", t.Dryrun))
+
+	return strings.Join(out, ",")
+}
+
+func joinRR(rrs []dns.RR) string {
+	var out []string
+	for _, rr := range rrs {
+		out = append(out, rr.String())
+	}
+	return strings.Join(out, ";")
+}
+
+// Planning repairs of verifier changes w.r.t policy: changes
+// are classified per key as missed (create only), differ
+// (remove and create) and unexpected (remove only), keys are
+// processed in order up to max repairs, manual keys are
+// reported only
+func PlanRepairs(changed *TChangedSetZone, policy string, max int,
+	manual map[string]bool) *TRepairResult {
+
+	var result TRepairResult
+	result.Policy = policy
+
+	var repairs TChangedSetZone
+	repairs.age = time.Now().Unix()
+	repairs.rrchanges = make(map[int]map[string][]dns.RR)
+	for _, change := range []int{ChangeCreate, ChangeRemove} {
+		repairs.rrchanges[change] = make(map[string][]dns.RR)
+	}
+	result.changed = &repairs
+
+	if changed == nil || policy == RepairOff {
+		return &result
+	}
+
+	keys := make(map[string]bool)
+	for _, change := range []int{ChangeCreate, ChangeRemove} {
+		for k := range changed.rrchanges[change] {
+			keys[k] = true
+		}
+	}
+
+	var sorted []string
+	for k := range keys {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+
+	now := time.Now()
+	for _, k := range sorted {
+		created := changed.rrchanges[ChangeCreate][k]
+		removed := changed.rrchanges[ChangeRemove][k]
+
+		kind := RepairKindDiffer
+		switch {
+		case len(removed) == 0:
+			kind = RepairKindMissed
+		case len(created) == 0:
+			kind = RepairKindUnexpected
+		}
+
+		result.Found++
+
+		record := TRepairRecord{Time: now, Policy: policy, Kind: kind, Key: k,
+			From: joinRR(removed), To: joinRR(created)}
+
+		repair := policy == RepairAll || (policy == RepairMissing && kind == RepairKindMissed)
+		for _, rrs := range [][]dns.RR{created, removed} {
+			for _, rr := range rrs {
+				if manual[RecordKey(rr)] {
+					repair = false
+				}
+			}
+		}
+		if !repair {
+			result.Reported++
+			result.Records = append(result.Records, record)
+			continue
+		}
+
+		if result.Repaired >= max {
+			result.Deferred++
+			continue
+		}
+
+		result.Repaired++
+		record.repair = true
+		result.Records = append(result.Records, record)
+
+		if len(created) > 0 {
+			repairs.rrchanges[ChangeCreate][k] = created
+			repairs.created += len(created)
+		}
+		if len(removed) > 0 {
+			repairs.rrchanges[ChangeRemove][k] = removed
+			repairs.removed += len(removed)
+		}
+	}
+
+	return &result
+}
+
+// Repairing verifier changes of snapshot (merged from all
+// zones) w.r.t configured policy, manual keys are never
+// repaired
+func (j *VerifierWorker) Repair(snapshot *TSnapshotZone, changed *TChangedSetZone,
+	manual map[string]bool, options *VerifyOptions) (*TRepairResult, error) {
+
+	dryrun := options.Dryrun
+	id := fmt.Sprintf("(verifier) (repair) %s", DryrunString(dryrun))
+
+	config := j.p.L().Verifier.Repair
+	get := config.Get
+	if options.OnCook {
+		get = config.GetOnCook
+	}
+	policy, max, err := get()
+	if err != nil {
+		j.p.G().L.Errorf("%s error repair policy, err:'%s'", id, err)
+		return nil, err
+	}
+
+	result := PlanRepairs(changed, policy, max, manual)
+	result.Dryrun = dryrun
+	if policy == RepairOff {
+		return result, nil
+	}
+
+	if result.Repaired > 0 {
+		actions := result.changed.AsActions()
+
+		var r *TSyncMapResult
+		if r, err = snapshot.SyncMap(TransferModeIXFR, actions, dryrun); err != nil {
+			j.p.G().L.Errorf("%s error syncing map, err:'%s'", id, err)
+			return result, err
+		}
+		result.Created = r.Created
+		result.Removed = r.Removed
+	}
+
+	for i := range result.Records {
+		result.Records[i].Dryrun = dryrun
+		result.Records[i].Applied = result.Records[i].repair && !dryrun
+	}
+
+	for _, record := range result.Records {
+		action := "reported"
+		if record.Applied {
+			action = "repaired"
+		}
+		j.p.G().L.Infof("%s policy:'%s' %s kind:'%s' k:'%s' from:'%s' to:'%s'", id, policy,
+			action, record.Kind, record.Key, record.From, record.To)
+	}
+
+	j.PushRepairMetrics(result)
+
+	if err = j.AuditRepairs(result.Records); err != nil {
+		j.p.G().L.Errorf("%s error writing audit log, err:'%s'", id, err)
+	}
+
+	j.p.G().L.Debugf("%s result %s", id, result.AsString())
+
+	return result, nil
+}
+
+func (j *VerifierWorker) PushRepairMetrics(result *TRepairResult) {
+	counts := make(map[string]map[bool]int)
+	for _, kind := range RepairKinds {
+		counts[kind] = make(map[bool]int)
+	}
+	for _, record := range result.Records {
+		counts[record.Kind][record.Applied]++
+	}
+
+	for _, kind := range RepairKinds {
+		for _, applied := range []bool{true, false} {
+			action := "reported"
+			if applied {
+				action = "repaired"
+			}
+			tags := []string{fmt.Sprintf("kind=%s", kind), fmt.Sprintf("action=%s", action)}
+			j.p.PushMetric(MetricVerifierRepairs, tags, float64(counts[kind][applied]))
+		}
+	}
+
+	j.p.PushMetric(MetricVerifierRepairsDeferred, nil, float64(result.Deferred))
+}
+
+func (j *VerifierWorker) AuditFilename() string {
+	if audit := j.p.L().Verifier.Repair.Audit; len(audit) > 0 {
+		return audit
+	}
+	path := j.p.L().Options.Snapshots.Directory
+	if len(path) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s/%s", path, DefaultRepairAuditFilename)
+}
+
+// Appending repair records into audit log as json lines
+func (j *VerifierWorker) AuditRepairs(records []TRepairRecord) error {
+	filename := j.AuditFilename()
+	if len(filename) == 0 || len(records) == 0 {
+		return nil
+	}
+
+	var b strings.Builder
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		b.Write(line)
+		b.WriteString("\n")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = f.WriteString(b.String()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
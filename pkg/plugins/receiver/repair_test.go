@@ -0,0 +1,173 @@
+package receiver
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func newRepairChanges(t *testing.T) *TChangedSetZone {
+	var changed TChangedSetZone
+	changed.age = time.Now().Unix()
+	changed.rrchanges = make(map[int]map[string][]dns.RR)
+	for _, change := range []int{ChangeCreate, ChangeRemove} {
+		changed.rrchanges[change] = make(map[string][]dns.RR)
+	}
+
+	add := func(change int, k string, s string) {
+		rr, err := dns.NewRR(s)
+		if err != nil {
+			t.Fatalf("error parsing rr:'%s', err:'%s'", s, err)
+		}
+		changed.rrchanges[change][k] = append(changed.rrchanges[change][k], rr)
+	}
+
+	// missed
+	add(ChangeCreate, "a.example.net.-A", "a.example.net. 300 IN A 192.0.2.1")
+	add(ChangeCreate, "b.example.net.-A", "b.example.net. 300 IN A 192.0.2.2")
+
+	// differ on ip
+	add(ChangeRemove, "c.example.net.-A", "c.example.net. 300 IN A 192.0.2.30")
+	add(ChangeCreate, "c.example.net.-A", "c.example.net. 300 IN A 192.0.2.3")
+
+	// unexpected
+	add(ChangeRemove, "d.example.net.-AAAA", "d.example.net. 300 IN AAAA 2001:db8::4")
+
+	return &changed
+}
+
+func TestPlanRepairs(t *testing.T) {
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		policy string
+		max    int
+
+		repaired int
+		reported int
+		deferred int
+		keys     []string
+
+		// keys of manual records
+		manual map[string]bool
+	}
+
+	var Tests = []TTest{
+		{"0e1f2a3b-4c5d-4e6f-8a7b-9c0d1e2f3a4b", true, RepairOff, 10, 0, 0, 0, nil, nil},
+		{"1f2a3b4c-5d6e-4f7a-9b8c-0d1e2f3a4b5c", true, RepairReport, 10, 0, 4, 0, nil, nil},
+		{"2a3b4c5d-6e7f-4a8b-8c9d-1e2f3a4b5c6d", true, RepairMissing, 10, 2, 2, 0,
+			[]string{"a.example.net.-A", "b.example.net.-A"}, nil},
+		{"3b4c5d6e-7f8a-4b9c-9d0e-2f3a4b5c6d7e", true, RepairMissing, 1, 1, 2, 1,
+			[]string{"a.example.net.-A"}, nil},
+		{"4c5d6e7f-8a9b-4c0d-8e1f-3a4b5c6d7e8f", true, RepairAll, 10, 4, 0, 0,
+			[]string{"a.example.net.-A", "b.example.net.-A", "c.example.net.-A",
+				"d.example.net.-AAAA"}, nil},
+		{"5d6e7f8a-9b0c-4d1e-9f2a-4b5c6d7e8f9a", true, RepairAll, 3, 3, 0, 1,
+			[]string{"a.example.net.-A", "b.example.net.-A", "c.example.net.-A"}, nil},
+		{"8a9b0c1d-2e3f-4a4b-8c5d-7e8f9a0b1c2d", true, RepairAll, 10, 2, 2, 0,
+			[]string{"a.example.net.-A", "b.example.net.-A"},
+			map[string]bool{"c.example.net.-A": true, "d.example.net.-AAAA": true}},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		result := PlanRepairs(newRepairChanges(t), test.policy, test.max, test.manual)
+		if result.Repaired != test.repaired || result.Reported != test.reported ||
+			result.Deferred != test.deferred {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("unexpected result %s", result.AsString()))
+			continue
+		}
+
+		var keys []string
+		for _, record := range result.Records {
+			if record.repair {
+				keys = append(keys, record.Key)
+			}
+		}
+
+		if strings.Join(keys, ",") != strings.Join(test.keys, ",") {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("repaired keys expected:'%v' got:'%v'",
+				test.keys, keys))
+			continue
+		}
+
+		// changes selected keep both parts of differ
+		if test.policy == RepairAll && test.max >= 3 && test.manual == nil &&
+			(len(result.changed.rrchanges[ChangeRemove]["c.example.net.-A"]) != 1 ||
+				len(result.changed.rrchanges[ChangeCreate]["c.example.net.-A"]) != 1) {
+			t.Error("\nUUID", test.uuid, "differ record is not replaced")
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
+
+func TestAuditRepairs(t *testing.T) {
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+	p.c.Options.Snapshots.Directory = t.TempDir()
+
+	verifier, _ := NewVerifierWorker(p, nil)
+
+	uuid := "6e7f8a9b-0c1d-4e2f-8a3b-5c6d7e8f9a0b"
+
+	result := PlanRepairs(newRepairChanges(t), RepairMissing, 10, nil)
+	for i := 0; i < 2; i++ {
+		if err = verifier.AuditRepairs(result.Records); err != nil {
+			t.Error("\nUUID", uuid, fmt.Sprintf("error writing audit, err:'%s'", err))
+			return
+		}
+	}
+
+	content, err := os.ReadFile(verifier.AuditFilename())
+	if err != nil {
+		t.Error("\nUUID", uuid, fmt.Sprintf("error reading audit, err:'%s'", err))
+		return
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2*len(result.Records) || !strings.Contains(lines[0], `"kind":"missed"`) {
+		t.Error("\nUUID", uuid, fmt.Sprintf("unexpected audit:'%s'", content))
+		return
+	}
+
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+}
+
+func TestRepairPolicy(t *testing.T) {
+	uuid := "7f8a9b0c-1d2e-4f3a-9b4c-6d7e8f9a0b1c"
+
+	var config TConfigRepair
+	policy, max, err := config.Get()
+	if err != nil || policy != DefaultRepairPolicy || max != DefaultRepairMax {
+		t.Error("\nUUID", uuid, fmt.Sprintf("unexpected defaults policy:'%s' max:'%d' err:'%v'",
+			policy, max, err))
+		return
+	}
+
+	if policy, _, err = config.GetOnCook(); err != nil || policy != DefaultRepairOnCookPolicy {
+		t.Error("\nUUID", uuid, fmt.Sprintf("unexpected on cook policy:'%s' err:'%v'", policy, err))
+		return
+	}
+
+	config.Policy = "repair-some"
+	if _, _, err = config.Get(); err == nil {
+		t.Error("\nUUID", uuid, "expected error on unknown policy")
+		return
+	}
+
+	fmt.Printf("Test:'%s' ... OK\n", uuid)
+}
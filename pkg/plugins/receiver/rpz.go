@@ -0,0 +1,617 @@
+package receiver
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"github.com/yandex/yadns-controller/pkg/plugins/offloader"
+)
+
+// response policy zones (RPZ) are translated into map
+// records: exact qname triggers with "CNAME ." action
+// (NXDOMAIN) are answered with sinkhole addresses and local
+// data A/AAAA actions are answered as is. Policies take
+// precedence over zones data (but not over overrides).
+// Maps could not answer NXDOMAIN, NODATA or drop queries,
+// so other triggers and actions are reported as skipped.
+// Policy names are registered in hits map of offloader
+// (counted by BPF program if bpf metrics enabled) and hits
+// per policy and action are exported as metrics
+
+const (
+	// policy actions as shown in metrics
+	RPZActionNXDOMAIN = "nxdomain"
+	RPZActionRedirect = "redirect"
+
+	// number of policies per zone and action
+	MetricRPZPolicies = "receiver-rpz-policies"
+
+	// hits per zone and action and hits per policy
+	// (only policies with hits are pushed)
+	MetricRPZHits       = "receiver-rpz-hits"
+	MetricRPZPolicyHits = "receiver-rpz-policy-hits"
+
+	// interval of hits registration and export
+	DefaultRPZHitsInterval = 60 * time.Second
+
+	// trigger labels (preceding zone apex) of
+	// triggers other than qname
+	RPZTriggerPrefix = "rpz-"
+)
+
+var RPZActions = []string{RPZActionNXDOMAIN, RPZActionRedirect}
+
+type TConfigRPZ struct {
+	// addresses (IPv4 and/or IPv6) answered for
+	// NXDOMAIN policies, policies are skipped if
+	// no sinkhole address is set
+	Sinkhole []string `json:"sinkhole" yaml:"sinkhole"`
+
+	// ttl of sinkhole records, policy record ttl
+	// is used if not set
+	TTL uint32 `json:"ttl" yaml:"ttl"`
+
+	// precedence of policy zone over other policy
+	// zones, the lower value wins
+	Precedence int `json:"precedence" yaml:"precedence"`
+}
+
+type RPZPolicies struct {
+	zone   string
+	config TConfigRPZ
+
+	// records skipped with their reasons
+	skips map[string]TSkippedRecord
+
+	// number of policies (names) per action
+	counts map[string]int
+}
+
+func NewRPZPolicies(zone string, config *TConfigRPZ) *RPZPolicies {
+	var r RPZPolicies
+	r.zone = Dot(strings.ToLower(zone))
+	if config != nil {
+		r.config = *config
+	}
+	r.skips = make(map[string]TSkippedRecord)
+	r.counts = make(map[string]int)
+	return &r
+}
+
+func (r *RPZPolicies) skip(rr dns.RR, reason int) {
+	r.skips[rr.String()] = NewSkippedRecord(rr, reason)
+}
+
+// Making sinkhole records of qname for NXDOMAIN action
+func (r *RPZPolicies) sinkhole(qname string, ttl uint32) []dns.RR {
+	if r.config.TTL > 0 {
+		ttl = r.config.TTL
+	}
+
+	var out []dns.RR
+	for _, address := range r.config.Sinkhole {
+		addr, err := netip.ParseAddr(address)
+		if err != nil {
+			continue
+		}
+		qtype := "AAAA"
+		if addr.Is4() {
+			qtype = "A"
+		}
+		if rr, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", qname, ttl, qtype,
+			addr.String())); err == nil && rr != nil {
+			out = append(out, rr)
+		}
+	}
+	return out
+}
+
+// Translating policy zone AXFR-like records into records
+// of trigger names. As policy records are out of policy
+// zone apex, SOA owner is set to root (keeping SOA data)
+// so records are kept as in zone on snapshot reload
+func (r *RPZPolicies) Translate(rr []dns.RR) ([]dns.RR, error) {
+	var soa dns.RR
+
+	nxdomain := make(map[string][]dns.RR)
+	redirect := make(map[string][]dns.RR)
+
+	for _, q := range rr {
+		if q == nil {
+			continue
+		}
+
+		h := q.Header()
+		name := strings.ToLower(h.Name)
+
+		if h.Rrtype == dns.TypeSOA {
+			soa = q
+			continue
+		}
+
+		if !dns.IsSubDomain(r.zone, name) {
+			r.skip(q, SkipByZone)
+			continue
+		}
+
+		// zone apex records (e.g. NS) are not policies
+		if name == r.zone {
+			r.skip(q, SkipByType)
+			continue
+		}
+
+		trigger := strings.TrimSuffix(name, fmt.Sprintf(".%s", r.zone))
+		labels := dns.SplitDomainName(trigger)
+
+		// client ip, response ip and nameserver triggers and
+		// wildcards could not be matched by maps
+		if strings.HasPrefix(labels[len(labels)-1], RPZTriggerPrefix) || labels[0] == "*" {
+			r.skip(q, SkipByTrigger)
+			continue
+		}
+
+		qname := Dot(trigger)
+
+		switch v := q.(type) {
+		case *dns.CNAME:
+			// "CNAME ." is NXDOMAIN, others are NODATA ("*."),
+			// passthru, drop, tcp-only and local data CNAME
+			if v.Target != "." {
+				r.skip(q, SkipByAction)
+				continue
+			}
+			records := r.sinkhole(qname, h.Ttl)
+			if len(records) == 0 {
+				r.skip(q, SkipByAction)
+				continue
+			}
+			nxdomain[qname] = records
+		case *dns.A, *dns.AAAA:
+			record := dns.Copy(q)
+			record.Header().Name = qname
+			redirect[qname] = append(redirect[qname], record)
+		default:
+			r.skip(q, SkipByType)
+		}
+	}
+
+	if soa == nil {
+		return nil, fmt.Errorf("no SOA found in policy zone:'%s'", r.zone)
+	}
+
+	soa = dns.Copy(soa)
+	soa.Header().Name = "."
+
+	out := []dns.RR{soa}
+	for qname, records := range nxdomain {
+		out = append(out, records...)
+		r.counts[RPZActionNXDOMAIN]++
+
+		// NXDOMAIN takes precedence over local data
+		// of the same name
+		for _, q := range redirect[qname] {
+			r.skip(q, SkipByAction)
+		}
+	}
+	for qname, records := range redirect {
+		if _, ok := nxdomain[qname]; ok {
+			continue
+		}
+		out = append(out, records...)
+		r.counts[RPZActionRedirect]++
+	}
+	out = append(out, soa)
+
+	return out, nil
+}
+
+// Pushing number of policies per action (zero counts are
+// pushed as well to reset them)
+func (r *RPZPolicies) PushMetrics(p *TReceiverPlugin) {
+	for _, action := range RPZActions {
+		tags := []string{fmt.Sprintf("zone=%s", RemoveDot(r.zone)), fmt.Sprintf("action=%s", action)}
+		p.PushMetric(MetricRPZPolicies, tags, float64(r.counts[action]))
+	}
+}
+
+// Getting snapshot of policy zone transferred via AXFR from
+// primary or read from file:// master file, transfer is
+// skipped if primary SOA serial is not changed. IXFR actions
+// are calculated as difference of translated snapshots
+func (j *ImporterWorker) GetZoneSnapshotRPZ(zone string,
+	options *TZoneSnapshotOptions) (*TSnapshotZone, error) {
+
+	id := "(importer) (rpz) (snapshot)"
+
+	var err error
+	var rr []dns.RR
+
+	if strings.HasPrefix(options.Server, "file://") {
+		filename := strings.TrimPrefix(options.Server, "file:///")
+		j.p.G().L.Debugf("%s request policy zone:'%s' filename:'%s'", id, zone, filename)
+
+		if rr, err = ParseMasterFile(filename, zone, filepath.Dir(filename)); err != nil {
+			j.p.G().L.Errorf("%s error parsing policy zone:'%s' file:'%s', err:'%s'",
+				id, zone, filename, err)
+			return nil, err
+		}
+	} else {
+		keys := options.Tsig
+		failed := func(key *TTsigKey, err error) {
+			if j.p.zones != nil {
+				j.p.zones.TsigFailure(zone, key, err)
+			}
+		}
+
+		opts := &TransferOptions{Key: options.Key, TLS: options.TLS}
+
+		var current *TSnapshotZone
+		if options.SnapshotMode == SnapshotMemoryExists && j.p.zones != nil {
+			current = j.p.zones.GetLastZoneSnapshot(zone)
+		}
+
+		if current != nil {
+			serial, _ := current.Serial()
+
+			request := func(key *TTsigKey) error {
+				var err error
+				opts, err = RequestSOAWithOptions(options.Server, zone,
+					&TransferOptions{Tsig: key, TLS: options.TLS})
+				return err
+			}
+
+			var key *TTsigKey
+			if key, err = WithTsigKeys(keys, request, failed); err != nil {
+				j.p.G().L.Errorf("%s error SOA request zone:'%s' via server:'%s', err:'%s'",
+					id, zone, options.Server, err)
+				return nil, err
+			}
+
+			if opts.Serial == serial {
+				j.p.G().L.Debugf("%s no any changes for policy zone:'%s' via primary:'%s' detected",
+					id, zone, options.Server)
+
+				if opts.Conn != nil {
+					opts.Conn.Close()
+				}
+
+				// beware snapshot could be nil	(as no any changes occured)
+				return nil, nil
+			}
+
+			opts.Key = options.Key
+			keys = PreferTsigKey(keys, key)
+		}
+
+		opts.Mode = TransferModeAXFR
+		transfer := func(key *TTsigKey) error {
+			var err error
+			opts.Tsig = key
+			rr, err = TransferZone(options.Server, zone, opts)
+			return err
+		}
+
+		if _, err = WithTsigKeys(keys, transfer, failed); err != nil {
+			j.p.G().L.Errorf("%s error transfering policy zone:'%s', err:'%s'", id, zone, err)
+			return nil, err
+		}
+	}
+
+	policies := NewRPZPolicies(zone, options.RPZ)
+
+	records, err := policies.Translate(rr)
+	if err != nil {
+		j.p.G().L.Errorf("%s error translating policy zone:'%s', err:'%s'", id, zone, err)
+		return nil, err
+	}
+
+	snapshot, err := NewSnapshotZoneFromRR(j.p, records, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range policies.skips {
+		snapshot.skips[k] = v
+	}
+	policies.PushMetrics(j.p)
+
+	j.p.G().L.Debugf("%s policy zone:'%s' rr:'%d' nxdomain:'%d' redirect:'%d' skipped:'%d'",
+		id, zone, len(rr), policies.counts[RPZActionNXDOMAIN],
+		policies.counts[RPZActionRedirect], len(policies.skips))
+
+	return snapshot, nil
+}
+
+// Checking if zone state is a response policy zone
+func (s TZoneState) IsPolicy() bool {
+	return s.Config != nil && s.Config.Type == TransferTypeRPZ
+}
+
+func (s TZoneState) precedence() int {
+	if s.Config == nil || s.Config.RPZ == nil {
+		return 0
+	}
+	return s.Config.RPZ.Precedence
+}
+
+// Getting policy zones in order of precedence
+func (z *ZonesState) PolicyZones() []string {
+	states := z.States()
+
+	var zones []string
+	for zone, state := range states {
+		if state.IsPolicy() {
+			zones = append(zones, zone)
+		}
+	}
+
+	sort.Slice(zones, func(i, j int) bool {
+		pi, pj := states[zones[i]].precedence(), states[zones[j]].precedence()
+		if pi != pj {
+			return pi < pj
+		}
+		return zones[i] < zones[j]
+	})
+
+	return zones
+}
+
+// Getting policies rrsets taking precedence over zone: all
+// policies for zones (or empty zone) and policies of higher
+// precedence for policy zone
+func (z *ZonesState) PolicyRRsets(zone string) map[string][]dns.RR {
+	out := make(map[string][]dns.RR)
+	if z == nil {
+		return out
+	}
+
+	for _, policy := range z.PolicyZones() {
+		if policy == zone {
+			break
+		}
+		snapshot := z.GetLastZoneSnapshot(policy)
+		if snapshot == nil {
+			continue
+		}
+		for k, v := range snapshot.rrsets {
+			if _, ok := out[k]; !ok {
+				out[k] = v
+			}
+		}
+	}
+	return out
+}
+
+// Getting record of key (if any) placed into maps if zone
+// data is absent: records of policy zones of lower precedence
+// and then records of zones, empty zone means all of them
+func (z *ZonesState) UnderlyingRR(zone string, key string) dns.RR {
+	policies := z.PolicyZones()
+
+	var zones []string
+	for name, state := range z.States() {
+		if !state.IsPolicy() {
+			zones = append(zones, name)
+		}
+	}
+	sort.Strings(zones)
+
+	start := 0
+	for i, policy := range policies {
+		if policy == zone {
+			start = i + 1
+		}
+	}
+
+	ordered := append(policies[start:], zones...)
+	for _, name := range ordered {
+		snapshot := z.GetLastZoneSnapshot(name)
+		if snapshot == nil {
+			continue
+		}
+		if rrset, ok := snapshot.rrsets[key]; ok && len(rrset) == 1 {
+			return rrset[0]
+		}
+	}
+	return nil
+}
+
+// Adding actions to restore underlying records of policies
+// removed from policy zone, actions of other zones are
+// returned as is
+func (z *ZonesState) RestorePolicyActions(zone string, sa *TSnapshotActions) *TSnapshotActions {
+	if z == nil || sa == nil {
+		return sa
+	}
+
+	state, ok := z.GetState(zone)
+	if !ok || !state.IsPolicy() {
+		return sa
+	}
+
+	out := sa.Without(nil)
+	for i, sections := range sa.actions {
+		for k := range sections[SectionDeletion] {
+			if _, ok := sections[SectionAddition][k]; ok {
+				continue
+			}
+			if rr := z.UnderlyingRR(zone, k); rr != nil {
+				out.Add(i, SectionAddition, k, rr)
+			}
+		}
+	}
+	return out
+}
+
+// policy record (name and type) counted in hits map
+type TPolicyTrigger struct {
+	Zone   string
+	Action string
+	Name   string
+	Qtype  uint16
+}
+
+// Getting policy records placed into maps keyed as records
+// keys, policy zone of higher precedence wins. As translated
+// records do not keep action, records of sinkhole addresses
+// are taken as NXDOMAIN action
+func (z *ZonesState) PolicyTriggers() map[string]TPolicyTrigger {
+	out := make(map[string]TPolicyTrigger)
+
+	for _, zone := range z.PolicyZones() {
+		snapshot := z.GetLastZoneSnapshot(zone)
+		state, ok := z.GetState(zone)
+		if snapshot == nil || !ok {
+			continue
+		}
+
+		sinkhole := make(map[string]bool)
+		if state.Config.RPZ != nil {
+			for _, address := range state.Config.RPZ.Sinkhole {
+				if addr, err := netip.ParseAddr(address); err == nil {
+					sinkhole[addr.String()] = true
+				}
+			}
+		}
+
+		for k, rrset := range snapshot.rrsets {
+			if _, ok := out[k]; ok || len(rrset) == 0 {
+				continue
+			}
+
+			var address string
+			switch v := rrset[0].(type) {
+			case *dns.A:
+				address = v.A.String()
+			case *dns.AAAA:
+				address = v.AAAA.String()
+			default:
+				continue
+			}
+
+			action := RPZActionRedirect
+			if addr, err := netip.ParseAddr(address); err == nil && sinkhole[addr.String()] {
+				action = RPZActionNXDOMAIN
+			}
+
+			h := rrset[0].Header()
+			out[k] = TPolicyTrigger{Zone: zone, Action: action,
+				Name: strings.ToLower(h.Name), Qtype: h.Rrtype}
+		}
+	}
+	return out
+}
+
+// Registering policy records in hits map (and removing keys
+// of policies removed) and pushing hits metrics, returning
+// hits per policy name
+func (z *ZonesState) SyncPolicyHits(hits offloader.RRHits) (map[string]uint64, error) {
+	id := "(rpz) (hits)"
+
+	triggers := z.PolicyTriggers()
+
+	keys, err := hits.Keys()
+	if err != nil {
+		z.p.G().L.Errorf("%s error listing hits map keys, err:'%s'", id, err)
+		return nil, err
+	}
+
+	registered := make(map[string]bool)
+	for _, key := range keys {
+		name, err := UnpackName(key.Qname)
+		if err == nil {
+			k := fmt.Sprintf("%s-%s", Dot(strings.ToLower(name)), dns.Type(key.Qtype).String())
+			if _, ok := triggers[k]; ok {
+				registered[k] = true
+				continue
+			}
+		}
+		if err := hits.Remove(key.Qname, key.Qtype); err != nil {
+			z.p.G().L.Errorf("%s error removing hits key:'%s', err:'%s'", id, key.AsRawString(), err)
+		}
+	}
+
+	totals := make(map[string]map[string]uint64)
+	for _, zone := range z.PolicyZones() {
+		totals[zone] = make(map[string]uint64)
+	}
+
+	out := make(map[string]uint64)
+	policies := make(map[string]TPolicyTrigger)
+	for k, trigger := range triggers {
+		qname, err := PackName(trigger.Name)
+		if err != nil {
+			continue
+		}
+
+		if !registered[k] {
+			if err := hits.Register(qname, trigger.Qtype); err != nil {
+				z.p.G().L.Errorf("%s error registering policy:'%s' in hits map, err:'%s'",
+					id, k, err)
+			}
+			continue
+		}
+
+		count, err := hits.Hits(qname, trigger.Qtype)
+		if err != nil {
+			continue
+		}
+		out[trigger.Name] += count
+		policies[trigger.Name] = trigger
+		totals[trigger.Zone][trigger.Action] += count
+	}
+
+	for zone, actions := range totals {
+		for _, action := range RPZActions {
+			tags := []string{fmt.Sprintf("zone=%s", RemoveDot(zone)), fmt.Sprintf("action=%s", action)}
+			z.p.PushMetric(MetricRPZHits, tags, float64(actions[action]))
+		}
+	}
+
+	for name, count := range out {
+		if count == 0 {
+			continue
+		}
+		trigger := policies[name]
+		tags := []string{fmt.Sprintf("zone=%s", RemoveDot(trigger.Zone)),
+			fmt.Sprintf("action=%s", trigger.Action), fmt.Sprintf("policy=%s", RemoveDot(name))}
+		z.p.PushMetric(MetricRPZPolicyHits, tags, float64(count))
+	}
+
+	z.p.G().L.Debugf("%s policies records:'%d' registered:'%d'", id, len(triggers), len(registered))
+
+	return out, nil
+}
+
+// Syncing policy hits periodically, hits map could be
+// absent (e.g. offloader is not running)
+func (z *ZonesState) RunPolicyHits(ctx context.Context) error {
+	id := "(rpz) (hits) (worker)"
+
+	timer := time.NewTicker(DefaultRPZHitsInterval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			hits := &offloader.RRHitsMap{PinPath: z.p.L().PinPath}
+			if err := hits.LoadPinnedMap(); err != nil {
+				z.p.G().L.Debugf("%s error loading pinned map:'%s', err:'%s'", id,
+					hits.MapName(), err)
+				continue
+			}
+			z.SyncPolicyHits(hits)
+			hits.Close()
+		case <-ctx.Done():
+			z.p.G().L.Debugf("%s context stop on policy hits", id)
+			return ctx.Err()
+		}
+	}
+}
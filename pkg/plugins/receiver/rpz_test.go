@@ -0,0 +1,438 @@
+package receiver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+
+	"github.com/yandex/yadns-controller/pkg/plugins/offloader"
+)
+
+var TestRPZ = `$ORIGIN rpz.example.
+$TTL 300
+@                    IN SOA ns.rpz.example. hostmaster.rpz.example. 7 3600 600 86400 60
+@                    IN NS  ns.rpz.example.
+bad.example.com      IN CNAME .
+evil.example.com     IN A    192.0.2.10
+evil.example.com     IN AAAA 2001:db8::10
+both.example.com     IN CNAME .
+both.example.com     IN A    192.0.2.11
+*.wild.example.com   IN CNAME .
+nodata.example.com   IN CNAME *.
+pass.example.com     IN CNAME rpz-passthru.
+32.1.2.0.192.rpz-client-ip IN CNAME .
+txt.example.com      IN TXT  "text"
+`
+
+func TestRPZTranslate(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+
+	filename := filepath.Join(t.TempDir(), "rpz.example")
+	if err := os.WriteFile(filename, []byte(TestRPZ), 0644); err != nil {
+		t.Error(fmt.Sprintf("Error writing policy zone, err:'%s'", err))
+		return
+	}
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		// sinkhole addresses
+		sinkhole []string
+
+		// expected records in snapshot as "name/type/address"
+		records []string
+
+		// expected counts per action and skip reason
+		actions map[string]int
+		skipped map[string]int
+	}
+
+	var Tests = []TTest{
+		{
+			"9d4e5f6a-7b8c-4d9e-8f0a-1b2c3d4e5f6a",
+			true,
+			[]string{"192.0.2.254", "2001:db8::254"},
+			[]string{
+				"bad.example.com./A/192.0.2.254",
+				"bad.example.com./AAAA/2001:db8::254",
+				"both.example.com./A/192.0.2.254",
+				"both.example.com./AAAA/2001:db8::254",
+				"evil.example.com./A/192.0.2.10",
+				"evil.example.com./AAAA/2001:db8::10",
+			},
+			map[string]int{RPZActionNXDOMAIN: 2, RPZActionRedirect: 1},
+			map[string]int{SkipReasonTrigger: 2, SkipReasonAction: 3, SkipReasonType: 2},
+		},
+		{
+			// no sinkhole set, NXDOMAIN policies are skipped
+			"0e5f6a7b-8c9d-4e0f-9a1b-2c3d4e5f6a7b",
+			true,
+			nil,
+			[]string{
+				"both.example.com./A/192.0.2.11",
+				"evil.example.com./A/192.0.2.10",
+				"evil.example.com./AAAA/2001:db8::10",
+			},
+			map[string]int{RPZActionNXDOMAIN: 0, RPZActionRedirect: 2},
+			map[string]int{SkipReasonTrigger: 2, SkipReasonAction: 4, SkipReasonType: 2},
+		},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		var importer ImporterWorker
+		importer.p = p
+
+		options := TZoneSnapshotOptions{
+			Source: SourceRPZ,
+			Server: fmt.Sprintf("file:///%s", filename),
+			RPZ:    &TConfigRPZ{Sinkhole: test.sinkhole},
+		}
+
+		snapshot, err := importer.GetZoneSnapshotRPZ("rpz.example", &options)
+		if err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error making snapshot, err:'%s'", err))
+			continue
+		}
+
+		if serial, _ := snapshot.Serial(); serial != 7 {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("serial expected:'7' got:'%d'", serial))
+			continue
+		}
+
+		var got []string
+		for _, rrset := range snapshot.rrsets {
+			for _, r := range rrset {
+				address := ""
+				switch v := r.(type) {
+				case *dns.A:
+					address = v.A.String()
+				case *dns.AAAA:
+					address = v.AAAA.String()
+				}
+				got = append(got, fmt.Sprintf("%s/%s/%s", r.Header().Name,
+					dns.Type(r.Header().Rrtype).String(), address))
+			}
+		}
+		sort.Strings(got)
+
+		if strings.Join(got, " ") != strings.Join(test.records, " ") {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("records expected:'%s' got:'%s'",
+				strings.Join(test.records, " "), strings.Join(got, " ")))
+			continue
+		}
+
+		policies := NewRPZPolicies("rpz.example", options.RPZ)
+		rr, _ := ParseMasterFile(filename, "rpz.example", "")
+		if _, err := policies.Translate(rr); err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error translating, err:'%s'", err))
+			continue
+		}
+
+		failed := false
+		for action, count := range test.actions {
+			if policies.counts[action] != count {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("action:'%s' expected:'%d' got:'%d'",
+					action, count, policies.counts[action]))
+				failed = true
+			}
+		}
+
+		report := snapshot.SkipReport("")
+		for reason, count := range test.skipped {
+			if report.Counts[reason] != count {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("reason:'%s' expected:'%d' got:'%d'",
+					reason, count, report.Counts[reason]))
+				failed = true
+			}
+		}
+		if failed {
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
+
+func TestRPZPrecedence(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+
+	zones := NewZonesState(p)
+
+	state := func(zone string, config TConfigZone, records ...string) {
+		var rr []dns.RR
+		for _, s := range records {
+			r, _ := dns.NewRR(s)
+			rr = append(rr, r)
+		}
+		snapshot := TSnapshotZone{p: p, zone: zone, rrsets: make(map[string][]dns.RR)}
+		for _, r := range rr {
+			h := r.Header()
+			key := fmt.Sprintf("%s-%s", h.Name, dns.Type(h.Rrtype).String())
+			snapshot.rrsets[key] = append(snapshot.rrsets[key], r)
+		}
+		zones.zones[zone] = TZoneState{Zone: zone, Config: &config, SnapshotID: 0,
+			Snapshots: map[int]TSnapshotZone{0: snapshot}}
+	}
+
+	state("example.com", TConfigZone{Type: TransferTypeAXFR},
+		"www.example.com. 300 IN A 192.0.2.1",
+		"mail.example.com. 300 IN A 192.0.2.2")
+	state("block.rpz", TConfigZone{Type: TransferTypeRPZ, RPZ: &TConfigRPZ{Precedence: 10}},
+		"www.example.com. 60 IN A 192.0.2.254",
+		"mail.example.com. 60 IN A 192.0.2.254")
+	state("allow.rpz", TConfigZone{Type: TransferTypeRPZ, RPZ: &TConfigRPZ{Precedence: 1}},
+		"mail.example.com. 60 IN A 192.0.2.100")
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		// zone and key requested
+		zone string
+		key  string
+
+		// expected address of policy over zone (if any)
+		// and underlying record address
+		policy     string
+		underlying string
+	}
+
+	var Tests = []TTest{
+		{"1f6a7b8c-9d0e-4f1a-8b2c-3d4e5f6a7b8c", true, "example.com", "www.example.com.-A", "192.0.2.254", "192.0.2.254"},
+		{"2a7b8c9d-0e1f-4a2b-9c3d-4e5f6a7b8c9d", true, "example.com", "mail.example.com.-A", "192.0.2.100", "192.0.2.100"},
+		{"3b8c9d0e-1f2a-4b3c-8d4e-5f6a7b8c9d0e", true, "block.rpz", "mail.example.com.-A", "192.0.2.100", "192.0.2.2"},
+		{"4c9d0e1f-2a3b-4c4d-9e5f-6a7b8c9d0e1f", true, "allow.rpz", "mail.example.com.-A", "", "192.0.2.254"},
+	}
+
+	address := func(r dns.RR) string {
+		if r == nil {
+			return ""
+		}
+		return r.(*dns.A).A.String()
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		policy := ""
+		if rrset, ok := zones.PolicyRRsets(test.zone)[test.key]; ok {
+			policy = address(rrset[0])
+		}
+		if policy != test.policy {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("policy expected:'%s' got:'%s'", test.policy, policy))
+			continue
+		}
+
+		if underlying := address(zones.UnderlyingRR(test.zone, test.key)); underlying != test.underlying {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("underlying expected:'%s' got:'%s'",
+				test.underlying, underlying))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+
+	// removing policy restores underlying record
+	r, _ := dns.NewRR("mail.example.com. 60 IN A 192.0.2.100")
+	var sa TSnapshotActions
+	sa.actions = make(map[int]map[int]map[string][]dns.RR)
+	sa.Add(0, SectionDeletion, "mail.example.com.-A", r)
+
+	restored := zones.RestorePolicyActions("allow.rpz", &sa)
+	additions := restored.actions[0][SectionAddition]["mail.example.com.-A"]
+	if len(additions) != 1 || address(additions[0]) != "192.0.2.254" {
+		t.Error(fmt.Sprintf("expected policy of lower precedence restored, got:'%v'", additions))
+	}
+
+	if zones.RestorePolicyActions("example.com", &sa) != &sa {
+		t.Error("expected actions of zone returned as is")
+	}
+}
+
+// in memory hits map
+type testRRHits struct {
+	hits map[offloader.RRKey]uint64
+}
+
+func (m *testRRHits) MapName() string      { return "test_rr_hits" }
+func (m *testRRHits) LoadPinnedMap() error { return nil }
+func (m *testRRHits) Close() error         { return nil }
+
+func (m *testRRHits) key(qname offloader.RRQname, qtype uint16) offloader.RRKey {
+	return offloader.RRKey{Qtype: qtype, Qclass: offloader.DefaultClassIN, Qname: qname}
+}
+
+func (m *testRRHits) Register(qname offloader.RRQname, qtype uint16) error {
+	if _, ok := m.hits[m.key(qname, qtype)]; !ok {
+		m.hits[m.key(qname, qtype)] = 0
+	}
+	return nil
+}
+
+func (m *testRRHits) Remove(qname offloader.RRQname, qtype uint16) error {
+	delete(m.hits, m.key(qname, qtype))
+	return nil
+}
+
+func (m *testRRHits) Hits(qname offloader.RRQname, qtype uint16) (uint64, error) {
+	hits, ok := m.hits[m.key(qname, qtype)]
+	if !ok {
+		return 0, fmt.Errorf("key not found")
+	}
+	return hits, nil
+}
+
+func (m *testRRHits) Keys() ([]offloader.RRKey, error) {
+	var out []offloader.RRKey
+	for k := range m.hits {
+		out = append(out, k)
+	}
+	return out, nil
+}
+
+func (m *testRRHits) hit(name string, qtype uint16) {
+	qname, _ := PackName(name)
+	if _, ok := m.hits[m.key(qname, qtype)]; ok {
+		m.hits[m.key(qname, qtype)]++
+	}
+}
+
+func TestRPZHits(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+
+	zones := NewZonesState(p)
+
+	state := func(zone string, config TConfigZone, records ...string) {
+		snapshot := TSnapshotZone{p: p, zone: zone, rrsets: make(map[string][]dns.RR)}
+		for _, s := range records {
+			r, _ := dns.NewRR(s)
+			snapshot.rrsets[RecordKey(r)] = append(snapshot.rrsets[RecordKey(r)], r)
+		}
+		zones.zones[zone] = TZoneState{Zone: zone, Config: &config, SnapshotID: 0,
+			Snapshots: map[int]TSnapshotZone{0: snapshot}}
+	}
+
+	state("example.com", TConfigZone{Type: TransferTypeAXFR},
+		"www.example.com. 300 IN A 192.0.2.1")
+	state("block.rpz", TConfigZone{Type: TransferTypeRPZ,
+		RPZ: &TConfigRPZ{Precedence: 10, Sinkhole: []string{"192.0.2.254"}}},
+		"bad.example.com. 60 IN A 192.0.2.254",
+		"evil.example.com. 60 IN A 192.0.2.10",
+		"evil.example.com. 60 IN AAAA 2001:db8::10")
+	state("allow.rpz", TConfigZone{Type: TransferTypeRPZ, RPZ: &TConfigRPZ{Precedence: 1}},
+		"bad.example.com. 60 IN A 192.0.2.100")
+
+	hits := &testRRHits{hits: make(map[offloader.RRKey]uint64)}
+
+	// stale key of policy removed
+	hits.Register(func() offloader.RRQname { q, _ := PackName("old.example.com"); return q }(), dns.TypeA)
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		// hits made before sync as "name/type"
+		hits []string
+
+		// expected keys registered and hits per policy
+		keys     []string
+		policies map[string]uint64
+	}
+
+	var Tests = []TTest{
+		{
+			// policies are registered, stale key removed
+			"5d0e1f2a-3b4c-4d5e-8f6a-7b8c9d0e1f2a",
+			true,
+			nil,
+			[]string{"bad.example.com-A", "evil.example.com-A", "evil.example.com-AAAA"},
+			map[string]uint64{},
+		},
+		{
+			// hits of policy zone of higher precedence, names
+			// out of policies are not counted
+			"6e1f2a3b-4c5d-4e6f-9a7b-8c9d0e1f2a3b",
+			true,
+			[]string{"bad.example.com/A", "evil.example.com/A", "evil.example.com/AAAA",
+				"evil.example.com/AAAA", "www.example.com/A"},
+			[]string{"bad.example.com-A", "evil.example.com-A", "evil.example.com-AAAA"},
+			map[string]uint64{"bad.example.com.": 1, "evil.example.com.": 3},
+		},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		for _, h := range test.hits {
+			parts := strings.Split(h, "/")
+			hits.hit(parts[0], dns.StringToType[parts[1]])
+		}
+
+		policies, err := zones.SyncPolicyHits(hits)
+		if err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error syncing hits, err:'%s'", err))
+			continue
+		}
+
+		var keys []string
+		for k := range hits.hits {
+			name, _ := UnpackName(k.Qname)
+			keys = append(keys, fmt.Sprintf("%s-%s", name, dns.Type(k.Qtype).String()))
+		}
+		sort.Strings(keys)
+
+		if strings.Join(keys, ",") != strings.Join(test.keys, ",") {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("keys expected:'%v' got:'%v'", test.keys, keys))
+			continue
+		}
+
+		failed := false
+		for name, count := range test.policies {
+			if policies[name] != count {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("policy:'%s' expected:'%d' got:'%d'",
+					name, count, policies[name]))
+				failed = true
+			}
+		}
+		if failed {
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+
+	triggers := zones.PolicyTriggers()
+	if triggers["bad.example.com.-A"].Zone != "allow.rpz" ||
+		triggers["bad.example.com.-A"].Action != RPZActionRedirect {
+		t.Error(fmt.Sprintf("expected policy of higher precedence, got:'%v'", triggers["bad.example.com.-A"]))
+	}
+}
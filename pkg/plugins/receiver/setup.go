@@ -133,6 +133,16 @@ type TConfigDataCooker struct {
 
 	// snapshot per bpf map
 	Snapshots TSnapshotsDataCooker `json:"snapshots" yaml:"snapshots"`
+
+	// mass change guards checked before zone
+	// update is applied
+	Guards TConfigGuards `json:"guards" yaml:"guards"`
+
+	// precedence of zones owning maps entries
+	Ownership TConfigOwnership `json:"ownership" yaml:"ownership"`
+
+	// write-ahead journal of maps mutations
+	Journal TConfigJournal `json:"journal" yaml:"journal"`
 }
 
 type TSnapshotsDataCooker struct {
@@ -153,6 +163,9 @@ type TSnapshotsDataCooker struct {
 
 	// number snapshots to keep
 	Keep int `json:"keep" yaml:"keep"`
+
+	// purge of zones removed from configuration
+	Purge TConfigPurge `json:"purge" yaml:"purge"`
 }
 
 type TConfigDataVerifier struct {
@@ -161,6 +174,9 @@ type TConfigDataVerifier struct {
 	Interval int `json:"interval" yaml:"interval"`
 
 	VerifyOnCook bool `json:"verify-oncook" yaml:"verify-oncook"`
+
+	// repair policy of differences found
+	Repair TConfigRepair `json:"repair" yaml:"repair"`
 }
 
 type TConfigVerifier struct {
@@ -196,6 +212,14 @@ func (t *TConfigVerifier) AsString() string {
 type TDataReceiverOptions struct {
 	Incremental bool `json:"incremental" yaml:"incremental"`
 
+	// file to persist zones added or changed
+	// in runtime via api
+	ZonesOverlay string `json:"zones-overlay" yaml:"zones-overlay"`
+
+	// file to persist local records overriding
+	// zones data
+	Overrides string `json:"overrides" yaml:"overrides"`
+
 	// snapshot per zone
 	Snapshots TSnapshotsDataReceiver `json:"snapshots" yaml:"snapshots"`
 }
@@ -212,6 +236,10 @@ type TSnapshotsDataReceiver struct {
 	// syncing blob to bpf.Map age on startup
 	StartupValidInterval int `json:"startup-validinterval" yaml:"startup-validinterval"`
 
+	// rebuilding zones states from pinned maps on startup
+	// if snapshots are lost or too old
+	RebuildOnStartup bool `json:"rebuild-onstartup" yaml:"rebuild-onstartup"`
+
 	// snapshots directory
 	Directory string `json:"directory" yaml:"directory"`
 }
@@ -220,6 +248,16 @@ type TConfigHTTPTransfer struct {
 	// enabling axfr transfer
 	Enabled bool `json:"enabled" yaml:"enabled"`
 
+	// http client settings for endpoints
+	Client TConfigHTTPClient `json:"client" yaml:"client"`
+
+	// base directory for $INCLUDE in zone files, if
+	// not set $INCLUDE is not allowed
+	IncludeDirectory string `json:"include-directory" yaml:"include-directory"`
+
+	// backoff for failed endpoints
+	Backoff TBackoff `json:"backoff" yaml:"backoff"`
+
 	// zones configuration
 	Zones TZones `json:"zones" yaml:"zones"`
 }
@@ -242,6 +280,21 @@ type TConfigAxfrTransfer struct {
 
 	// notify configuration
 	Notify TNotify `json:"notify" yaml:"notify"`
+
+	// named TSIG keys for transfers
+	Tsig TConfigTsig `json:"tsig" yaml:"tsig"`
+
+	// TLS settings for tls:// primaries
+	TLS TConfigTLS `json:"tls" yaml:"tls"`
+}
+
+type TConfigTsig struct {
+	// keys defined in configuration, map key is
+	// TSIG key name
+	Keys map[string]TTsigKey `json:"keys" yaml:"keys"`
+
+	// a list of BIND-style key files
+	KeyFiles []string `json:"key-files" yaml:"key-files"`
 }
 
 type TNotify struct {
@@ -286,6 +339,18 @@ type TZones struct {
 	// Aliases primary map configuration (used in
 	// zones configuration)
 	Primary map[string]string `json:"primary" yaml:"primary"`
+
+	// TSIG keys names per primary (or alias) tried
+	// in order, zone keys take precedence
+	PrimaryTsigKeys map[string][]string `json:"primary-tsig-keys" yaml:"primary-tsig-keys"`
+
+	// TLS settings per primary (or alias) overriding
+	// global TLS settings
+	PrimaryTLS map[string]TConfigTLS `json:"primary-tls" yaml:"primary-tls"`
+
+	// catalog zones (rfc9432) defining member secondary
+	// zones, map key is catalog zone name
+	Catalogs map[string]TConfigCatalog `json:"catalogs" yaml:"catalogs"`
 }
 
 type TConfigZone struct {
@@ -301,8 +366,26 @@ type TConfigZone struct {
 	// override refresh counter
 	Refresh int `json:"refresh" yaml:"refresh"`
 
-	// a type of zone: could be axfr, http (of file)
+	// a type of zone: could be axfr, http (of file),
+	// random, rpz, hosts, json or dynamic
 	Type string `json:"type" yaml:"type"`
+
+	// TSIG keys names tried in order (for dynamic
+	// zone keys allowed to sign updates)
+	TsigKeys []string `json:"tsig-keys" yaml:"tsig-keys"`
+
+	// addresses (or networks) allowed to update
+	// dynamic zone
+	AllowUpdate []string `json:"allow-update,omitempty" yaml:"allow-update,omitempty"`
+
+	// generator settings for "random" zone type
+	Random *TConfigRandom `json:"random,omitempty" yaml:"random,omitempty"`
+
+	// policy settings for "rpz" zone type
+	RPZ *TConfigRPZ `json:"rpz,omitempty" yaml:"rpz,omitempty"`
+
+	// mass change guards overriding cooker guards
+	Guards *TConfigGuards `json:"guards,omitempty" yaml:"guards,omitempty"`
 }
 
 func (t *TConfigZone) String() string {
@@ -316,6 +399,10 @@ func (t *TConfigZone) String() string {
 		out = append(out, fmt.Sprintf("refresh:'%d'", t.Refresh))
 	}
 
+	if len(t.TsigKeys) > 0 {
+		out = append(out, fmt.Sprintf("tsig-keys:['%s']", strings.Join(t.TsigKeys, ",")))
+	}
+
 	return strings.Join(out, ",")
 }
 
@@ -335,6 +422,21 @@ type TTransfer struct {
 
 	// transfer interval
 	TransfersInterval int `json:"transfers-interval" yaml:"transfers-interval"`
+
+	// backoff for failed primaries
+	Backoff TBackoff `json:"backoff" yaml:"backoff"`
+}
+
+type TBackoff struct {
+	// initial backoff interval in seconds, it is
+	// doubled on each consecutive failure
+	Initial int `json:"initial" yaml:"initial"`
+
+	// max backoff interval in seconds
+	Max int `json:"max" yaml:"max"`
+
+	// jitter as a fraction of interval [0, 1]
+	Jitter float64 `json:"jitter" yaml:"jitter"`
 }
 
 type TConfigCooker struct {
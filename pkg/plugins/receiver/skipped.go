@@ -0,0 +1,175 @@
+package receiver
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// skip report keeps records of zone that are not placed
+// into bpf maps with the reason: qname is too long, rrset
+// has more than one address, type is not supported,
+// record is out of zone or policy trigger or action of
+// response policy zone is not supported
+
+const (
+	// skip reasons as shown in report and metrics
+	SkipReasonLength       = "qname-length"
+	SkipReasonMultiAddress = "multi-address"
+	SkipReasonType         = "unsupported-type"
+	SkipReasonZone         = "out-of-zone"
+	SkipReasonTrigger      = "rpz-trigger"
+	SkipReasonAction       = "rpz-action"
+	SkipReasonUnknown      = "unknown"
+
+	// skipped records per zone and reason
+	MetricSkipped = "receiver-skipped"
+)
+
+var SkipReasons = []string{SkipReasonLength, SkipReasonMultiAddress,
+	SkipReasonType, SkipReasonZone, SkipReasonTrigger, SkipReasonAction}
+
+func SkipReasonAsString(reason int) string {
+	switch reason {
+	case SkipByLength:
+		return SkipReasonLength
+	case SkipByCount:
+		return SkipReasonMultiAddress
+	case SkipByType:
+		return SkipReasonType
+	case SkipByZone:
+		return SkipReasonZone
+	case SkipByTrigger:
+		return SkipReasonTrigger
+	case SkipByAction:
+		return SkipReasonAction
+	}
+	return SkipReasonUnknown
+}
+
+type TSkippedRecord struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+	Record string `json:"record"`
+}
+
+func NewSkippedRecord(r dns.RR, reason int) TSkippedRecord {
+	h := r.Header()
+	return TSkippedRecord{
+		Name:   h.Name,
+		Type:   dns.Type(h.Rrtype).String(),
+		Reason: SkipReasonAsString(reason),
+		Record: r.String(),
+	}
+}
+
+type TSkipReport struct {
+	Zone      string    `json:"zone"`
+	Serial    uint32    `json:"serial"`
+	Timestamp time.Time `json:"timestamp"`
+
+	// number of skipped records per reason
+	Counts map[string]int `json:"counts"`
+
+	// skipped records (optionally filtered
+	// by reason)
+	Records []TSkippedRecord `json:"records"`
+}
+
+func (t *TSnapshotZone) Skip(r dns.RR, reason int) {
+	if t.skips == nil {
+		t.skips = make(map[string]TSkippedRecord)
+	}
+	t.skips[r.String()] = NewSkippedRecord(r, reason)
+}
+
+func (t *TSnapshotZone) Unskip(r dns.RR) {
+	delete(t.skips, r.String())
+}
+
+// Getting all skipped records of snapshot: records filtered
+// out and records of rrsets with more than one address (they
+// are kept in snapshot but not placed into bpf maps)
+func (t *TSnapshotZone) Skipped() []TSkippedRecord {
+	var out []TSkippedRecord
+	for _, record := range t.skips {
+		out = append(out, record)
+	}
+
+	for _, rrset := range t.rrsets {
+		if len(rrset) <= 1 {
+			continue
+		}
+		for _, r := range rrset {
+			out = append(out, NewSkippedRecord(r, SkipByCount))
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Reason != out[j].Reason {
+			return out[i].Reason < out[j].Reason
+		}
+		return out[i].Record < out[j].Record
+	})
+
+	return out
+}
+
+// Making skip report of snapshot, if reason is set only
+// records of reason are listed (counts are always full)
+func (t *TSnapshotZone) SkipReport(reason string) *TSkipReport {
+	var report TSkipReport
+	report.Zone = t.zone
+	report.Serial, _ = t.Serial()
+	report.Timestamp = t.timestamp
+	report.Counts = make(map[string]int)
+	report.Records = []TSkippedRecord{}
+
+	for _, r := range SkipReasons {
+		report.Counts[r] = 0
+	}
+
+	for _, record := range t.Skipped() {
+		report.Counts[record.Reason]++
+		if len(reason) > 0 && record.Reason != reason {
+			continue
+		}
+		report.Records = append(report.Records, record)
+	}
+
+	return &report
+}
+
+func (z *ZonesState) GetSkipReport(zone string, reason string) (*TSkipReport, error) {
+	if _, ok := z.GetZonesConfigs()[zone]; !ok {
+		return nil, fmt.Errorf("%w zone:'%s'", ErrZoneNotFound, zone)
+	}
+
+	lock := z.ZoneLock(zone)
+	lock.Lock()
+	defer lock.Unlock()
+
+	snapshot := z.GetLastZoneSnapshot(zone)
+	if snapshot == nil {
+		return nil, fmt.Errorf("%w zone:'%s' has no snapshot", ErrZoneNotFound, zone)
+	}
+
+	return snapshot.SkipReport(reason), nil
+}
+
+// Pushing number of skipped records per reason of zone
+// snapshot (zero counts are pushed as well to reset them)
+func (z *ZonesState) PushSkipMetrics(zone string, snapshot *TSnapshotZone) {
+	if z == nil || snapshot == nil {
+		return
+	}
+
+	report := snapshot.SkipReport("")
+	for _, reason := range SkipReasons {
+		tags := []string{fmt.Sprintf("zone=%s", zone), fmt.Sprintf("reason=%s", reason)}
+		z.p.PushMetric(MetricSkipped, tags, float64(report.Counts[reason]))
+	}
+}
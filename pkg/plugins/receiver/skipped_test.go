@@ -0,0 +1,141 @@
+package receiver
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestSkipReport(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+
+	soa := func(serial int) string {
+		return fmt.Sprintf("example.net. 3600 IN SOA ns1.example.net. hostmaster.example.net. %d 3600 600 86400 300", serial)
+	}
+
+	long := fmt.Sprintf("%s.example.net.", strings.Repeat("a", 40))
+
+	axfr := []string{
+		soa(1),
+		"example.net. 3600 IN NS ns1.example.net.",
+		"www.example.net. 300 IN A 192.0.2.1",
+		"multi.example.net. 300 IN A 192.0.2.2",
+		"multi.example.net. 300 IN A 192.0.2.3",
+		fmt.Sprintf("%s 300 IN A 192.0.2.4", long),
+		"www.example.org. 300 IN A 192.0.2.5",
+		soa(1),
+	}
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		// ixfr applied to snapshot (if any)
+		ixfr []string
+
+		// expected counts per reason and records
+		counts  map[string]int
+		records int
+		skipped int
+	}
+
+	var Tests = []TTest{
+		{
+			"4e9f0a1b-2c3d-4e4f-8a5b-6c7d8e9f0a1b",
+			true,
+			nil,
+			map[string]int{SkipReasonLength: 1, SkipReasonMultiAddress: 2,
+				SkipReasonType: 1, SkipReasonZone: 1},
+			3, 5,
+		},
+		{
+			// removing long name and adding txt and
+			// resolving multi address rrset
+			"5f0a1b2c-3d4e-4f5a-9b6c-7d8e9f0a1b2c",
+			true,
+			[]string{
+				soa(2),
+				soa(1),
+				fmt.Sprintf("%s 300 IN A 192.0.2.4", long),
+				"multi.example.net. 300 IN A 192.0.2.3",
+				soa(2),
+				"www.example.net. 300 IN TXT \"text\"",
+				soa(2),
+			},
+			map[string]int{SkipReasonLength: 0, SkipReasonMultiAddress: 0,
+				SkipReasonType: 2, SkipReasonZone: 1},
+			2, 3,
+		},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		var rr []dns.RR
+		for _, s := range axfr {
+			r, _ := dns.NewRR(s)
+			rr = append(rr, r)
+		}
+
+		snapshot, err := NewSnapshotZoneFromRR(p, rr, "example.net")
+		if err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error making snapshot, err:'%s'", err))
+			continue
+		}
+
+		if len(test.ixfr) > 0 {
+			var ixfr []dns.RR
+			for _, s := range test.ixfr {
+				r, _ := dns.NewRR(s)
+				ixfr = append(ixfr, r)
+			}
+			if _, _, _, err := snapshot.ApplyIXFR(ixfr); err != nil {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("error applying ixfr, err:'%s'", err))
+				continue
+			}
+		}
+
+		report := snapshot.SkipReport("")
+		failed := false
+		for reason, count := range test.counts {
+			if report.Counts[reason] != count {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("reason:'%s' expected:'%d' got:'%d'",
+					reason, count, report.Counts[reason]))
+				failed = true
+			}
+		}
+		if failed {
+			continue
+		}
+
+		records, skipped := snapshot.Counters()
+		if records != test.records || skipped != test.skipped || len(report.Records) != skipped {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("records:'%d' skipped:'%d' expected '%d' '%d'",
+				records, skipped, test.records, test.skipped))
+			continue
+		}
+
+		for _, reason := range SkipReasons {
+			filtered := snapshot.SkipReport(reason)
+			if len(filtered.Records) != test.counts[reason] {
+				t.Error("\nUUID", test.uuid, fmt.Sprintf("reason:'%s' filtered records expected:'%d' got:'%d'",
+					reason, test.counts[reason], len(filtered.Records)))
+				failed = true
+			}
+		}
+		if failed {
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
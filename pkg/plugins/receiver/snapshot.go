@@ -1,13 +1,10 @@
 package receiver
 
 import (
-	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
-	"io"
-	"io/ioutil"
-	"net/http"
 	"os"
 	"sort"
 	"strings"
@@ -33,6 +30,11 @@ type TSnapshotZone struct {
 	// "imported" rrset snapshot data
 	rrsets map[string][]dns.RR
 
+	// records skipped by filter with their reasons
+	// (multi-address rrsets are not here, they are
+	// detected in rrsets)
+	skips map[string]TSkippedRecord
+
 	// imports actions detected for
 	// current snapshot via blob or via
 	// AXFR/IXFR methods
@@ -50,6 +52,9 @@ type TImportActions struct {
 
 	// actions for IXFR mode
 	actions *TSnapshotActions
+
+	// http response validators of snapshot (if any)
+	validated *THTTPResponse
 }
 
 const (
@@ -57,6 +62,20 @@ const (
 	SourceFile = 102
 	SourceAXFR = 103
 
+	// synthetic random zone
+	SourceRandom = 104
+
+	// response policy zone
+	SourceRPZ = 105
+
+	// records sources: hosts file and
+	// json (or yaml) records list
+	SourceHosts = 106
+	SourceJSON  = 107
+
+	// dynamic zone updated by DNS UPDATE
+	SourceDynamic = 108
+
 	SourceUnknown = 0
 
 	// default zone snapshot options
@@ -69,22 +88,48 @@ type TZoneSnapshotOptions struct {
 	Incremental bool
 
 	// possible source type of snapshot, possible
-	// values: SourceHTTP, SourceFile, SourceAXFR
+	// values: SourceHTTP, SourceFile, SourceAXFR,
+	// SourceRandom, SourceRPZ, SourceHosts,
+	// SourceJSON, SourceDynamic
 	Source int
 
 	// primary server to fetch data
 	Server string
 
+	// http endpoints tried in order by http source
+	// (server if not set)
+	Endpoints []string
+
 	// optional key (if set to some value AXFR
 	// transfer uses it), should be set in DIG
 	// format
 	Key string
 
+	// named TSIG keys tried in order
+	Tsig []*TTsigKey
+
+	// TLS configuration for tls:// primaries
+	TLS *tls.Config
+
+	// random zone generator settings
+	Random *TConfigRandom
+
+	// response policy zone settings
+	RPZ *TConfigRPZ
+
 	// setting if memory snapshots already has
 	// a snapshot of zone requested
 	SnapshotMode int
 }
 
+// Getting http endpoints to fetch data from in order
+func (t *TZoneSnapshotOptions) HTTPEndpoints() []string {
+	if len(t.Endpoints) > 0 {
+		return t.Endpoints
+	}
+	return []string{t.Server}
+}
+
 func NewXFR(data string) ([]dns.RR, error) {
 	rows := strings.Split(data, "\n")
 
@@ -115,6 +160,17 @@ func NewSnapshotZone(p *TReceiverPlugin, data string, zone string) (*TSnapshotZo
 		return nil, err
 	}
 
+	p.G().L.Debugf("%s received from  zone:'%s' bytes:'%d' rr:'%d'", id,
+		zone, len(data), len(rr))
+
+	return NewSnapshotZoneFromRR(p, rr, zone)
+}
+
+// Creating snapshot from a list of AXFR-like records
+// with SOA, records are filtered as loosed import
+func NewSnapshotZoneFromRR(p *TReceiverPlugin, rr []dns.RR, zone string) (*TSnapshotZone, error) {
+	id := "(snapshot)"
+
 	var config TConfigImporter
 	config.Zone = append(config.Zone, zone)
 	config.Server = "localhost"
@@ -130,6 +186,7 @@ func NewSnapshotZone(p *TReceiverPlugin, data string, zone string) (*TSnapshotZo
 	snapshot.p = p
 	snapshot.soa = soa
 	snapshot.rrsets = rrsets
+	snapshot.skips = importer.skips
 
 	snapshot.zone = zone
 	if len(zone) == 0 {
@@ -144,8 +201,8 @@ func NewSnapshotZone(p *TReceiverPlugin, data string, zone string) (*TSnapshotZo
 
 	snapshot.timestamp = time.Now()
 
-	p.G().L.Debugf("%s received from  zone:'%s' bytes:'%d' rr:'%d' -> rrsets:'%d'", id,
-		zone, len(data), len(rr), len(rrsets))
+	p.G().L.Debugf("%s zone:'%s' rr:'%d' -> rrsets:'%d'", id,
+		zone, len(rr), len(rrsets))
 
 	return &snapshot, err
 }
@@ -156,12 +213,7 @@ func NewSnapshotZoneFromFile(p *TReceiverPlugin, filename string,
 
 	p.G().L.Debugf("%s reading zone:'%s' snapshot:'%s'", id, zone, filename)
 
-	content, err := ioutil.ReadFile(filename)
-	if err != nil {
-		return nil, err
-	}
-
-	return NewSnapshotZone(p, string(content), zone)
+	return ReadSnapshotFile(p, filename, zone)
 }
 
 func NewSnapshotZoneFromBlob(p *TReceiverPlugin, path string,
@@ -199,9 +251,9 @@ func NewSnapshotZoneFromSnapshot(p *TReceiverPlugin, zone string) (*TSnapshotZon
 func ValidateSnapshotZoneFile(p *TReceiverPlugin, filename string, zone string) bool {
 	id := "(validate)"
 
-	// getting a list of file attributes, could be 0
-	// if file does not exist
-	age := GetFileAge(filename)
+	// getting snapshot age, could be 0 if file
+	// does not exist
+	age := SnapshotFileAge(filename)
 	if age > 0 {
 		options := p.L().Options
 		max := options.Snapshots.ReadValidInterval
@@ -228,46 +280,14 @@ type TZoneConfig struct {
 func NewSnapshotZoneFromEndpoint(p *TReceiverPlugin, ctx context.Context,
 	endpoint []string, zone string) (*TSnapshotZone, error) {
 
-	id := "(snapshot) (endpoint)"
-
-	var config TZoneConfig
-	config.Zone = zone
+	source := NewHTTPSource(p, &p.L().HTTPTransfer.Client)
 
-	content, err := json.MarshalIndent(config, "", "   ")
+	response, err := source.Fetch(ctx, endpoint, zone, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	var body []byte
-	for _, e := range endpoint {
-		t0 := time.Now()
-		p.G().L.Debugf("%s requesting zone:'%s' snapshot over endpoint:'%s'", id, zone, e)
-
-		bodyReader := bytes.NewReader(content)
-
-		req, err := http.NewRequestWithContext(ctx, "POST", e, bodyReader)
-		if err != nil {
-			return nil, err
-		}
-		req.Header.Set("User-Agent", p.G().Runtime.GetUseragent())
-
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil {
-			return nil, err
-		}
-
-		defer resp.Body.Close()
-
-		if body, err = io.ReadAll(resp.Body); err != nil {
-			return nil, err
-		}
-
-		p.G().L.Debugf("%s recevied size:'%d' finished in '%s'",
-			id, len(body), time.Since(t0))
-	}
-
-	return NewSnapshotZone(p, string(body), zone)
+	return NewSnapshotZone(p, string(response.Body), zone)
 }
 
 func (t *TSnapshotZone) WriteSnapshotZone(dryrun bool) error {
@@ -291,20 +311,19 @@ func (t *TSnapshotZone) WriteSnapshotZone(dryrun bool) error {
 		return nil
 	}
 
-	var b strings.Builder
-	fmt.Fprintf(&b, "%s\n", t.soa.String())
-
-	for _, vv := range t.rrsets {
-		for _, v := range vv {
-			fmt.Fprintf(&b, "%s\n", v.String())
-		}
+	content, err := t.Container()
+	if err != nil {
+		return err
 	}
-	fmt.Fprintf(&b, "%s\n", t.soa.String())
 
-	if err = os.WriteFile(filename, []byte(b.String()), 0644); err != nil {
+	if err = WriteFileAtomic(filename, content, 0644); err != nil {
 		return err
 	}
 
+	if err = t.WriteGeneration(content, t.p.L().Cooker.Snapshots.Keep); err != nil {
+		t.p.G().L.Errorf("%s error writing generation zone:'%s', err:'%s'", id, t.zone, err)
+	}
+
 	return nil
 }
 
@@ -363,6 +382,32 @@ func (t *TSnapshotZone) Equal(s *TSnapshotZone) bool {
 	return true
 }
 
+// Copying snapshot with its rrsets, so changes applied
+// to copy do not change the snapshot
+func (t *TSnapshotZone) Clone() *TSnapshotZone {
+	s := *t
+	s.rrsets = make(map[string][]dns.RR, len(t.rrsets))
+	for k, v := range t.rrsets {
+		s.rrsets[k] = append([]dns.RR{}, v...)
+	}
+	return &s
+}
+
+// Getting number of records in snapshot and number of
+// records skipped: filtered out or not placed into bpf
+// maps as rrset has more than one record
+func (t *TSnapshotZone) Counters() (int, int) {
+	records := 0
+	skipped := len(t.skips)
+	for _, rrset := range t.rrsets {
+		records += len(rrset)
+		if len(rrset) > 1 {
+			skipped += len(rrset)
+		}
+	}
+	return records, skipped
+}
+
 func (t *TSnapshotZone) Refresh() (uint32, error) {
 	if t.soa == nil {
 		return 0, nil
@@ -500,6 +545,29 @@ func (t *TSnapshotActions) Add(action int, section int, key string, r dns.RR) {
 		append(t.actions[action][section][key], r)
 }
 
+// Making a copy of actions without keys set (if any)
+func (t *TSnapshotActions) Without(keys map[string][]dns.RR) *TSnapshotActions {
+	if t == nil {
+		return nil
+	}
+
+	var out TSnapshotActions
+	out.actions = make(map[int]map[int]map[string][]dns.RR)
+	for i, sections := range t.actions {
+		for section, actions := range sections {
+			for k, rr := range actions {
+				if _, ok := keys[k]; ok {
+					continue
+				}
+				for _, r := range rr {
+					out.Add(i, section, k, r)
+				}
+			}
+		}
+	}
+	return &out
+}
+
 func (t *TSnapshotActions) Dump(p *TReceiverPlugin) {
 	id := "(notifier) (snapshot) (actions) (dump)"
 
@@ -624,56 +692,68 @@ func (t *TSnapshotZone) ApplyIXFR(ixfr []dns.RR) (dns.RR, int, *TSnapshotActions
 
 		// Here we have only RRSET records, and we need
 		// filtering them to have A/AAAA only also checking
-		// fqdn length
+		// fqdn length, skipped records should be tracked
+		// w.r.t operations performed
+		reason := 0
+		switch {
+		case len(t.zone) > 0 && !dns.IsSubDomain(Dot(t.zone), h.Name):
+			reason = SkipByZone
+		case h.Rrtype != dns.TypeA && h.Rrtype != dns.TypeAAAA:
+			reason = SkipByType
+		case len(h.Name) >= offloader.DefaultQnameMaxLength:
+			reason = SkipByLength
+		}
 
-		// adding only ALLOWED types of RR
-		if h.Rrtype == dns.TypeA || h.Rrtype == dns.TypeAAAA {
-			if len(h.Name) >= offloader.DefaultQnameMaxLength {
-				// skipping but we should ensure that
-				// fqdn length is also could be skipped
-				// w.r.t operations performed
-				continue
+		if reason > 0 {
+			switch section {
+			case SectionAddition:
+				t.Skip(r, reason)
+			case SectionDeletion:
+				t.Unskip(r)
 			}
-			key := fmt.Sprintf("%s-%s", h.Name, dns.Type(h.Rrtype).String())
-
-			t.p.G().L.Debugf("%s %s [%d]/[%d] k:'%s' rr:'%s''\n", id, SectionString(section),
-				i, len(ixfr), key, r.String())
-
-			if _, ok := t.rrsets[key]; !ok {
-				// we do not have corresponding key, deletation
-				// is automatically skipped, for creation we need
-				// check if record exists. Snapshot could contain
-				// a multiple RRSET for one type
-				switch section {
-				case SectionAddition:
-					SA.Add(action, SectionAddition, key, r)
+			continue
+		}
 
-					t.rrsets[key] = append(t.rrsets[key], r)
-				}
-				continue
-			}
+		// adding only ALLOWED types of RR
+		key := fmt.Sprintf("%s-%s", h.Name, dns.Type(h.Rrtype).String())
 
-			rrset := t.rrsets[key]
-			index := t.Exists(r, rrset)
+		t.p.G().L.Debugf("%s %s [%d]/[%d] k:'%s' rr:'%s''\n", id, SectionString(section),
+			i, len(ixfr), key, r.String())
 
+		if _, ok := t.rrsets[key]; !ok {
+			// we do not have corresponding key, deletation
+			// is automatically skipped, for creation we need
+			// check if record exists. Snapshot could contain
+			// a multiple RRSET for one type
 			switch section {
-			case SectionDeletion:
-				if index >= 0 {
-					t.rrsets[key] = SlicesDelete(t.rrsets[key], index, index+1)
-					SA.Add(action, SectionDeletion, key, r)
-					if len(t.rrsets[key]) == 0 {
-						// also removing a map entry if no
-						// any data found in slice
-						delete(t.rrsets, key)
-					}
-
-				}
 			case SectionAddition:
-				//if index < 0 {
-				t.rrsets[key] = append(t.rrsets[key], r)
 				SA.Add(action, SectionAddition, key, r)
-				//}
+
+				t.rrsets[key] = append(t.rrsets[key], r)
+			}
+			continue
+		}
+
+		rrset := t.rrsets[key]
+		index := t.Exists(r, rrset)
+
+		switch section {
+		case SectionDeletion:
+			if index >= 0 {
+				t.rrsets[key] = SlicesDelete(t.rrsets[key], index, index+1)
+				SA.Add(action, SectionDeletion, key, r)
+				if len(t.rrsets[key]) == 0 {
+					// also removing a map entry if no
+					// any data found in slice
+					delete(t.rrsets, key)
+				}
+
 			}
+		case SectionAddition:
+			//if index < 0 {
+			t.rrsets[key] = append(t.rrsets[key], r)
+			SA.Add(action, SectionAddition, key, r)
+			//}
 		}
 	}
 
@@ -749,6 +829,9 @@ type TVerifyResult struct {
 
 	// number of unexpected records
 	Unexpected int `json:"unexpected"`
+
+	// repairs made w.r.t verifier policy
+	Repair *TRepairResult `json:"repair,omitempty"`
 }
 
 func (t *TVerifyResult) AsString() string {
@@ -805,6 +888,12 @@ func (t *TSnapshotZone) VerifyMap() (*TVerifyResult, *TChangedSetZone, error) {
 		}
 	}
 
+	// overrides are expected in maps instead of
+	// snapshot records
+	for k, rr := range t.p.Overrides().Records() {
+		rrsrc[k] = rr
+	}
+
 	serial, _ := t.Serial()
 	t.p.G().L.Debugf("%s src axfr zone:'%s' SOA serial:'%d' synced map entries:'%d' verified:'%d' as '%d'",
 		id, t.zone, serial, result.Total, result.Verified, len(rrsrc))
@@ -905,6 +994,34 @@ func (t *TSnapshotZone) VerifyMap() (*TVerifyResult, *TChangedSetZone, error) {
 	return &result, &changed, err
 }
 
+// Purging all snapshot records from bpf maps (e.g. as
+// zone is removed from configuration), rrsets with more
+// than one record are never synced into maps, skipping them
+func (t *TSnapshotZone) PurgeMap(dryrun bool) (*TSyncMapResult, error) {
+	var actions TSnapshotActions
+	actions.actions = make(map[int]map[int]map[string][]dns.RR)
+
+	for k, rrset := range t.rrsets {
+		if len(rrset) > 1 {
+			continue
+		}
+		for _, rr := range rrset {
+			actions.Add(0, SectionDeletion, k, rr)
+		}
+	}
+
+	// actions are applied w.r.t the snapshot state after
+	// deletion, so we need an empty one
+	var purged TSnapshotZone
+	purged.p = t.p
+	purged.zone = t.zone
+	purged.soa = t.soa
+	purged.timestamp = time.Now()
+	purged.rrsets = make(map[string][]dns.RR)
+
+	return purged.SyncMap(TransferModeIXFR, &actions, dryrun)
+}
+
 func (t *TSnapshotZone) LoadMaps() (map[uint16]offloader.RRMap, error) {
 	var err error
 	id := "(snapshot) (load) (maps)"
@@ -973,12 +1090,12 @@ func (t *TSyncMapResult) AsString() string {
 	return strings.Join(out, ",")
 }
 
+// Syncing maps with snapshot, mutations are staged and
+// written via journal (if enabled), ownership claims are
+// committed only as mutations are written
 func (t *TSnapshotZone) SyncMap(mode int, sa *TSnapshotActions,
 	dryrun bool) (*TSyncMapResult, error) {
 
-	var err error
-	var result TSyncMapResult
-
 	id := fmt.Sprintf("(snapshot) (sync) (map) %s", DryrunString(dryrun))
 
 	rrmaps, err := t.LoadMaps()
@@ -988,22 +1105,77 @@ func (t *TSnapshotZone) SyncMap(mode int, sa *TSnapshotActions,
 	}
 	defer t.UnloadMaps(rrmaps)
 
+	return t.writeMap(mode, sa, dryrun, rrmaps)
+}
+
+func (t *TSnapshotZone) writeMap(mode int, sa *TSnapshotActions, dryrun bool,
+	rrmaps map[uint16]offloader.RRMap) (*TSyncMapResult, error) {
+
 	obj := NewObjects(t.p)
 
+	journal := t.p.Journal()
+	if dryrun || journal == nil || !journal.Enabled() {
+		result, plan, err := t.syncMap(mode, sa, dryrun, obj, rrmaps)
+		if err == nil && !dryrun {
+			t.p.Owners().Commit(plan)
+		}
+		return result, err
+	}
+
+	batch := NewJournalBatch(t.zone, mode)
+	obj.Stage(batch)
+
+	result, plan, err := t.syncMap(mode, sa, dryrun, obj, rrmaps)
+	if err != nil {
+		return result, err
+	}
+
+	if err = journal.Write(NewObjects(t.p), rrmaps, batch); err != nil {
+		return result, err
+	}
+
+	// batch is durable and applied
+	t.p.Owners().Commit(plan)
+
+	return result, nil
+}
+
+// Syncing maps with snapshot, ownership plan (if any) is
+// returned to be committed by caller
+func (t *TSnapshotZone) syncMap(mode int, sa *TSnapshotActions, dryrun bool,
+	obj *Objects, rrmaps map[uint16]offloader.RRMap) (*TSyncMapResult, *TOwnershipPlan, error) {
+
+	var err error
+	var result TSyncMapResult
+
+	id := fmt.Sprintf("(snapshot) (sync) (map) %s", DryrunString(dryrun))
+
 	serial, _ := t.Serial()
 
+	// overrides take precedence over snapshot records
+	// and are not changed by IXFR actions
+	overrides := t.p.Overrides()
+
 	switch mode {
 	case TransferModeAXFR:
+		// entries owned by zone (or zones) are replaced only
+		// if ownership is tracked
+		if owners := t.p.Owners(); owners != nil {
+			return t.SyncOwnedMap(obj, rrmaps, owners, dryrun)
+		}
+
 		// sync map in AXFR mode assumes that we clean all
 		// rr and push data (beware import mode only not
 		// receiver, as receiver should make snapshots for
 		// all configured zones at once, stacking data
 
+		// removals of clean are staged into journal batch
+		// as other mutations (if journal enabled)
 		if !dryrun {
 			if result.Removed, err = obj.CleanRR(); err != nil {
 				t.p.G().L.Errorf("%s error cleaning map zone:'%s', err:'%s'",
 					id, t.zone, err)
-				return nil, err
+				return nil, nil, err
 			}
 		}
 
@@ -1012,9 +1184,11 @@ func (t *TSnapshotZone) SyncMap(mode int, sa *TSnapshotActions,
 			t.p.G().L.Debugf("%s skip clean RR in bpf map as dry-run set", id)
 		}
 
+		rrsets := overrides.ApplyRRsets(t.rrsets)
+
 		entries := 0
 		created := 0
-		for i, rrset := range t.rrsets {
+		for i, rrset := range rrsets {
 			entries += len(rrset)
 
 			// we have to skip all fqdn with IP addresses
@@ -1030,14 +1204,14 @@ func (t *TSnapshotZone) SyncMap(mode int, sa *TSnapshotActions,
 				dump := entries < DefaultDumpMaxRRsets*10
 				if dump {
 					t.p.G().L.Debugf("%s [%d]/[%d] axfr k:'%s' CREATE as %s'", id,
-						created, len(t.rrsets), i,
+						created, len(rrsets), i,
 						rr.String())
 				}
 
 				if !dryrun {
 					if err = obj.UpdateDNSRR(ObjectCreate, rrmaps[h.Rrtype], rr, dump); err != nil {
 						t.p.G().L.Errorf("%s error create rr:'%s', err:'%s'", id, rr.String(), err)
-						return nil, err
+						return nil, nil, err
 					}
 				}
 			}
@@ -1050,6 +1224,27 @@ func (t *TSnapshotZone) SyncMap(mode int, sa *TSnapshotActions,
 
 	case TransferModeIXFR:
 
+		// overrides of keys touched are written as their
+		// actions are skipped
+		touched := overrides.Touched(sa)
+		sa = overrides.FilterActions(sa)
+
+		// policies of higher precedence are not changed,
+		// removed policies restore records underlying
+		if len(t.zone) > 0 {
+			sa = sa.Without(t.p.zones.PolicyRRsets(t.zone))
+			sa = t.p.zones.RestorePolicyActions(t.zone, sa)
+		}
+
+		// keys owned by others are not changed by zone,
+		// records of keys released are restored
+		owners := t.p.Owners()
+		var plan *TOwnershipPlan
+		if len(t.zone) > 0 && owners != nil {
+			plan = owners.PlanActions(t.zone, t.rrsets, sa)
+			sa = plan.FilterActions(t.zone, sa)
+		}
+
 		// actions are grouped by int number of IXFR group, so
 		// we need to sort all keys first
 		var ixfr []int
@@ -1151,9 +1346,25 @@ func (t *TSnapshotZone) SyncMap(mode int, sa *TSnapshotActions,
 				id, i, t.zone, serial, created, removed)
 		}
 
+		for k, rr := range touched {
+			if _, err := writeOwnedRR(obj, rrmaps, rr, dryrun); err != nil {
+				t.p.G().L.Errorf("%s error writing override k:'%s', err:'%s'", id, k, err)
+			}
+		}
+
+		if plan != nil {
+			for k, rr := range plan.Restore(t.zone) {
+				if _, err := writeOwnedRR(obj, rrmaps, rr, dryrun); err != nil {
+					t.p.G().L.Errorf("%s error restoring k:'%s', err:'%s'", id, k, err)
+				}
+			}
+		}
+
 		result.Created = created
 		result.Removed = removed
+
+		return &result, plan, err
 	}
 
-	return &result, err
+	return &result, nil, err
 }
@@ -8,6 +8,7 @@ import (
 	"crypto/sha1"
 	"crypto/sha256"
 	"crypto/sha512"
+	"crypto/tls"
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
@@ -127,21 +128,91 @@ type TransferOptions struct {
 
 	// optional TSIG key
 	Key string `json:"key"`
+
+	// optional named TSIG key, overrides key above
+	Tsig *TTsigKey `json:"-"`
+
+	// TLS configuration for tls:// primaries and
+	// connection opened (e.g. on SOA request) to reuse,
+	// connection is closed after transfer
+	TLS  *tls.Config `json:"-"`
+	Conn *dns.Conn   `json:"-"`
 }
 
 // Getting SOA record of zone as transfer option
 func RequestSOA(server string, zone string) (*TransferOptions, error) {
+	return RequestSOAWithOptions(server, zone, nil)
+}
+
+// Getting SOA record of zone signed with TSIG key (if set),
+// TLS primaries are requested via connection (if set) or
+// a new TLS connection, connection is kept in options
+// returned to reuse for transfer
+func RequestSOAWithOptions(server string, zone string, options *TransferOptions) (*TransferOptions, error) {
+
+	if options == nil {
+		options = new(TransferOptions)
+	}
 
 	c := new(dns.Client)
 
 	m := new(dns.Msg)
 	m.SetQuestion(Dot(zone), dns.TypeSOA)
-	r, _, err := c.Exchange(m, server)
+
+	if key := options.Tsig; key != nil {
+		c.TsigSecret = map[string]string{key.Name: key.Secret}
+		provider := TsigHMACProvider(key.Secret)
+		c.TsigProvider = &provider
+		m.SetTsig(key.Name, key.Algorithm, 300, time.Now().Unix())
+	}
+
+	address, secure := ParsePrimaryServer(server)
+
+	conn := options.Conn
+	if conn == nil && secure {
+		var err error
+		if conn, err = DialTransfer(server, options.TLS); err != nil {
+			return nil, err
+		}
+	}
+
+	var r *dns.Msg
+	var err error
+	if conn != nil {
+		r, _, err = c.ExchangeWithConn(m, conn)
+	} else {
+		r, _, err = c.Exchange(m, address)
+	}
+
 	if err != nil {
 		// network error possible occurs
+		if conn != nil {
+			conn.Close()
+		}
 		return nil, err
 	}
 
+	result, err := soaTransferOptions(r)
+	if err != nil {
+		// connection is still usable (e.g. for the next
+		// TSIG key), closing only if we opened it
+		if conn != nil && options.Conn == nil {
+			conn.Close()
+		}
+		return nil, err
+	}
+
+	result.Tsig = options.Tsig
+	result.TLS = options.TLS
+	result.Conn = conn
+
+	return result, nil
+}
+
+// Converting SOA response into transfer options
+func soaTransferOptions(r *dns.Msg) (*TransferOptions, error) {
+	var err error
+
 	if r == nil {
 		// something went wrong
 		err = fmt.Errorf("error occured on exchange")
@@ -149,8 +220,10 @@ func RequestSOA(server string, zone string) (*TransferOptions, error) {
 	}
 
 	if r.Rcode != dns.RcodeSuccess {
-		// possible REFUSED response
-		err = fmt.Errorf("error on receive code response")
+		// possible REFUSED response or NOTAUTH if
+		// TSIG verification failed
+		err = fmt.Errorf("error on receive code response, rcode: %d (%s)",
+			r.Rcode, dns.RcodeToString[r.Rcode])
 		return nil, err
 	}
 
@@ -196,9 +269,31 @@ func TransferZone(server string, zone string, options *TransferOptions) ([]dns.R
 
 	dt := new(dns.Transfer)
 
-	dt.DialTimeout = 10 * time.Second
+	dt.DialTimeout = DefaultDialTimeout
 	dt.ReadTimeout = 20 * time.Second
 
+	address, secure := ParsePrimaryServer(server)
+	if secure {
+		var tlsconfig *tls.Config
+		if options != nil {
+			tlsconfig = options.TLS
+		}
+		if tlsconfig == nil {
+			var err error
+			if tlsconfig, err = NewTransferTLS(&TConfigTLS{}, server); err != nil {
+				return out, err
+			}
+		}
+		dt.TLS = tlsconfig
+	}
+
+	// reusing connection, it is closed on transfer
+	// completion, so could not be used again
+	if options != nil && options.Conn != nil {
+		dt.Conn = options.Conn
+		options.Conn = nil
+	}
+
 	m := new(dns.Msg)
 	if tsig != nil {
 		dt.TsigSecret = tsig
@@ -217,7 +312,14 @@ func TransferZone(server string, zone string, options *TransferOptions) ([]dns.R
 		m.SetIxfr(Dot(zone), options.Serial, options.Ns, options.Mbox)
 	}
 
-	if tsig != nil {
+	if options != nil && options.Tsig != nil {
+		dt.TsigSecret = map[string]string{options.Tsig.Name: options.Tsig.Secret}
+		provider := TsigHMACProvider(options.Tsig.Secret)
+		dt.TsigProvider = &provider
+		m.SetTsig(options.Tsig.Name, options.Tsig.Algorithm, 300, time.Now().Unix())
+	}
+
+	if tsig != nil && (options == nil || options.Tsig == nil) {
 		sig := dns.HmacMD5
 		switch Dot(algo) {
 		case HmacSHA256:
@@ -230,14 +332,17 @@ func TransferZone(server string, zone string, options *TransferOptions) ([]dns.R
 		m.SetTsig(GetTSIGUser(tsig), sig, 300, time.Now().Unix())
 	}
 
-	c, err := dt.In(m, server)
+	c, err := dt.In(m, address)
 	if err != nil {
+		if dt.Conn != nil {
+			dt.Conn.Close()
+		}
 		return out, err
 	}
 
 	for msg := range c {
 		if msg.Error != nil {
-			err = fmt.Errorf("error transferring zone '%s' from '%s', err:'%s'",
+			err = fmt.Errorf("error transferring zone '%s' from '%s', err:'%w'",
 				zone, server, msg.Error)
 			return out, err
 		}
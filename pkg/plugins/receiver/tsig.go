@@ -0,0 +1,426 @@
+package receiver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/miekg/dns"
+)
+
+// named TSIG keys could be defined in configuration or
+// loaded from BIND-style key files, each zone and each
+// primary alias could reference a list of keys, keys are
+// tried in order (as key rollover is in progress)
+
+type TTsigKey struct {
+	// key name (fqdn)
+	Name string `json:"name" yaml:"name"`
+
+	// one of hmac algorithms
+	Algorithm string `json:"algorithm" yaml:"algorithm"`
+
+	// base64 encoded secret
+	Secret string `json:"secret" yaml:"secret"`
+}
+
+func (t *TTsigKey) String() string {
+	return fmt.Sprintf("name:'%s' algorithm:'%s'", t.Name, t.Algorithm)
+}
+
+// Getting canonical algorithm name supported by miekg/dns
+// and tsig provider, algorithm could be set in short
+// (BIND) form, e.g. "hmac-sha256"
+func TsigAlgorithm(algorithm string) (string, error) {
+	algorithms := map[string]string{
+		"hmac-md5":                 HmacMD5,
+		"hmac-md5.sig-alg.reg.int": HmacMD5,
+		"hmac-sha1":                HmacSHA1,
+		"hmac-sha224":              HmacSHA224,
+		"hmac-sha256":              HmacSHA256,
+		"hmac-sha384":              HmacSHA384,
+		"hmac-sha512":              HmacSHA512,
+	}
+
+	name := RemoveDot(strings.ToLower(strings.TrimSpace(algorithm)))
+	if len(name) == 0 {
+		return HmacSHA256, nil
+	}
+
+	if value, ok := algorithms[name]; ok {
+		return value, nil
+	}
+
+	return "", fmt.Errorf("tsig algorithm:'%s' is not supported", algorithm)
+}
+
+// Validating and normalizing key: name and algorithm
+// should be in canonical form, secret should be base64
+func NewTsigKey(name string, algorithm string, secret string) (*TTsigKey, error) {
+	var key TTsigKey
+
+	key.Name = dns.CanonicalName(name)
+	if _, ok := dns.IsDomainName(key.Name); !ok || key.Name == "." {
+		return nil, fmt.Errorf("tsig key name:'%s' is not correct", name)
+	}
+
+	var err error
+	if key.Algorithm, err = TsigAlgorithm(algorithm); err != nil {
+		return nil, err
+	}
+
+	key.Secret = strings.TrimSpace(secret)
+	if len(key.Secret) == 0 {
+		return nil, fmt.Errorf("tsig key:'%s' has empty secret", name)
+	}
+	if _, err := fromBase64([]byte(key.Secret)); err != nil {
+		return nil, fmt.Errorf("tsig key:'%s' secret is not base64, err:'%s'", name, err)
+	}
+
+	return &key, nil
+}
+
+// Tokenizing BIND configuration: strings, braces, semicolons
+// and words, comments ("#", "//" and "/* */") are skipped
+func bindTokens(content string) ([]string, error) {
+	var tokens []string
+
+	runes := []rune(content)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			continue
+
+		case r == '#' || (r == '/' && i+1 < len(runes) && runes[i+1] == '/'):
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			end := strings.Index(string(runes[i+2:]), "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i += 2 + len([]rune(string(runes[i+2:])[:end])) + 1
+
+		case r == '{' || r == '}' || r == ';':
+			tokens = append(tokens, string(r))
+
+		case r == '"':
+			j := i + 1
+			for j < len(runes) && runes[j] != '"' {
+				j++
+			}
+			if j >= len(runes) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, string(runes[i:j+1]))
+			i = j
+
+		default:
+			j := i
+			for j < len(runes) && !unicode.IsSpace(runes[j]) &&
+				!strings.ContainsRune("{};\"#", runes[j]) {
+				j++
+			}
+			tokens = append(tokens, string(runes[i:j]))
+			i = j - 1
+		}
+	}
+
+	return tokens, nil
+}
+
+// Parsing BIND-style keys definitions:
+// key "name" { algorithm hmac-sha256; secret "base64"; };
+func ParseBindKeys(content string) ([]*TTsigKey, error) {
+	tokens, err := bindTokens(content)
+	if err != nil {
+		return nil, err
+	}
+
+	unquote := func(s string) string {
+		return strings.Trim(s, "\"")
+	}
+
+	expect := func(i int, token string) error {
+		if i >= len(tokens) || tokens[i] != token {
+			found := "EOF"
+			if i < len(tokens) {
+				found = tokens[i]
+			}
+			return fmt.Errorf("expected '%s' found '%s'", token, found)
+		}
+		return nil
+	}
+
+	var keys []*TTsigKey
+	i := 0
+	for i < len(tokens) {
+		if tokens[i] != "key" {
+			return nil, fmt.Errorf("unexpected statement '%s'", tokens[i])
+		}
+		if i+1 >= len(tokens) {
+			return nil, fmt.Errorf("key name expected")
+		}
+		name := unquote(tokens[i+1])
+		i += 2
+
+		if err := expect(i, "{"); err != nil {
+			return nil, fmt.Errorf("key:'%s' %s", name, err)
+		}
+		i++
+
+		algorithm := ""
+		secret := ""
+		for i < len(tokens) && tokens[i] != "}" {
+			if i+2 >= len(tokens) {
+				return nil, fmt.Errorf("key:'%s' unterminated clause", name)
+			}
+			clause, value := tokens[i], unquote(tokens[i+1])
+			switch clause {
+			case "algorithm":
+				algorithm = value
+			case "secret":
+				secret = value
+			default:
+				return nil, fmt.Errorf("key:'%s' unexpected clause '%s'", name, clause)
+			}
+			if err := expect(i+2, ";"); err != nil {
+				return nil, fmt.Errorf("key:'%s' %s", name, err)
+			}
+			i += 3
+		}
+
+		if err := expect(i, "}"); err != nil {
+			return nil, fmt.Errorf("key:'%s' %s", name, err)
+		}
+		if err := expect(i+1, ";"); err != nil {
+			return nil, fmt.Errorf("key:'%s' %s", name, err)
+		}
+		i += 2
+
+		if len(algorithm) == 0 {
+			return nil, fmt.Errorf("key:'%s' has no algorithm", name)
+		}
+
+		key, err := NewTsigKey(name, algorithm, secret)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// named keys available for zones and primaries
+type TsigKeyring struct {
+	keys map[string]*TTsigKey
+}
+
+func (t *TsigKeyring) Get(name string) (*TTsigKey, bool) {
+	if t == nil {
+		return nil, false
+	}
+	key, ok := t.keys[dns.CanonicalName(name)]
+	return key, ok
+}
+
+func (t *TsigKeyring) Count() int {
+	if t == nil {
+		return 0
+	}
+	return len(t.keys)
+}
+
+// Loading keys from configuration and key files, errors
+// are collected for each key (or file) and the rest of
+// keys are loaded
+func NewTsigKeyring(config *TConfigTsig) (*TsigKeyring, []error) {
+	var keyring TsigKeyring
+	keyring.keys = make(map[string]*TTsigKey)
+
+	var errs []error
+
+	for name, v := range config.Keys {
+		key, err := NewTsigKey(name, v.Algorithm, v.Secret)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		keyring.keys[key.Name] = key
+	}
+
+	for _, filename := range config.KeyFiles {
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		keys, err := ParseBindKeys(string(content))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("file:'%s', err:'%s'", filename, err))
+			continue
+		}
+
+		for _, key := range keys {
+			keyring.keys[key.Name] = key
+		}
+	}
+
+	return &keyring, errs
+}
+
+// Detecting if transfer (or SOA request) failed as TSIG
+// verification failed on server or client side
+func IsTsigError(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	tsigs := []error{dns.ErrSig, dns.ErrTime, dns.ErrKeyAlg, dns.ErrSecret, dns.ErrAuth}
+	for _, e := range tsigs {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+
+	// server responds NOTAUTH as TSIG verification failed
+	notauth := []string{
+		fmt.Sprintf("rcode: %d", dns.RcodeNotAuth),
+		dns.RcodeToString[dns.RcodeNotAuth],
+	}
+	for _, s := range notauth {
+		if strings.Contains(err.Error(), s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Loading named TSIG keys from configuration, keys
+// failed to load are reported and skipped
+func (z *ZonesState) LoadTsigKeys() {
+	id := "(zones) (tsig)"
+
+	keyring, errs := NewTsigKeyring(&z.p.L().AxfrTransfer.Tsig)
+	for _, err := range errs {
+		z.p.G().L.Errorf("%s error loading tsig key, err:'%s'", id, err)
+	}
+
+	z.p.G().L.Debugf("%s loaded tsig keys:'%d'", id, keyring.Count())
+	z.keyring = keyring
+}
+
+// Getting a list of TSIG keys to use for zone transfer
+// via primary: zone keys override primary alias keys
+func (z *ZonesState) TsigKeys(zone string, primary string) ([]*TTsigKey, error) {
+	var names []string
+
+	config, err := z.GetConfig(zone)
+	if err == nil && len(config.TsigKeys) > 0 {
+		names = config.TsigKeys
+	}
+
+	if len(names) == 0 {
+		var configs []map[string][]string
+		if z.p.L().AxfrTransfer.Enabled {
+			configs = append(configs, z.p.L().AxfrTransfer.Zones.PrimaryTsigKeys)
+		}
+		if z.p.L().HTTPTransfer.Enabled {
+			configs = append(configs, z.p.L().HTTPTransfer.Zones.PrimaryTsigKeys)
+		}
+		for _, config := range configs {
+			if keys, ok := config[primary]; ok {
+				names = keys
+				break
+			}
+		}
+	}
+
+	var keys []*TTsigKey
+	for _, name := range names {
+		key, ok := z.keyring.Get(name)
+		if !ok {
+			return nil, fmt.Errorf("zone:'%s' tsig key:'%s' not found", zone, name)
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// Running request with keys in order, if request failed as
+// TSIG verification the next key is tried, nil key means
+// request without TSIG, the key succeeded is returned
+func WithTsigKeys(keys []*TTsigKey, request func(key *TTsigKey) error,
+	failed func(key *TTsigKey, err error)) (*TTsigKey, error) {
+
+	if len(keys) == 0 {
+		return nil, request(nil)
+	}
+
+	var err error
+	for _, key := range keys {
+		if err = request(key); err == nil || !IsTsigError(err) {
+			return key, err
+		}
+
+		if failed != nil {
+			failed(key, err)
+		}
+	}
+
+	return nil, err
+}
+
+// Moving key to be the first one to try, e.g. the
+// key succeeded on SOA request is used for transfer
+func PreferTsigKey(keys []*TTsigKey, key *TTsigKey) []*TTsigKey {
+	if key == nil {
+		return keys
+	}
+
+	out := []*TTsigKey{key}
+	for _, k := range keys {
+		if k != key {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// Counting TSIG failures per zone and key name
+func (z *ZonesState) TsigFailure(zone string, key *TTsigKey, err error) {
+	id := "(zones) (tsig)"
+
+	if z == nil {
+		return
+	}
+
+	z.lock.Lock()
+	k := fmt.Sprintf("%s/%s", zone, key.Name)
+	z.tsigfailures[k]++
+	count := z.tsigfailures[k]
+	z.lock.Unlock()
+
+	z.p.G().L.Errorf("%s zone:'%s' tsig key %s failed, trying next key (if any), err:'%s'",
+		id, zone, key.String(), err)
+
+	tags := []string{fmt.Sprintf("zone=%s", zone), fmt.Sprintf("key=%s", key.Name)}
+	z.p.PushMetric(MetricTsigFailures, tags, float64(count))
+}
+
+// Getting TSIG failures count for zone and key
+func (z *ZonesState) GetTsigFailures(zone string, key string) int64 {
+	z.lock.RLock()
+	defer z.lock.RUnlock()
+	return z.tsigfailures[fmt.Sprintf("%s/%s", zone, dns.CanonicalName(key))]
+}
@@ -0,0 +1,263 @@
+package receiver
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestParseBindKeys(t *testing.T) {
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+		content string
+
+		// expected keys as "name algorithm" or error
+		keys []string
+		err  bool
+	}
+
+	var Tests = []TTest{
+		{
+			"6f1d2c3b-4a5e-4d6f-8a7b-9c0d1e2f3a4b",
+			true,
+			`key "transfer.example.net" {
+				algorithm hmac-sha256;
+				secret "c2VjcmV0MQ==";
+			};`,
+			[]string{"transfer.example.net. hmac-sha256."},
+			false,
+		},
+		{
+			"7a2e3d4c-5b6f-4e7a-9b8c-0d1e2f3a4b5c",
+			true,
+			`# rollover keys
+			key old-key { algorithm HMAC-MD5; secret "c2VjcmV0MQ=="; };
+			// the new one
+			key "new-key." {
+				/* sha512 is
+				   preferred */
+				algorithm "hmac-sha512";
+				secret "c2VjcmV0Mg==";
+			};`,
+			[]string{"old-key. hmac-md5.sig-alg.reg.int.", "new-key. hmac-sha512."},
+			false,
+		},
+		{
+			"8b3f4e5d-6c7a-4f8b-8c9d-1e2f3a4b5c6d",
+			true,
+			`key "k1" { algorithm hmac-sha1; secret "c2VjcmV0MQ=="; };
+			 key "k2" { algorithm hmac-sha224; secret "c2VjcmV0MQ=="; };
+			 key "k3" { algorithm hmac-sha384; secret "c2VjcmV0MQ=="; };`,
+			[]string{"k1. hmac-sha1.", "k2. hmac-sha224.", "k3. hmac-sha384."},
+			false,
+		},
+		{
+			"9c4a5f6e-7d8b-4a9c-9d0e-2f3a4b5c6d7e",
+			true,
+			`key "k1" { algorithm hmac-gost; secret "c2VjcmV0MQ=="; };`,
+			nil,
+			true,
+		},
+		{
+			"0d5b6a7f-8e9c-4b0d-8e1f-3a4b5c6d7e8f",
+			true,
+			`key "k1" { algorithm hmac-sha256; secret "c2VjcmV0MQ=="; }`,
+			nil,
+			true,
+		},
+		{
+			"1e6c7b8a-9f0d-4c1e-9f2a-4b5c6d7e8f9a",
+			true,
+			`key "k1" { algorithm hmac-sha256; secret "not base64!"; };`,
+			nil,
+			true,
+		},
+		{
+			"2f7d8c9b-0a1e-4d2f-8a3b-5c6d7e8f9a0b",
+			true,
+			`options { directory "/var/named"; };`,
+			nil,
+			true,
+		},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		keys, err := ParseBindKeys(test.content)
+		if test.err {
+			if err == nil {
+				t.Error("\nUUID", test.uuid, "error expected")
+				continue
+			}
+			fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+			continue
+		}
+
+		if err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error parsing keys, err:'%s'", err))
+			continue
+		}
+
+		var out []string
+		for _, key := range keys {
+			out = append(out, fmt.Sprintf("%s %s", key.Name, key.Algorithm))
+		}
+
+		if strings.Join(out, ",") != strings.Join(test.keys, ",") {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("keys expected:'%s' got:'%s'",
+				strings.Join(test.keys, ","), strings.Join(out, ",")))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
+
+// starting primary serving zone via AXFR signed
+// with TSIG keys
+func NewTestTsigPrimary(t *testing.T, zone string, keys map[string]string) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening, err:'%s'", err)
+	}
+
+	handler := func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+
+		tsig := r.IsTsig()
+		if tsig == nil || w.TsigStatus() != nil {
+			m.Rcode = dns.RcodeNotAuth
+			_ = w.WriteMsg(m)
+			return
+		}
+
+		soa, _ := dns.NewRR(fmt.Sprintf("%s 3600 IN SOA ns1.%s hostmaster.%s 10 3600 600 86400 300",
+			zone, zone, zone))
+		a, _ := dns.NewRR(fmt.Sprintf("www.%s 300 IN A 192.0.2.1", zone))
+		m.Answer = []dns.RR{soa, a, soa}
+		m.SetTsig(tsig.Hdr.Name, tsig.Algorithm, 300, time.Now().Unix())
+		_ = w.WriteMsg(m)
+	}
+
+	server := &dns.Server{Listener: l, TsigSecret: keys,
+		Handler: dns.HandlerFunc(handler)}
+	go func() {
+		_ = server.ActivateAndServe()
+	}()
+
+	return l.Addr().String(), func() { _ = server.Shutdown() }
+}
+
+func TestTsigRollover(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+
+	zone := "example.net."
+	secret := "c2VjcmV0LWtleS1mb3ItdGVzdGluZy10c2lnLTAwMQ=="
+
+	server, shutdown := NewTestTsigPrimary(t, zone, map[string]string{"new-key.": secret})
+	defer shutdown()
+
+	old, _ := NewTsigKey("old-key", "hmac-sha256", "b2xkLXNlY3JldA==")
+	wrong, _ := NewTsigKey("new-key", "hmac-sha256", "d3Jvbmctc2VjcmV0")
+	right, _ := NewTsigKey("new-key", "hmac-sha256", secret)
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		keys []*TTsigKey
+
+		// expected key succeeded, failures count or error
+		key      *TTsigKey
+		failures int
+		err      bool
+	}
+
+	var Tests = []TTest{
+		{
+			"3a8e9d0c-1b2f-4e3a-9b4c-6d7e8f9a0b1c",
+			true,
+			[]*TTsigKey{right},
+			right, 0, false,
+		},
+		{
+			"4b9f0e1d-2c3a-4f4b-8c5d-7e8f9a0b1c2d",
+			true,
+			[]*TTsigKey{old, right},
+			right, 1, false,
+		},
+		{
+			"5c0a1f2e-3d4b-4a5c-9d6e-8f9a0b1c2d3e",
+			true,
+			[]*TTsigKey{wrong, old},
+			nil, 2, true,
+		},
+		{
+			"6d1b2a3f-4e5c-4b6d-8e7f-9a0b1c2d3e4f",
+			true,
+			nil,
+			nil, 0, true,
+		},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		zones := NewZonesState(p)
+		failed := func(key *TTsigKey, err error) {
+			zones.TsigFailure(zone, key, err)
+		}
+
+		var rr []dns.RR
+		transfer := func(key *TTsigKey) error {
+			var err error
+			rr, err = TransferZone(server, zone, &TransferOptions{Mode: TransferModeAXFR, Tsig: key})
+			return err
+		}
+
+		key, err := WithTsigKeys(test.keys, transfer, failed)
+		if test.err != (err != nil) {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error expected:'%t' got:'%v'", test.err, err))
+			continue
+		}
+
+		if key != test.key {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("key expected:'%v' got:'%v'", test.key, key))
+			continue
+		}
+
+		failures := int64(0)
+		for _, k := range test.keys {
+			failures += zones.GetTsigFailures(zone, k.Name)
+		}
+		if failures != int64(test.failures) {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("failures expected:'%d' got:'%d'",
+				test.failures, failures))
+			continue
+		}
+
+		if err == nil && len(rr) != 3 {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("rrs expected:'3' got:'%d'", len(rr)))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
@@ -0,0 +1,410 @@
+package receiver
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// dynamic zones are updated by DNS UPDATE (rfc2136) messages
+// received by notifier dns server: request should be allowed
+// by zone ACL "allow-update" and (or) signed with one of zone
+// TSIG keys, prerequisites are evaluated against the current
+// snapshot, A/AAAA updates are applied to snapshot and maps
+// via IXFR actions, SOA serial is incremented and snapshot is
+// persisted, it is loaded from snapshot file on startup
+
+const (
+	// SOA serial of empty dynamic zone without snapshot
+	DefaultDynamicSerial = 1
+)
+
+// Getting TSIG secrets of all keys loaded to verify
+// requests signed
+func (t *TsigKeyring) Secrets() map[string]string {
+	out := make(map[string]string)
+	if t == nil {
+		return out
+	}
+	for name, key := range t.keys {
+		out[name] = key.Secret
+	}
+	return out
+}
+
+// Checking if dynamic zone update is allowed for source
+// address and TSIG key name verified (empty if request is
+// not signed), zone without ACL and keys is not updated
+func AuthorizeUpdate(config *TConfigZone, source netip.Addr, key string) int {
+	if config == nil || config.Type != TransferTypeDynamic {
+		return dns.RcodeNotAuth
+	}
+
+	if len(config.AllowUpdate) == 0 && len(config.TsigKeys) == 0 {
+		return dns.RcodeRefused
+	}
+
+	if len(config.AllowUpdate) > 0 {
+		allowed := false
+		for _, acl := range config.AllowUpdate {
+			if prefix, err := netip.ParsePrefix(acl); err == nil {
+				allowed = allowed || prefix.Contains(source)
+				continue
+			}
+			if addr, err := netip.ParseAddr(acl); err == nil {
+				allowed = allowed || addr == source
+			}
+		}
+		if !allowed {
+			return dns.RcodeRefused
+		}
+	}
+
+	if len(config.TsigKeys) > 0 {
+		signed := false
+		for _, name := range config.TsigKeys {
+			signed = signed || (len(key) > 0 && dns.CanonicalName(name) == dns.CanonicalName(key))
+		}
+		if !signed {
+			return dns.RcodeNotAuth
+		}
+	}
+
+	return dns.RcodeSuccess
+}
+
+func updateKey(r dns.RR) string {
+	h := r.Header()
+	return fmt.Sprintf("%s-%s", strings.ToLower(h.Name), dns.Type(h.Rrtype).String())
+}
+
+func nameInUse(rrsets map[string][]dns.RR, name string) bool {
+	prefix := fmt.Sprintf("%s-", strings.ToLower(name))
+	for k, rrset := range rrsets {
+		if strings.HasPrefix(k, prefix) && len(rrset) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Evaluating prerequisites section (rfc2136 3.2) against
+// snapshot rrsets
+func checkPrerequisites(zone string, rrsets map[string][]dns.RR, prereq []dns.RR) int {
+	values := make(map[string][]dns.RR)
+
+	for _, r := range prereq {
+		h := r.Header()
+		if !dns.IsSubDomain(zone, strings.ToLower(h.Name)) {
+			return dns.RcodeNotZone
+		}
+
+		switch h.Class {
+		case dns.ClassANY:
+			if h.Rrtype == dns.TypeANY {
+				if !nameInUse(rrsets, h.Name) {
+					return dns.RcodeNameError
+				}
+				continue
+			}
+			if len(rrsets[updateKey(r)]) == 0 {
+				return dns.RcodeNXRrset
+			}
+		case dns.ClassNONE:
+			if h.Rrtype == dns.TypeANY {
+				if nameInUse(rrsets, h.Name) {
+					return dns.RcodeYXDomain
+				}
+				continue
+			}
+			if len(rrsets[updateKey(r)]) > 0 {
+				return dns.RcodeYXRrset
+			}
+		case dns.ClassINET:
+			values[updateKey(r)] = append(values[updateKey(r)], r)
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+
+	// value dependent rrsets should be equal (ttl is
+	// not compared)
+	for k, rrset := range values {
+		current := rrsets[k]
+		if len(current) != len(rrset) {
+			return dns.RcodeNXRrset
+		}
+		for _, r := range rrset {
+			found := false
+			for _, c := range current {
+				found = found || dns.IsDuplicate(r, c)
+			}
+			if !found {
+				return dns.RcodeNXRrset
+			}
+		}
+	}
+
+	return dns.RcodeSuccess
+}
+
+// Checking update section (rfc2136 3.4.1), only A/AAAA
+// records could be updated
+func prescanUpdate(zone string, updates []dns.RR) int {
+	for _, r := range updates {
+		h := r.Header()
+		if !dns.IsSubDomain(zone, strings.ToLower(h.Name)) {
+			return dns.RcodeNotZone
+		}
+
+		switch h.Class {
+		case dns.ClassINET, dns.ClassNONE:
+			if h.Rrtype != dns.TypeA && h.Rrtype != dns.TypeAAAA {
+				return dns.RcodeRefused
+			}
+		case dns.ClassANY:
+			if h.Rrtype != dns.TypeANY && h.Rrtype != dns.TypeA && h.Rrtype != dns.TypeAAAA {
+				return dns.RcodeRefused
+			}
+		default:
+			return dns.RcodeFormatError
+		}
+	}
+	return dns.RcodeSuccess
+}
+
+// Applying update message to snapshot, new snapshot with
+// SOA serial incremented is returned (nil if no changes)
+// with rcode of update
+func UpdateSnapshot(snapshot *TSnapshotZone, r *dns.Msg) (*TSnapshotZone, int) {
+	zone := Dot(strings.ToLower(snapshot.zone))
+
+	if rcode := checkPrerequisites(zone, snapshot.rrsets, r.Answer); rcode != dns.RcodeSuccess {
+		return nil, rcode
+	}
+
+	if rcode := prescanUpdate(zone, r.Ns); rcode != dns.RcodeSuccess {
+		return nil, rcode
+	}
+
+	rrsets := make(map[string][]dns.RR)
+	for k, v := range snapshot.rrsets {
+		rrsets[k] = append([]dns.RR{}, v...)
+	}
+
+	for _, r := range r.Ns {
+		h := r.Header()
+		key := updateKey(r)
+
+		switch h.Class {
+		case dns.ClassINET:
+			q := dns.Copy(r)
+			q.Header().Name = strings.ToLower(h.Name)
+
+			// duplicate record replaces existing one
+			// (ttl could be changed)
+			var rrset []dns.RR
+			for _, c := range rrsets[key] {
+				if !dns.IsDuplicate(c, q) {
+					rrset = append(rrset, c)
+				}
+			}
+			rrsets[key] = append(rrset, q)
+
+		case dns.ClassANY:
+			if h.Rrtype == dns.TypeANY {
+				for _, t := range []uint16{dns.TypeA, dns.TypeAAAA} {
+					delete(rrsets, fmt.Sprintf("%s-%s", strings.ToLower(h.Name), dns.Type(t).String()))
+				}
+				continue
+			}
+			delete(rrsets, key)
+
+		case dns.ClassNONE:
+			// duplicates are compared with class as well
+			q := dns.Copy(r)
+			q.Header().Class = dns.ClassINET
+			q.Header().Name = strings.ToLower(h.Name)
+
+			var rrset []dns.RR
+			for _, c := range rrsets[key] {
+				if !dns.IsDuplicate(c, q) {
+					rrset = append(rrset, c)
+				}
+			}
+			rrsets[key] = rrset
+			if len(rrset) == 0 {
+				delete(rrsets, key)
+			}
+		}
+	}
+
+	var state TZoneState
+	next := *snapshot
+	next.rrsets = rrsets
+	next.timestamp = time.Now()
+
+	changed, err := state.DetectChangedState(snapshot, &next)
+	if err != nil {
+		return nil, dns.RcodeServerFailure
+	}
+	if changed.created+changed.removed == 0 {
+		return nil, dns.RcodeSuccess
+	}
+
+	soa := dns.Copy(snapshot.soa).(*dns.SOA)
+	soa.Serial++
+	next.soa = soa
+
+	next.imports = &TImportActions{mode: TransferModeIXFR, zone: snapshot.zone,
+		actions: changed.AsActions()}
+
+	return &next, dns.RcodeSuccess
+}
+
+// Getting snapshot of dynamic zone: snapshot file is the
+// source of data (regardless of its age), empty zone is
+// created if no snapshot exists
+func (j *ImporterWorker) GetZoneSnapshotDynamic(zone string,
+	options *TZoneSnapshotOptions) (*TSnapshotZone, error) {
+
+	id := "(importer) (dynamic) (snapshot)"
+
+	// dynamic zone is changed only by updates
+	if options.SnapshotMode == SnapshotMemoryExists {
+		return nil, nil
+	}
+
+	filename := GetSnapshotFilename(j.p, zone)
+	if GetFileAge(filename) > 0 {
+		j.p.G().L.Debugf("%s loading dynamic zone:'%s' snapshot:'%s'", id, zone, filename)
+		return NewSnapshotZoneFromFile(j.p, filename, zone)
+	}
+
+	j.p.G().L.Debugf("%s dynamic zone:'%s' has no snapshot, starting empty", id, zone)
+
+	soa := NewSyntheticSOA(zone, DefaultDynamicSerial)
+	return NewSnapshotZoneFromRR(j.p, []dns.RR{soa, soa}, zone)
+}
+
+// Processing update message of dynamic zone, rcode of
+// response is returned
+func (j *NotifierWorker) Update(w dns.ResponseWriter, r *dns.Msg) int {
+	id := "(notifier) (update)"
+
+	if len(r.Question) != 1 || r.Question[0].Qtype != dns.TypeSOA {
+		return dns.RcodeFormatError
+	}
+
+	zone := RemoveDot(strings.ToLower(r.Question[0].Name))
+
+	var source netip.Addr
+	if addr, ok := w.RemoteAddr().(*net.UDPAddr); ok {
+		source = addr.AddrPort().Addr().Unmap()
+	}
+
+	// key name is set only if request is signed and
+	// signature is verified
+	key := ""
+	if t := r.IsTsig(); t != nil {
+		if err := w.TsigStatus(); err != nil {
+			j.p.G().L.Errorf("%s zone:'%s' source:'%s' tsig key:'%s' verification failed, err:'%s'",
+				id, zone, source, t.Hdr.Name, err)
+			return dns.RcodeNotAuth
+		}
+		key = t.Hdr.Name
+	}
+
+	config, err := j.zones.GetConfig(zone)
+	if err != nil {
+		config = nil
+	}
+
+	if rcode := AuthorizeUpdate(config, source, key); rcode != dns.RcodeSuccess {
+		j.p.G().L.Errorf("%s zone:'%s' source:'%s' key:'%s' update is not allowed as '%s'",
+			id, zone, source, key, dns.RcodeToString[rcode])
+		return rcode
+	}
+
+	if pin := j.zones.GetPin(zone); pin != nil {
+		j.p.G().L.Errorf("%s zone:'%s' pinned to generation:'%d', update refused",
+			id, zone, pin.Generation)
+		return dns.RcodeRefused
+	}
+
+	lock := j.zones.ZoneLock(zone)
+	lock.Lock()
+	defer lock.Unlock()
+
+	snapshot := j.zones.GetLastZoneSnapshot(zone)
+	if snapshot == nil || snapshot.soa == nil {
+		j.p.G().L.Errorf("%s zone:'%s' memory snapshot not found", id, zone)
+		return dns.RcodeServerFailure
+	}
+
+	next, rcode := UpdateSnapshot(snapshot, r)
+	if rcode != dns.RcodeSuccess || next == nil {
+		j.p.G().L.Debugf("%s zone:'%s' source:'%s' key:'%s' update finished as '%s' changed:'%t'",
+			id, zone, source, key, dns.RcodeToString[rcode], next != nil)
+		return rcode
+	}
+
+	state, _ := j.zones.GetState(zone)
+	if state.SnapshotCount == 0 {
+		state.SnapshotCount = DefaultSnapshotCount
+	}
+	state.SnapshotID = (state.SnapshotID + 1) % state.SnapshotCount
+	state.Snapshots[state.SnapshotID] = *next
+	state.State = state.DetectState(j.p, next)
+	j.zones.SetState(zone, state)
+
+	serial, _ := next.Serial()
+	next.imports.actions.Dump(j.p)
+
+	dryrun := j.p.L().Cooker.Dryrun
+	cooker, _ := NewCookerWorker(j.p, &TConfigCooker{Dryrun: dryrun}, j.zones)
+
+	result, err := cooker.CookIncrementZone(context.Background(), zone, CookerNoLock)
+	if err != nil {
+		j.p.G().L.Errorf("%s error cooking zone:'%s', err:'%s'", id, zone, err)
+		return dns.RcodeServerFailure
+	}
+
+	if err = next.WriteSnapshotZone(dryrun); err != nil {
+		j.p.G().L.Errorf("%s error writing blob for zone:'%s', err:'%s'", id, zone, err)
+		return dns.RcodeServerFailure
+	}
+
+	j.p.G().L.Debugf("%s zone:'%s' source:'%s' key:'%s' updated serial:'%d' created:'%d' removed:'%d'",
+		id, zone, source, key, serial, result.Created, result.Removed)
+
+	return dns.RcodeSuccess
+}
+
+// Handling update message, response is signed if request
+// is signed with key verified
+func (j *NotifierWorker) HandleUpdate(w dns.ResponseWriter, r *dns.Msg) {
+	id := "(notifier) (update) (handler)"
+
+	m := new(dns.Msg)
+	m.SetReply(r)
+
+	m.SetRcode(r, j.Update(w, r))
+
+	if t := r.IsTsig(); t != nil && w.TsigStatus() == nil {
+		m.SetTsig(t.Hdr.Name, t.Algorithm, 300, time.Now().Unix())
+	}
+
+	if len(r.Question) > 0 {
+		j.p.G().L.Debugf("%s request %s", id, j.ReqString(w, r, m))
+	}
+
+	if err := w.WriteMsg(m); err != nil {
+		j.p.G().L.Debugf("%s error writing response, err:'%s'", id, err)
+	}
+}
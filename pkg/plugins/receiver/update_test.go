@@ -0,0 +1,257 @@
+package receiver
+
+import (
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestAuthorizeUpdate(t *testing.T) {
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		config TConfigZone
+		source string
+		key    string
+
+		rcode int
+	}
+
+	acl := TConfigZone{Type: TransferTypeDynamic, AllowUpdate: []string{"192.0.2.0/24", "2001:db8::1"}}
+	keys := TConfigZone{Type: TransferTypeDynamic, TsigKeys: []string{"update-key"}}
+	both := TConfigZone{Type: TransferTypeDynamic, AllowUpdate: []string{"192.0.2.0/24"},
+		TsigKeys: []string{"update-key"}}
+
+	var Tests = []TTest{
+		{"0b1c2d3e-4f5a-4b6c-8d7e-8f9a0b1c2d3e", true, acl, "192.0.2.10", "", dns.RcodeSuccess},
+		{"1c2d3e4f-5a6b-4c7d-9e8f-9a0b1c2d3e4f", true, acl, "2001:db8::1", "", dns.RcodeSuccess},
+		{"2d3e4f5a-6b7c-4d8e-8f9a-0b1c2d3e4f5a", true, acl, "198.51.100.1", "", dns.RcodeRefused},
+		{"3e4f5a6b-7c8d-4e9f-9a0b-1c2d3e4f5a6b", true, keys, "198.51.100.1", "update-key.", dns.RcodeSuccess},
+		{"4f5a6b7c-8d9e-4f0a-8b1c-2d3e4f5a6b7c", true, keys, "198.51.100.1", "", dns.RcodeNotAuth},
+		{"5a6b7c8d-9e0f-4a1b-9c2d-3e4f5a6b7c8d", true, both, "198.51.100.1", "update-key.", dns.RcodeRefused},
+		{"6b7c8d9e-0f1a-4b2c-8d3e-4f5a6b7c8d9e", true, both, "192.0.2.1", "other-key.", dns.RcodeNotAuth},
+		{"7c8d9e0f-1a2b-4c3d-9e4f-5a6b7c8d9e0f", true, TConfigZone{Type: TransferTypeDynamic}, "192.0.2.1", "", dns.RcodeRefused},
+		{"8d9e0f1a-2b3c-4d4e-8f5a-6b7c8d9e0f1a", true, TConfigZone{Type: TransferTypeAXFR, AllowUpdate: []string{"192.0.2.0/24"}}, "192.0.2.1", "", dns.RcodeNotAuth},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		rcode := AuthorizeUpdate(&test.config, netip.MustParseAddr(test.source), test.key)
+		if rcode != test.rcode {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("rcode expected:'%s' got:'%s'",
+				dns.RcodeToString[test.rcode], dns.RcodeToString[rcode]))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
+
+func TestUpdateSnapshot(t *testing.T) {
+
+	p, err := NewTestReceiverPlugin(t)
+	if err != nil {
+		t.Error(fmt.Sprintf("Error making testing environment, err:'%s'", err))
+		return
+	}
+
+	rr := func(records ...string) []dns.RR {
+		var out []dns.RR
+		for _, s := range records {
+			// rrsets deletion and prerequisites without rdata
+			// "name ttl class type" are not parsed by dns.NewRR
+			if fields := strings.Fields(s); len(fields) == 4 {
+				class := dns.StringToClass[fields[2]]
+				qtype := dns.StringToType[fields[3]]
+				out = append(out, &dns.ANY{Hdr: dns.RR_Header{Name: fields[0],
+					Rrtype: qtype, Class: class}})
+				continue
+			}
+			r, err := dns.NewRR(s)
+			if err != nil {
+				t.Fatalf("error parsing record:'%s', err:'%s'", s, err)
+			}
+			out = append(out, r)
+		}
+		return out
+	}
+
+	soa := NewSyntheticSOA("dyn.example", 10)
+	axfr := []dns.RR{soa}
+	axfr = append(axfr, rr(
+		"www.dyn.example. 300 IN A 192.0.2.1",
+		"api.dyn.example. 300 IN A 192.0.2.2",
+		"api.dyn.example. 300 IN AAAA 2001:db8::2",
+	)...)
+	axfr = append(axfr, soa)
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		prereq  []dns.RR
+		updates []dns.RR
+
+		rcode int
+
+		// expected records as "name/type/address" and
+		// serial (if changed)
+		records []string
+		serial  uint32
+	}
+
+	var Tests = []TTest{
+		{
+			// adding record if name is not in use
+			"9e0f1a2b-3c4d-4e5f-9a6b-7c8d9e0f1a2b",
+			true,
+			rr("new.dyn.example. 0 NONE ANY"),
+			rr("new.dyn.example. 60 IN A 192.0.2.3"),
+			dns.RcodeSuccess,
+			[]string{"api.dyn.example./A/192.0.2.2", "api.dyn.example./AAAA/2001:db8::2",
+				"new.dyn.example./A/192.0.2.3", "www.dyn.example./A/192.0.2.1"},
+			11,
+		},
+		{
+			// name is in use
+			"0f1a2b3c-4d5e-4f6a-8b7c-8d9e0f1a2b3c",
+			true,
+			rr("www.dyn.example. 0 NONE ANY"),
+			rr("www.dyn.example. 60 IN A 192.0.2.3"),
+			dns.RcodeYXDomain,
+			nil, 0,
+		},
+		{
+			// replacing rrset if value matched
+			"1a2b3c4d-5e6f-4a7b-9c8d-9e0f1a2b3c4d",
+			true,
+			rr("www.dyn.example. 0 IN A 192.0.2.1"),
+			rr("www.dyn.example. 0 ANY A", "www.dyn.example. 60 IN A 192.0.2.10"),
+			dns.RcodeSuccess,
+			[]string{"api.dyn.example./A/192.0.2.2", "api.dyn.example./AAAA/2001:db8::2",
+				"www.dyn.example./A/192.0.2.10"},
+			11,
+		},
+		{
+			// value does not match
+			"2b3c4d5e-6f7a-4b8c-8d9e-0f1a2b3c4d5e",
+			true,
+			rr("www.dyn.example. 0 IN A 192.0.2.99"),
+			rr("www.dyn.example. 0 ANY A"),
+			dns.RcodeNXRrset,
+			nil, 0,
+		},
+		{
+			// deleting all rrsets of name and a record
+			"3c4d5e6f-7a8b-4c9d-9e0f-1a2b3c4d5e6f",
+			true,
+			rr("api.dyn.example. 0 ANY AAAA"),
+			rr("api.dyn.example. 0 ANY ANY", "www.dyn.example. 0 NONE A 192.0.2.1"),
+			dns.RcodeSuccess,
+			nil,
+			11,
+		},
+		{
+			// no changes, serial is kept
+			"4d5e6f7a-8b9c-4d0e-8f1a-2b3c4d5e6f7a",
+			true,
+			nil,
+			rr("www.dyn.example. 300 IN A 192.0.2.1"),
+			dns.RcodeSuccess,
+			nil, 0,
+		},
+		{
+			"5e6f7a8b-9c0d-4e1f-9a2b-3c4d5e6f7a8b",
+			true,
+			nil,
+			rr("www.example.org. 60 IN A 192.0.2.1"),
+			dns.RcodeNotZone,
+			nil, 0,
+		},
+		{
+			"6f7a8b9c-0d1e-4f2a-8b3c-4d5e6f7a8b9c",
+			true,
+			nil,
+			rr("www.dyn.example. 60 IN TXT \"text\""),
+			dns.RcodeRefused,
+			nil, 0,
+		},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		snapshot, err := NewSnapshotZoneFromRR(p, axfr, "dyn.example")
+		if err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error making snapshot, err:'%s'", err))
+			continue
+		}
+
+		m := new(dns.Msg)
+		m.SetUpdate("dyn.example.")
+		m.Answer = test.prereq
+		m.Ns = test.updates
+
+		next, rcode := UpdateSnapshot(snapshot, m)
+		if rcode != test.rcode {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("rcode expected:'%s' got:'%s'",
+				dns.RcodeToString[test.rcode], dns.RcodeToString[rcode]))
+			continue
+		}
+
+		if test.serial == 0 {
+			if next != nil {
+				t.Error("\nUUID", test.uuid, "expected snapshot not changed")
+				continue
+			}
+			fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+			continue
+		}
+
+		if serial, _ := next.Serial(); serial != test.serial || next.imports.mode != TransferModeIXFR {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("serial expected:'%d' got:'%d'", test.serial, serial))
+			continue
+		}
+
+		var got []string
+		for _, rrset := range next.rrsets {
+			for _, r := range rrset {
+				address := ""
+				switch v := r.(type) {
+				case *dns.A:
+					address = v.A.String()
+				case *dns.AAAA:
+					address = v.AAAA.String()
+				}
+				got = append(got, fmt.Sprintf("%s/%s/%s", r.Header().Name,
+					dns.Type(r.Header().Rrtype).String(), address))
+			}
+		}
+		sort.Strings(got)
+
+		if strings.Join(got, " ") != strings.Join(test.records, " ") {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("records expected:'%s' got:'%s'",
+				strings.Join(test.records, " "), strings.Join(got, " ")))
+			continue
+		}
+
+		// snapshot updated is not changed
+		if len(snapshot.rrsets) != 3 {
+			t.Error("\nUUID", test.uuid, "original snapshot changed")
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
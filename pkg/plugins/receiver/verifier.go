@@ -2,7 +2,6 @@ package receiver
 
 import (
 	"context"
-	"fmt"
 	"math/rand"
 	"time"
 
@@ -331,7 +330,8 @@ func (j *VerifierWorker) TickVerifier(ctx context.Context) error {
 			counter++
 			j.p.G().L.Debugf("%s request to verify", id)
 
-			options := VerifyOptions{Dryrun: true}
+			// repairs are defined by verifier policy
+			options := VerifyOptions{Dryrun: false}
 			result, err := j.Verify(&options)
 			if err != nil {
 				j.p.G().L.Errorf("%s error verify snapshots and bpf maps, err:'%s'", id, err)
@@ -350,6 +350,10 @@ func (j *VerifierWorker) TickVerifier(ctx context.Context) error {
 
 type VerifyOptions struct {
 	Dryrun bool
+
+	// verify is run on cook, so its repair
+	// policy is used
+	OnCook bool
 }
 
 func (j *VerifierWorker) Verify(options *VerifyOptions) (*TVerifyResult, error) {
@@ -359,31 +363,36 @@ func (j *VerifierWorker) Verify(options *VerifyOptions) (*TVerifyResult, error)
 	t0 := time.Now()
 	j.p.G().L.Debugf("%s request to verify snapshots and bpf maps", id)
 
-	// creating the whole snapshot from zone
-	// current versions
+	// creating the whole snapshot from zone current
+	// versions: records expected are resolved w.r.t
+	// ownership, so keys claimed by more than one
+	// owner are verified as their owner record
 
 	var snapshot TSnapshotZone
 	snapshot.p = j.p
 	snapshot.timestamp = time.Now()
 	snapshot.rrsets = make(map[string][]dns.RR)
 
-	for z, state := range j.zones.zones {
-
-		// getting current snapshot
-		sid := state.SnapshotID
-		if _, ok := state.Snapshots[sid]; !ok {
-			err = fmt.Errorf("zone:'%s' state not found", z)
-			j.p.G().L.Debugf("%s error verify zone:'%s', err:'%s'", id, z, err)
-			return nil, err
-		}
-
-		current := state.Snapshots[sid]
+	expected, owners, err := j.zones.ResolveOwned()
+	if err != nil {
+		j.p.G().L.Debugf("%s error resolving owned records, err:'%s'", id, err)
+		return nil, err
+	}
+	for k, rr := range expected {
+		snapshot.rrsets[k] = []dns.RR{rr}
+	}
 
-		for k, v := range current.rrsets {
-			snapshot.rrsets[k] = append(snapshot.rrsets[k], v...)
+	// manual records are never repaired
+	manual := make(map[string]bool)
+	for k, owner := range owners {
+		if owner == OwnerManual {
+			manual[k] = true
 		}
+	}
 
-		j.p.G().L.Debugf("%s z:'%s' rrsets:'%d' merged", id, z, len(current.rrsets))
+	// policies replace zones rrsets
+	for k, v := range j.zones.PolicyRRsets("") {
+		snapshot.rrsets[k] = v
 	}
 
 	snapshot.Dump(j.p, "axfr", DefaultDumpMaxRRsets)
@@ -396,19 +405,13 @@ func (j *VerifierWorker) Verify(options *VerifyOptions) (*TVerifyResult, error)
 		return nil, err
 	}
 
-	// if we have changes in changed set try to apply them
+	// if we have changes in changed set try to repair
+	// them w.r.t repair policy
 	if changed != nil && changed.created+changed.removed > 0 {
-
-		actions := changed.AsActions()
-		mode := TransferModeIXFR
-
-		var r *TSyncMapResult
-		if r, err = snapshot.SyncMap(mode, actions, options.Dryrun); err != nil {
-			j.p.G().L.Errorf("%s error syncing map, err:'%s'",
-				id, err)
+		if result.Repair, err = j.Repair(&snapshot, changed, manual, options); err != nil {
+			j.p.G().L.Errorf("%s error repairing map, err:'%s'", id, err)
 			return result, err
 		}
-		j.p.G().L.Debugf("%s ixfr sync map '%s'", id, r.AsString())
 	}
 
 	j.p.G().L.Debugf("%s result %s", id, result.AsString())
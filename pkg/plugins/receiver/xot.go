@@ -0,0 +1,137 @@
+package receiver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// zone transfers over TLS (XoT, rfc9103): primaries with
+// "tls://" scheme are requested via TLS connection, the
+// connection opened for SOA request is reused for IXFR
+
+const (
+	// primary scheme for transfers over TLS
+	SchemeTLS = "tls://"
+
+	// default port for dns over TLS
+	DefaultTLSPort = "853"
+
+	// default timeout to dial primary
+	DefaultDialTimeout = 10 * time.Second
+)
+
+type TConfigTLS struct {
+	// CA bundle to verify primary certificate, if
+	// not set system roots are used
+	CAFile string `json:"ca-file" yaml:"ca-file"`
+
+	// client certificate and key for mutual TLS
+	CertFile string `json:"cert-file" yaml:"cert-file"`
+	KeyFile  string `json:"key-file" yaml:"key-file"`
+
+	// authentication domain name used as SNI and to
+	// verify primary certificate, if not set the host
+	// of primary is used
+	ServerName string `json:"server-name" yaml:"server-name"`
+}
+
+// Checking if primary server should be requested over TLS
+func IsTLSPrimary(server string) bool {
+	return strings.HasPrefix(strings.ToLower(server), SchemeTLS)
+}
+
+// Getting address to dial and if TLS should be used, port
+// 853 is used if not set for TLS primaries
+func ParsePrimaryServer(server string) (string, bool) {
+	if !IsTLSPrimary(server) {
+		return server, false
+	}
+
+	address := server[len(SchemeTLS):]
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(strings.Trim(address, "[]"), DefaultTLSPort)
+	}
+
+	return address, true
+}
+
+// Creating TLS client configuration for primary server
+func NewTransferTLS(config *TConfigTLS, server string) (*tls.Config, error) {
+	address, _ := ParsePrimaryServer(server)
+
+	var c tls.Config
+	c.MinVersion = tls.VersionTLS12
+
+	// rfc9103 requires ALPN "dot"
+	c.NextProtos = []string{"dot"}
+
+	c.ServerName = config.ServerName
+	if len(c.ServerName) == 0 {
+		if host, _, err := net.SplitHostPort(address); err == nil {
+			c.ServerName = host
+		}
+	}
+
+	if len(config.CAFile) > 0 {
+		content, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading ca-file:'%s', err:'%s'", config.CAFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(content) {
+			return nil, fmt.Errorf("no certificates found in ca-file:'%s'", config.CAFile)
+		}
+		c.RootCAs = pool
+	}
+
+	if len(config.CertFile) > 0 || len(config.KeyFile) > 0 {
+		cert, err := tls.LoadX509KeyPair(config.CertFile, config.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate:'%s', err:'%s'",
+				config.CertFile, err)
+		}
+		c.Certificates = []tls.Certificate{cert}
+	}
+
+	return &c, nil
+}
+
+// Dialing primary server over TLS, the connection could
+// be used for SOA request and then for transfer
+func DialTransfer(server string, config *tls.Config) (*dns.Conn, error) {
+	address, secure := ParsePrimaryServer(server)
+	if !secure {
+		return dns.DialTimeout("tcp", address, DefaultDialTimeout)
+	}
+
+	if config == nil {
+		var err error
+		if config, err = NewTransferTLS(&TConfigTLS{}, server); err != nil {
+			return nil, err
+		}
+	}
+
+	return dns.DialTimeoutWithTLS("tcp-tls", address, config, DefaultDialTimeout)
+}
+
+// Getting TLS configuration for zone primary: primary
+// (or alias) settings override global ones
+func (z *ZonesState) TransferTLS(primary string, server string) (*tls.Config, error) {
+	if !IsTLSPrimary(server) {
+		return nil, nil
+	}
+
+	config := z.p.L().AxfrTransfer.TLS
+	if c, ok := z.p.L().AxfrTransfer.Zones.PrimaryTLS[primary]; ok {
+		config = c
+	}
+
+	return NewTransferTLS(&config, server)
+}
@@ -0,0 +1,268 @@
+package receiver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// creating self-signed certificate and key, files
+// are written into directory as name.crt and name.key
+func NewTestCertificate(t *testing.T, dir string, name string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating key, err:'%s'", err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		DNSNames:              []string{name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("error creating certificate, err:'%s'", err)
+	}
+
+	keyder, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("error marshaling key, err:'%s'", err)
+	}
+
+	certfile := filepath.Join(dir, fmt.Sprintf("%s.crt", name))
+	keyfile := filepath.Join(dir, fmt.Sprintf("%s.key", name))
+
+	certpem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keypem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyder})
+
+	if err := os.WriteFile(certfile, certpem, 0600); err != nil {
+		t.Fatalf("error writing certificate, err:'%s'", err)
+	}
+	if err := os.WriteFile(keyfile, keypem, 0600); err != nil {
+		t.Fatalf("error writing key, err:'%s'", err)
+	}
+
+	return certfile, keyfile
+}
+
+// counting connections accepted by listener
+type TTestCountingListener struct {
+	net.Listener
+	accepted int32
+}
+
+func (l *TTestCountingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		atomic.AddInt32(&l.accepted, 1)
+	}
+	return conn, err
+}
+
+// starting primary serving SOA and transfers over TLS
+// requiring client certificate
+func NewTestTLSPrimary(t *testing.T, zone string, config *tls.Config) (string, *TTestCountingListener, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error listening, err:'%s'", err)
+	}
+
+	counting := &TTestCountingListener{Listener: l}
+
+	handler := func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Authoritative = true
+
+		soa, _ := dns.NewRR(fmt.Sprintf("%s 3600 IN SOA ns1.%s hostmaster.%s 20 3600 600 86400 300",
+			zone, zone, zone))
+		a, _ := dns.NewRR(fmt.Sprintf("www.%s 300 IN A 192.0.2.1", zone))
+
+		switch r.Question[0].Qtype {
+		case dns.TypeSOA:
+			m.Answer = []dns.RR{soa}
+		default:
+			m.Answer = []dns.RR{soa, a, soa}
+		}
+		_ = w.WriteMsg(m)
+	}
+
+	server := &dns.Server{Listener: tls.NewListener(counting, config), Net: "tcp-tls",
+		Handler: dns.HandlerFunc(handler)}
+	go func() {
+		_ = server.ActivateAndServe()
+	}()
+
+	return fmt.Sprintf("%s%s", SchemeTLS, l.Addr().String()), counting,
+		func() { _ = server.Shutdown() }
+}
+
+func TestParsePrimaryServer(t *testing.T) {
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+		server  string
+		address string
+		secure  bool
+	}
+
+	var Tests = []TTest{
+		{"7e2c3b4a-5f6d-4c7e-8f8a-0b1c2d3e4f5a", true, "[::1]:53", "[::1]:53", false},
+		{"8f3d4c5b-6a7e-4d8f-9a9b-1c2d3e4f5a6b", true, "tls://[::1]:8853", "[::1]:8853", true},
+		{"9a4e5d6c-7b8f-4e9a-8b0c-2d3e4f5a6b7c", true, "tls://[2001:db8::1]", "[2001:db8::1]:853", true},
+		{"0b5f6e7d-8c9a-4f0b-9c1d-3e4f5a6b7c8d", true, "tls://primary.example.net", "primary.example.net:853", true},
+		{"1c6a7f8e-9d0b-4a1c-8d2e-4f5a6b7c8d9e", true, "TLS://192.0.2.1:853", "192.0.2.1:853", true},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		address, secure := ParsePrimaryServer(test.server)
+		if address != test.address || secure != test.secure {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("expected:'%s' '%t' got:'%s' '%t'",
+				test.address, test.secure, address, secure))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
+
+func TestTransferTLS(t *testing.T) {
+
+	dir := t.TempDir()
+	zone := "example.net."
+
+	servercert, serverkey := NewTestCertificate(t, dir, "primary.test")
+	clientcert, clientkey := NewTestCertificate(t, dir, "secondary.test")
+	othercert, otherkey := NewTestCertificate(t, dir, "other.test")
+
+	cert, err := tls.LoadX509KeyPair(servercert, serverkey)
+	if err != nil {
+		t.Fatalf("error loading server certificate, err:'%s'", err)
+	}
+
+	content, _ := os.ReadFile(clientcert)
+	clients := x509.NewCertPool()
+	clients.AppendCertsFromPEM(content)
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    clients,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		NextProtos:   []string{"dot"},
+	}
+
+	server, listener, shutdown := NewTestTLSPrimary(t, zone, config)
+	defer shutdown()
+
+	type TTest struct {
+		uuid    string
+		enabled bool
+
+		config TConfigTLS
+
+		// expected error and accepted connections
+		err         bool
+		connections int32
+	}
+
+	var Tests = []TTest{
+		{
+			"2d7b8a9f-0e1c-4b2d-9e3f-5a6b7c8d9e0f",
+			true,
+			TConfigTLS{CAFile: servercert, CertFile: clientcert, KeyFile: clientkey,
+				ServerName: "primary.test"},
+			false, 1,
+		},
+		{
+			// client certificate is not trusted
+			"3e8c9b0a-1f2d-4c3e-8f4a-6b7c8d9e0f1a",
+			true,
+			TConfigTLS{CAFile: servercert, CertFile: othercert, KeyFile: otherkey,
+				ServerName: "primary.test"},
+			true, 1,
+		},
+		{
+			// authentication name does not match
+			"4f9d0c1b-2a3e-4d4f-9a5b-7c8d9e0f1a2b",
+			true,
+			TConfigTLS{CAFile: servercert, CertFile: clientcert, KeyFile: clientkey,
+				ServerName: "secondary.test"},
+			true, 1,
+		},
+		{
+			// primary certificate is not trusted
+			"5a0e1d2c-3b4f-4e5a-8b6c-8d9e0f1a2b3c",
+			true,
+			TConfigTLS{CAFile: othercert, CertFile: clientcert, KeyFile: clientkey,
+				ServerName: "primary.test"},
+			true, 1,
+		},
+	}
+
+	for _, test := range Tests {
+		if !test.enabled {
+			continue
+		}
+
+		atomic.StoreInt32(&listener.accepted, 0)
+
+		tlsconfig, err := NewTransferTLS(&test.config, server)
+		if err != nil {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error creating tls config, err:'%s'", err))
+			continue
+		}
+
+		// SOA request and IXFR share the same connection
+		var rr []dns.RR
+		opts, err := RequestSOAWithOptions(server, zone, &TransferOptions{TLS: tlsconfig})
+		if err == nil {
+			opts.Mode = TransferModeIXFR
+			opts.Serial = 10
+			rr, err = TransferZone(server, zone, opts)
+		}
+
+		if test.err != (err != nil) {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("error expected:'%t' got:'%v'", test.err, err))
+			continue
+		}
+
+		if err == nil && len(rr) != 3 {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("rrs expected:'3' got:'%d'", len(rr)))
+			continue
+		}
+
+		if accepted := atomic.LoadInt32(&listener.accepted); accepted != test.connections {
+			t.Error("\nUUID", test.uuid, fmt.Sprintf("connections expected:'%d' got:'%d'",
+				test.connections, accepted))
+			continue
+		}
+
+		fmt.Printf("Test:'%s' ... OK\n", test.uuid)
+	}
+}
@@ -3,6 +3,8 @@ package receiver
 import (
 	"encoding/hex"
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -16,10 +18,86 @@ type ZonesState struct {
 	// state of zone updated
 	zones map[string]TZoneState
 
-	// lock for update zone state
-	locks map[string]*sync.Mutex
+	// lock for update zone state and lock of
+	// locks map (zones lock is not taken as zone
+	// lock is kept for the whole zone sync)
+	locks    map[string]*sync.Mutex
+	locksmap sync.Mutex
+
+	// lock for zones states map, zones states
+	// are iterated and changed by many workers
+	states sync.RWMutex
+
+	// zones configurations defined out of main
+	// configuration file, e.g. in zones directory,
+	// each layer is a map of zone configurations
+	layers map[string]map[string]TConfigZone
+
+	// lock for layers configurations
+	lock sync.RWMutex
+
+	// zones transfers status
+	status map[string]*TZoneStatus
+
+	// lock for runtime layer updates
+	overlay sync.Mutex
+
+	// primaries health per zone and primary
+	health map[string]map[string]*TPrimaryHealth
+
+	// named TSIG keys and failures counters
+	// per zone and key name
+	keyring      *TsigKeyring
+	tsigfailures map[string]int64
+
+	// http responses validators per zone and
+	// endpoint for conditional requests
+	validators map[string]THTTPValidators
+
+	// records sources content digests per zone
+	digests map[string]TContentDigest
+
+	// local records overriding zones data
+	overrides *OverrideStore
+
+	// zones updates violating guards
+	quarantine *QuarantineStore
+
+	// zones pinned to snapshot generations
+	pins *PinStore
+
+	// owners of maps entries
+	owners *OwnershipIndex
+
+	// snapshots of zones removed from configuration
+	orphans *OrphansStore
+
+	// write-ahead journal of maps mutations
+	journal *Journal
 }
 
+const (
+	// zones configuration layer of catalog
+	// zones members
+	ZonesLayerCatalog = "catalog"
+
+	// zones configuration layer read from
+	// zones directory yaml files
+	ZonesLayerDirectory = "directory"
+
+	// zones configuration layer managed via api
+	// and persisted in overlay file
+	ZonesLayerRuntime = "runtime"
+
+	// zones defined in main configuration file
+	ZonesLayerConfig = "config"
+)
+
+// zones layers in order of precedence: each next layer
+// overrides zone configuration of previous one and main
+// configuration file
+var ZonesLayers = []string{ZonesLayerCatalog, ZonesLayerDirectory, ZonesLayerRuntime}
+
 const (
 	ZoneStateUnknown = 0
 
@@ -358,13 +436,62 @@ func NewZonesState(p *TReceiverPlugin) *ZonesState {
 	z.p = p
 	z.zones = make(map[string]TZoneState)
 	z.locks = make(map[string]*sync.Mutex)
+	z.layers = make(map[string]map[string]TConfigZone)
+	z.status = make(map[string]*TZoneStatus)
+	z.health = make(map[string]map[string]*TPrimaryHealth)
+	z.tsigfailures = make(map[string]int64)
+	z.validators = make(map[string]THTTPValidators)
+	z.digests = make(map[string]TContentDigest)
+	z.overrides = NewOverrideStore(p, p.L().Options.Overrides)
+	z.quarantine = NewQuarantineStore()
+	z.pins = NewPinStore(p)
+	z.owners = NewOwnershipIndex(p)
+	z.orphans = NewOrphansStore(p)
+	z.journal = NewJournal(p)
+	z.LoadTsigKeys()
 	return &z
 }
 
+// Getting zone state (if any)
+func (z *ZonesState) GetState(zone string) (TZoneState, bool) {
+	z.states.RLock()
+	defer z.states.RUnlock()
+
+	state, ok := z.zones[zone]
+	return state, ok
+}
+
+func (z *ZonesState) SetState(zone string, state TZoneState) {
+	z.states.Lock()
+	defer z.states.Unlock()
+
+	z.zones[zone] = state
+}
+
+func (z *ZonesState) DeleteState(zone string) {
+	z.states.Lock()
+	defer z.states.Unlock()
+
+	delete(z.zones, zone)
+}
+
+// Getting a copy of zones states to iterate over
+// without holding lock
+func (z *ZonesState) States() map[string]TZoneState {
+	z.states.RLock()
+	defer z.states.RUnlock()
+
+	out := make(map[string]TZoneState, len(z.zones))
+	for k, v := range z.zones {
+		out[k] = v
+	}
+	return out
+}
+
 func (z *ZonesState) DetectBlobState() int {
 	id := "(zones) (state)"
 	state := ZoneStateClean
-	for k, s := range z.zones {
+	for k, s := range z.States() {
 		if s.State == ZoneStateDirty {
 			state = ZoneStateDirty
 			z.p.G().L.Debugf("%s zone:'%s' detected as state:'%s'", id, k,
@@ -378,9 +505,8 @@ func (z *ZonesState) CreateBlob() int {
 	id := "(zones) (blob)"
 
 	counter := 0
-	for k := range z.zones {
+	for k, state := range z.States() {
 
-		state := z.zones[k]
 		sid := state.SnapshotID
 
 		if _, ok := state.Snapshots[sid]; !ok {
@@ -414,8 +540,13 @@ func (z *ZonesState) CreateBlob() int {
 			}
 		}
 
-		state.State = ZoneStateClean
-		z.zones[k] = state
+		// zone could be removed while blob is created
+		z.states.Lock()
+		if current, ok := z.zones[k]; ok {
+			current.State = ZoneStateClean
+			z.zones[k] = current
+		}
+		z.states.Unlock()
 	}
 
 	return counter
@@ -452,7 +583,7 @@ func (z *ZonesState) Primary(primary string) string {
 
 func (z *ZonesState) GetLastZoneSnapshot(zone string) *TSnapshotZone {
 
-	state := z.zones[zone]
+	state, _ := z.GetState(zone)
 	sid := state.SnapshotID
 	if _, ok := state.Snapshots[sid]; !ok {
 		return nil
@@ -482,7 +613,7 @@ func (z *ZonesState) RequestUpdate(pool *CollectorTransferPool,
 	state.SnapshotCount = DefaultSnapshotCount
 	state.Snapshots = make(map[int]TSnapshotZone)
 
-	z.zones[zone] = state
+	z.SetState(zone, state)
 
 	mode := TransferModeAXFR
 	if state.Config.Type == "http" {
@@ -495,20 +626,10 @@ func (z *ZonesState) RequestUpdate(pool *CollectorTransferPool,
 }
 
 func (z *ZonesState) GetConfig(zone string) (*TConfigZone, error) {
-	var zones []map[string]TConfigZone
-	if z.p.L().AxfrTransfer.Enabled {
-		zones = append(zones, z.p.L().AxfrTransfer.Zones.Secondary)
-	}
-	if z.p.L().HTTPTransfer.Enabled {
-		zones = append(zones, z.p.L().HTTPTransfer.Zones.Secondary)
-	}
-	for _, config := range zones {
-		for k, v := range config {
-			if k == zone {
-				conf := v
-				return &conf, nil
-			}
-		}
+	configs := z.GetZonesConfigs()
+	if v, ok := configs[zone]; ok {
+		conf := v
+		return &conf, nil
 	}
 	return nil, fmt.Errorf("not found")
 }
@@ -540,9 +661,172 @@ func (z *ZonesState) GetZonesConfigs() map[string]TConfigZone {
 		}
 	}
 
+	// layers configuration overrides main configuration
+	// file zones definitions
+	z.lock.RLock()
+	defer z.lock.RUnlock()
+
+	for _, layer := range ZonesLayers {
+		for k, v := range z.layers[layer] {
+			configs[k] = v
+		}
+	}
+
+	return configs
+}
+
+// Getting the layer zone configuration defined in
+func (z *ZonesState) GetZoneLayer(zone string) string {
+	z.lock.RLock()
+	defer z.lock.RUnlock()
+
+	for i := len(ZonesLayers) - 1; i >= 0; i-- {
+		if _, ok := z.layers[ZonesLayers[i]][zone]; ok {
+			return ZonesLayers[i]
+		}
+	}
+	return ZonesLayerConfig
+}
+
+// Getting a copy of layer zones configurations
+func (z *ZonesState) GetLayerZones(layer string) map[string]TConfigZone {
+	z.lock.RLock()
+	defer z.lock.RUnlock()
+
+	configs := make(map[string]TConfigZone)
+	for k, v := range z.layers[layer] {
+		configs[k] = v
+	}
 	return configs
 }
 
+// Replacing all zones configurations of layer, returning
+// the zones added, changed and removed from the layer
+func (z *ZonesState) SetLayerZones(layer string,
+	configs map[string]TConfigZone) ([]string, []string, []string) {
+
+	z.lock.Lock()
+	defer z.lock.Unlock()
+
+	var added, changed, removed []string
+
+	current := z.layers[layer]
+	for k, v := range configs {
+		c, ok := current[k]
+		if !ok {
+			added = append(added, k)
+			continue
+		}
+		if !reflect.DeepEqual(c, v) {
+			changed = append(changed, k)
+		}
+	}
+
+	for k := range current {
+		if _, ok := configs[k]; !ok {
+			removed = append(removed, k)
+		}
+	}
+
+	layerconfigs := make(map[string]TConfigZone)
+	for k, v := range configs {
+		layerconfigs[k] = v
+	}
+	z.layers[layer] = layerconfigs
+
+	sort.Strings(added)
+	sort.Strings(changed)
+	sort.Strings(removed)
+
+	return added, changed, removed
+}
+
+// Applying zone configuration changes to zones state
+// as zone was reconfigured or removed in some layer
+func (z *ZonesState) ReconfigureZones(changed []string, removed []string) {
+	id := "(zones) (reconfigure)"
+
+	configs := z.GetZonesConfigs()
+
+	// zones removed are purged after grace period
+	// via their snapshots if purge is enabled
+	purge := !z.p.L().Cooker.Snapshots.Purge.Enabled
+
+	for _, zone := range removed {
+		if v, ok := configs[zone]; ok && v.Enabled {
+			// zone is still defined in some other
+			// configuration layer
+			changed = append(changed, zone)
+			continue
+		}
+		if err := z.RemoveZone(zone, purge); err != nil {
+			z.p.G().L.Errorf("%s error removing zone:'%s', err:'%s'", id, zone, err)
+		}
+	}
+
+	for _, zone := range changed {
+		v, ok := configs[zone]
+		if !ok || !v.Enabled {
+			if err := z.RemoveZone(zone, purge); err != nil {
+				z.p.G().L.Errorf("%s error removing zone:'%s', err:'%s'", id, zone, err)
+			}
+			continue
+		}
+
+		lock := z.ZoneLock(zone)
+		lock.Lock()
+		if state, ok := z.GetState(zone); ok {
+			config := v
+			state.Config = &config
+			z.SetState(zone, state)
+			z.p.G().L.Debugf("%s zone:'%s' reconfigured as %s", id, zone, config.String())
+		}
+		lock.Unlock()
+	}
+}
+
+// Getting (or creating) zone lock
+func (z *ZonesState) ZoneLock(zone string) *sync.Mutex {
+	z.locksmap.Lock()
+	defer z.locksmap.Unlock()
+
+	if _, ok := z.locks[zone]; !ok {
+		var lock sync.Mutex
+		z.locks[zone] = &lock
+	}
+	return z.locks[zone]
+}
+
+// Removing zone from zones state, if purge is set all
+// zone records of the last snapshot are removed from
+// bpf maps
+func (z *ZonesState) RemoveZone(zone string, purge bool) error {
+	id := "(zones) (remove)"
+
+	lock := z.ZoneLock(zone)
+	lock.Lock()
+	defer lock.Unlock()
+
+	snapshot := z.GetLastZoneSnapshot(zone)
+	z.DeleteState(zone)
+
+	z.p.G().L.Debugf("%s zone:'%s' removed from zones state, purge:'%t'", id, zone, purge)
+
+	if !purge || snapshot == nil {
+		return nil
+	}
+
+	r, err := snapshot.PurgeMap(z.p.L().Cooker.Dryrun)
+	if err != nil {
+		z.p.G().L.Errorf("%s error purging zone:'%s' records, err:'%s'", id, zone, err)
+		return err
+	}
+
+	z.p.G().L.Debugf("%s zone:'%s' purged records %s", id, zone, r.AsString())
+
+	return nil
+}
+
 type TSnapshotsFilesState struct {
 	Max   int64 `json:"max"`
 	Min   int64 `json:"min"`
@@ -562,7 +846,7 @@ func (z *ZonesState) GetSnapshotsFilesState() (*TSnapshotsFilesState, error) {
 			continue
 		}
 		filename := GetSnapshotFilename(z.p, k)
-		age := int64(GetFileAge(filename))
+		age := int64(SnapshotFileAge(filename))
 		if state.Min > age {
 			state.Min = age
 		}
@@ -592,14 +876,13 @@ func (z *ZonesState) Update(pool *CollectorTransferPool) error {
 		if !v.Enabled {
 			continue
 		}
-		if _, ok := z.zones[k]; !ok {
+		// getting current state of zone, recalculating
+		// SOA timer and pushing it back
+		state, ok := z.GetState(k)
+		if !ok {
 			z.RequestUpdate(pool, k, v)
 			continue
 		}
-
-		// getting current state of zone, recalculating
-		// SOA timer and pushing it back
-		state := z.zones[k]
 		sid := state.SnapshotID
 		if _, ok := state.Snapshots[sid]; !ok {
 			z.RequestUpdate(pool, k, v)
@@ -639,3 +922,136 @@ func (z *ZonesState) Update(pool *CollectorTransferPool) error {
 
 	return err
 }
+
+type TZoneStatus struct {
+	// the last successful transfer time
+	LastTransfer time.Time `json:"last-transfer"`
+
+	// the last transfer error and its time
+	LastError     string    `json:"last-error"`
+	LastErrorTime time.Time `json:"last-error-time"`
+}
+
+// Setting zone transfer status as transfer attempt
+// is finished, err is nil if transfer succeeded
+func (z *ZonesState) SetTransferStatus(zone string, err error) {
+	z.lock.Lock()
+	defer z.lock.Unlock()
+
+	if _, ok := z.status[zone]; !ok {
+		z.status[zone] = new(TZoneStatus)
+	}
+
+	status := z.status[zone]
+	if err != nil {
+		status.LastError = err.Error()
+		status.LastErrorTime = time.Now()
+		return
+	}
+	status.LastTransfer = time.Now()
+}
+
+// Getting a copy of zone transfer status
+func (z *ZonesState) GetTransferStatus(zone string) TZoneStatus {
+	z.lock.RLock()
+	defer z.lock.RUnlock()
+
+	if status, ok := z.status[zone]; ok {
+		return *status
+	}
+	return TZoneStatus{}
+}
+
+const (
+	// zone is configured but has no snapshot yet
+	ZoneInfoStatePending = "pending"
+
+	// zone is configured as disabled
+	ZoneInfoStateDisabled = "disabled"
+)
+
+// zone information exported via api
+type TZoneInfo struct {
+	Zone string `json:"zone"`
+
+	// layer of zone configuration
+	Layer string `json:"layer"`
+
+	// current zone configuration
+	Config TConfigZone `json:"config"`
+
+	// zone state and the last snapshot serial
+	State  string `json:"state"`
+	Serial uint32 `json:"serial"`
+
+	// the last snapshot timestamp
+	Timestamp time.Time `json:"timestamp"`
+
+	// transfer status
+	TZoneStatus
+
+	// number of records in snapshot and number of records
+	// skipped (not placed into bpf maps)
+	Records int `json:"records"`
+	Skipped int `json:"skipped"`
+
+	// zone primaries health in preference order
+	Primaries []TPrimaryHealth `json:"primaries"`
+
+	// snapshot generation zone is pinned to
+	Pinned *TZonePin `json:"pinned,omitempty"`
+}
+
+func (z *ZonesState) GetZoneInfo(zone string) (*TZoneInfo, error) {
+	configs := z.GetZonesConfigs()
+
+	config, ok := configs[zone]
+	if !ok {
+		return nil, fmt.Errorf("zone:'%s' not found", zone)
+	}
+
+	var info TZoneInfo
+	info.Zone = zone
+	info.Layer = z.GetZoneLayer(zone)
+	info.Config = config
+	info.TZoneStatus = z.GetTransferStatus(zone)
+	info.Primaries = z.GetPrimariesHealth(zone, config.Primary)
+	info.Pinned = z.GetPin(zone)
+
+	info.State = ZoneInfoStatePending
+	if !config.Enabled {
+		info.State = ZoneInfoStateDisabled
+	}
+
+	// zone lock is not taken as it is kept by cooker
+	// for the whole sync, states are read via copies
+	if state, ok := z.GetState(zone); ok {
+		info.State = ZoneStateAsString(state.State)
+	}
+
+	if snapshot := z.GetLastZoneSnapshot(zone); snapshot != nil {
+		info.Serial, _ = snapshot.Serial()
+		info.Timestamp = snapshot.timestamp
+		info.Records, info.Skipped = snapshot.Counters()
+	}
+
+	return &info, nil
+}
+
+func (z *ZonesState) GetZonesInfo() []*TZoneInfo {
+	var zones []string
+	for zone := range z.GetZonesConfigs() {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+
+	var out []*TZoneInfo
+	for _, zone := range zones {
+		info, err := z.GetZoneInfo(zone)
+		if err != nil {
+			continue
+		}
+		out = append(out, info)
+	}
+	return out
+}